@@ -108,6 +108,7 @@ func main() {
 
 	route.V1_Me(rApi, ctrller.Project, midware)
 	route.V1_Signatures(rApi, ctrller.Signature, midware)
+	route.V1_SigningKey(rApi, ctrller.Signature, midware)
 	route.V1_Projects(rApi, ctrller.Project, ctrller.ProjectBuilder, ctrller.Certificate, ctrller.File, midware)
 	route.V1_Certificates(rApi, ctrller.Certificate, ctrller.File, midware)
 	route.V1_Auth(rApi, ctrller.Auth)
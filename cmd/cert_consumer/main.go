@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -126,9 +127,9 @@ func certificateGenerateJobHandler(ctx context.Context, jobPayload queue.Certifi
 	}
 	defer cleanupTempFiles(tempSigFiles)
 
-	if len(pageAnnotations.PageSignatureAnnotations) == 0 && len(pageAnnotations.PageColumnAnnotations) == 0 {
+	if project.HTMLTemplateFileID == "" && len(pageAnnotations.PageSignatureAnnotations) == 0 && len(pageAnnotations.PageColumnAnnotations) == 0 && len(pageAnnotations.PageImageAnnotations) == 0 && len(pageAnnotations.PageBarcodeAnnotations) == 0 && len(pageAnnotations.PageQRCodeAnnotations) == 0 {
 		app.Logger.Error("No annotations found for certificate generation")
-		return false, errors.New("at least one signed signature or column annotation is required to generate the certificate")
+		return false, errors.New("at least one signed signature, column, image, barcode or QR code annotation is required to generate the certificate")
 	}
 
 	templatePath, csvPath, err := prepareFiles(ctx, project, app)
@@ -138,6 +139,27 @@ func certificateGenerateJobHandler(ctx context.Context, jobPayload queue.Certifi
 	defer os.Remove(templatePath.Name())
 	defer os.Remove(csvPath.Name())
 
+	imageBundlePath, err := prepareImageBundle(ctx, project, app)
+	if err != nil {
+		return true, err
+	}
+	if imageBundlePath != "" {
+		defer os.Remove(imageBundlePath)
+	}
+
+	htmlTemplatePath, err := prepareHTMLTemplate(ctx, project, app)
+	if err != nil {
+		return true, err
+	}
+	if htmlTemplatePath != "" {
+		defer os.Remove(htmlTemplatePath)
+	}
+
+	signer, shouldRequeue, err := prepareSigner(ctx, project, app)
+	if err != nil {
+		return shouldRequeue, err
+	}
+
 	records, err := autocert.ReadCSVFromFile(csvPath.Name())
 	if err != nil {
 		app.Logger.Error("Failed to read csv file: ", err)
@@ -154,13 +176,19 @@ func certificateGenerateJobHandler(ctx context.Context, jobPayload queue.Certifi
 		return false, fmt.Errorf("csv file exceeds maximum number of certificates: %d", app.Config.APP.MAX_CERTIFICATES_PER_PROJECT)
 	}
 
-	generatedResults, outputDir, generateDuration, totalCert, err := generateCertificates(project, templatePath.Name(), csvPath.Name(), pageAnnotations, app)
+	// The certificates are uploaded and saved while the next ones are generated
+	uploader, err := newCertificateUploader(ctx, project, len(csvData), app)
 	if err != nil {
 		return true, err
 	}
+	outputDir, generateDuration, totalCert, err := generateCertificates(user, project, templatePath.Name(), csvPath.Name(), imageBundlePath, htmlTemplatePath, pageAnnotations, signer, uploader.Enqueue, app)
 	defer os.RemoveAll(outputDir)
+	if err != nil {
+		uploader.Abort()
+		return true, err
+	}
 
-	uploadDuration, err := uploadAndSaveCertificates(ctx, generatedResults, project, app)
+	uploadDuration, err := uploader.Finish()
 	if err != nil {
 		return true, err
 	}
@@ -224,6 +252,9 @@ func prepareAnnotations(ctx context.Context, project *model.Project, app *queue.
 	pageAnnotations := autocert.PageAnnotations{
 		PageSignatureAnnotations: make(map[uint][]autocert.SignatureAnnotate),
 		PageColumnAnnotations:    make(map[uint][]autocert.ColumnAnnotate),
+		PageImageAnnotations:     make(map[uint][]autocert.ImageAnnotate),
+		PageBarcodeAnnotations:   make(map[uint][]autocert.BarcodeAnnotate),
+		PageQRCodeAnnotations:    make(map[uint][]autocert.QRCodeAnnotate),
 	}
 	var tempFiles []string
 
@@ -245,6 +276,34 @@ func prepareAnnotations(ctx context.Context, project *model.Project, app *queue.
 		pageAnnotations.PageColumnAnnotations[column.Page] = append(pageAnnotations.PageColumnAnnotations[column.Page], *column.ToAutoCertColumnAnnotate())
 	}
 
+	for _, image := range project.ImageAnnotates {
+		annotate, err := image.ToAutoCertImageAnnotate(ctx, app.S3)
+		if err != nil {
+			app.Logger.Error("failed to convert image to autocert image annotate: ", err)
+			return pageAnnotations, tempFiles, err
+		}
+		pageAnnotations.PageImageAnnotations[image.Page] = append(pageAnnotations.PageImageAnnotations[image.Page], *annotate)
+		if annotate.ImageFilePath != "" {
+			tempFiles = append(tempFiles, annotate.ImageFilePath)
+		}
+	}
+
+	for _, barcode := range project.BarcodeAnnotates {
+		pageAnnotations.PageBarcodeAnnotations[barcode.Page] = append(pageAnnotations.PageBarcodeAnnotations[barcode.Page], *barcode.ToAutoCertBarcodeAnnotate())
+	}
+
+	for _, qrCode := range project.QRCodeAnnotates {
+		annotate, err := qrCode.ToAutoCertQRCodeAnnotate(ctx, app.S3)
+		if err != nil {
+			app.Logger.Error("failed to convert QR code to autocert QR code annotate: ", err)
+			return pageAnnotations, tempFiles, err
+		}
+		pageAnnotations.PageQRCodeAnnotations[qrCode.Page] = append(pageAnnotations.PageQRCodeAnnotations[qrCode.Page], *annotate)
+		if annotate.LogoFilePath != "" {
+			tempFiles = append(tempFiles, annotate.LogoFilePath)
+		}
+	}
+
 	return pageAnnotations, tempFiles, nil
 }
 
@@ -300,12 +359,122 @@ func prepareFiles(ctx context.Context, project *model.Project, app *queue.Certif
 	return templatePath, csvPath, nil
 }
 
-func generateCertificates(project *model.Project, templatePath, csvPath string, pageAnnotations autocert.PageAnnotations, app *queue.CertificateConsumerContext) ([]autocert.GeneratedResult, string, time.Duration, int, error) {
+// Download the image bundle of the project, return an empty path if the project has none
+func prepareImageBundle(ctx context.Context, project *model.Project, app *queue.CertificateConsumerContext) (string, error) {
+	if project.ImageBundleFileID == "" {
+		return "", nil
+	}
+
+	bundlePath, err := util.CreateTemp("autocert-image-bundle-*.zip")
+	if err != nil {
+		app.Logger.Error("failed to create temp file", err)
+		return "", err
+	}
+	bundlePath.Close()
+
+	if err := project.ImageBundleFile.DownloadToLocal(ctx, app.S3, bundlePath.Name()); err != nil {
+		os.Remove(bundlePath.Name())
+		app.Logger.Error("failed to download image bundle: ", err)
+		return "", err
+	}
+
+	return bundlePath.Name(), nil
+}
+
+// Download the HTML source of the project, return an empty path if the template is not HTML.
+// The downloaded template file is then its preview, which gives the page size.
+func prepareHTMLTemplate(ctx context.Context, project *model.Project, app *queue.CertificateConsumerContext) (string, error) {
+	if project.HTMLTemplateFileID == "" {
+		return "", nil
+	}
+
+	htmlPath, err := util.CreateTemp("autocert-html-template-*.html")
+	if err != nil {
+		app.Logger.Error("failed to create temp file", err)
+		return "", err
+	}
+	htmlPath.Close()
+
+	if err := project.HTMLTemplateFile.DownloadToLocal(ctx, app.S3, htmlPath.Name()); err != nil {
+		os.Remove(htmlPath.Name())
+		app.Logger.Error("failed to download HTML template: ", err)
+		return "", err
+	}
+
+	return htmlPath.Name(), nil
+}
+
+// The local time-stamp authority of B-T signatures, created once so every certificate is time-stamped by the same certificate
+var localTimestampAuthority = sync.OnceValues(func() (*autocert.LocalTimestampAuthority, error) {
+	return autocert.NewSelfSignedTimestampAuthority(config.GetSigningConfig().TSA_NAME)
+})
+
+// Load the key signing the certificates of the project, the one of the owner or else the one of the organisation.
+// Return a nil signer if the project is not signed.
+func prepareSigner(ctx context.Context, project *model.Project, app *queue.CertificateConsumerContext) (*autocert.PdfSigner, bool, error) {
+	level := autocert.SignatureLevel(project.SignatureLevel)
+	if level == autocert.SignatureLevelNone {
+		return nil, false, nil
+	}
+
+	var signer *autocert.PdfSigner
+	signingKey, err := app.Repository.SigningKey.GetByUserId(ctx, nil, project.UserID)
+	switch {
+	case err == nil:
+		pfx, err := signingKey.KeyFile.Download(ctx, app.S3)
+		if err != nil {
+			app.Logger.Error("failed to download signing key: ", err)
+			return nil, true, err
+		}
+		if signer, err = autocert.NewPdfSignerFromPKCS12(pfx, app.Config.Signing.KEY_SECRET); err != nil {
+			app.Logger.Error("failed to decode signing key: ", err)
+			return nil, false, errors.New("invalid signing key")
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound) && app.Config.Signing.PKCS12_PATH != "":
+		pfx, err := os.ReadFile(app.Config.Signing.PKCS12_PATH)
+		if err != nil {
+			app.Logger.Error("failed to read organisation signing key: ", err)
+			return nil, false, errors.New("failed to read organisation signing key")
+		}
+		if signer, err = autocert.NewPdfSignerFromPKCS12(pfx, app.Config.Signing.PKCS12_PASSWORD); err != nil {
+			app.Logger.Error("failed to decode organisation signing key: ", err)
+			return nil, false, errors.New("invalid organisation signing key")
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, false, errors.New("the project owner has no signing key and no organisation key is configured")
+	default:
+		return nil, true, err
+	}
+
+	if time.Now().After(signer.Certificate.NotAfter) {
+		return nil, false, errors.New("certificate of the signing key has expired")
+	}
+
+	signer.Reason = fmt.Sprintf("Certificate of %s", project.Title)
+	if level == autocert.SignatureLevelBT {
+		tsa, err := localTimestampAuthority()
+		if err != nil {
+			app.Logger.Error("failed to create time-stamp authority: ", err)
+			return nil, true, err
+		}
+		signer.Timestamper = tsa
+	}
+
+	return signer, false, nil
+}
+
+// Return the output directory, which is to be removed even on error, the duration and the number of certificates
+func generateCertificates(user *model.User, project *model.Project, templatePath, csvPath, imageBundlePath, htmlTemplatePath string, pageAnnotations autocert.PageAnnotations, signer *autocert.PdfSigner, resultCallback autocert.ResultCallback, app *queue.CertificateConsumerContext) (string, time.Duration, int, error) {
 	cfg := autocert.NewDefaultConfig()
 	settings := autocert.NewDefaultSettings(fmt.Sprintf("%s/share/certificates", app.Config.FRONTEND_URL) + "/%s")
 
 	// if !app.Config.IsProduction() {
 	settings.ProgressCallback = func(progress autocert.ProgressInfo) {
+		if progress.Warning != "" {
+			app.Logger.Warnf("[%s] - Warning: %s \n", project.ID, progress.Warning)
+			return
+		}
+
 		app.Logger.Infof("\r[%s] - [%s] Progress: %d/%d (%.1f%%) | Elapsed: %v | ETA: %v | Time Left: %v \n",
 			project.ID,
 			progress.CurrentPhase,
@@ -321,148 +490,241 @@ func generateCertificates(project *model.Project, templatePath, csvPath string,
 	// }
 
 	settings.EmbedQRCode = project.EmbedQr
+	settings.RasterFormats = project.ToAutoCertRasterFormats()
+	settings.RasterDPI = project.RasterDPI
+	settings.PDFA = project.PDFA
+	settings.Signer = signer
+	// The owner of the project issues the certificates
+	settings.Author = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	settings.TitleTemplate = project.MetadataTitle
+	settings.Title = project.Title
+	settings.SubjectTemplate = project.MetadataSubject
+	encryption, err := project.ToAutoCertPdfEncryption(app.Config.Encryption.PDF_PASSWORD_SECRET)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	settings.Encryption = encryption
+	settings.FileNameTemplate = project.FileNameTemplate
+	settings.ZipGroupByColumn = project.ZipGroupByColumn
+	settings.OutlineTitle = project.OutlineTitle
+	settings.MergedIndex = project.MergedIndex
+	settings.Imposition = project.ToAutoCertImposition()
+	settings.ResultCallback = resultCallback
 	outFilePattern := "certificate_%s"
 	cg := autocert.NewCertificateGenerator(project.ID, templatePath, csvPath, *cfg, pageAnnotations, *settings, outFilePattern)
+	cg.ImageBundlePath = imageBundlePath
+	cg.HTMLTemplatePath = htmlTemplatePath
 
 	startTime := time.Now()
 	generatedResults, err := cg.Generate()
 
 	if err != nil {
 		app.Logger.Errorf("failed to generate certificate: %w", err)
-		return nil, cg.OutputDir(), 0, 0, fmt.Errorf("failed to generate certificate: %w", err)
+		return cg.OutputDir(), 0, 0, fmt.Errorf("failed to generate certificate: %w", err)
 	}
 
 	duration := time.Since(startTime)
-	notNormalCertResult := 0
 
-	if settings.MergeAfterGenerate {
-		notNormalCertResult++
-	}
-	if settings.ZipAfterGenerate {
-		notNormalCertResult++
+	// The merged file, the ZIP, the print sheets and the images are not certificates on their own
+	totalCertCount := 0
+	for _, result := range generatedResults {
+		if result.Type == autocert.CertificateTypeNormal {
+			totalCertCount++
+		}
 	}
 
-	totalCertCount := len(generatedResults) - notNormalCertResult
-
 	app.Logger.Infof("Time taken to generate %d certificates: %v", totalCertCount, duration.Truncate(time.Millisecond))
-	return generatedResults, cg.OutputDir(), duration, totalCertCount, nil
+	return cg.OutputDir(), duration, totalCertCount, nil
 }
 
-func uploadAndSaveCertificates(ctx context.Context, generatedResults []autocert.GeneratedResult, project *model.Project, app *queue.CertificateConsumerContext) (time.Duration, error) {
-	startTime := time.Now()
+// certificateUploader uploads each generated file as soon as the generator passes it to Enqueue and removes it from
+// the disk once it is uploaded. The certificates are saved together with the completed status by Finish in a single
+// transaction, Abort removes what was uploaded.
+type certificateUploader struct {
+	ctx     context.Context
+	project *model.Project
+	app     *queue.CertificateConsumerContext
+
+	tasks   chan autocert.GeneratedResult
+	uploads chan uploadResult
+	workers sync.WaitGroup
+	// Closed once the uploads are collected
+	collected chan struct{}
+	startTime time.Time
+	// Each certificate is saved in it once uploaded, it is committed with the completed status of the project by Finish
+	tx *gorm.DB
+
+	mu       sync.Mutex
+	err      error
+	uploaded []uploadResult
+}
 
-	uploadedFiles, err := uploadFilesWithCleanup(ctx, generatedResults, project, app)
-	if err != nil {
-		return 0, err
+func newCertificateUploader(ctx context.Context, project *model.Project, rowCount int, app *queue.CertificateConsumerContext) (*certificateUploader, error) {
+	tx := app.Repository.DB.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", tx.Error)
 	}
 
-	certificates := make([]*model.Certificate, len(uploadedFiles))
-	for i, result := range uploadedFiles {
-		certificates[i] = &model.Certificate{
-			BaseModel: model.BaseModel{
-				ID: result.certificateID,
-			},
-			Number:    result.certificateNumber,
-			Type:      result.certificateType,
-			ProjectID: project.ID,
-			CertificateFile: model.File{
-				FileName:       util.ToGeneratedCertificateDirectoryPath(project.ID, result.fileInfo.Key),
-				UniqueFileName: result.fileInfo.Key,
-				BucketName:     result.fileInfo.Bucket,
-				Size:           result.fileInfo.Size,
-			},
+	maxUploadWorkers := util.DetermineWorkers(rowCount)
+	app.Logger.Infof("Using %d workers for uploading files", maxUploadWorkers)
+
+	u := &certificateUploader{
+		ctx:     ctx,
+		project: project,
+		app:     app,
+		// Bounded so that the generation waits for the uploads instead of filling the disk
+		tasks:     make(chan autocert.GeneratedResult, maxUploadWorkers),
+		uploads:   make(chan uploadResult, maxUploadWorkers),
+		collected: make(chan struct{}),
+		startTime: time.Now(),
+		tx:        tx,
+	}
+
+	for range maxUploadWorkers {
+		u.workers.Add(1)
+		go u.upload()
+	}
+	go u.collect()
+
+	return u, nil
+}
+
+// Queue the upload of the result, it fails once an upload or a save failed to stop the generation
+func (u *certificateUploader) Enqueue(result autocert.GeneratedResult) error {
+	if err := u.failed(); err != nil {
+		return err
+	}
+	u.tasks <- result
+	return nil
+}
+
+func (u *certificateUploader) upload() {
+	defer u.workers.Done()
+
+	for result := range u.tasks {
+		info, err := util.UploadFileToS3ByPath(result.FilePath, &util.FileUploadOptions{
+			DirectoryPath: util.GetGeneratedCertificateDirectoryPath(u.project.ID),
+			UniquePrefix:  false,
+			Bucket:        u.app.Config.Minio.BUCKET,
+			S3:            u.app.S3,
+		})
+		if err == nil {
+			if err := os.Remove(result.FilePath); err != nil {
+				u.app.Logger.Warnf("Failed to remove uploaded file %s: %v", result.FilePath, err)
+			}
+		}
+
+		u.uploads <- uploadResult{
+			certificateID:     result.ID,
+			certificateNumber: result.Number,
+			certificateType:   result.Type,
+			fileInfo:          info,
+			err:               err,
 		}
 	}
+}
 
-	tx := app.Repository.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+func (u *certificateUploader) collect() {
+	defer close(u.collected)
+
+	for result := range u.uploads {
+		if result.err != nil {
+			u.app.Logger.Errorf("Failed to upload certificate number %d: %v", result.certificateNumber, result.err)
+			u.fail(fmt.Errorf("failed to upload files: %w", result.err))
+			continue
 		}
-	}()
 
-	if _, err := app.Repository.Certificate.CreateMany(ctx, tx, certificates); err != nil {
-		tx.Rollback()
-		cleanupUploadedFiles(ctx, uploadedFiles, app)
-		return 0, fmt.Errorf("failed to create certificates in db: %w", err)
+		u.mu.Lock()
+		u.uploaded = append(u.uploaded, result)
+		u.mu.Unlock()
+
+		// The transaction is rolled back once anything failed
+		if u.failed() != nil {
+			continue
+		}
+		if _, err := u.app.Repository.Certificate.Create(u.ctx, u.tx, u.certificate(result)); err != nil {
+			u.app.Logger.Errorf("Failed to create certificate number %d in db: %v", result.certificateNumber, err)
+			u.fail(fmt.Errorf("failed to create certificate in db: %w", err))
+		}
 	}
+}
 
-	if err := app.Repository.Project.UpdateStatus(ctx, tx, project.ID, constant.ProjectStatusCompleted); err != nil {
-		tx.Rollback()
-		cleanupUploadedFiles(ctx, uploadedFiles, app)
-		return 0, fmt.Errorf("failed to update project status to completed: %w", err)
+func (u *certificateUploader) certificate(result uploadResult) *model.Certificate {
+	return &model.Certificate{
+		BaseModel: model.BaseModel{
+			ID: result.certificateID,
+		},
+		Number:    result.certificateNumber,
+		Type:      result.certificateType,
+		ProjectID: u.project.ID,
+		CertificateFile: model.File{
+			FileName:       util.ToGeneratedCertificateDirectoryPath(u.project.ID, result.fileInfo.Key),
+			UniqueFileName: result.fileInfo.Key,
+			BucketName:     result.fileInfo.Bucket,
+			Size:           result.fileInfo.Size,
+		},
 	}
+}
 
-	if err := tx.Commit().Error; err != nil {
-		cleanupUploadedFiles(ctx, uploadedFiles, app)
-		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+func (u *certificateUploader) fail(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.err == nil {
+		u.err = err
 	}
+}
 
-	duration := time.Since(startTime)
-	app.Logger.Infof("Time taken to upload and save all certificates: %v", duration.Truncate(time.Millisecond))
-	return duration, nil
+func (u *certificateUploader) failed() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.err
 }
 
-func uploadFilesWithCleanup(ctx context.Context, generatedResults []autocert.GeneratedResult, project *model.Project, app *queue.CertificateConsumerContext) ([]uploadResult, error) {
-	maxUploadWorkers := util.DetermineWorkers(len(generatedResults))
-	app.Logger.Infof("Using %d workers for uploading files", maxUploadWorkers)
+// Wait for the queued uploads
+func (u *certificateUploader) wait() error {
+	close(u.tasks)
+	u.workers.Wait()
+	close(u.uploads)
+	<-u.collected
+	return u.failed()
+}
 
-	taskChan := make(chan autocert.GeneratedResult, len(generatedResults))
-	resultChan := make(chan uploadResult, len(generatedResults))
-	var wg sync.WaitGroup
+// Wait for the uploads and their certificates once the generation succeeded, then mark the project as completed and
+// commit. Return the time spent uploading and saving after the generation.
+func (u *certificateUploader) Finish() (time.Duration, error) {
+	generatedAt := time.Now()
 
-	for range maxUploadWorkers {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for result := range taskChan {
-				info, err := util.UploadFileToS3ByPath(result.FilePath, &util.FileUploadOptions{
-					DirectoryPath: util.GetGeneratedCertificateDirectoryPath(project.ID),
-					UniquePrefix:  false,
-					Bucket:        app.Config.Minio.BUCKET,
-					S3:            app.S3,
-				})
-
-				resultChan <- uploadResult{
-					certificateID:     result.ID,
-					certificateNumber: result.Number,
-					certificateType:   result.Type,
-					fileInfo:          info,
-					err:               err,
-				}
-			}
-		}()
+	if err := u.wait(); err != nil {
+		u.rollback()
+		return 0, err
 	}
 
-	for _, gr := range generatedResults {
-		taskChan <- gr
+	if err := u.app.Repository.Project.UpdateStatus(u.ctx, u.tx, u.project.ID, constant.ProjectStatusCompleted); err != nil {
+		u.rollback()
+		return 0, fmt.Errorf("failed to update project status to completed: %w", err)
 	}
-	close(taskChan)
 
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
+	if err := u.tx.Commit().Error; err != nil {
+		cleanupUploadedFiles(u.ctx, u.uploaded, u.app)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
-	var uploadedFiles []uploadResult
-	var uploadErrors []error
+	duration := time.Since(generatedAt)
+	u.app.Logger.Infof("Time taken to upload and save all certificates: %v, of which %v after the generation", time.Since(u.startTime).Truncate(time.Millisecond), duration.Truncate(time.Millisecond))
+	return duration, nil
+}
 
-	for result := range resultChan {
-		if result.err != nil {
-			uploadErrors = append(uploadErrors, result.err)
-		} else {
-			uploadedFiles = append(uploadedFiles, result)
-		}
-	}
+// Wait for the uploads after the generation failed and remove what was uploaded and saved
+func (u *certificateUploader) Abort() {
+	u.wait()
+	u.rollback()
+}
 
-	// Remove the uploaded files if there were any errors
-	if len(uploadErrors) > 0 {
-		app.Logger.Errorf("Failed to upload %d files: %v", len(uploadErrors), uploadErrors[0])
-		cleanupUploadedFiles(ctx, uploadedFiles, app)
-		return nil, fmt.Errorf("failed to upload %d files: %v", len(uploadErrors), uploadErrors[0])
+func (u *certificateUploader) rollback() {
+	if err := u.tx.Rollback().Error; err != nil {
+		u.app.Logger.Errorf("Failed to rollback certificates of project %s: %v", u.project.ID, err)
 	}
-
-	return uploadedFiles, nil
+	cleanupUploadedFiles(u.ctx, u.uploaded, u.app)
 }
 
 func cleanupUploadedFiles(ctx context.Context, uploadedFiles []uploadResult, app *queue.CertificateConsumerContext) {
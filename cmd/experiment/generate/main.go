@@ -166,6 +166,7 @@ func main() {
 	settings := autocert.Settings{
 		RemoveLineBreaksBool: true,
 		EmbedQRCode:          true,
+		QrURLPattern:         "http://localhost:3000/share/certificates/%s",
 	}
 
 	// Create a CertificateGenerator.
@@ -14,7 +14,7 @@ func main() {
 		Size:   12,
 		Color:  "#000000",
 		Weight: autocert.FontWeightRegular,
-	}, autocert.Settings{
+	}, autocert.Wrap{}, autocert.Typography{}, autocert.Settings{
 		RemoveLineBreaksBool: false,
 	})
 	if err != nil {
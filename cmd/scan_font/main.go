@@ -21,7 +21,29 @@ func main() {
 		log.Fatalf("Failed to scan font directory: %v", err)
 	}
 
-	data, err := json.MarshalIndent(fonts, "", "  ")
+	// Keep the fallback list of the existing file, only the font list comes from the scan
+	var fallbacks []string
+	// Keep the name of fonts already in the existing file such that saved annotations still find their font
+	names := make(map[string]string)
+	if existing, err := autocert.GetFontConfig(outputFile); err == nil {
+		fallbacks = existing.Fallbacks
+		for _, font := range existing.Fonts {
+			names[font.Path] = font.Name
+		}
+	}
+
+	fontConfig := autocert.FontConfig{
+		Fonts:     make([]*autocert.FontMetadata, len(fonts)),
+		Fallbacks: fallbacks,
+	}
+	for i := range fonts {
+		if name, ok := names[fonts[i].Path]; ok {
+			fonts[i].Name = name
+		}
+		fontConfig.Fonts[i] = &fonts[i]
+	}
+
+	data, err := json.MarshalIndent(fontConfig, "", "  ")
 	if err != nil {
 		log.Fatalf("Failed to marshal JSON: %v", err)
 	}
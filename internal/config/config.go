@@ -18,6 +18,8 @@ type Config struct {
 	Auth         AuthConfig
 	Minio        MinioConfig
 	RabbitMQ     RabbitMQConfig
+	Signing      SigningConfig
+	Encryption   EncryptionConfig
 }
 
 type APPConfig struct {
@@ -79,6 +81,22 @@ type RabbitMQConfig struct {
 	VHost    string
 }
 
+type SigningConfig struct {
+	// Protects the PKCS#12 keys uploaded by project owners in storage, uploading keys is disabled when empty
+	KEY_SECRET string
+	// PKCS#12 key of the organisation, used when the project owner has not uploaded one. Leave empty to require one.
+	PKCS12_PATH     string
+	PKCS12_PASSWORD string
+	// Name of the local time-stamp authority of B-T signatures
+	TSA_NAME string
+}
+
+type EncryptionConfig struct {
+	// Protects the PDF owner passwords of the projects in storage, owner passwords and so the per-row user passwords
+	// are disabled when empty
+	PDF_PASSWORD_SECRET string
+}
+
 func (c Config) IsProduction() bool {
 	return strings.EqualFold(c.ENV, "production")
 }
@@ -166,6 +184,21 @@ func GetAuthConfig() AuthConfig {
 	}
 }
 
+func GetSigningConfig() SigningConfig {
+	return SigningConfig{
+		KEY_SECRET:      env.GetString("SIGNING_KEY_SECRET", ""),
+		PKCS12_PATH:     env.GetString("SIGNING_PKCS12_PATH", ""),
+		PKCS12_PASSWORD: env.GetString("SIGNING_PKCS12_PASSWORD", ""),
+		TSA_NAME:        env.GetString("SIGNING_TSA_NAME", "AutoCert Time-Stamp Authority"),
+	}
+}
+
+func GetEncryptionConfig() EncryptionConfig {
+	return EncryptionConfig{
+		PDF_PASSWORD_SECRET: env.GetString("PDF_PASSWORD_SECRET", ""),
+	}
+}
+
 func GetEnvironment() string {
 	return env.GetString("ENV", "development")
 }
@@ -182,5 +215,7 @@ func GetConfig() Config {
 		Auth:         GetAuthConfig(),
 		Minio:        GetMinioConfig(),
 		RabbitMQ:     GetRabbitMQConfig(),
+		Signing:      GetSigningConfig(),
+		Encryption:   GetEncryptionConfig(),
 	}
 }
@@ -21,6 +21,15 @@ const (
 	AnnotateSignatureInvite  ProjectPermission = "annotate:signature:invite"
 	AnnotateSignatureApprove ProjectPermission = "annotate:signature:approve"
 	AnnotateSignatureReject  ProjectPermission = "annotate:signature:reject"
+	AnnotateImageAdd         ProjectPermission = "annotate:image:add"
+	AnnotateImageUpdate      ProjectPermission = "annotate:image:update"
+	AnnotateImageRemove      ProjectPermission = "annotate:image:remove"
+	AnnotateBarcodeAdd       ProjectPermission = "annotate:barcode:add"
+	AnnotateBarcodeUpdate    ProjectPermission = "annotate:barcode:update"
+	AnnotateBarcodeRemove    ProjectPermission = "annotate:barcode:remove"
+	AnnotateQRCodeAdd        ProjectPermission = "annotate:qrcode:add"
+	AnnotateQRCodeUpdate     ProjectPermission = "annotate:qrcode:update"
+	AnnotateQRCodeRemove     ProjectPermission = "annotate:qrcode:remove"
 	SettingsUpdate           ProjectPermission = "settings:update"
 	TableUpdate              ProjectPermission = "table:update"
 )
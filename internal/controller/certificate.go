@@ -68,6 +68,7 @@ func (cc CertificateController) GetCertificatesByProjectId(ctx *gin.Context) {
 		Certificates         []Certificate                 `json:"certificates"`
 		CertificateMergedUrl string                        `json:"certificateMergedUrl,omitempty"`
 		CertificateZipUrl    string                        `json:"certificateZipUrl,omitempty"`
+		CertificatePrintUrl  string                        `json:"certificatePrintUrl,omitempty"`
 	}
 
 	type GetCertificatesByProjectIdResponse struct {
@@ -105,7 +106,7 @@ func (cc CertificateController) GetCertificatesByProjectId(ctx *gin.Context) {
 		return
 	}
 
-	certificates, certificateMerged, certificateZip, totalCertificates, err := cc.app.Repository.Certificate.GetByProjectId(ctx, nil, projectId, params.Page, params.PageSize)
+	certificates, certificateMerged, certificateZip, certificateImposed, totalCertificates, err := cc.app.Repository.Certificate.GetByProjectId(ctx, nil, projectId, params.Page, params.PageSize)
 	if err != nil {
 		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get certificates", util.GenerateErrorMessages(err), nil)
 		return
@@ -135,7 +136,7 @@ func (cc CertificateController) GetCertificatesByProjectId(ctx *gin.Context) {
 		signatories = []repository.ProjectSignatory{}
 	}
 
-	var certMergedUrl, certZipUrl string
+	var certMergedUrl, certZipUrl, certPrintUrl string
 	certificateList := make([]Certificate, 0, len(*certificates))
 
 	for _, ca := range *certificates {
@@ -147,7 +148,7 @@ func (cc CertificateController) GetCertificatesByProjectId(ctx *gin.Context) {
 				CreatedAt: ca.CreatedAt.String(),
 			}
 			if ca.CertificateFileId != "" {
-				url, err := ca.CertificateFile.ToPresignedUrl(ctx, cc.app.S3)
+				url, err := ca.CertificateFile.ToPresignedDownloadUrl(ctx, cc.app.S3, "inline")
 				if err != nil {
 					util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get presigned URL for certificate", util.GenerateErrorMessages(err), nil)
 					return
@@ -159,7 +160,7 @@ func (cc CertificateController) GetCertificatesByProjectId(ctx *gin.Context) {
 	}
 
 	if certificateMerged != nil && certificateMerged.CertificateFileId != "" {
-		certMergedUrl, err = certificateMerged.CertificateFile.ToPresignedUrl(ctx, cc.app.S3)
+		certMergedUrl, err = certificateMerged.CertificateFile.ToPresignedDownloadUrl(ctx, cc.app.S3, "inline")
 		if err != nil {
 			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get presigned URL for merged certificate", util.GenerateErrorMessages(err), nil)
 			return
@@ -167,13 +168,21 @@ func (cc CertificateController) GetCertificatesByProjectId(ctx *gin.Context) {
 	}
 
 	if certificateZip != nil && certificateZip.CertificateFileId != "" {
-		certZipUrl, err = certificateZip.CertificateFile.ToPresignedUrl(ctx, cc.app.S3)
+		certZipUrl, err = certificateZip.CertificateFile.ToPresignedDownloadUrl(ctx, cc.app.S3, "attachment")
 		if err != nil {
 			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get presigned URL for certificate zip", util.GenerateErrorMessages(err), nil)
 			return
 		}
 	}
 
+	if certificateImposed != nil && certificateImposed.CertificateFileId != "" {
+		certPrintUrl, err = certificateImposed.CertificateFile.ToPresignedDownloadUrl(ctx, cc.app.S3, "attachment")
+		if err != nil {
+			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get presigned URL for print certificate", util.GenerateErrorMessages(err), nil)
+			return
+		}
+	}
+
 	logList := make([]ProjectLog, len(logs))
 	for i, l := range logs {
 		logList[i] = ProjectLog{
@@ -198,6 +207,7 @@ func (cc CertificateController) GetCertificatesByProjectId(ctx *gin.Context) {
 			Signatories:          signatories,
 			CertificateMergedUrl: certMergedUrl,
 			CertificateZipUrl:    certZipUrl,
+			CertificatePrintUrl:  certPrintUrl,
 		},
 		Page:     params.Page,
 		PageSize: params.PageSize,
@@ -238,15 +248,56 @@ func (cc CertificateController) GetGeneratedCertificateById(ctx *gin.Context) {
 		return
 	}
 
-	url, err := certificate.CertificateFile.ToPresignedUrl(ctx, cc.app.S3)
+	url, err := certificate.CertificateFile.ToPresignedDownloadUrl(ctx, cc.app.S3, "inline")
 	if err != nil {
 		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get presigned URL for certificate", util.GenerateErrorMessages(err), nil)
 		return
 	}
 
+	// Images exported next to the PDF, by format
+	imageUrls := map[autocert.RasterFormat]string{}
+	if certificate.Type == autocert.CertificateTypeNormal {
+		images, err := cc.app.Repository.Certificate.GetImagesByNumber(ctx, nil, certificate.ProjectID, certificate.Number)
+		if err != nil {
+			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get certificate images", util.GenerateErrorMessages(err), nil)
+			return
+		}
+
+		for _, img := range images {
+			format, ok := autocert.RasterFormatOf(img.Type)
+			if !ok || img.CertificateFileId == "" {
+				continue
+			}
+
+			imageUrl, err := img.CertificateFile.ToPresignedDownloadUrl(ctx, cc.app.S3, "inline")
+			if err != nil {
+				util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get presigned URL for certificate image", util.GenerateErrorMessages(err), nil)
+				return
+			}
+			imageUrls[format] = imageUrl
+		}
+	}
+
+	// Digital signature of the certificate, nil when it is not signed
+	var signature *autocert.SignatureVerification
+	if certificate.Type == autocert.CertificateTypeNormal {
+		pdf, err := certificate.CertificateFile.Download(ctx, cc.app.S3)
+		if err != nil {
+			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to download certificate", util.GenerateErrorMessages(err), nil)
+			return
+		}
+
+		signature, err = autocert.VerifyPdfSignature(pdf)
+		if err != nil && !errors.Is(err, autocert.ErrPdfNotSigned) {
+			cc.app.Logger.Errorf("Failed to verify signature of certificate %s: %v", certificate.ID, err)
+		}
+	}
+
 	util.ResponseSuccess(ctx, gin.H{
 		"id":             certificate.ID,
 		"certificateUrl": url,
+		"imageUrls":      imageUrls,
+		"signature":      signature,
 		"number":         certificate.Number,
 		"issuer":         certificate.Project.User.LastName,
 		"issuedAt":       certificate.CreatedAt.String(),
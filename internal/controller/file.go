@@ -23,8 +23,8 @@ func CacheRequest(ctx *gin.Context, time time.Duration) {
 	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(time.Seconds())))
 }
 
+// Serve the thumbnail of a page of the project template, the page is the query "page" and defaults to 1
 func (fc FileController) ServeProjectThumbnail(ctx *gin.Context) {
-	selectedPages := "1"
 	projectId := ctx.Params.ByName("projectId")
 	if projectId == "" {
 		util.ResponseFailed(ctx, http.StatusBadRequest, "Project ID is required", util.GenerateErrorMessages(errors.New(ErrProjectIdRequired), "projectId"), nil)
@@ -52,6 +52,13 @@ func (fc FileController) ServeProjectThumbnail(ctx *gin.Context) {
 		return
 	}
 
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 || page > project.PageCount {
+		util.ResponseFailed(ctx, http.StatusBadRequest, "Invalid page number", util.GenerateErrorMessages(fmt.Errorf(ErrInvalidPageNumber, project.PageCount, page), "page"), nil)
+		return
+	}
+	selectedPages := strconv.Itoa(page)
+
 	tempOutDir, err := util.MkdirTemp("autocert_pdf_thumbnail_*")
 	if err != nil {
 		util.ResponseFailed(ctx, http.StatusInternalServerError, "Error creating temporary directory", util.GenerateErrorMessages(err), nil)
@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/SeakMengs/AutoCert/internal/constant"
@@ -30,12 +32,18 @@ const (
 	ErrTemplateFileIsInvalidOrNotSupported  = "template file is invalid or not supported"
 	ErrFailedToGetPageCountFromTemplateFile = "failed to get page count from template file"
 	ErrInvalidPageNumber                    = "page number must be between 1 and %d for the provided template, but got %d"
+	ErrTemplateFileHasNoPage                = "template file has no page"
 )
 
 func (pc ProjectController) CreateProject(ctx *gin.Context) {
 	type Request struct {
 		Title string `json:"title" form:"title" binding:"required,strNotEmpty,min=1,max=100"`
-		Page  int    `json:"page" form:"page" binding:"required,number,gte=1"`
+		// Page of an image, SVG or HTML template, ignored for a PDF template
+		PaperSize   string  `json:"paperSize" form:"paperSize"`
+		Orientation string  `json:"orientation" form:"orientation"`
+		Width       float64 `json:"width" form:"width"`
+		Height      float64 `json:"height" form:"height"`
+		DPI         float64 `json:"dpi" form:"dpi"`
 	}
 	var body Request
 
@@ -80,8 +88,33 @@ func (pc ProjectController) CreateProject(ctx *gin.Context) {
 	}
 	defer os.Remove(tempFile.Name())
 
-	// Optimize also validate the file
-	err = autocert.OptimizePdf(*file, tempFile.Name())
+	templateFileName := file.Filename
+	isHTMLTemplate := autocert.IsHTMLTemplate(file.Filename)
+	if autocert.IsImageTemplate(file.Filename) || isHTMLTemplate {
+		opts := autocert.TemplateOptions{
+			PaperSize:   autocert.PaperSize(body.PaperSize),
+			Orientation: autocert.Orientation(body.Orientation),
+			Width:       body.Width,
+			Height:      body.Height,
+			DPI:         body.DPI,
+		}
+		if err := opts.Validate(); err != nil {
+			util.ResponseFailed(ctx, http.StatusBadRequest, "Invalid template page", util.GenerateErrorMessages(err, "paperSize"), nil)
+			return
+		}
+
+		if isHTMLTemplate {
+			// The HTML is rendered per row at generation, the PDF is its preview used by the builder and thumbnails
+			err = autocert.HTMLTemplateToPdf(*file, tempFile.Name(), opts)
+		} else {
+			// Image and SVG templates become a single page PDF, such that the rest of the pipeline stays PDF based
+			err = autocert.ImageTemplateToPdf(*file, tempFile.Name(), opts)
+		}
+		templateFileName = strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename)) + ".pdf"
+	} else {
+		// Optimize also validate the file
+		err = autocert.OptimizePdf(*file, tempFile.Name())
+	}
 	if err != nil {
 		pc.app.Logger.Error(err)
 		util.ResponseFailed(ctx, http.StatusBadRequest, "Invalid template file", util.GenerateErrorMessages(errors.New(ErrTemplateFileIsInvalidOrNotSupported), "templateFile"), nil)
@@ -96,37 +129,21 @@ func (pc ProjectController) CreateProject(ctx *gin.Context) {
 	}
 	defer src.Close()
 
-	outDir, err := util.MkdirTemp("extracted-")
-	if err != nil {
-		pc.app.Logger.Error(err)
-		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to create temp directory", util.GenerateErrorMessages(err), nil)
-		return
-	}
-	defer os.RemoveAll(outDir)
-
 	pageCount, err := autocert.GetPageCount(src)
 	if err != nil {
 		pc.app.Logger.Error(err)
 		util.ResponseFailed(ctx, http.StatusBadRequest, "Invalid template file", util.GenerateErrorMessages(errors.New(ErrFailedToGetPageCountFromTemplateFile), "templateFile"), nil)
 		return
 	}
-	if body.Page > 0 && body.Page > int(pageCount) {
-		pc.app.Logger.Error(err)
-		util.ResponseFailed(ctx, http.StatusBadRequest, "Invalid page number", util.GenerateErrorMessages(fmt.Errorf(ErrInvalidPageNumber, pageCount, body.Page), "templateFile"), nil)
-		return
-	}
-
-	// extract the pdf file with selected page, will be removed after function end
-	finalPdf, err := autocert.ExtractPdfByPage(src.Name(), outDir, fmt.Sprintf("%d", body.Page))
-	if err != nil || finalPdf == "" {
-		pc.app.Logger.Error(err)
-		util.ResponseFailed(ctx, http.StatusBadRequest, "Invalid template file", util.GenerateErrorMessages(errors.New(ErrTemplateFileIsInvalidOrNotSupported), "templateFile"), nil)
+	if pageCount < 1 {
+		util.ResponseFailed(ctx, http.StatusBadRequest, "Invalid template file", util.GenerateErrorMessages(errors.New(ErrTemplateFileHasNoPage), "templateFile"), nil)
 		return
 	}
 
 	newProjectId := uuid.NewString()
 
-	info, err := util.UploadFileToS3ByPath(finalPdf, &util.FileUploadOptions{
+	// Every page of the template is kept, annotates can be placed on any of them
+	info, err := util.UploadFileToS3ByPath(tempFile.Name(), &util.FileUploadOptions{
 		DirectoryPath: util.GetProjectDirectoryPath(newProjectId),
 		UniquePrefix:  true,
 		Bucket:        pc.app.Config.Minio.BUCKET,
@@ -136,6 +153,32 @@ func (pc ProjectController) CreateProject(ctx *gin.Context) {
 		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to upload file", util.GenerateErrorMessages(err), nil)
 		return
 	}
+	uploadedFiles := []minio.UploadInfo{info}
+
+	var htmlTemplateFile model.File
+	if isHTMLTemplate {
+		htmlInfo, err := util.UploadFileToS3ByFileHeader(file, &util.FileUploadOptions{
+			DirectoryPath: util.GetProjectDirectoryPath(newProjectId),
+			UniquePrefix:  true,
+			Bucket:        pc.app.Config.Minio.BUCKET,
+			S3:            pc.app.S3,
+		})
+		if err != nil {
+			if err := pc.app.S3.RemoveObject(ctx, info.Bucket, info.Key, minio.RemoveObjectOptions{}); err != nil {
+				pc.app.Logger.Errorf("Failed to delete file: %v", err)
+			}
+			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to upload file", util.GenerateErrorMessages(err), nil)
+			return
+		}
+		uploadedFiles = append(uploadedFiles, htmlInfo)
+
+		htmlTemplateFile = model.File{
+			FileName:       util.ToProjectDirectoryPath(newProjectId, file.Filename),
+			UniqueFileName: htmlInfo.Key,
+			BucketName:     htmlInfo.Bucket,
+			Size:           htmlInfo.Size,
+		}
+	}
 
 	tx := pc.app.Repository.DB.Begin()
 	defer tx.Commit()
@@ -151,22 +194,26 @@ func (pc ProjectController) CreateProject(ctx *gin.Context) {
 		BaseModel: model.BaseModel{
 			ID: newProjectId,
 		},
-		Title:  body.Title,
-		UserID: user.ID,
+		Title:     body.Title,
+		UserID:    user.ID,
+		PageCount: pageCount,
 		TemplateFile: model.File{
-			FileName:       util.ToProjectDirectoryPath(newProjectId, file.Filename),
+			FileName:       util.ToProjectDirectoryPath(newProjectId, templateFileName),
 			UniqueFileName: info.Key,
 			BucketName:     info.Bucket,
 			Size:           info.Size,
 		},
+		HTMLTemplateFile: htmlTemplateFile,
 	})
 	if err != nil {
-		// delete the file from s3 if project creation failed
-		if err := pc.app.S3.RemoveObject(ctx, info.Bucket, info.Key, minio.RemoveObjectOptions{}); err != nil {
-			pc.app.Logger.Errorf("Failed to delete file: %v", err)
-			tx.Rollback()
-			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to delete file", util.GenerateErrorMessages(err), nil)
-			return
+		// delete the files from s3 if project creation failed
+		for _, uploaded := range uploadedFiles {
+			if err := pc.app.S3.RemoveObject(ctx, uploaded.Bucket, uploaded.Key, minio.RemoveObjectOptions{}); err != nil {
+				pc.app.Logger.Errorf("Failed to delete file: %v", err)
+				tx.Rollback()
+				util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to delete file", util.GenerateErrorMessages(err), nil)
+				return
+			}
 		}
 
 		tx.Rollback()
@@ -190,17 +237,56 @@ func (pc ProjectController) GetProjectById(ctx *gin.Context) {
 		SignatureUrl string `json:"signatureUrl"`
 	}
 
+	type ImageAnnotate struct {
+		model.ImageAnnotate
+		ImageUrl string `json:"imageUrl"`
+	}
+
+	type QRCodeAnnotate struct {
+		model.QRCodeAnnotate
+		LogoUrl string `json:"logoUrl"`
+	}
+
 	type ProjectById struct {
-		ID                 string                 `json:"id"`
-		Title              string                 `json:"title"`
-		IsPublic           bool                   `json:"isPublic"`
-		Status             constant.ProjectStatus `json:"status"`
-		EmbedQr            bool                   `json:"embedQr"`
-		TemplateUrl        string                 `json:"templateUrl"`
-		CSVFileUrl         string                 `json:"csvFileUrl"`
-		MaxCertificate     int                    `json:"maxCertificate"`
-		ColumnAnnotates    []model.ColumnAnnotate `json:"columnAnnotates"`
-		SignatureAnnotates []SignatureAnnotate    `json:"signatureAnnotates"`
+		ID                    string                  `json:"id"`
+		Title                 string                  `json:"title"`
+		IsPublic              bool                    `json:"isPublic"`
+		Status                constant.ProjectStatus  `json:"status"`
+		EmbedQr               bool                    `json:"embedQr"`
+		RasterFormats         model.StringArray       `json:"rasterFormats"`
+		RasterDPI             float64                 `json:"rasterDpi"`
+		PDFA                  bool                    `json:"pdfa"`
+		SignatureLevel        string                  `json:"signatureLevel"`
+		MetadataTitle         string                  `json:"metadataTitle"`
+		MetadataSubject       string                  `json:"metadataSubject"`
+		EncryptPdf            bool                    `json:"encryptPdf"`
+		HasPdfOwnerPassword   bool                    `json:"hasPdfOwnerPassword"`
+		PdfPermissions        model.StringArray       `json:"pdfPermissions"`
+		PdfUserPasswordColumn string                  `json:"pdfUserPasswordColumn"`
+		FileNameTemplate      string                  `json:"fileNameTemplate"`
+		ZipGroupByColumn      string                  `json:"zipGroupByColumn"`
+		OutlineTitle          string                  `json:"outlineTitle"`
+		MergedIndex           bool                    `json:"mergedIndex"`
+		ImposePdf             bool                    `json:"imposePdf"`
+		ImposeSheet           string                  `json:"imposeSheet"`
+		ImposeSheetWidth      float64                 `json:"imposeSheetWidth"`
+		ImposeSheetHeight     float64                 `json:"imposeSheetHeight"`
+		ImposeColumns         int                     `json:"imposeColumns"`
+		ImposeRows            int                     `json:"imposeRows"`
+		ImposeGutter          float64                 `json:"imposeGutter"`
+		ImposeBleed           float64                 `json:"imposeBleed"`
+		ImposeMarks           string                  `json:"imposeMarks"`
+		PageCount             int                     `json:"pageCount"`
+		TemplateUrl           string                  `json:"templateUrl"`
+		CSVFileUrl            string                  `json:"csvFileUrl"`
+		ImageBundleUrl        string                  `json:"imageBundleUrl"`
+		HTMLTemplateUrl       string                  `json:"htmlTemplateUrl"`
+		MaxCertificate        int                     `json:"maxCertificate"`
+		ColumnAnnotates       []model.ColumnAnnotate  `json:"columnAnnotates"`
+		SignatureAnnotates    []SignatureAnnotate     `json:"signatureAnnotates"`
+		ImageAnnotates        []ImageAnnotate         `json:"imageAnnotates"`
+		BarcodeAnnotates      []model.BarcodeAnnotate `json:"barcodeAnnotates"`
+		QRCodeAnnotates       []QRCodeAnnotate        `json:"qrCodeAnnotates"`
 	}
 
 	type GetProjectByIdResponse struct {
@@ -253,6 +339,24 @@ func (pc ProjectController) GetProjectById(ctx *gin.Context) {
 		}
 	}
 
+	var imageBundleUrl string
+	if project.ImageBundleFileID != "" {
+		imageBundleUrl, err = project.ImageBundleFile.ToPresignedUrl(ctx, pc.app.S3)
+		if err != nil {
+			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get image bundle URL", util.GenerateErrorMessages(err), nil)
+			return
+		}
+	}
+
+	var htmlTemplateUrl string
+	if project.HTMLTemplateFileID != "" {
+		htmlTemplateUrl, err = project.HTMLTemplateFile.ToPresignedUrl(ctx, pc.app.S3)
+		if err != nil {
+			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get HTML template URL", util.GenerateErrorMessages(err), nil)
+			return
+		}
+	}
+
 	if len(project.SignatureAnnotates) == 0 {
 		project.SignatureAnnotates = []model.SignatureAnnotate{}
 	}
@@ -289,19 +393,80 @@ func (pc ProjectController) GetProjectById(ctx *gin.Context) {
 		}
 	}
 
+	imageAnnotates := []ImageAnnotate{}
+	for _, ia := range project.ImageAnnotates {
+		var imageUrl string
+		if ia.ImageFileID != "" {
+			imageUrl, err = ia.ImageFile.ToPresignedUrl(ctx, pc.app.S3)
+			if err != nil {
+				util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get image file URL", util.GenerateErrorMessages(err), nil)
+				return
+			}
+		}
+		imageAnnotates = append(imageAnnotates, ImageAnnotate{
+			ImageAnnotate: ia,
+			ImageUrl:      imageUrl,
+		})
+	}
+
+	qrCodeAnnotates := []QRCodeAnnotate{}
+	for _, qa := range project.QRCodeAnnotates {
+		var logoUrl string
+		if qa.LogoFileID != "" {
+			logoUrl, err = qa.LogoFile.ToPresignedUrl(ctx, pc.app.S3)
+			if err != nil {
+				util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get QR code logo URL", util.GenerateErrorMessages(err), nil)
+				return
+			}
+		}
+		qrCodeAnnotates = append(qrCodeAnnotates, QRCodeAnnotate{
+			QRCodeAnnotate: qa,
+			LogoUrl:        logoUrl,
+		})
+	}
+
 	util.ResponseSuccess(ctx, GetProjectByIdResponse{
 		Roles: roles,
 		Project: ProjectById{
-			ID:                 project.ID,
-			Title:              project.Title,
-			TemplateUrl:        templateUrl,
-			IsPublic:           project.IsPublic,
-			Status:             project.Status,
-			EmbedQr:            project.EmbedQr,
-			CSVFileUrl:         csvFileUrl,
-			MaxCertificate:     pc.app.Config.APP.MAX_CERTIFICATES_PER_PROJECT,
-			ColumnAnnotates:    project.ColumnAnnotates,
-			SignatureAnnotates: signatureAnnotates,
+			ID:                    project.ID,
+			Title:                 project.Title,
+			TemplateUrl:           templateUrl,
+			IsPublic:              project.IsPublic,
+			Status:                project.Status,
+			EmbedQr:               project.EmbedQr,
+			RasterFormats:         project.RasterFormats,
+			RasterDPI:             project.RasterDPI,
+			PDFA:                  project.PDFA,
+			SignatureLevel:        project.SignatureLevel,
+			MetadataTitle:         project.MetadataTitle,
+			MetadataSubject:       project.MetadataSubject,
+			EncryptPdf:            project.EncryptPdf,
+			HasPdfOwnerPassword:   project.PdfOwnerPassword != "",
+			PdfPermissions:        project.PdfPermissions,
+			PdfUserPasswordColumn: project.PdfUserPasswordColumn,
+			FileNameTemplate:      project.FileNameTemplate,
+			ZipGroupByColumn:      project.ZipGroupByColumn,
+			OutlineTitle:          project.OutlineTitle,
+			MergedIndex:           project.MergedIndex,
+			ImposePdf:             project.ImposePdf,
+			ImposeSheet:           project.ImposeSheet,
+			ImposeSheetWidth:      project.ImposeSheetWidth,
+			ImposeSheetHeight:     project.ImposeSheetHeight,
+			ImposeColumns:         project.ImposeColumns,
+			ImposeRows:            project.ImposeRows,
+			ImposeGutter:          project.ImposeGutter,
+			ImposeBleed:           project.ImposeBleed,
+			ImposeMarks:           project.ImposeMarks,
+			PageCount:             project.PageCount,
+			CSVFileUrl:            csvFileUrl,
+			ImageBundleUrl:        imageBundleUrl,
+			HTMLTemplateUrl:       htmlTemplateUrl,
+			MaxCertificate:        pc.app.Config.APP.MAX_CERTIFICATES_PER_PROJECT,
+			ColumnAnnotates:       project.ColumnAnnotates,
+			SignatureAnnotates:    signatureAnnotates,
+			ImageAnnotates:        imageAnnotates,
+			BarcodeAnnotates:      project.BarcodeAnnotates,
+			QRCodeAnnotates:       qrCodeAnnotates,
 		},
 	})
 }
@@ -450,8 +615,9 @@ func (pc ProjectController) Generate(ctx *gin.Context) {
 		return
 	}
 
-	if len(project.SignatureAnnotates) == 0 && len(project.ColumnAnnotates) == 0 {
-		util.ResponseFailed(ctx, http.StatusBadRequest, "Project must have at least one signature or column annotate", util.GenerateErrorMessages(errors.New("project must have at least one signature or column annotate"), "noAnnotate"), nil)
+	// The placeholders of an HTML template are enough to make certificates differ
+	if project.HTMLTemplateFileID == "" && len(project.SignatureAnnotates) == 0 && len(project.ColumnAnnotates) == 0 && len(project.ImageAnnotates) == 0 && len(project.BarcodeAnnotates) == 0 && len(project.QRCodeAnnotates) == 0 {
+		util.ResponseFailed(ctx, http.StatusBadRequest, "Project must have at least one signature, column, image, barcode or QR code annotate", util.GenerateErrorMessages(errors.New("project must have at least one signature, column, image, barcode or QR code annotate"), "noAnnotate"), nil)
 		return
 	}
 
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -37,8 +38,13 @@ type AnnotateType string
 const (
 	AnnotateTypeColumn    AnnotateType = "column"
 	AnnotateTypeSignature AnnotateType = "signature"
+	AnnotateTypeImage     AnnotateType = "image"
+	AnnotateTypeBarcode   AnnotateType = "barcode"
+	AnnotateTypeQRCode    AnnotateType = "qrcode"
 )
 
+var ALLOWED_IMAGE_FILE_TYPE = []string{".png", ".jpg", ".jpeg"}
+
 // Error keys for frontend translation - grouped by category
 const (
 	ErrKeyInvalidPayload = "invalidPayload"
@@ -71,6 +77,21 @@ type SignatureAnnotateState struct {
 	Type AnnotateType `json:"type" binding:"required" form:"type"`
 }
 
+type ImageAnnotateState struct {
+	model.ImageAnnotate
+	Type AnnotateType `json:"type" binding:"required" form:"type"`
+}
+
+type BarcodeAnnotateState struct {
+	model.BarcodeAnnotate
+	Type AnnotateType `json:"type" binding:"required" form:"type"`
+}
+
+type QRCodeAnnotateState struct {
+	model.QRCodeAnnotate
+	Type AnnotateType `json:"type" binding:"required" form:"type"`
+}
+
 type AutoCertSettings struct {
 	QrCodeEnabled bool `json:"qrCodeEnabled" binding:"required" form:"qrCodeEnabled"`
 }
@@ -103,6 +124,50 @@ type AnnotateSignatureRemove struct {
 	ID string `json:"id" binding:"required" form:"id"`
 }
 
+type AnnotateImageAdd struct {
+	ImageAnnotateState
+	Page int `json:"page" binding:"required" form:"page"`
+}
+
+type AnnotateImageUpdate struct {
+	ImageAnnotateState
+	Page int `json:"page" binding:"required" form:"page"`
+}
+
+type AnnotateImageRemove struct {
+	ID string `json:"id" binding:"required" form:"id"`
+}
+
+type AnnotateBarcodeAdd struct {
+	BarcodeAnnotateState
+	Page int `json:"page" binding:"required" form:"page"`
+}
+
+type AnnotateBarcodeUpdate struct {
+	BarcodeAnnotateState
+	Page int `json:"page" binding:"required" form:"page"`
+}
+
+type AnnotateBarcodeRemove struct {
+	ID string `json:"id" binding:"required" form:"id"`
+}
+
+type AnnotateQRCodeAdd struct {
+	QRCodeAnnotateState
+	Page int `json:"page" binding:"required" form:"page"`
+}
+
+type AnnotateQRCodeUpdate struct {
+	QRCodeAnnotateState
+	Page int `json:"page" binding:"required" form:"page"`
+	// Remove the centre logo, ignored when a new logo is sent
+	RemoveLogo bool `json:"removeLogo" form:"removeLogo"`
+}
+
+type AnnotateQRCodeRemove struct {
+	ID string `json:"id" binding:"required" form:"id"`
+}
+
 type AnnotateSignatureInvite struct {
 	ID       string `json:"id" binding:"required" form:"id"`
 	SendMail bool   `json:"sendMail" form:"sendMail"`
@@ -120,10 +185,69 @@ type AnnotateSignatureApprove struct {
 
 type SettingsUpdate struct {
 	QrCodeEnabled bool `json:"qrCodeEnabled" binding:"required" form:"qrCodeEnabled"`
+	// Image formats each certificate is also exported to and their DPI, omitted keeps the current value
+	RasterFormats *[]autocert.RasterFormat `json:"rasterFormats" form:"rasterFormats"`
+	RasterDPI     *float64                 `json:"rasterDpi" form:"rasterDpi"`
+	// Convert the certificates to PDF/A-2b, omitted keeps the current value
+	PDFA *bool `json:"pdfa" form:"pdfa"`
+	// Digitally sign the certificates at PAdES level "B-B" or "B-T", "" to not sign, omitted keeps the current value
+	SignatureLevel *autocert.SignatureLevel `json:"signatureLevel" form:"signatureLevel"`
+	// Title and subject of the document information of each certificate, text interpolating the columns in {{ }},
+	// Eg: "Certificate of Completion – {{Name}}". Empty uses the project title and no subject, omitted keeps the current value
+	MetadataTitle   *string `json:"metadataTitle" form:"metadataTitle"`
+	MetadataSubject *string `json:"metadataSubject" form:"metadataSubject"`
+	// Encrypt the certificates and the merged PDF, omitted keeps the current value
+	Encryption *EncryptionSettingsUpdate `json:"encryption" form:"encryption"`
+	// Name of each certificate file without extension, text interpolating the columns and the fields Number,
+	// CertificateID and Date in {{ }}, Eg: "{{StudentID}}_{{LastName}}". Empty names the files by row number, omitted
+	// keeps the current value
+	FileNameTemplate *string `json:"fileNameTemplate" form:"fileNameTemplate"`
+	// CSV column whose values are the folders of the certificates in the ZIP, empty for none, omitted keeps the current value
+	ZipGroupByColumn *string `json:"zipGroupByColumn" form:"zipGroupByColumn"`
+	// Title of the bookmark of each certificate in the merged PDF, a column or a template of the columns in {{ }}, Eg:
+	// "{{LastName}}, {{FirstName}}". Empty uses the file name, omitted keeps the current value
+	OutlineTitle *string `json:"outlineTitle" form:"outlineTitle"`
+	// Start the merged PDF with index pages listing the bookmarks and their page, omitted keeps the current value
+	MergedIndex *bool `json:"mergedIndex" form:"mergedIndex"`
+	// Impose the certificates on the sheets of a print shop, omitted keeps the current value
+	Imposition *ImpositionSettingsUpdate `json:"imposition" form:"imposition"`
+}
+
+type ImpositionSettingsUpdate struct {
+	Enabled bool `json:"enabled" form:"enabled"`
+	// SRA3, A3, A4, letter or custom, omitted keeps the current value or SRA3 when enabling
+	Sheet *autocert.PaperSize `json:"sheet" form:"sheet"`
+	// Width and height in mm of a custom sheet, omitted keeps the current value
+	SheetWidth  *float64 `json:"sheetWidth" form:"sheetWidth"`
+	SheetHeight *float64 `json:"sheetHeight" form:"sheetHeight"`
+	// Grid of certificates on each sheet, 0 fits as many as the sheet holds, omitted keeps the current value
+	Columns *int `json:"columns" form:"columns"`
+	Rows    *int `json:"rows" form:"rows"`
+	// In mm, omitted keeps the current value
+	Gutter *float64 `json:"gutter" form:"gutter"`
+	// In mm, omitted keeps the current value or 3 mm when enabling
+	Bleed *float64 `json:"bleed" form:"bleed"`
+	// "", crop or registration, omitted keeps the current value or crop when enabling
+	Marks *autocert.MarkStyle `json:"marks" form:"marks"`
+}
+
+type EncryptionSettingsUpdate struct {
+	Enabled bool `json:"enabled" form:"enabled"`
+	// Password which lifts the restrictions, empty for a random one, omitted keeps the current value
+	OwnerPassword *string `json:"ownerPassword" form:"ownerPassword"`
+	// What the recipients may do, omitted keeps the current value or allows printing when enabling
+	Permissions *[]autocert.PdfPermission `json:"permissions" form:"permissions"`
+	// CSV column holding the password to open the certificate of each row, empty for none, omitted keeps the current value
+	UserPasswordColumn *string `json:"userPasswordColumn" form:"userPasswordColumn"`
 }
 
+// Owner password of an AES-256 encrypted PDF, longer passwords are truncated by the security handler
+const maxPdfOwnerPasswordLength = 127
+
 type TableUpdate struct {
 	CSVFile *multipart.FileHeader `form:"csvFile" binding:"required"`
+	// Optional ZIP of images read by the image annotates which have a column value
+	ImageBundle *multipart.FileHeader `form:"imageBundle"`
 }
 
 // AutoCertChangeEvent is a generic wrapper that holds the event type and raw payload.
@@ -185,11 +309,11 @@ func (pbc ProjectBuilderController) ProjectBuilder(ctx *gin.Context) {
 		switch event.Type {
 		case constant.TableUpdate:
 			tableUpdateEvents = append(tableUpdateEvents, event)
-		case constant.AnnotateColumnAdd, constant.AnnotateSignatureAdd:
+		case constant.AnnotateColumnAdd, constant.AnnotateSignatureAdd, constant.AnnotateImageAdd, constant.AnnotateBarcodeAdd, constant.AnnotateQRCodeAdd:
 			addEvents = append(addEvents, event)
-		case constant.AnnotateColumnUpdate, constant.AnnotateSignatureUpdate, constant.SettingsUpdate, constant.AnnotateSignatureInvite, constant.AnnotateSignatureApprove, constant.AnnotateSignatureReject:
+		case constant.AnnotateColumnUpdate, constant.AnnotateSignatureUpdate, constant.AnnotateImageUpdate, constant.AnnotateBarcodeUpdate, constant.AnnotateQRCodeUpdate, constant.SettingsUpdate, constant.AnnotateSignatureInvite, constant.AnnotateSignatureApprove, constant.AnnotateSignatureReject:
 			updateEvents = append(updateEvents, event)
-		case constant.AnnotateColumnRemove, constant.AnnotateSignatureRemove:
+		case constant.AnnotateColumnRemove, constant.AnnotateSignatureRemove, constant.AnnotateImageRemove, constant.AnnotateBarcodeRemove, constant.AnnotateQRCodeRemove:
 			removeEvents = append(removeEvents, event)
 		default:
 			otherEvents = append(otherEvents, event)
@@ -280,22 +404,232 @@ func (pbc ProjectBuilderController) getEventHandlers() map[constant.ProjectPermi
 		constant.AnnotateSignatureInvite:  pbc.handleAnnotateSignatureInvite,
 		constant.AnnotateSignatureApprove: pbc.handleAnnotateSignatureApprove,
 		constant.AnnotateSignatureReject:  pbc.handleAnnotateSignatureReject,
+		constant.AnnotateImageAdd:         pbc.handleAnnotateImageAdd,
+		constant.AnnotateImageUpdate:      pbc.handleAnnotateImageUpdate,
+		constant.AnnotateImageRemove:      pbc.handleAnnotateImageRemove,
+		constant.AnnotateBarcodeAdd:       pbc.handleAnnotateBarcodeAdd,
+		constant.AnnotateBarcodeUpdate:    pbc.handleAnnotateBarcodeUpdate,
+		constant.AnnotateBarcodeRemove:    pbc.handleAnnotateBarcodeRemove,
+		constant.AnnotateQRCodeAdd:        pbc.handleAnnotateQRCodeAdd,
+		constant.AnnotateQRCodeUpdate:     pbc.handleAnnotateQRCodeUpdate,
+		constant.AnnotateQRCodeRemove:     pbc.handleAnnotateQRCodeRemove,
 		constant.SettingsUpdate:           pbc.handleSettingsUpdate,
 		constant.TableUpdate:              pbc.handleTableUpdate,
 	}
 }
 
+// Annotates can be placed on any page of the template
+func validateAnnotatePage(project *model.Project, page int) error {
+	if page < 1 || page > project.PageCount {
+		return fmt.Errorf(ErrInvalidPageNumber, project.PageCount, page)
+	}
+	return nil
+}
+
+// Validate the text options of a column annotate and fill in the defaults of the empty ones
+func validateColumnAnnotate(ca *model.ColumnAnnotate) error {
+	if _, err := autocert.ParseValueTemplate(ca.Value); err != nil {
+		return err
+	}
+	if _, err := autocert.ParseCondition(ca.Condition); err != nil {
+		return err
+	}
+	if !autocert.FontWeight(ca.FontWeight).IsValid() {
+		return fmt.Errorf("invalid font weight: %s", ca.FontWeight)
+	}
+	if _, ok := autocert.ParseTextAlign(ca.TextAlign); !ok {
+		return fmt.Errorf("invalid text align: %s", ca.TextAlign)
+	}
+	if !autocert.VerticalAlign(ca.VerticalAlign).IsValid() {
+		return fmt.Errorf("invalid vertical align: %s", ca.VerticalAlign)
+	}
+	if !autocert.TextTransform(ca.TextTransform).IsValid() {
+		return fmt.Errorf("invalid text transform: %s", ca.TextTransform)
+	}
+	if err := (autocert.Wrap{MaxLines: ca.MaxLines, Overflow: autocert.TextOverflow(ca.TextOverflow)}).Validate(); err != nil {
+		return err
+	}
+	if ca.LetterSpacing < autocert.MinLetterSpacing || ca.LetterSpacing > autocert.MaxLetterSpacing {
+		return fmt.Errorf("letter spacing must be between %g and %g em", autocert.MinLetterSpacing, autocert.MaxLetterSpacing)
+	}
+	if ca.LineHeight < 0 {
+		return fmt.Errorf("line height must not be negative")
+	}
+
+	if ca.TextAlign == "" {
+		ca.TextAlign = autocert.TextAlignCenter.String()
+	}
+	if ca.VerticalAlign == "" {
+		ca.VerticalAlign = string(autocert.VerticalAlignMiddle)
+	}
+	if ca.TextTransform == "" {
+		ca.TextTransform = string(autocert.TextTransformNone)
+	}
+	if ca.TextOverflow == "" {
+		ca.TextOverflow = string(autocert.TextOverflowShrink)
+	}
+
+	return nil
+}
+
+// Check the columns read by the template values against the CSV headers. A value which is a single column name is
+// not checked, such that a table can be replaced before its column annotates are changed to the new columns.
+func validateValueTemplates(columnAnnotates []model.ColumnAnnotate, headers []string) error {
+	for _, ca := range columnAnnotates {
+		if !autocert.IsValueTemplate(ca.Value) {
+			continue
+		}
+
+		valueTemplate, err := autocert.ParseValueTemplate(ca.Value)
+		if err != nil {
+			return err
+		}
+		if err := valueTemplate.Validate(headers); err != nil {
+			return fmt.Errorf("value %q of page %d: %w", ca.Value, ca.Page, err)
+		}
+	}
+
+	return nil
+}
+
+// Check the columns read by the conditions of the annotates against the CSV headers
+func validateConditions(columnAnnotates []model.ColumnAnnotate, signatureAnnotates []model.SignatureAnnotate, imageAnnotates []model.ImageAnnotate, barcodeAnnotates []model.BarcodeAnnotate, qrCodeAnnotates []model.QRCodeAnnotate, headers []string) error {
+	var annotates []model.BaseAnnotateModel
+	for _, ca := range columnAnnotates {
+		annotates = append(annotates, ca.BaseAnnotateModel)
+	}
+	for _, sa := range signatureAnnotates {
+		annotates = append(annotates, sa.BaseAnnotateModel)
+	}
+	for _, ia := range imageAnnotates {
+		annotates = append(annotates, ia.BaseAnnotateModel)
+	}
+	for _, ba := range barcodeAnnotates {
+		annotates = append(annotates, ba.BaseAnnotateModel)
+	}
+	for _, qa := range qrCodeAnnotates {
+		annotates = append(annotates, qa.BaseAnnotateModel)
+	}
+
+	for _, annot := range annotates {
+		condition, err := autocert.ParseCondition(annot.Condition)
+		if err != nil {
+			return err
+		}
+		if err := condition.Validate(headers); err != nil {
+			return fmt.Errorf("condition %q of page %d: %w", annot.Condition, annot.Page, err)
+		}
+	}
+
+	return nil
+}
+
+func hasPerRowImageAnnotates(imageAnnotates []model.ImageAnnotate) bool {
+	return slices.ContainsFunc(imageAnnotates, func(ia model.ImageAnnotate) bool {
+		return ia.Value != ""
+	})
+}
+
+// Check the columns read by the image annotates against the CSV headers. When a new image bundle is uploaded,
+// also check that every image named in the rows is in the bundle, a blank cell means the row has no image.
+func validateImageAnnotates(imageAnnotates []model.ImageAnnotate, headers []string, rows []map[string]string, imageBundle *autocert.ImageBundle) error {
+	for _, ia := range imageAnnotates {
+		if ia.Value == "" {
+			continue
+		}
+		if !slices.Contains(headers, ia.Value) {
+			return fmt.Errorf("column %q of the image annotate on page %d is not in the table", ia.Value, ia.Page)
+		}
+		if imageBundle == nil {
+			continue
+		}
+
+		for i, row := range rows {
+			name := strings.TrimSpace(row[ia.Value])
+			if name != "" && !imageBundle.Has(name) {
+				return fmt.Errorf("image %q of row %d is not in the image bundle", name, i+1)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Check the columns read by the settings of the project against the CSV headers, such that a table missing one fails
+// its upload instead of the generation
+func validateSettingColumns(project *model.Project, headers []string) error {
+	if column := project.PdfUserPasswordColumn; project.EncryptPdf && column != "" && !slices.Contains(headers, column) {
+		return fmt.Errorf("user password column %q is not in the table", column)
+	}
+	if column := project.ZipGroupByColumn; column != "" && !slices.Contains(headers, column) {
+		return fmt.Errorf("ZIP group by column %q is not in the table", column)
+	}
+	if project.OutlineTitle != "" {
+		outlineTitleTemplate, err := autocert.ParseValueTemplate(project.OutlineTitle)
+		if err != nil {
+			return fmt.Errorf("invalid outline title: %w", err)
+		}
+		if err := outlineTitleTemplate.Validate(headers); err != nil {
+			return fmt.Errorf("invalid outline title: %w", err)
+		}
+	}
+	if project.FileNameTemplate != "" {
+		fileNameTemplate, err := autocert.ParseTextTemplate(project.FileNameTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid file name template: %w", err)
+		}
+		if err := autocert.ValidateFileNameTemplate(fileNameTemplate, headers); err != nil {
+			return fmt.Errorf("invalid file name template: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Check the columns read by the barcode annotates against the CSV headers and that every non blank value
+// can be encoded by the symbology, Eg: EAN only accepts digits
+func validateBarcodeAnnotates(barcodeAnnotates []model.BarcodeAnnotate, headers []string, rows []map[string]string) error {
+	for _, ba := range barcodeAnnotates {
+		if ba.Value == "" {
+			continue
+		}
+		if !slices.Contains(headers, ba.Value) {
+			return fmt.Errorf("column %q of the barcode annotate on page %d is not in the table", ba.Value, ba.Page)
+		}
+
+		for i, row := range rows {
+			content := strings.TrimSpace(row[ba.Value])
+			if content == "" {
+				continue
+			}
+			if _, err := autocert.EncodeBarcode(content, autocert.BarcodeSymbology(ba.Symbology)); err != nil {
+				return fmt.Errorf("value %q of row %d cannot be encoded as %s barcode: %w", content, i+1, ba.Symbology, err)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (pbc ProjectBuilderController) handleAnnotateColumnAdd(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
 	var payload AnnotateColumnAdd
 	if err := json.Unmarshal(data, &payload); err != nil {
 		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateColumnAdd")
 	}
+
+	if err := validateColumnAnnotate(&payload.ColumnAnnotate); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
 	pbc.app.Logger.Debugf("AnnotateColumnAdd: %+v", payload)
 
 	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateColumnAdd}) {
 		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to add column annotate")
 	}
 
+	if err := validateAnnotatePage(project, payload.Page); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
 	err := pbc.app.Repository.ColumnAnnotate.Create(ctx, tx, &model.ColumnAnnotate{
 		BaseModel: model.BaseModel{
 			ID: payload.ID,
@@ -307,6 +641,7 @@ func (pbc ProjectBuilderController) handleAnnotateColumnAdd(ctx *gin.Context, tx
 			Width:     payload.Width,
 			Height:    payload.Height,
 			Color:     payload.Color,
+			Condition: payload.Condition,
 			ProjectID: project.ID,
 		},
 		Value:          payload.Value,
@@ -314,7 +649,17 @@ func (pbc ProjectBuilderController) handleAnnotateColumnAdd(ctx *gin.Context, tx
 		FontSize:       payload.FontSize,
 		FontColor:      payload.FontColor,
 		FontWeight:     payload.FontWeight,
+		FontItalic:     payload.FontItalic,
+		FontFallbacks:  payload.FontFallbacks,
 		TextFitRectBox: payload.TextFitRectBox,
+		TextAlign:      payload.TextAlign,
+		VerticalAlign:  payload.VerticalAlign,
+		LetterSpacing:  payload.LetterSpacing,
+		LineHeight:     payload.LineHeight,
+		TextTransform:  payload.TextTransform,
+		TextWrap:       payload.TextWrap,
+		MaxLines:       payload.MaxLines,
+		TextOverflow:   payload.TextOverflow,
 	})
 	if err != nil {
 		return ErrKeyDatabaseError, nil, nil, errors.New("failed to add column annotate")
@@ -328,12 +673,20 @@ func (pbc ProjectBuilderController) handleAnnotateColumnUpdate(ctx *gin.Context,
 	if err := json.Unmarshal(data, &payload); err != nil {
 		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateColumnUpdate")
 	}
+
+	if err := validateColumnAnnotate(&payload.ColumnAnnotate); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
 	pbc.app.Logger.Debugf("AnnotateColumnUpdate: %+v \n", payload)
 
 	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateColumnUpdate}) {
 		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to update column annotate")
 	}
 
+	if err := validateAnnotatePage(project, payload.Page); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
 	err := pbc.app.Repository.ColumnAnnotate.Update(ctx, tx, map[string]any{
 		"id":                payload.ID,
 		"page":              uint(payload.Page),
@@ -342,13 +695,24 @@ func (pbc ProjectBuilderController) handleAnnotateColumnUpdate(ctx *gin.Context,
 		"width":             payload.Width,
 		"height":            payload.Height,
 		"color":             payload.Color,
+		"condition":         payload.Condition,
 		"project_id":        project.ID,
 		"value":             payload.Value,
 		"font_name":         payload.FontName,
 		"font_size":         payload.FontSize,
 		"font_color":        payload.FontColor,
 		"font_weight":       payload.FontWeight,
+		"font_italic":       payload.FontItalic,
+		"font_fallbacks":    payload.FontFallbacks,
 		"text_fit_rect_box": payload.TextFitRectBox,
+		"text_align":        payload.TextAlign,
+		"vertical_align":    payload.VerticalAlign,
+		"letter_spacing":    payload.LetterSpacing,
+		"line_height":       payload.LineHeight,
+		"text_transform":    payload.TextTransform,
+		"text_wrap":         payload.TextWrap,
+		"max_lines":         payload.MaxLines,
+		"text_overflow":     payload.TextOverflow,
 	})
 	if err != nil {
 		return ErrKeyDatabaseError, nil, nil, errors.New("failed to update column annotate")
@@ -387,6 +751,14 @@ func (pbc ProjectBuilderController) handleAnnotateSignatureAdd(ctx *gin.Context,
 		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to add signature annotate")
 	}
 
+	if err := validateAnnotatePage(project, payload.Page); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	if _, err := autocert.ParseCondition(payload.Condition); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
 	err := pbc.app.Repository.SignatureAnnotate.Create(ctx, tx, &model.SignatureAnnotate{
 		BaseModel: model.BaseModel{
 			ID: payload.ID,
@@ -398,6 +770,7 @@ func (pbc ProjectBuilderController) handleAnnotateSignatureAdd(ctx *gin.Context,
 			Width:     payload.Width,
 			Height:    payload.Height,
 			Color:     payload.Color,
+			Condition: payload.Condition,
 			ProjectID: project.ID,
 		},
 		Status: constant.SignatoryStatusNotInvited,
@@ -422,6 +795,14 @@ func (pbc ProjectBuilderController) handleAnnotateSignatureUpdate(ctx *gin.Conte
 		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to update signature annotate")
 	}
 
+	if err := validateAnnotatePage(project, payload.Page); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	if _, err := autocert.ParseCondition(payload.Condition); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
 	annot, err := pbc.app.Repository.SignatureAnnotate.GetById(ctx, tx, payload.ID, project.ID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -438,6 +819,7 @@ func (pbc ProjectBuilderController) handleAnnotateSignatureUpdate(ctx *gin.Conte
 		"width":      payload.Width,
 		"height":     payload.Height,
 		"color":      payload.Color,
+		"condition":  payload.Condition,
 		"project_id": project.ID,
 	})
 	if err != nil {
@@ -651,126 +1033,1202 @@ func (pbc ProjectBuilderController) handleAnnotateSignatureReject(ctx *gin.Conte
 	return "", nil, nil, nil
 }
 
-func (pbc ProjectBuilderController) handleSettingsUpdate(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
-	var payload SettingsUpdate
-	if err := json.Unmarshal(data, &payload); err != nil {
-		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for SettingsUpdate")
+// Validate the image options of an image annotate and fill in the defaults of the empty ones
+func validateImageAnnotate(ia *model.ImageAnnotate) error {
+	if _, err := autocert.ParseCondition(ia.Condition); err != nil {
+		return err
 	}
-	pbc.app.Logger.Debugf("SettingsUpdate: %+v", payload)
-
-	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.SettingsUpdate}) {
-		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to update settings")
+	if !autocert.ImageFit(ia.Fit).IsValid() {
+		return fmt.Errorf("invalid image fit: %s", ia.Fit)
 	}
 
-	err := pbc.app.Repository.Project.UpdateSetting(ctx, tx, project.ID, payload.QrCodeEnabled)
-	if err != nil {
-		return ErrKeyDatabaseError, nil, nil, errors.New("failed to update project settings")
+	ia.Value = strings.TrimSpace(ia.Value)
+	if ia.Fit == "" {
+		ia.Fit = string(autocert.ImageFitContain)
 	}
 
-	return "", nil, nil, nil
+	return nil
 }
 
-func (pbc ProjectBuilderController) handleTableUpdate(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
-	var payload TableUpdate
-	if err := json.Unmarshal(data, &payload); err != nil {
-		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for TableUpdate")
+// The static image of an image annotate is sent as the form file image_annotate_file_{id}, return nil if not sent
+func (pbc ProjectBuilderController) getImageAnnotateFile(ctx *gin.Context, id string) (*multipart.FileHeader, string, error) {
+	imageFile, err := ctx.FormFile(fmt.Sprintf("image_annotate_file_%s", id))
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			return nil, "", nil
+		}
+		pbc.app.Logger.Errorf("Failed to get image file: %v", err)
+		return nil, ErrKeyFileOperationFailed, errors.New("failed to get image file")
 	}
 
-	file, err := ctx.FormFile("csvFile")
+	ext := strings.ToLower(filepath.Ext(imageFile.Filename))
+	if !slices.Contains(ALLOWED_IMAGE_FILE_TYPE, ext) {
+		return nil, ErrKeyInvalidFileType, errors.New("invalid file type")
+	}
+
+	return imageFile, "", nil
+}
+
+// Upload the static image of an image annotate, onError removes the uploaded file
+func (pbc ProjectBuilderController) uploadImageAnnotateFile(ctx *gin.Context, project *model.Project, imageFile *multipart.FileHeader) (*model.File, func(), error) {
+	info, err := util.UploadFileToS3ByFileHeader(imageFile, &util.FileUploadOptions{
+		DirectoryPath: util.GetProjectDirectoryPath(project.ID),
+		UniquePrefix:  true,
+		Bucket:        pbc.app.Config.Minio.BUCKET,
+		S3:            pbc.app.S3,
+	})
 	if err != nil {
-		pbc.app.Logger.Errorf("Failed to get csv file: %v", err)
-		return ErrKeyFileOperationFailed, nil, nil, errors.New("failed to get csv file")
+		return nil, nil, errors.New("failed to upload image file")
 	}
-	if file == nil {
-		return ErrKeyFileRequired, nil, nil, errors.New("csv file is required")
+
+	onError := func() {
+		if deleteErr := pbc.app.S3.RemoveObject(ctx, info.Bucket, info.Key, minio.RemoveObjectOptions{}); deleteErr != nil {
+			pbc.app.Logger.Errorf("Failed to cleanup uploaded image file on error: %v", deleteErr)
+		}
 	}
-	payload.CSVFile = file
 
-	pbc.app.Logger.Debugf("TableUpdate: %+v", payload.CSVFile.Filename)
+	return &model.File{
+		FileName:       util.ToProjectDirectoryPath(project.ID, imageFile.Filename),
+		UniqueFileName: info.Key,
+		BucketName:     info.Bucket,
+		Size:           info.Size,
+	}, onError, nil
+}
 
-	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.TableUpdate}) {
-		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to update table")
-	}
+// Whether a table is sent with the events, it is processed last and checks the annotates and the settings against it
+func hasTableUpdate(ctx *gin.Context) bool {
+	_, err := ctx.FormFile("csvFile")
+	return !errors.Is(err, http.ErrMissingFile)
+}
 
-	f, err := payload.CSVFile.Open()
-	if err != nil {
-		return ErrKeyFileOperationFailed, nil, nil, errors.New("failed to open csv file")
+// Read the records of the table of the project, the first one being the headers. nil if the project has no table.
+func (pbc ProjectBuilderController) readProjectTable(ctx *gin.Context, project *model.Project) ([][]string, string, error) {
+	if project.CSVFileID == "" {
+		return nil, "", nil
 	}
-	defer f.Close()
 
-	// Create a temp file
-	tmp, err := util.CreateTemp("autocert-*.csv")
+	csvBytes, err := project.CSVFile.Download(ctx, pbc.app.S3)
 	if err != nil {
-		pbc.app.Logger.Errorf("Failed to create temp file: %v", err)
-		return ErrKeyFileOperationFailed, nil, nil, fmt.Errorf("failed to save table data")
+		pbc.app.Logger.Errorf("Failed to download csv file: %v", err)
+		return nil, ErrKeyFileOperationFailed, errors.New("failed to get csv file")
+	}
+	records, err := autocert.ReadCSVFromReader(bytes.NewReader(csvBytes))
+	if err != nil || len(records) == 0 {
+		return nil, ErrKeyInvalidPayload, errors.New("invalid csv file")
 	}
-	defer func() {
-		tmp.Close()
-		os.Remove(tmp.Name())
-	}()
 
-	if _, err := io.Copy(tmp, f); err != nil {
-		pbc.app.Logger.Errorf("Failed to copy file: %v", err)
-		return ErrKeyFileOperationFailed, nil, nil, fmt.Errorf("failed to save table data")
+	return records, "", nil
+}
+
+// An image annotate which reads a column needs the image bundle, check its column against the table and that every
+// image named in the rows is in the bundle, such that a missing image fails the event instead of the generation
+func (pbc ProjectBuilderController) validateImageAnnotateBundle(ctx *gin.Context, project *model.Project, ia model.ImageAnnotate) (string, error) {
+	if ia.Value == "" {
+		return "", nil
 	}
-	if err := tmp.Close(); err != nil {
-		pbc.app.Logger.Errorf("Failed to close temp file: %v", err)
-		return ErrKeyFileOperationFailed, nil, nil, fmt.Errorf("failed to save table data")
+	// A table sent with the events is processed last and checks the annotates against it and its image bundle
+	if hasTableUpdate(ctx) {
+		return "", nil
+	}
+	if project.ImageBundleFileID == "" {
+		return ErrKeyFileRequired, errors.New("image bundle is required by the image annotates which read a column")
 	}
 
-	records, err := autocert.ReadCSVFromFile(tmp.Name())
+	records, errKey, err := pbc.readProjectTable(ctx, project)
 	if err != nil {
-		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid csv file")
+		return errKey, err
+	}
+	// The table is checked against the annotates when it is uploaded
+	if records == nil {
+		return "", nil
 	}
 	csvData, err := autocert.ParseCSVToMap(records)
 	if err != nil {
-		pbc.app.Logger.Errorf("Failed to parse csv file: %v", err)
-		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid csv file")
+		return ErrKeyInvalidPayload, errors.New("invalid csv file")
 	}
 
-	if len(csvData) > pbc.app.Config.APP.MAX_CERTIFICATES_PER_PROJECT {
-		pbc.app.Logger.Warnf("CSV file exceeds maximum number of certificates: %d", len(csvData))
-		return ErrKeyTableExceedLimit, nil, nil, fmt.Errorf("csv file exceeds maximum number of certificates: %d", pbc.app.Config.APP.MAX_CERTIFICATES_PER_PROJECT)
+	tmp, err := util.CreateTemp("autocert-image-bundle-*.zip")
+	if err != nil {
+		pbc.app.Logger.Errorf("Failed to create temp file: %v", err)
+		return ErrKeyFileOperationFailed, errors.New("failed to get image bundle")
 	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
 
-	info, err := util.UploadFileToS3ByPath(tmp.Name(), &util.FileUploadOptions{
-		DirectoryPath: util.GetProjectDirectoryPath(project.ID),
-		UniquePrefix:  true,
-		Bucket:        pbc.app.Config.Minio.BUCKET,
-		S3:            pbc.app.S3,
-	})
+	if err := project.ImageBundleFile.DownloadToLocal(ctx, pbc.app.S3, tmp.Name()); err != nil {
+		pbc.app.Logger.Errorf("Failed to download image bundle: %v", err)
+		return ErrKeyFileOperationFailed, errors.New("failed to get image bundle")
+	}
+	imageBundle, err := autocert.OpenImageBundle(tmp.Name())
 	if err != nil {
-		pbc.app.Logger.Warnf("Failed to upload csv file: %v", err)
-		return ErrKeyFileUploadFailed, nil, nil, errors.New("failed to upload csv file")
+		return ErrKeyInvalidFileType, errors.New("invalid image bundle")
 	}
+	defer imageBundle.Close()
 
-	onError := func() {
-		if deleteErr := pbc.app.S3.RemoveObject(ctx, info.Bucket, info.Key, minio.RemoveObjectOptions{}); deleteErr != nil {
-			pbc.app.Logger.Errorf("Failed to cleanup uploaded csv file on error: %v", deleteErr)
-		}
+	if err := validateImageAnnotates([]model.ImageAnnotate{ia}, records[0], csvData, imageBundle); err != nil {
+		return ErrKeyInvalidPayload, err
 	}
 
-	err = pbc.app.Repository.Project.UpdateCSVFile(ctx, tx, *project, &model.File{
-		FileName:       util.ToProjectDirectoryPath(project.ID, tmp.Name()),
-		UniqueFileName: info.Key,
-		BucketName:     info.Bucket,
-		Size:           info.Size,
-	})
-	if err != nil {
-		pbc.app.Logger.Warnf("Failed to update project table: %v", err)
-		return ErrKeyDatabaseError, nil, onError, errors.New("failed to update project table")
+	return "", nil
+}
+
+func (pbc ProjectBuilderController) handleAnnotateImageAdd(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload AnnotateImageAdd
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateImageAdd")
 	}
+	pbc.app.Logger.Debugf("AnnotateImageAdd: %+v", payload)
 
-	onComplete := func() {
-		// remove old project csv file if exists
-		if project.CSVFile.UniqueFileName != "" {
-			if err := project.CSVFile.Delete(ctx, pbc.app.S3); err != nil {
-				pbc.app.Logger.Errorf("Failed to delete old project csv file: %v", err)
-			}
-		}
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateImageAdd}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to add image annotate")
 	}
 
-	return "", onComplete, onError, nil
+	if err := validateAnnotatePage(project, payload.Page); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	if err := validateImageAnnotate(&payload.ImageAnnotate); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	if errKey, err := pbc.validateImageAnnotateBundle(ctx, project, payload.ImageAnnotate); err != nil {
+		return errKey, nil, nil, err
+	}
+
+	var file *model.File
+	var onError func()
+	// A static image is uploaded with the event, otherwise the image is read from the image bundle by the value column
+	if payload.Value == "" {
+		imageFile, errKey, err := pbc.getImageAnnotateFile(ctx, payload.ID)
+		if err != nil {
+			return errKey, nil, nil, err
+		}
+		if imageFile == nil {
+			return ErrKeyFileRequired, nil, nil, errors.New("image file is required for an image annotate without column")
+		}
+
+		file, onError, err = pbc.uploadImageAnnotateFile(ctx, project, imageFile)
+		if err != nil {
+			return ErrKeyFileUploadFailed, nil, nil, err
+		}
+	}
+
+	err := pbc.app.Repository.ImageAnnotate.Create(ctx, tx, &model.ImageAnnotate{
+		BaseModel: model.BaseModel{
+			ID: payload.ID,
+		},
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			Page:      uint(payload.Page),
+			X:         payload.X,
+			Y:         payload.Y,
+			Width:     payload.Width,
+			Height:    payload.Height,
+			Color:     payload.Color,
+			Condition: payload.Condition,
+			ProjectID: project.ID,
+		},
+		Value: payload.Value,
+		Fit:   payload.Fit,
+	}, file)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, onError, errors.New("failed to add image annotate")
+	}
+
+	return "", nil, onError, nil
+}
+
+func (pbc ProjectBuilderController) handleAnnotateImageUpdate(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload AnnotateImageUpdate
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateImageUpdate")
+	}
+	pbc.app.Logger.Debugf("AnnotateImageUpdate: %+v", payload)
+
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateImageUpdate}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to update image annotate")
+	}
+
+	if err := validateAnnotatePage(project, payload.Page); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	if err := validateImageAnnotate(&payload.ImageAnnotate); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	annot, err := pbc.app.Repository.ImageAnnotate.GetById(ctx, tx, payload.ID, project.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrKeyNotFound, nil, nil, errors.New("image annotate not found")
+		}
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to get image annotate")
+	}
+
+	if errKey, err := pbc.validateImageAnnotateBundle(ctx, project, payload.ImageAnnotate); err != nil {
+		return errKey, nil, nil, err
+	}
+
+	// The static image is replaced only if a new file is sent
+	imageFile, errKey, err := pbc.getImageAnnotateFile(ctx, payload.ID)
+	if err != nil {
+		return errKey, nil, nil, err
+	}
+	if payload.Value == "" && imageFile == nil && annot.ImageFileID == "" {
+		return ErrKeyFileRequired, nil, nil, errors.New("image file is required for an image annotate without column")
+	}
+	// An annotate which reads a column has no static image, a file sent with it is ignored
+	if payload.Value != "" {
+		imageFile = nil
+	}
+	removeImage := payload.Value != "" && annot.ImageFileID != ""
+
+	updates := map[string]any{
+		"id":         payload.ID,
+		"page":       uint(payload.Page),
+		"x":          payload.X,
+		"y":          payload.Y,
+		"width":      payload.Width,
+		"height":     payload.Height,
+		"color":      payload.Color,
+		"condition":  payload.Condition,
+		"project_id": project.ID,
+		"value":      payload.Value,
+		"fit":        payload.Fit,
+	}
+	if removeImage {
+		updates["image_file_id"] = nil
+	}
+
+	err = pbc.app.Repository.ImageAnnotate.Update(ctx, tx, updates)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to update image annotate")
+	}
+
+	// remove the replaced or removed image file if exists
+	onComplete := func() {
+		if annot.ImageFile.UniqueFileName != "" {
+			if err := annot.ImageFile.Delete(ctx, pbc.app.S3); err != nil {
+				pbc.app.Logger.Errorf("Failed to delete old image file: %v", err)
+			}
+		}
+	}
+
+	if imageFile == nil {
+		if !removeImage {
+			return "", nil, nil, nil
+		}
+		if err := pbc.app.Repository.File.Delete(ctx, tx, annot.ImageFileID); err != nil {
+			return ErrKeyDatabaseError, nil, nil, errors.New("failed to remove image file")
+		}
+		return "", onComplete, nil, nil
+	}
+
+	file, onError, err := pbc.uploadImageAnnotateFile(ctx, project, imageFile)
+	if err != nil {
+		return ErrKeyFileUploadFailed, nil, nil, err
+	}
+
+	if err := pbc.app.Repository.ImageAnnotate.UpdateImageFile(ctx, tx, payload.ID, file); err != nil {
+		return ErrKeyDatabaseError, nil, onError, errors.New("failed to update image file")
+	}
+	if annot.ImageFileID != "" {
+		if err := pbc.app.Repository.File.Delete(ctx, tx, annot.ImageFileID); err != nil {
+			return ErrKeyDatabaseError, nil, onError, errors.New("failed to remove old image file")
+		}
+	}
+
+	return "", onComplete, onError, nil
+}
+
+func (pbc ProjectBuilderController) handleAnnotateImageRemove(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload AnnotateImageRemove
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateImageRemove")
+	}
+	pbc.app.Logger.Debugf("AnnotateImageRemove: %+v", payload)
+
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateImageRemove}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to remove image annotate")
+	}
+
+	annot, err := pbc.app.Repository.ImageAnnotate.GetById(ctx, tx, payload.ID, project.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrKeyNotFound, nil, nil, errors.New("image annotate not found")
+		}
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to get image annotate")
+	}
+
+	err = pbc.app.Repository.ImageAnnotate.Delete(ctx, tx, payload.ID)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to remove image annotate")
+	}
+
+	onComplete := func() {
+		if annot.ImageFile.UniqueFileName != "" {
+			annot.ImageFile.Delete(ctx, pbc.app.S3)
+		}
+	}
+
+	return "", onComplete, nil, nil
+}
+
+// Validate the options of a barcode annotate and fill in the defaults of the empty ones
+func validateBarcodeAnnotate(ba *model.BarcodeAnnotate) error {
+	if _, err := autocert.ParseCondition(ba.Condition); err != nil {
+		return err
+	}
+	if !autocert.BarcodeSymbology(ba.Symbology).IsValid() {
+		return fmt.Errorf("invalid barcode symbology: %s", ba.Symbology)
+	}
+
+	ba.Value = strings.TrimSpace(ba.Value)
+	if ba.Symbology == "" {
+		ba.Symbology = string(autocert.BarcodeSymbologyCode128)
+	}
+	if ba.BarColor == "" {
+		ba.BarColor = "#000000"
+	}
+
+	return nil
+}
+
+func (pbc ProjectBuilderController) handleAnnotateBarcodeAdd(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload AnnotateBarcodeAdd
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateBarcodeAdd")
+	}
+	pbc.app.Logger.Debugf("AnnotateBarcodeAdd: %+v", payload)
+
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateBarcodeAdd}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to add barcode annotate")
+	}
+
+	if err := validateAnnotatePage(project, payload.Page); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	if err := validateBarcodeAnnotate(&payload.BarcodeAnnotate); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	err := pbc.app.Repository.BarcodeAnnotate.Create(ctx, tx, &model.BarcodeAnnotate{
+		BaseModel: model.BaseModel{
+			ID: payload.ID,
+		},
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			Page:      uint(payload.Page),
+			X:         payload.X,
+			Y:         payload.Y,
+			Width:     payload.Width,
+			Height:    payload.Height,
+			Color:     payload.Color,
+			Condition: payload.Condition,
+			ProjectID: project.ID,
+		},
+		Value:     payload.Value,
+		Symbology: payload.Symbology,
+		BarColor:  payload.BarColor,
+		ShowText:  payload.ShowText,
+		FontName:  payload.FontName,
+	})
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to add barcode annotate")
+	}
+
+	return "", nil, nil, nil
+}
+
+func (pbc ProjectBuilderController) handleAnnotateBarcodeUpdate(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload AnnotateBarcodeUpdate
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateBarcodeUpdate")
+	}
+	pbc.app.Logger.Debugf("AnnotateBarcodeUpdate: %+v", payload)
+
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateBarcodeUpdate}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to update barcode annotate")
+	}
+
+	if err := validateAnnotatePage(project, payload.Page); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	if err := validateBarcodeAnnotate(&payload.BarcodeAnnotate); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	err := pbc.app.Repository.BarcodeAnnotate.Update(ctx, tx, map[string]any{
+		"id":         payload.ID,
+		"page":       uint(payload.Page),
+		"x":          payload.X,
+		"y":          payload.Y,
+		"width":      payload.Width,
+		"height":     payload.Height,
+		"color":      payload.Color,
+		"condition":  payload.Condition,
+		"project_id": project.ID,
+		"value":      payload.Value,
+		"symbology":  payload.Symbology,
+		"bar_color":  payload.BarColor,
+		"show_text":  payload.ShowText,
+		"font_name":  payload.FontName,
+	})
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to update barcode annotate")
+	}
+
+	return "", nil, nil, nil
+}
+
+func (pbc ProjectBuilderController) handleAnnotateBarcodeRemove(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload AnnotateBarcodeRemove
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateBarcodeRemove")
+	}
+	pbc.app.Logger.Debugf("AnnotateBarcodeRemove: %+v", payload)
+
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateBarcodeRemove}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to remove barcode annotate")
+	}
+
+	err := pbc.app.Repository.BarcodeAnnotate.Delete(ctx, tx, payload.ID)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to remove barcode annotate")
+	}
+
+	return "", nil, nil, nil
+}
+
+// Validate the options of a QR code annotate and fill in the defaults of the empty ones
+func validateQRCodeAnnotate(qa *model.QRCodeAnnotate) error {
+	if _, err := autocert.ParseCondition(qa.Condition); err != nil {
+		return err
+	}
+	if !autocert.QRErrorCorrection(qa.ErrorCorrection).IsValid() {
+		return fmt.Errorf("invalid QR code error correction level: %s", qa.ErrorCorrection)
+	}
+	if qa.QuietZone < 0 {
+		return fmt.Errorf("QR code quiet zone must not be negative")
+	}
+
+	qa.Caption = strings.TrimSpace(qa.Caption)
+	if qa.ErrorCorrection == "" {
+		qa.ErrorCorrection = string(autocert.QRErrorCorrectionMedium)
+	}
+	if qa.ForegroundColor == "" {
+		qa.ForegroundColor = "#000000"
+	}
+
+	return nil
+}
+
+// The centre logo of a QR code annotate is sent as the form file qrcode_annotate_logo_{id}, return nil if not sent
+func (pbc ProjectBuilderController) getQRCodeLogoFile(ctx *gin.Context, id string) (*multipart.FileHeader, string, error) {
+	logoFile, err := ctx.FormFile(fmt.Sprintf("qrcode_annotate_logo_%s", id))
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			return nil, "", nil
+		}
+		pbc.app.Logger.Errorf("Failed to get QR code logo file: %v", err)
+		return nil, ErrKeyFileOperationFailed, errors.New("failed to get QR code logo file")
+	}
+
+	ext := strings.ToLower(filepath.Ext(logoFile.Filename))
+	if !slices.Contains(ALLOWED_IMAGE_FILE_TYPE, ext) {
+		return nil, ErrKeyInvalidFileType, errors.New("invalid file type")
+	}
+
+	return logoFile, "", nil
+}
+
+func (pbc ProjectBuilderController) handleAnnotateQRCodeAdd(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload AnnotateQRCodeAdd
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateQRCodeAdd")
+	}
+	pbc.app.Logger.Debugf("AnnotateQRCodeAdd: %+v", payload)
+
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateQRCodeAdd}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to add QR code annotate")
+	}
+
+	if err := validateAnnotatePage(project, payload.Page); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	if err := validateQRCodeAnnotate(&payload.QRCodeAnnotate); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	logoFile, errKey, err := pbc.getQRCodeLogoFile(ctx, payload.ID)
+	if err != nil {
+		return errKey, nil, nil, err
+	}
+
+	var file *model.File
+	var onError func()
+	if logoFile != nil {
+		if !autocert.QRErrorCorrection(payload.ErrorCorrection).AllowsLogo() {
+			return ErrKeyInvalidPayload, nil, nil, errors.New("a QR code logo requires error correction level Q or H")
+		}
+
+		file, onError, err = pbc.uploadImageAnnotateFile(ctx, project, logoFile)
+		if err != nil {
+			return ErrKeyFileUploadFailed, nil, nil, err
+		}
+	}
+
+	err = pbc.app.Repository.QRCodeAnnotate.Create(ctx, tx, &model.QRCodeAnnotate{
+		BaseModel: model.BaseModel{
+			ID: payload.ID,
+		},
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			Page:      uint(payload.Page),
+			X:         payload.X,
+			Y:         payload.Y,
+			Width:     payload.Width,
+			Height:    payload.Height,
+			Color:     payload.Color,
+			Condition: payload.Condition,
+			ProjectID: project.ID,
+		},
+		ForegroundColor: payload.ForegroundColor,
+		BackgroundColor: payload.BackgroundColor,
+		ErrorCorrection: payload.ErrorCorrection,
+		QuietZone:       payload.QuietZone,
+		ShowCaption:     payload.ShowCaption,
+		Caption:         payload.Caption,
+		FontName:        payload.FontName,
+	}, file)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, onError, errors.New("failed to add QR code annotate")
+	}
+
+	return "", nil, onError, nil
+}
+
+func (pbc ProjectBuilderController) handleAnnotateQRCodeUpdate(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload AnnotateQRCodeUpdate
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateQRCodeUpdate")
+	}
+	pbc.app.Logger.Debugf("AnnotateQRCodeUpdate: %+v", payload)
+
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateQRCodeUpdate}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to update QR code annotate")
+	}
+
+	if err := validateAnnotatePage(project, payload.Page); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	if err := validateQRCodeAnnotate(&payload.QRCodeAnnotate); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	annot, err := pbc.app.Repository.QRCodeAnnotate.GetById(ctx, tx, payload.ID, project.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrKeyNotFound, nil, nil, errors.New("QR code annotate not found")
+		}
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to get QR code annotate")
+	}
+
+	// The logo is replaced only if a new file is sent
+	logoFile, errKey, err := pbc.getQRCodeLogoFile(ctx, payload.ID)
+	if err != nil {
+		return errKey, nil, nil, err
+	}
+	removeLogo := payload.RemoveLogo && logoFile == nil && annot.LogoFileID != ""
+	hasLogo := logoFile != nil || (annot.LogoFileID != "" && !removeLogo)
+	if hasLogo && !autocert.QRErrorCorrection(payload.ErrorCorrection).AllowsLogo() {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("a QR code logo requires error correction level Q or H")
+	}
+
+	updates := map[string]any{
+		"id":               payload.ID,
+		"page":             uint(payload.Page),
+		"x":                payload.X,
+		"y":                payload.Y,
+		"width":            payload.Width,
+		"height":           payload.Height,
+		"color":            payload.Color,
+		"condition":        payload.Condition,
+		"project_id":       project.ID,
+		"foreground_color": payload.ForegroundColor,
+		"background_color": payload.BackgroundColor,
+		"error_correction": payload.ErrorCorrection,
+		"quiet_zone":       payload.QuietZone,
+		"show_caption":     payload.ShowCaption,
+		"caption":          payload.Caption,
+		"font_name":        payload.FontName,
+	}
+	if removeLogo {
+		updates["logo_file_id"] = nil
+	}
+
+	err = pbc.app.Repository.QRCodeAnnotate.Update(ctx, tx, updates)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to update QR code annotate")
+	}
+
+	// remove the replaced or removed logo file if exists
+	onComplete := func() {
+		if annot.LogoFile.UniqueFileName != "" {
+			if err := annot.LogoFile.Delete(ctx, pbc.app.S3); err != nil {
+				pbc.app.Logger.Errorf("Failed to delete old QR code logo file: %v", err)
+			}
+		}
+	}
+
+	if logoFile == nil {
+		if removeLogo {
+			return "", onComplete, nil, nil
+		}
+		return "", nil, nil, nil
+	}
+
+	file, onError, err := pbc.uploadImageAnnotateFile(ctx, project, logoFile)
+	if err != nil {
+		return ErrKeyFileUploadFailed, nil, nil, err
+	}
+
+	if err := pbc.app.Repository.QRCodeAnnotate.UpdateLogoFile(ctx, tx, payload.ID, file); err != nil {
+		return ErrKeyDatabaseError, nil, onError, errors.New("failed to update QR code logo file")
+	}
+
+	return "", onComplete, onError, nil
+}
+
+func (pbc ProjectBuilderController) handleAnnotateQRCodeRemove(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload AnnotateQRCodeRemove
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for AnnotateQRCodeRemove")
+	}
+	pbc.app.Logger.Debugf("AnnotateQRCodeRemove: %+v", payload)
+
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.AnnotateQRCodeRemove}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to remove QR code annotate")
+	}
+
+	annot, err := pbc.app.Repository.QRCodeAnnotate.GetById(ctx, tx, payload.ID, project.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrKeyNotFound, nil, nil, errors.New("QR code annotate not found")
+		}
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to get QR code annotate")
+	}
+
+	err = pbc.app.Repository.QRCodeAnnotate.Delete(ctx, tx, payload.ID)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to remove QR code annotate")
+	}
+
+	onComplete := func() {
+		if annot.LogoFile.UniqueFileName != "" {
+			annot.LogoFile.Delete(ctx, pbc.app.S3)
+		}
+	}
+
+	return "", onComplete, nil, nil
+}
+
+func (pbc ProjectBuilderController) handleSettingsUpdate(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload SettingsUpdate
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for SettingsUpdate")
+	}
+	pbc.app.Logger.Debugf("SettingsUpdate: %+v", payload)
+
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.SettingsUpdate}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to update settings")
+	}
+
+	rasterFormats := project.ToAutoCertRasterFormats()
+	if payload.RasterFormats != nil {
+		rasterFormats = *payload.RasterFormats
+	}
+	rasterDPI := project.RasterDPI
+	if payload.RasterDPI != nil {
+		rasterDPI = *payload.RasterDPI
+	}
+	if err := autocert.ValidateRasterSettings(rasterFormats, rasterDPI); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, err
+	}
+
+	pdfa := project.PDFA
+	if payload.PDFA != nil {
+		pdfa = *payload.PDFA
+	}
+
+	signatureLevel := autocert.SignatureLevel(project.SignatureLevel)
+	if payload.SignatureLevel != nil && *payload.SignatureLevel != signatureLevel {
+		signatureLevel = *payload.SignatureLevel
+		if !signatureLevel.IsValid() {
+			return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("invalid signature level %q, must be %q, %q or empty", signatureLevel, autocert.SignatureLevelBB, autocert.SignatureLevelBT)
+		}
+
+		// The certificates are signed with the key of the owner, or the one of the organisation when the owner has none
+		if signatureLevel != autocert.SignatureLevelNone && pbc.app.Config.Signing.PKCS12_PATH == "" {
+			if _, err := pbc.app.Repository.SigningKey.GetByUserId(ctx, tx, project.UserID); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrKeyInvalidPayload, nil, nil, errors.New("the project owner has no signing key, upload one before enabling digital signatures")
+				}
+				return ErrKeyDatabaseError, nil, nil, errors.New("failed to get signing key")
+			}
+		}
+	}
+
+	metadataTitle := project.MetadataTitle
+	if payload.MetadataTitle != nil {
+		metadataTitle = strings.TrimSpace(*payload.MetadataTitle)
+	}
+	metadataSubject := project.MetadataSubject
+	if payload.MetadataSubject != nil {
+		metadataSubject = strings.TrimSpace(*payload.MetadataSubject)
+	}
+	for name, value := range map[string]string{"title": metadataTitle, "subject": metadataSubject} {
+		if len(value) > 255 {
+			return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("metadata %s must be at most 255 characters", name)
+		}
+		if _, err := autocert.ParseTextTemplate(value); err != nil {
+			return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("invalid metadata %s: %w", name, err)
+		}
+	}
+
+	// The settings reading a column are checked against the table, a table sent with the events checks them instead
+	var headers []string
+	if !hasTableUpdate(ctx) {
+		records, errKey, err := pbc.readProjectTable(ctx, project)
+		if err != nil {
+			return errKey, nil, nil, err
+		}
+		if records != nil {
+			headers = records[0]
+		}
+	}
+
+	encryption, err := project.ToAutoCertPdfEncryption(pbc.app.Config.Encryption.PDF_PASSWORD_SECRET)
+	if err != nil {
+		// Setting a new owner password or disabling the encryption replaces the one which cannot be opened, Eg: after
+		// the secret of the server changed
+		pbc.app.Logger.Errorf("Failed to read PDF encryption of project %s: %v", project.ID, err)
+		if payload.Encryption == nil || (payload.Encryption.Enabled && payload.Encryption.OwnerPassword == nil) {
+			return ErrKeyInvalidPayload, nil, nil, errors.New("failed to open the current owner password, set it again")
+		}
+		encryption = nil
+	}
+	if payload.Encryption != nil {
+		if !payload.Encryption.Enabled {
+			encryption = nil
+		} else {
+			if encryption == nil {
+				encryption = &autocert.PdfEncryption{Permissions: autocert.DefaultPdfPermissions}
+			}
+			if payload.Encryption.OwnerPassword != nil {
+				encryption.OwnerPassword = *payload.Encryption.OwnerPassword
+			}
+			if payload.Encryption.Permissions != nil {
+				encryption.Permissions = *payload.Encryption.Permissions
+			}
+			if payload.Encryption.UserPasswordColumn != nil {
+				encryption.UserPasswordColumn = strings.TrimSpace(*payload.Encryption.UserPasswordColumn)
+			}
+		}
+	}
+	if encryption != nil {
+		// The owner password the user password column requires is sealed under PDF_PASSWORD_SECRET
+		if encryption.UserPasswordColumn != "" && pbc.app.Config.Encryption.PDF_PASSWORD_SECRET == "" {
+			return ErrKeyInvalidPayload, nil, nil, errors.New("user passwords require an owner password, which requires PDF_PASSWORD_SECRET to be set on the server")
+		}
+		if err := encryption.Validate(headers); err != nil {
+			return ErrKeyInvalidPayload, nil, nil, err
+		}
+		if len(encryption.OwnerPassword) > maxPdfOwnerPasswordLength {
+			return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("owner password must be at most %d characters", maxPdfOwnerPasswordLength)
+		}
+		// Encryption rewrites the PDF after it is converted and signed, see autocert.Settings.Encryption
+		if pdfa {
+			return ErrKeyInvalidPayload, nil, nil, errors.New("PDF/A does not allow encryption, disable one of them")
+		}
+		if signatureLevel != autocert.SignatureLevelNone {
+			return ErrKeyInvalidPayload, nil, nil, errors.New("encrypted certificates cannot be digitally signed, disable one of them")
+		}
+		if encryption.UserPasswordColumn != "" && len(rasterFormats) > 0 {
+			return ErrKeyInvalidPayload, nil, nil, errors.New("images cannot be protected by the user password, disable the image export")
+		}
+	}
+
+	fileNameTemplate := project.FileNameTemplate
+	if payload.FileNameTemplate != nil {
+		fileNameTemplate = strings.TrimSpace(*payload.FileNameTemplate)
+	}
+	if len(fileNameTemplate) > 255 {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("file name template must be at most 255 characters")
+	}
+	fileNameValueTemplate, err := autocert.ParseTextTemplate(fileNameTemplate)
+	if err != nil {
+		return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("invalid file name template: %w", err)
+	}
+	if err := autocert.ValidateFileNameTemplate(fileNameValueTemplate, headers); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("invalid file name template: %w", err)
+	}
+
+	zipGroupByColumn := project.ZipGroupByColumn
+	if payload.ZipGroupByColumn != nil {
+		zipGroupByColumn = strings.TrimSpace(*payload.ZipGroupByColumn)
+	}
+	if zipGroupByColumn != "" && headers != nil && !slices.Contains(headers, zipGroupByColumn) {
+		return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("ZIP group by column %q is not in the table", zipGroupByColumn)
+	}
+
+	outlineTitle := project.OutlineTitle
+	if payload.OutlineTitle != nil {
+		outlineTitle = strings.TrimSpace(*payload.OutlineTitle)
+	}
+	if len(outlineTitle) > 255 {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("outline title must be at most 255 characters")
+	}
+	if outlineTitle != "" {
+		outlineTitleTemplate, err := autocert.ParseValueTemplate(outlineTitle)
+		if err != nil {
+			return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("invalid outline title: %w", err)
+		}
+		if headers != nil {
+			if err := outlineTitleTemplate.Validate(headers); err != nil {
+				return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("invalid outline title: %w", err)
+			}
+		}
+	}
+
+	mergedIndex := project.MergedIndex
+	if payload.MergedIndex != nil {
+		mergedIndex = *payload.MergedIndex
+	}
+
+	imposition := project.ToAutoCertImposition()
+	if payload.Imposition != nil {
+		if !payload.Imposition.Enabled {
+			imposition = nil
+		} else {
+			if imposition == nil {
+				defaultImposition := autocert.DefaultImposition
+				imposition = &defaultImposition
+			}
+			if payload.Imposition.Sheet != nil {
+				imposition.Sheet = *payload.Imposition.Sheet
+			}
+			if payload.Imposition.SheetWidth != nil {
+				imposition.Width = *payload.Imposition.SheetWidth
+			}
+			if payload.Imposition.SheetHeight != nil {
+				imposition.Height = *payload.Imposition.SheetHeight
+			}
+			if payload.Imposition.Columns != nil {
+				imposition.Columns = *payload.Imposition.Columns
+			}
+			if payload.Imposition.Rows != nil {
+				imposition.Rows = *payload.Imposition.Rows
+			}
+			if payload.Imposition.Gutter != nil {
+				imposition.Gutter = *payload.Imposition.Gutter
+			}
+			if payload.Imposition.Bleed != nil {
+				imposition.Bleed = *payload.Imposition.Bleed
+			}
+			if payload.Imposition.Marks != nil {
+				imposition.Marks = *payload.Imposition.Marks
+			}
+		}
+	}
+	if imposition != nil {
+		if err := imposition.Validate(); err != nil {
+			return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("invalid imposition: %w", err)
+		}
+	}
+
+	// The owner password is kept sealed under the secret of the server, see model.Project.ToAutoCertPdfEncryption
+	if encryption != nil && encryption.OwnerPassword != "" {
+		if pbc.app.Config.Encryption.PDF_PASSWORD_SECRET == "" {
+			return ErrKeyInvalidPayload, nil, nil, errors.New("owner passwords require PDF_PASSWORD_SECRET to be set on the server")
+		}
+		if encryption.OwnerPassword, err = util.SealString(encryption.OwnerPassword, pbc.app.Config.Encryption.PDF_PASSWORD_SECRET); err != nil {
+			pbc.app.Logger.Errorf("Failed to seal PDF owner password: %v", err)
+			return ErrKeyInvalidPayload, nil, nil, errors.New("failed to seal owner password")
+		}
+	}
+
+	err = pbc.app.Repository.Project.UpdateSetting(ctx, tx, project.ID, payload.QrCodeEnabled, rasterFormats, rasterDPI, pdfa, signatureLevel, metadataTitle, metadataSubject, encryption, fileNameTemplate, zipGroupByColumn, outlineTitle, mergedIndex, imposition)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to update project settings")
+	}
+
+	return "", nil, nil, nil
+}
+
+func (pbc ProjectBuilderController) handleTableUpdate(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
+	var payload TableUpdate
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid payload for TableUpdate")
+	}
+
+	file, err := ctx.FormFile("csvFile")
+	if err != nil {
+		pbc.app.Logger.Errorf("Failed to get csv file: %v", err)
+		return ErrKeyFileOperationFailed, nil, nil, errors.New("failed to get csv file")
+	}
+	if file == nil {
+		return ErrKeyFileRequired, nil, nil, errors.New("csv file is required")
+	}
+	payload.CSVFile = file
+
+	pbc.app.Logger.Debugf("TableUpdate: %+v", payload.CSVFile.Filename)
+
+	if !util.HasPermission(user.Email, roles, []constant.ProjectPermission{constant.TableUpdate}) {
+		return ErrKeyPermissionDenied, nil, nil, errors.New("you do not have permission to update table")
+	}
+
+	f, err := payload.CSVFile.Open()
+	if err != nil {
+		return ErrKeyFileOperationFailed, nil, nil, errors.New("failed to open csv file")
+	}
+	defer f.Close()
+
+	// Create a temp file
+	tmp, err := util.CreateTemp("autocert-*.csv")
+	if err != nil {
+		pbc.app.Logger.Errorf("Failed to create temp file: %v", err)
+		return ErrKeyFileOperationFailed, nil, nil, fmt.Errorf("failed to save table data")
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	if _, err := io.Copy(tmp, f); err != nil {
+		pbc.app.Logger.Errorf("Failed to copy file: %v", err)
+		return ErrKeyFileOperationFailed, nil, nil, fmt.Errorf("failed to save table data")
+	}
+	if err := tmp.Close(); err != nil {
+		pbc.app.Logger.Errorf("Failed to close temp file: %v", err)
+		return ErrKeyFileOperationFailed, nil, nil, fmt.Errorf("failed to save table data")
+	}
+
+	records, err := autocert.ReadCSVFromFile(tmp.Name())
+	if err != nil {
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid csv file")
+	}
+	csvData, err := autocert.ParseCSVToMap(records)
+	if err != nil {
+		pbc.app.Logger.Errorf("Failed to parse csv file: %v", err)
+		return ErrKeyInvalidPayload, nil, nil, errors.New("invalid csv file")
+	}
+
+	if len(csvData) > pbc.app.Config.APP.MAX_CERTIFICATES_PER_PROJECT {
+		pbc.app.Logger.Warnf("CSV file exceeds maximum number of certificates: %d", len(csvData))
+		return ErrKeyTableExceedLimit, nil, nil, fmt.Errorf("csv file exceeds maximum number of certificates: %d", pbc.app.Config.APP.MAX_CERTIFICATES_PER_PROJECT)
+	}
+
+	// Table update is processed after the annotate events, so the annotates are already up to date
+	columnAnnotates, err := pbc.app.Repository.ColumnAnnotate.GetByProjectId(ctx, tx, project.ID)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to get column annotates")
+	}
+	signatureAnnotates, err := pbc.app.Repository.SignatureAnnotate.GetByProjectId(ctx, tx, project.ID)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to get signature annotates")
+	}
+	imageAnnotates, err := pbc.app.Repository.ImageAnnotate.GetByProjectId(ctx, tx, project.ID)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to get image annotates")
+	}
+	barcodeAnnotates, err := pbc.app.Repository.BarcodeAnnotate.GetByProjectId(ctx, tx, project.ID)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to get barcode annotates")
+	}
+	qrCodeAnnotates, err := pbc.app.Repository.QRCodeAnnotate.GetByProjectId(ctx, tx, project.ID)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to get QR code annotates")
+	}
+	if len(records) > 0 {
+		if err := validateValueTemplates(columnAnnotates, records[0]); err != nil {
+			return ErrKeyInvalidPayload, nil, nil, err
+		}
+		if err := validateConditions(columnAnnotates, signatureAnnotates, imageAnnotates, barcodeAnnotates, qrCodeAnnotates, records[0]); err != nil {
+			return ErrKeyInvalidPayload, nil, nil, err
+		}
+		if err := validateBarcodeAnnotates(barcodeAnnotates, records[0], csvData); err != nil {
+			return ErrKeyInvalidPayload, nil, nil, err
+		}
+	}
+
+	// A placeholder of the HTML template reading a column missing in the table would render empty for every row
+	if project.HTMLTemplateFileID != "" && len(records) > 0 {
+		source, err := project.HTMLTemplateFile.Download(ctx, pbc.app.S3)
+		if err != nil {
+			pbc.app.Logger.Errorf("Failed to download HTML template: %v", err)
+			return ErrKeyFileOperationFailed, nil, nil, errors.New("failed to get HTML template")
+		}
+		htmlTemplate, err := autocert.ParseHTMLTemplate(string(source))
+		if err != nil {
+			return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("invalid HTML template: %w", err)
+		}
+		if err := htmlTemplate.Validate(records[0]); err != nil {
+			return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("invalid HTML template: %w", err)
+		}
+	}
+
+	// Settings update is processed before table update, check the settings it saved
+	settings, err := pbc.app.Repository.Project.GetById(ctx, tx, project.ID)
+	if err != nil {
+		return ErrKeyDatabaseError, nil, nil, errors.New("failed to get project settings")
+	}
+	if len(records) > 0 {
+		if err := validateSettingColumns(settings, records[0]); err != nil {
+			return ErrKeyInvalidPayload, nil, nil, err
+		}
+	}
+
+	// The image bundle is optional, it is only needed by the image annotates which read a column
+	bundleHeader, err := ctx.FormFile("imageBundle")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		pbc.app.Logger.Errorf("Failed to get image bundle: %v", err)
+		return ErrKeyFileOperationFailed, nil, nil, errors.New("failed to get image bundle")
+	}
+	payload.ImageBundle = bundleHeader
+
+	var bundlePath string
+	var imageBundle *autocert.ImageBundle
+	if payload.ImageBundle != nil {
+		if !strings.EqualFold(filepath.Ext(payload.ImageBundle.Filename), ".zip") {
+			return ErrKeyInvalidFileType, nil, nil, errors.New("image bundle must be a zip file")
+		}
+
+		bundlePath, err = saveFormFileToTemp(payload.ImageBundle, "autocert-image-bundle-*.zip")
+		if err != nil {
+			pbc.app.Logger.Errorf("Failed to save image bundle: %v", err)
+			return ErrKeyFileOperationFailed, nil, nil, errors.New("failed to save image bundle")
+		}
+		defer os.Remove(bundlePath)
+
+		imageBundle, err = autocert.OpenImageBundle(bundlePath)
+		if err != nil {
+			return ErrKeyInvalidFileType, nil, nil, errors.New("invalid image bundle")
+		}
+		defer imageBundle.Close()
+
+		if imageBundle.Len() == 0 {
+			return ErrKeyInvalidPayload, nil, nil, fmt.Errorf("image bundle must contain at least one %s image", strings.Join(autocert.ImageBundleExtensions, ", "))
+		}
+	}
+
+	if imageBundle == nil && project.ImageBundleFileID == "" && hasPerRowImageAnnotates(imageAnnotates) {
+		return ErrKeyFileRequired, nil, nil, errors.New("image bundle is required by the image annotates which read a column")
+	}
+	if len(records) > 0 {
+		if err := validateImageAnnotates(imageAnnotates, records[0], csvData, imageBundle); err != nil {
+			return ErrKeyInvalidPayload, nil, nil, err
+		}
+	}
+
+	info, err := util.UploadFileToS3ByPath(tmp.Name(), &util.FileUploadOptions{
+		DirectoryPath: util.GetProjectDirectoryPath(project.ID),
+		UniquePrefix:  true,
+		Bucket:        pbc.app.Config.Minio.BUCKET,
+		S3:            pbc.app.S3,
+	})
+	if err != nil {
+		pbc.app.Logger.Warnf("Failed to upload csv file: %v", err)
+		return ErrKeyFileUploadFailed, nil, nil, errors.New("failed to upload csv file")
+	}
+
+	onError := func() {
+		if deleteErr := pbc.app.S3.RemoveObject(ctx, info.Bucket, info.Key, minio.RemoveObjectOptions{}); deleteErr != nil {
+			pbc.app.Logger.Errorf("Failed to cleanup uploaded csv file on error: %v", deleteErr)
+		}
+	}
+
+	err = pbc.app.Repository.Project.UpdateCSVFile(ctx, tx, *project, &model.File{
+		FileName:       util.ToProjectDirectoryPath(project.ID, tmp.Name()),
+		UniqueFileName: info.Key,
+		BucketName:     info.Bucket,
+		Size:           info.Size,
+	})
+	if err != nil {
+		pbc.app.Logger.Warnf("Failed to update project table: %v", err)
+		return ErrKeyDatabaseError, nil, onError, errors.New("failed to update project table")
+	}
+
+	if imageBundle != nil {
+		bundleInfo, err := util.UploadFileToS3ByPath(bundlePath, &util.FileUploadOptions{
+			DirectoryPath: util.GetProjectDirectoryPath(project.ID),
+			UniquePrefix:  true,
+			Bucket:        pbc.app.Config.Minio.BUCKET,
+			S3:            pbc.app.S3,
+		})
+		if err != nil {
+			pbc.app.Logger.Warnf("Failed to upload image bundle: %v", err)
+			return ErrKeyFileUploadFailed, nil, onError, errors.New("failed to upload image bundle")
+		}
+
+		onCsvError := onError
+		onError = func() {
+			onCsvError()
+			if deleteErr := pbc.app.S3.RemoveObject(ctx, bundleInfo.Bucket, bundleInfo.Key, minio.RemoveObjectOptions{}); deleteErr != nil {
+				pbc.app.Logger.Errorf("Failed to cleanup uploaded image bundle on error: %v", deleteErr)
+			}
+		}
+
+		err = pbc.app.Repository.Project.UpdateImageBundleFile(ctx, tx, *project, &model.File{
+			FileName:       util.ToProjectDirectoryPath(project.ID, payload.ImageBundle.Filename),
+			UniqueFileName: bundleInfo.Key,
+			BucketName:     bundleInfo.Bucket,
+			Size:           bundleInfo.Size,
+		})
+		if err != nil {
+			pbc.app.Logger.Warnf("Failed to update project image bundle: %v", err)
+			return ErrKeyDatabaseError, nil, onError, errors.New("failed to update project image bundle")
+		}
+	}
+
+	onComplete := func() {
+		// remove old project csv file if exists
+		if project.CSVFile.UniqueFileName != "" {
+			if err := project.CSVFile.Delete(ctx, pbc.app.S3); err != nil {
+				pbc.app.Logger.Errorf("Failed to delete old project csv file: %v", err)
+			}
+		}
+		// remove old image bundle if replaced
+		if imageBundle != nil && project.ImageBundleFile.UniqueFileName != "" {
+			if err := project.ImageBundleFile.Delete(ctx, pbc.app.S3); err != nil {
+				pbc.app.Logger.Errorf("Failed to delete old project image bundle: %v", err)
+			}
+		}
+	}
+
+	return "", onComplete, onError, nil
+}
+
+func saveFormFileToTemp(fileHeader *multipart.FileHeader, pattern string) (string, error) {
+	f, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	tmp, err := util.CreateTemp(pattern)
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, f); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), tmp.Close()
 }
 
 func (pbc ProjectBuilderController) handleAnnotateSignatureApprove(ctx *gin.Context, tx *gorm.DB, user *auth.JWTPayload, roles []constant.ProjectRole, project *model.Project, data json.RawMessage) (string, func(), func(), error) {
@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SeakMengs/AutoCert/internal/model"
+	"github.com/SeakMengs/AutoCert/internal/util"
+	"github.com/SeakMengs/AutoCert/pkg/autocert"
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+)
+
+var ALLOWED_SIGNING_KEY_FILE_TYPE = []string{".p12", ".pfx"}
+
+func getSigningKeyDirectoryPath(userId string) string {
+	return fmt.Sprintf("users/%s/signing_keys", userId)
+}
+
+func signingKeyResponse(signingKey *model.SigningKey) gin.H {
+	return gin.H{
+		"subjectName": signingKey.SubjectName,
+		"notAfter":    signingKey.NotAfter,
+	}
+}
+
+// Upload the PKCS#12 key used to digitally sign the certificates of the user's projects, it replaces the current one
+func (sc SignatureController) PutSigningKey(ctx *gin.Context) {
+	user, err := sc.getAuthUser(ctx)
+	if err != nil {
+		sc.app.Logger.Errorf("Failed to get auth user: %v", err)
+		util.ResponseFailed(ctx, http.StatusUnauthorized, "Unauthorized", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	if sc.app.Config.Signing.KEY_SECRET == "" {
+		util.ResponseFailed(ctx, http.StatusForbidden, "Signing keys are disabled", util.GenerateErrorMessages(errors.New("uploading signing keys is disabled on this server"), "forbidden"), nil)
+		return
+	}
+
+	keyFile, err := ctx.FormFile("pkcs12File")
+	if err != nil {
+		sc.app.Logger.Error(err)
+		util.ResponseFailed(ctx, http.StatusBadRequest, "No PKCS#12 file uploaded", util.GenerateErrorMessages(errors.New("PKCS#12 file is required"), "pkcs12File"), nil)
+		return
+	}
+
+	// 1mb
+	if keyFile.Size > 1024*1024 {
+		util.ResponseFailed(ctx, http.StatusBadRequest, "File size exceeds limit", util.GenerateErrorMessages(errors.New("file size exceeds limit"), "pkcs12File"), nil)
+		return
+	}
+
+	ext := filepath.Ext(keyFile.Filename)
+	if !slices.Contains(ALLOWED_SIGNING_KEY_FILE_TYPE, strings.ToLower(ext)) {
+		util.ResponseFailed(ctx, http.StatusBadRequest, "Invalid file type", util.GenerateErrorMessages(errors.New("invalid file type, must be .p12 or .pfx"), "pkcs12File"), nil)
+		return
+	}
+
+	f, err := keyFile.Open()
+	if err != nil {
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to read PKCS#12 file", util.GenerateErrorMessages(err), nil)
+		return
+	}
+	pfx, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to read PKCS#12 file", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	signer, err := autocert.NewPdfSignerFromPKCS12(pfx, ctx.PostForm("password"))
+	if err != nil {
+		util.ResponseFailed(ctx, http.StatusBadRequest, "Invalid PKCS#12 file or password", util.GenerateErrorMessages(err, "pkcs12File"), nil)
+		return
+	}
+	if time.Now().After(signer.Certificate.NotAfter) {
+		util.ResponseFailed(ctx, http.StatusBadRequest, "Certificate of the key has expired", util.GenerateErrorMessages(errors.New("certificate of the key has expired"), "pkcs12File"), nil)
+		return
+	}
+
+	// Keep the key under the secret of the server instead of the password of the user
+	encoded, err := signer.PKCS12(sc.app.Config.Signing.KEY_SECRET)
+	if err != nil {
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to encode signing key", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	tmpDir, err := os.MkdirTemp("", "autocert_signing_key_")
+	if err != nil {
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to create temp directory", util.GenerateErrorMessages(err), nil)
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpFile := filepath.Join(tmpDir, "signing_key.p12")
+	if err := os.WriteFile(tmpFile, encoded, 0600); err != nil {
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to write signing key", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	info, err := util.UploadFileToS3ByPath(tmpFile, &util.FileUploadOptions{
+		DirectoryPath: getSigningKeyDirectoryPath(user.ID),
+		UniquePrefix:  true,
+		Bucket:        sc.app.Config.Minio.BUCKET,
+		S3:            sc.app.S3,
+	})
+	if err != nil {
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to upload file", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	tx := sc.app.Repository.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to save signing key", util.GenerateErrorMessages(errors.New("failed to save signing key")), nil)
+			return
+		}
+	}()
+
+	current, err := sc.app.Repository.SigningKey.GetByUserId(ctx, tx, user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		sc.app.S3.RemoveObject(ctx, info.Bucket, info.Key, minio.RemoveObjectOptions{})
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to save signing key", util.GenerateErrorMessages(err), nil)
+		return
+	}
+	if current != nil {
+		if err := sc.app.Repository.SigningKey.DeleteByUserId(ctx, tx, user.ID); err != nil {
+			tx.Rollback()
+			sc.app.S3.RemoveObject(ctx, info.Bucket, info.Key, minio.RemoveObjectOptions{})
+			util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to save signing key", util.GenerateErrorMessages(err), nil)
+			return
+		}
+	}
+
+	signingKey := model.SigningKey{
+		UserID:      user.ID,
+		SubjectName: signer.Certificate.Subject.CommonName,
+		NotAfter:    signer.Certificate.NotAfter,
+		KeyFile: model.File{
+			FileName:       filepath.Join(getSigningKeyDirectoryPath(user.ID), "signing_key.p12"),
+			UniqueFileName: info.Key,
+			BucketName:     info.Bucket,
+			Size:           info.Size,
+		},
+	}
+
+	if _, err := sc.app.Repository.SigningKey.Create(ctx, tx, &signingKey); err != nil {
+		tx.Rollback()
+		sc.app.S3.RemoveObject(ctx, info.Bucket, info.Key, minio.RemoveObjectOptions{})
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to save signing key", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		sc.app.S3.RemoveObject(ctx, info.Bucket, info.Key, minio.RemoveObjectOptions{})
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to save signing key", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	if current != nil && current.KeyFileID != "" {
+		if err := current.KeyFile.Delete(ctx, sc.app.S3); err != nil {
+			// Intentionally not return failed because even if delete file fail, it doesn't affect the system.
+			sc.app.Logger.Errorf("failed to delete previous signing key from storage with err: %v", err)
+		}
+	}
+
+	util.ResponseSuccess(ctx, gin.H{
+		"signingKey": signingKeyResponse(&signingKey),
+	})
+}
+
+func (sc SignatureController) GetSigningKey(ctx *gin.Context) {
+	user, err := sc.getAuthUser(ctx)
+	if err != nil {
+		sc.app.Logger.Errorf("Failed to get auth user: %v", err)
+		util.ResponseFailed(ctx, http.StatusUnauthorized, "Unauthorized", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	signingKey, err := sc.app.Repository.SigningKey.GetByUserId(ctx, nil, user.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			util.ResponseSuccess(ctx, gin.H{
+				"signingKey": nil,
+			})
+			return
+		}
+
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to get signing key", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	util.ResponseSuccess(ctx, gin.H{
+		"signingKey": signingKeyResponse(signingKey),
+	})
+}
+
+func (sc SignatureController) RemoveSigningKey(ctx *gin.Context) {
+	user, err := sc.getAuthUser(ctx)
+	if err != nil {
+		sc.app.Logger.Errorf("Failed to get auth user: %v", err)
+		util.ResponseFailed(ctx, http.StatusUnauthorized, "Unauthorized", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	signingKey, err := sc.app.Repository.SigningKey.GetByUserId(ctx, nil, user.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			util.ResponseFailed(ctx, http.StatusBadRequest, "Failed to remove signing key", util.GenerateErrorMessages(errors.New("signing key not found"), "notFound"), nil)
+			return
+		}
+
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to remove signing key", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	if err := sc.app.Repository.SigningKey.DeleteByUserId(ctx, nil, user.ID); err != nil {
+		util.ResponseFailed(ctx, http.StatusInternalServerError, "Failed to remove signing key", util.GenerateErrorMessages(err), nil)
+		return
+	}
+
+	if signingKey.KeyFileID != "" {
+		if err := signingKey.KeyFile.Delete(ctx, sc.app.S3); err != nil {
+			// Intentionally not return failed because even if delete file fail, it doesn't affect the system.
+			sc.app.Logger.Errorf("failed to delete signing key from storage with err: %v", err)
+		}
+	}
+
+	util.ResponseSuccess(ctx, nil)
+}
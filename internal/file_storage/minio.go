@@ -70,6 +70,10 @@ func (mc *MinioClient) FGetObject(ctx context.Context, bucketName string, object
 	return mc.internalClient.FGetObject(ctx, bucketName, objectName, filePath, opts)
 }
 
+func (mc *MinioClient) GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*minio.Object, error) {
+	return mc.internalClient.GetObject(ctx, bucketName, objectName, opts)
+}
+
 func (mc *MinioClient) ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo {
 	return mc.internalClient.ListObjects(ctx, bucketName, opts)
 }
@@ -0,0 +1,36 @@
+package model
+
+import "github.com/SeakMengs/AutoCert/pkg/autocert"
+
+type BarcodeAnnotate struct {
+	BaseAnnotateModel
+	BaseModel
+
+	// Column of the CSV holding the content of the barcode, empty to encode the certificate id
+	Value     string `gorm:"type:varchar(255);default:''" json:"value" form:"value"`
+	Symbology string `gorm:"type:varchar(20);default:'code128'" json:"symbology" form:"symbology"`
+	BarColor  string `gorm:"type:varchar(20);default:'#000000'" json:"barColor" form:"barColor"`
+	ShowText  bool   `gorm:"type:boolean;default:false" json:"showText" form:"showText"`
+	FontName  string `gorm:"type:varchar(200)" json:"fontName" form:"fontName"`
+}
+
+func (ba BarcodeAnnotate) TableName() string {
+	return "barcode_annotates"
+}
+
+func (ba BarcodeAnnotate) ToAutoCertBarcodeAnnotate() *autocert.BarcodeAnnotate {
+	return &autocert.BarcodeAnnotate{
+		BaseAnnotate: autocert.BaseAnnotate{
+			ID:        ba.ID,
+			Type:      autocert.AnnotateTypeBarcode,
+			Position:  autocert.Position{X: ba.X, Y: ba.Y},
+			Size:      autocert.Size{Width: ba.Width, Height: ba.Height},
+			Condition: ba.Condition,
+		},
+		Value:     ba.Value,
+		Symbology: autocert.BarcodeSymbology(ba.Symbology),
+		BarColor:  ba.BarColor,
+		ShowText:  ba.ShowText,
+		FontName:  ba.FontName,
+	}
+}
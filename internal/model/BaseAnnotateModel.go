@@ -7,6 +7,8 @@ type BaseAnnotateModel struct {
 	Width  float64 `gorm:"type:double precision;not null" json:"width" form:"width" binding:"required"`
 	Height float64 `gorm:"type:double precision;not null" json:"height" form:"height" binding:"required"`
 	Color  string  `gorm:"type:varchar(20)" json:"color" form:"color" binding:"required"`
+	// Optional expression evaluated per CSV row, the annotation is stamped only when true. See autocert.Condition
+	Condition string `gorm:"type:varchar(500);default:''" json:"condition" form:"condition"`
 
 	ProjectID string  `gorm:"type:text;not null" json:"-" form:"projectId"`
 	Project   Project `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-" form:"-"`
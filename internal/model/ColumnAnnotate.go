@@ -6,12 +6,22 @@ type ColumnAnnotate struct {
 	BaseAnnotateModel
 	BaseModel
 
-	Value          string  `gorm:"type:varchar(200)" json:"value" form:"value" binding:"required"`
-	FontName       string  `gorm:"type:varchar(200)" json:"fontName" form:"fontName"`
-	FontSize       float64 `gorm:"type:double precision;not null" json:"fontSize" form:"fontSize"`
-	FontWeight     string  `gorm:"type:varchar(50)" json:"fontWeight" form:"fontWeight"`
-	FontColor      string  `gorm:"type:varchar(20)" json:"fontColor" form:"fontColor"`
-	TextFitRectBox bool    `gorm:"type:boolean;default:true" json:"textFitRectBox" form:"textFitRectBox"`
+	Value          string      `gorm:"type:varchar(500)" json:"value" form:"value" binding:"required"`
+	FontName       string      `gorm:"type:varchar(200)" json:"fontName" form:"fontName"`
+	FontSize       float64     `gorm:"type:double precision;not null" json:"fontSize" form:"fontSize"`
+	FontWeight     string      `gorm:"type:varchar(50)" json:"fontWeight" form:"fontWeight"`
+	FontItalic     bool        `gorm:"type:boolean;default:false" json:"fontItalic" form:"fontItalic"`
+	FontFallbacks  StringArray `gorm:"type:jsonb;default:'[]'" json:"fontFallbacks" form:"fontFallbacks"`
+	FontColor      string      `gorm:"type:varchar(20)" json:"fontColor" form:"fontColor"`
+	TextFitRectBox bool        `gorm:"type:boolean;default:true" json:"textFitRectBox" form:"textFitRectBox"`
+	TextAlign      string      `gorm:"type:varchar(20);default:'center'" json:"textAlign" form:"textAlign"`
+	VerticalAlign  string      `gorm:"type:varchar(20);default:'middle'" json:"verticalAlign" form:"verticalAlign"`
+	LetterSpacing  float64     `gorm:"type:double precision;default:0" json:"letterSpacing" form:"letterSpacing"`
+	LineHeight     float64     `gorm:"type:double precision;default:1" json:"lineHeight" form:"lineHeight"`
+	TextTransform  string      `gorm:"type:varchar(20);default:'none'" json:"textTransform" form:"textTransform"`
+	TextWrap       bool        `gorm:"type:boolean;default:false" json:"textWrap" form:"textWrap"`
+	MaxLines       int         `gorm:"type:integer;default:0" json:"maxLines" form:"maxLines"`
+	TextOverflow   string      `gorm:"type:varchar(20);default:'shrink'" json:"textOverflow" form:"textOverflow"`
 }
 
 func (ca ColumnAnnotate) TableName() string {
@@ -19,20 +29,32 @@ func (ca ColumnAnnotate) TableName() string {
 }
 
 func (ca ColumnAnnotate) ToAutoCertColumnAnnotate() *autocert.ColumnAnnotate {
+	// Invalid alignment is rejected when saving, center is the default
+	textAlign, _ := autocert.ParseTextAlign(ca.TextAlign)
+
 	return &autocert.ColumnAnnotate{
 		BaseAnnotate: autocert.BaseAnnotate{
-			ID:       ca.ID,
-			Type:     autocert.AnnotateTypeColumn,
-			Position: autocert.Position{X: ca.X, Y: ca.Y},
-			Size:     autocert.Size{Width: ca.Width, Height: ca.Height},
+			ID:        ca.ID,
+			Type:      autocert.AnnotateTypeColumn,
+			Position:  autocert.Position{X: ca.X, Y: ca.Y},
+			Size:      autocert.Size{Width: ca.Width, Height: ca.Height},
+			Condition: ca.Condition,
 		},
 		Value:          ca.Value,
 		FontName:       ca.FontName,
 		FontColor:      ca.FontColor,
 		FontSize:       ca.FontSize,
 		FontWeight:     autocert.FontWeight(ca.FontWeight),
+		FontItalic:     ca.FontItalic,
+		FontFallbacks:  ca.FontFallbacks,
 		TextFitRectBox: ca.TextFitRectBox,
-		// TODO: add text align to model
-		TextAlign: autocert.TextAlignCenter,
+		TextAlign:      textAlign,
+		VerticalAlign:  autocert.VerticalAlign(ca.VerticalAlign),
+		LetterSpacing:  ca.LetterSpacing,
+		LineHeight:     ca.LineHeight,
+		TextTransform:  autocert.TextTransform(ca.TextTransform),
+		TextWrap:       ca.TextWrap,
+		MaxLines:       ca.MaxLines,
+		TextOverflow:   autocert.TextOverflow(ca.TextOverflow),
 	}
 }
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"time"
 
 	filestorage "github.com/SeakMengs/AutoCert/internal/file_storage"
+	"github.com/SeakMengs/AutoCert/internal/util"
 	"github.com/minio/minio-go/v7"
 )
 
@@ -26,6 +29,18 @@ func (f File) TableName() string {
 }
 
 func (f File) ToPresignedUrl(ctx context.Context, s3 *filestorage.MinioClient) (string, error) {
+	return f.toPresignedUrl(ctx, s3, nil)
+}
+
+// Same as ToPresignedUrl but the response is named after the file instead of its key, disposition is "inline" to
+// open it in the browser or "attachment" to download it
+func (f File) ToPresignedDownloadUrl(ctx context.Context, s3 *filestorage.MinioClient, disposition string) (string, error) {
+	reqParams := make(url.Values)
+	reqParams.Set("response-content-disposition", util.ContentDisposition(disposition, filepath.Base(f.FileName)))
+	return f.toPresignedUrl(ctx, s3, reqParams)
+}
+
+func (f File) toPresignedUrl(ctx context.Context, s3 *filestorage.MinioClient, reqParams url.Values) (string, error) {
 	if f.BucketName == "" || f.UniqueFileName == "" {
 		return "", errors.New("bucket name and unique file name cannot be empty")
 	}
@@ -45,7 +60,7 @@ func (f File) ToPresignedUrl(ctx context.Context, s3 *filestorage.MinioClient) (
 		f.UniqueFileName,
 		// 60min expiration time
 		time.Minute*60,
-		nil,
+		reqParams,
 	)
 	if err != nil {
 		return "", err
@@ -72,6 +87,21 @@ func (f File) DownloadToLocal(ctx context.Context, s3 *filestorage.MinioClient,
 	return nil
 }
 
+// Download the file into memory, meant for small files such as a key or a single certificate
+func (f File) Download(ctx context.Context, s3 *filestorage.MinioClient) ([]byte, error) {
+	if f.BucketName == "" || f.UniqueFileName == "" {
+		return nil, errors.New("bucket name and unique file name cannot be empty")
+	}
+
+	obj, err := s3.GetObject(ctx, f.BucketName, f.UniqueFileName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return io.ReadAll(obj)
+}
+
 func (f File) Delete(ctx context.Context, s3 *filestorage.MinioClient) error {
 	if f.BucketName == "" || f.UniqueFileName == "" {
 		return errors.New("bucket name and unique file name cannot be empty")
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"context"
+	"path/filepath"
+
+	filestorage "github.com/SeakMengs/AutoCert/internal/file_storage"
+	"github.com/SeakMengs/AutoCert/internal/util"
+	"github.com/SeakMengs/AutoCert/pkg/autocert"
+)
+
+type ImageAnnotate struct {
+	BaseAnnotateModel
+	BaseModel
+
+	// Column of the CSV holding the file name of the image in the project image bundle, empty for a static image
+	Value       string `gorm:"type:varchar(255);default:''" json:"value" form:"value"`
+	Fit         string `gorm:"type:varchar(20);default:'contain'" json:"fit" form:"fit"`
+	ImageFileID string `gorm:"type:text;default:null" json:"-" form:"imageFileId"`
+
+	ImageFile File `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-" form:"-"`
+}
+
+func (ia ImageAnnotate) TableName() string {
+	return "image_annotates"
+}
+
+// Don't forget to defer remove the file after using the temp file
+func (ia ImageAnnotate) ToAutoCertImageAnnotate(ctx context.Context, s3 *filestorage.MinioClient) (*autocert.ImageAnnotate, error) {
+	annotate := &autocert.ImageAnnotate{
+		BaseAnnotate: autocert.BaseAnnotate{
+			ID:        ia.ID,
+			Type:      autocert.AnnotateTypeImage,
+			Position:  autocert.Position{X: ia.X, Y: ia.Y},
+			Size:      autocert.Size{Width: ia.Width, Height: ia.Height},
+			Condition: ia.Condition,
+		},
+		Value: ia.Value,
+		Fit:   autocert.ImageFit(ia.Fit),
+	}
+
+	if ia.Value != "" || ia.ImageFileID == "" {
+		return annotate, nil
+	}
+
+	tmp, err := util.CreateTemp("autocert_image_file_*" + filepath.Ext(ia.ImageFile.FileName))
+	if err != nil {
+		return nil, err
+	}
+
+	err = ia.ImageFile.DownloadToLocal(ctx, s3, tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	annotate.ImageFilePath = tmp.Name()
+
+	return annotate, nil
+}
@@ -1,26 +1,113 @@
 package model
 
 import (
+	"fmt"
+
 	"github.com/SeakMengs/AutoCert/internal/constant"
+	"github.com/SeakMengs/AutoCert/internal/util"
+	"github.com/SeakMengs/AutoCert/pkg/autocert"
 )
 
 type Project struct {
 	BaseModel
-	Title          string                 `gorm:"type:varchar(30);not null;" json:"title" form:"title" binding:"required"`
-	IsPublic       bool                   `gorm:"type:boolean;default:false" json:"isPublic" form:"isPublic"`
-	EmbedQr        bool                   `gorm:"type:boolean;default:false" json:"embedQr" form:"embedQr"`
-	Status         constant.ProjectStatus `gorm:"type:integer;default:0" json:"status" form:"status"`
-	TemplateFileID string                 `gorm:"type:text;not null" json:"templateFileId" form:"templateFileId" binding:"required"`
-	CSVFileID      string                 `gorm:"type:text;default:null" json:"csvFileId" form:"csvFileId"`
-	UserID         string                 `gorm:"type:text;not null" json:"userId" form:"userId"`
+	Title                 string                 `gorm:"type:varchar(30);not null;" json:"title" form:"title" binding:"required"`
+	IsPublic              bool                   `gorm:"type:boolean;default:false" json:"isPublic" form:"isPublic"`
+	EmbedQr               bool                   `gorm:"type:boolean;default:false" json:"embedQr" form:"embedQr"`
+	RasterFormats         StringArray            `gorm:"type:jsonb;default:'[]'" json:"rasterFormats" form:"rasterFormats"`
+	RasterDPI             float64                `gorm:"type:double precision;default:0" json:"rasterDpi" form:"rasterDpi"`
+	PDFA                  bool                   `gorm:"type:boolean;default:false" json:"pdfa" form:"pdfa"`
+	SignatureLevel        string                 `gorm:"type:varchar(3);default:''" json:"signatureLevel" form:"signatureLevel"`
+	MetadataTitle         string                 `gorm:"type:text;default:''" json:"metadataTitle" form:"metadataTitle"`
+	MetadataSubject       string                 `gorm:"type:text;default:''" json:"metadataSubject" form:"metadataSubject"`
+	EncryptPdf            bool                   `gorm:"type:boolean;default:false" json:"encryptPdf" form:"encryptPdf"`
+	PdfOwnerPassword      string                 `gorm:"type:text;default:''" json:"-" form:"-"`
+	PdfPermissions        StringArray            `gorm:"type:jsonb;default:'[]'" json:"pdfPermissions" form:"pdfPermissions"`
+	PdfUserPasswordColumn string                 `gorm:"type:text;default:''" json:"pdfUserPasswordColumn" form:"pdfUserPasswordColumn"`
+	FileNameTemplate      string                 `gorm:"type:text;default:''" json:"fileNameTemplate" form:"fileNameTemplate"`
+	ZipGroupByColumn      string                 `gorm:"type:text;default:''" json:"zipGroupByColumn" form:"zipGroupByColumn"`
+	OutlineTitle          string                 `gorm:"type:text;default:''" json:"outlineTitle" form:"outlineTitle"`
+	MergedIndex           bool                   `gorm:"type:boolean;default:false" json:"mergedIndex" form:"mergedIndex"`
+	ImposePdf             bool                   `gorm:"type:boolean;default:false" json:"imposePdf" form:"imposePdf"`
+	ImposeSheet           string                 `gorm:"type:varchar(10);default:''" json:"imposeSheet" form:"imposeSheet"`
+	ImposeSheetWidth      float64                `gorm:"type:double precision;default:0" json:"imposeSheetWidth" form:"imposeSheetWidth"`
+	ImposeSheetHeight     float64                `gorm:"type:double precision;default:0" json:"imposeSheetHeight" form:"imposeSheetHeight"`
+	ImposeColumns         int                    `gorm:"type:integer;default:0" json:"imposeColumns" form:"imposeColumns"`
+	ImposeRows            int                    `gorm:"type:integer;default:0" json:"imposeRows" form:"imposeRows"`
+	ImposeGutter          float64                `gorm:"type:double precision;default:0" json:"imposeGutter" form:"imposeGutter"`
+	ImposeBleed           float64                `gorm:"type:double precision;default:0" json:"imposeBleed" form:"imposeBleed"`
+	ImposeMarks           string                 `gorm:"type:varchar(20);default:''" json:"imposeMarks" form:"imposeMarks"`
+	Status                constant.ProjectStatus `gorm:"type:integer;default:0" json:"status" form:"status"`
+	PageCount             int                    `gorm:"type:integer;default:1" json:"pageCount" form:"pageCount"`
+	TemplateFileID        string                 `gorm:"type:text;not null" json:"templateFileId" form:"templateFileId" binding:"required"`
+	CSVFileID             string                 `gorm:"type:text;default:null" json:"csvFileId" form:"csvFileId"`
+	ImageBundleFileID     string                 `gorm:"type:text;default:null" json:"imageBundleFileId" form:"imageBundleFileId"`
+	HTMLTemplateFileID    string                 `gorm:"type:text;default:null" json:"htmlTemplateFileId" form:"htmlTemplateFileId"`
+	UserID                string                 `gorm:"type:text;not null" json:"userId" form:"userId"`
 
 	TemplateFile       File                `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"templateFile,omitempty" form:"templateFile"`
 	CSVFile            File                `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"csvFile,omitempty" form:"csvFile"`
+	ImageBundleFile    File                `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"imageBundleFile,omitempty" form:"imageBundleFile"`
+	HTMLTemplateFile   File                `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"htmlTemplateFile,omitempty" form:"htmlTemplateFile"`
 	User               User                `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"user,omitempty" form:"user"`
 	SignatureAnnotates []SignatureAnnotate `json:"signatureAnnotates,omitempty" form:"signatureAnnotates"`
 	ColumnAnnotates    []ColumnAnnotate    `json:"columnAnnotates,omitempty" form:"columnAnnotates"`
+	ImageAnnotates     []ImageAnnotate     `json:"imageAnnotates,omitempty" form:"imageAnnotates"`
+	BarcodeAnnotates   []BarcodeAnnotate   `json:"barcodeAnnotates,omitempty" form:"barcodeAnnotates"`
+	QRCodeAnnotates    []QRCodeAnnotate    `json:"qrCodeAnnotates,omitempty" form:"qrCodeAnnotates"`
 }
 
 func (p Project) TableName() string {
 	return "projects"
 }
+
+// Return nil if the certificates are not encrypted. The owner password is stored sealed under secret, see
+// util.SealString.
+func (p Project) ToAutoCertPdfEncryption(secret string) (*autocert.PdfEncryption, error) {
+	if !p.EncryptPdf {
+		return nil, nil
+	}
+
+	var ownerPassword string
+	if p.PdfOwnerPassword != "" {
+		var err error
+		if ownerPassword, err = util.OpenString(p.PdfOwnerPassword, secret); err != nil {
+			return nil, fmt.Errorf("failed to open PDF owner password: %w", err)
+		}
+	}
+
+	permissions := make([]autocert.PdfPermission, len(p.PdfPermissions))
+	for i, permission := range p.PdfPermissions {
+		permissions[i] = autocert.PdfPermission(permission)
+	}
+	return &autocert.PdfEncryption{
+		OwnerPassword:      ownerPassword,
+		Permissions:        permissions,
+		UserPasswordColumn: p.PdfUserPasswordColumn,
+	}, nil
+}
+
+// Return nil if the certificates are not imposed for print
+func (p Project) ToAutoCertImposition() *autocert.Imposition {
+	if !p.ImposePdf {
+		return nil
+	}
+
+	return &autocert.Imposition{
+		Sheet:   autocert.PaperSize(p.ImposeSheet),
+		Width:   p.ImposeSheetWidth,
+		Height:  p.ImposeSheetHeight,
+		Columns: p.ImposeColumns,
+		Rows:    p.ImposeRows,
+		Gutter:  p.ImposeGutter,
+		Bleed:   p.ImposeBleed,
+		Marks:   autocert.MarkStyle(p.ImposeMarks),
+	}
+}
+
+func (p Project) ToAutoCertRasterFormats() []autocert.RasterFormat {
+	formats := make([]autocert.RasterFormat, len(p.RasterFormats))
+	for i, format := range p.RasterFormats {
+		formats[i] = autocert.RasterFormat(format)
+	}
+	return formats
+}
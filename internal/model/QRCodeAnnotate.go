@@ -0,0 +1,69 @@
+package model
+
+import (
+	"context"
+	"path/filepath"
+
+	filestorage "github.com/SeakMengs/AutoCert/internal/file_storage"
+	"github.com/SeakMengs/AutoCert/internal/util"
+	"github.com/SeakMengs/AutoCert/pkg/autocert"
+)
+
+type QRCodeAnnotate struct {
+	BaseAnnotateModel
+	BaseModel
+
+	ForegroundColor string `gorm:"type:varchar(20);default:'#000000'" json:"foregroundColor" form:"foregroundColor"`
+	// Empty for a transparent background
+	BackgroundColor string `gorm:"type:varchar(20);default:''" json:"backgroundColor" form:"backgroundColor"`
+	ErrorCorrection string `gorm:"type:varchar(1);default:'M'" json:"errorCorrection" form:"errorCorrection"`
+	QuietZone       int    `gorm:"type:integer" json:"quietZone" form:"quietZone"`
+	ShowCaption     bool   `gorm:"type:boolean;default:false" json:"showCaption" form:"showCaption"`
+	// Empty to caption the verification URL
+	Caption    string `gorm:"type:varchar(255);default:''" json:"caption" form:"caption"`
+	FontName   string `gorm:"type:varchar(200)" json:"fontName" form:"fontName"`
+	LogoFileID string `gorm:"type:text;default:null" json:"-" form:"logoFileId"`
+
+	LogoFile File `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-" form:"-"`
+}
+
+func (qa QRCodeAnnotate) TableName() string {
+	return "qr_code_annotates"
+}
+
+// Don't forget to defer remove the logo file after using the temp file
+func (qa QRCodeAnnotate) ToAutoCertQRCodeAnnotate(ctx context.Context, s3 *filestorage.MinioClient) (*autocert.QRCodeAnnotate, error) {
+	annotate := &autocert.QRCodeAnnotate{
+		BaseAnnotate: autocert.BaseAnnotate{
+			ID:        qa.ID,
+			Type:      autocert.AnnotateTypeQRCode,
+			Position:  autocert.Position{X: qa.X, Y: qa.Y},
+			Size:      autocert.Size{Width: qa.Width, Height: qa.Height},
+			Condition: qa.Condition,
+		},
+		ForegroundColor: qa.ForegroundColor,
+		BackgroundColor: qa.BackgroundColor,
+		ErrorCorrection: autocert.QRErrorCorrection(qa.ErrorCorrection),
+		QuietZone:       qa.QuietZone,
+		ShowCaption:     qa.ShowCaption,
+		Caption:         qa.Caption,
+		FontName:        qa.FontName,
+	}
+
+	if qa.LogoFileID == "" {
+		return annotate, nil
+	}
+
+	tmp, err := util.CreateTemp("autocert_qrcode_logo_*" + filepath.Ext(qa.LogoFile.FileName))
+	if err != nil {
+		return nil, err
+	}
+
+	err = qa.LogoFile.DownloadToLocal(ctx, s3, tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	annotate.LogoFilePath = tmp.Name()
+
+	return annotate, nil
+}
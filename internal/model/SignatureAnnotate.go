@@ -41,10 +41,11 @@ func (sa SignatureAnnotate) ToAutoCertSignatureAnnotate(ctx context.Context, s3
 
 	return &autocert.SignatureAnnotate{
 		BaseAnnotate: autocert.BaseAnnotate{
-			ID:       sa.ID,
-			Type:     autocert.AnnotateTypeSignature,
-			Position: autocert.Position{X: sa.X, Y: sa.Y},
-			Size:     autocert.Size{Width: sa.Width, Height: sa.Height},
+			ID:        sa.ID,
+			Type:      autocert.AnnotateTypeSignature,
+			Position:  autocert.Position{X: sa.X, Y: sa.Y},
+			Size:      autocert.Size{Width: sa.Width, Height: sa.Height},
+			Condition: sa.Condition,
 		},
 		SignatureFilePath: tmp.Name(),
 		Email:             sa.Email,
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// PKCS#12 key of a project owner used to digitally sign the certificates of their projects. The file is
+// re-encrypted with the signing key secret of the server, the password given on upload is not kept.
+type SigningKey struct {
+	BaseModel
+	UserID      string    `gorm:"type:text;not null;uniqueIndex" json:"-" form:"userId"`
+	KeyFileID   string    `gorm:"type:text;not null" json:"-" form:"keyFileId"`
+	SubjectName string    `gorm:"type:text;not null" json:"subjectName" form:"subjectName"`
+	NotAfter    time.Time `gorm:"type:timestamptz;not null" json:"notAfter" form:"notAfter"`
+
+	User    User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"-" form:"-"`
+	KeyFile File `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-" form:"-"`
+}
+
+func (sk SigningKey) TableName() string {
+	return "signing_keys"
+}
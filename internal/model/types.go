@@ -0,0 +1,39 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// StringArray is stored as a jsonb array, it implements driver.Valuer so it also works with map updates
+type StringArray []string
+
+func (sa StringArray) Value() (driver.Value, error) {
+	if sa == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal(sa)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (sa *StringArray) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*sa = StringArray{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for StringArray: %T", value)
+	}
+
+	return json.Unmarshal(data, sa)
+}
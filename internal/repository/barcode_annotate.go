@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	constant "github.com/SeakMengs/AutoCert/internal/constant"
+	"github.com/SeakMengs/AutoCert/internal/model"
+	"gorm.io/gorm"
+)
+
+type BarcodeAnnotateRepository struct {
+	*baseRepository
+}
+
+func (bar BarcodeAnnotateRepository) Create(ctx context.Context, tx *gorm.DB, ba *model.BarcodeAnnotate) error {
+	bar.logger.Debugf("Create barcode annotate with data: %v \n", ba)
+
+	db := bar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if err := db.WithContext(ctx).Model(&model.BarcodeAnnotate{}).Create(&ba).Error; err != nil {
+		bar.logger.Errorf("Failed to create barcode annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (bar BarcodeAnnotateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB, projectId string) ([]model.BarcodeAnnotate, error) {
+	bar.logger.Debugf("Get barcode annotates of project id: %s \n", projectId)
+
+	db := bar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	var bas []model.BarcodeAnnotate
+	if err := db.WithContext(ctx).Model(&model.BarcodeAnnotate{}).Where(model.BarcodeAnnotate{
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			ProjectID: projectId,
+		},
+	}).Find(&bas).Error; err != nil {
+		bar.logger.Errorf("Failed to get barcode annotates: %v", err)
+		return nil, err
+	}
+
+	return bas, nil
+}
+
+func (bar BarcodeAnnotateRepository) Update(ctx context.Context, tx *gorm.DB, ba map[string]any) error {
+	bar.logger.Debugf("Update barcode annotate with data: %v \n", ba)
+
+	db := bar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if ba["id"] == "" {
+		bar.logger.Errorf("Failed to update barcode annotate: ID is empty")
+		return errors.New("ID cannot be empty for update operation")
+	}
+
+	// remove key that cannot be updated
+	var forbiddenKeys = []string{"created_at", "updated_at"}
+
+	for _, key := range forbiddenKeys {
+		delete(ba, key)
+	}
+
+	if err := db.WithContext(ctx).Model(&model.BarcodeAnnotate{}).Where(model.BarcodeAnnotate{
+		BaseModel: model.BaseModel{
+			ID: ba["id"].(string),
+		},
+	}).Updates(&ba).Error; err != nil {
+		bar.logger.Errorf("Failed to update barcode annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (bar BarcodeAnnotateRepository) Delete(ctx context.Context, tx *gorm.DB, id string) error {
+	bar.logger.Debugf("Delete barcode annotate with id: %s \n", id)
+
+	db := bar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if err := db.WithContext(ctx).Model(&model.BarcodeAnnotate{}).Where(model.BarcodeAnnotate{
+		BaseModel: model.BaseModel{
+			ID: id,
+		},
+	}).Delete(&model.BarcodeAnnotate{}).Error; err != nil {
+		bar.logger.Errorf("Failed to delete barcode annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
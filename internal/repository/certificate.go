@@ -46,8 +46,8 @@ func (cr CertificateRepository) CreateMany(ctx context.Context, tx *gorm.DB, cer
 	return certificates, nil
 }
 
-// Return certificates, merged certificate, zip certificate, and total count
-func (plr CertificateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB, projectId string, page, pageSize uint) (*[]model.Certificate, *model.Certificate, *model.Certificate, int64, error) {
+// Return certificates, merged certificate, zip certificate, imposed certificate, and total count
+func (plr CertificateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB, projectId string, page, pageSize uint) (*[]model.Certificate, *model.Certificate, *model.Certificate, *model.Certificate, int64, error) {
 	plr.logger.Debugf("Get certificates by project id: %s", projectId)
 
 	db := plr.getDB(tx)
@@ -57,13 +57,14 @@ func (plr CertificateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB
 	var certificates []model.Certificate
 	var certificateMerged model.Certificate
 	var certificateZip model.Certificate
+	var certificateImposed model.Certificate
 	total := int64(0)
 
 	if err := db.WithContext(ctx).Model(&model.Certificate{}).Where(map[string]any{
 		"project_id": projectId,
 		"type":       autocert.CertificateTypeNormal,
 	}).Count(&total).Error; err != nil {
-		return &certificates, &certificateMerged, &certificateZip, total, err
+		return &certificates, &certificateMerged, &certificateZip, &certificateImposed, total, err
 	}
 
 	query := db.WithContext(ctx).Model(&model.Certificate{}).Where(map[string]any{
@@ -72,7 +73,7 @@ func (plr CertificateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB
 	}).Preload("CertificateFile").Order("number asc")
 
 	if err := query.Offset(int((page - 1) * pageSize)).Limit(int(pageSize)).Find(&certificates).Error; err != nil {
-		return &certificates, &certificateMerged, &certificateZip, total, err
+		return &certificates, &certificateMerged, &certificateZip, &certificateImposed, total, err
 	}
 
 	if err := db.WithContext(ctx).Model(&model.Certificate{}).Where(model.Certificate{
@@ -80,7 +81,7 @@ func (plr CertificateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB
 		Type:      autocert.CertificateTypeMerged,
 	}).Preload("CertificateFile").First(&certificateMerged).Error; err != nil {
 		if err != gorm.ErrRecordNotFound {
-			return &certificates, &certificateMerged, &certificateZip, total, err
+			return &certificates, &certificateMerged, &certificateZip, &certificateImposed, total, err
 		}
 	}
 
@@ -89,11 +90,20 @@ func (plr CertificateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB
 		Type:      autocert.CertificateTypeZip,
 	}).Preload("CertificateFile").First(&certificateZip).Error; err != nil {
 		if err != gorm.ErrRecordNotFound {
-			return &certificates, &certificateMerged, &certificateZip, total, err
+			return &certificates, &certificateMerged, &certificateZip, &certificateImposed, total, err
 		}
 	}
 
-	return &certificates, &certificateMerged, &certificateZip, total, nil
+	if err := db.WithContext(ctx).Model(&model.Certificate{}).Where(model.Certificate{
+		ProjectID: projectId,
+		Type:      autocert.CertificateTypeImposed,
+	}).Preload("CertificateFile").First(&certificateImposed).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return &certificates, &certificateMerged, &certificateZip, &certificateImposed, total, err
+		}
+	}
+
+	return &certificates, &certificateMerged, &certificateZip, &certificateImposed, total, nil
 }
 
 func (plr CertificateRepository) GetByNumber(ctx context.Context, tx *gorm.DB, projectId string, number int) (*model.Certificate, error) {
@@ -104,13 +114,31 @@ func (plr CertificateRepository) GetByNumber(ctx context.Context, tx *gorm.DB, p
 	defer cancel()
 
 	var certificate model.Certificate
-	if err := db.WithContext(ctx).Model(&model.Certificate{}).Where(map[string]any{"number": number, "project_id": projectId}).Preload("CertificateFile").First(&certificate).Error; err != nil {
+	if err := db.WithContext(ctx).Model(&model.Certificate{}).Where(map[string]any{"number": number, "project_id": projectId, "type": autocert.CertificateTypeNormal}).Preload("CertificateFile").First(&certificate).Error; err != nil {
 		return &certificate, err
 	}
 
 	return &certificate, nil
 }
 
+// Return the images of the certificate of the number, see autocert.Settings.RasterFormats
+func (plr CertificateRepository) GetImagesByNumber(ctx context.Context, tx *gorm.DB, projectId string, number int) ([]model.Certificate, error) {
+	plr.logger.Debugf("Get certificate images by number: %d, project id: %s", number, projectId)
+
+	db := plr.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	var images []model.Certificate
+	if err := db.WithContext(ctx).Model(&model.Certificate{}).Where(map[string]any{"number": number, "project_id": projectId}).
+		Where("type IN (?)", []autocert.CertificateType{autocert.CertificateTypePNG, autocert.CertificateTypeJPEG, autocert.CertificateTypeWebP}).
+		Preload("CertificateFile").Order("type asc").Find(&images).Error; err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
 func (plr CertificateRepository) GetById(ctx context.Context, tx *gorm.DB, id string) (*model.Certificate, error) {
 	plr.logger.Debugf("Get certificate by id: %s", id)
 
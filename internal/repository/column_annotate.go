@@ -28,6 +28,26 @@ func (car ColumnAnnotateRepository) Create(ctx context.Context, tx *gorm.DB, ca
 	return nil
 }
 
+func (car ColumnAnnotateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB, projectId string) ([]model.ColumnAnnotate, error) {
+	car.logger.Debugf("Get column annotates of project id: %s \n", projectId)
+
+	db := car.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	var cas []model.ColumnAnnotate
+	if err := db.WithContext(ctx).Model(&model.ColumnAnnotate{}).Where(model.ColumnAnnotate{
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			ProjectID: projectId,
+		},
+	}).Find(&cas).Error; err != nil {
+		car.logger.Errorf("Failed to get column annotates: %v", err)
+		return nil, err
+	}
+
+	return cas, nil
+}
+
 func (car ColumnAnnotateRepository) Update(ctx context.Context, tx *gorm.DB, ca map[string]any) error {
 	car.logger.Debugf("Update column annotate with data: %v \n", ca)
 
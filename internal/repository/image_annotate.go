@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	constant "github.com/SeakMengs/AutoCert/internal/constant"
+	"github.com/SeakMengs/AutoCert/internal/model"
+	"gorm.io/gorm"
+)
+
+type ImageAnnotateRepository struct {
+	*baseRepository
+}
+
+// imageFile is the uploaded static image, nil if the image is read from the image bundle
+func (iar ImageAnnotateRepository) Create(ctx context.Context, tx *gorm.DB, ia *model.ImageAnnotate, imageFile *model.File) error {
+	iar.logger.Debugf("Create image annotate with data: %v \n", ia)
+
+	db := iar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if imageFile != nil {
+		if err := db.WithContext(ctx).Model(&model.File{}).Create(imageFile).Error; err != nil {
+			return err
+		}
+		ia.ImageFileID = imageFile.ID
+	}
+
+	if err := db.WithContext(ctx).Model(&model.ImageAnnotate{}).Create(&ia).Error; err != nil {
+		iar.logger.Errorf("Failed to create image annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (iar ImageAnnotateRepository) GetById(ctx context.Context, tx *gorm.DB, id string, projectId string) (*model.ImageAnnotate, error) {
+	iar.logger.Debugf("Get image annotate with id: %s \n", id)
+
+	db := iar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	ia := &model.ImageAnnotate{}
+	if err := db.WithContext(ctx).Model(&model.ImageAnnotate{}).Where(model.ImageAnnotate{
+		BaseModel: model.BaseModel{
+			ID: id,
+		},
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			ProjectID: projectId,
+		},
+	}).Preload("ImageFile").First(ia).Error; err != nil {
+		iar.logger.Errorf("Failed to get image annotate: %v", err)
+		return nil, err
+	}
+
+	return ia, nil
+}
+
+func (iar ImageAnnotateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB, projectId string) ([]model.ImageAnnotate, error) {
+	iar.logger.Debugf("Get image annotates of project id: %s \n", projectId)
+
+	db := iar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	var ias []model.ImageAnnotate
+	if err := db.WithContext(ctx).Model(&model.ImageAnnotate{}).Where(model.ImageAnnotate{
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			ProjectID: projectId,
+		},
+	}).Find(&ias).Error; err != nil {
+		iar.logger.Errorf("Failed to get image annotates: %v", err)
+		return nil, err
+	}
+
+	return ias, nil
+}
+
+func (iar ImageAnnotateRepository) Update(ctx context.Context, tx *gorm.DB, ia map[string]any) error {
+	iar.logger.Debugf("Update image annotate with data: %v \n", ia)
+
+	db := iar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if ia["id"] == "" {
+		iar.logger.Errorf("Failed to update image annotate: ID is empty")
+		return errors.New("ID cannot be empty for update operation")
+	}
+
+	// remove key that cannot be updated
+	var forbiddenKeys = []string{"created_at", "updated_at"}
+
+	for _, key := range forbiddenKeys {
+		delete(ia, key)
+	}
+
+	if err := db.WithContext(ctx).Model(&model.ImageAnnotate{}).Where(model.ImageAnnotate{
+		BaseModel: model.BaseModel{
+			ID: ia["id"].(string),
+		},
+	}).Updates(&ia).Error; err != nil {
+		iar.logger.Errorf("Failed to update image annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Replace the static image of the image annotate, the old file is not deleted from the storage
+func (iar ImageAnnotateRepository) UpdateImageFile(ctx context.Context, tx *gorm.DB, id string, imageFile *model.File) error {
+	iar.logger.Debugf("Update image file of image annotate with id: %s \n", id)
+
+	db := iar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if err := db.WithContext(ctx).Model(&model.File{}).Create(imageFile).Error; err != nil {
+		return err
+	}
+
+	if err := db.WithContext(ctx).Model(&model.ImageAnnotate{}).Where(model.ImageAnnotate{
+		BaseModel: model.BaseModel{
+			ID: id,
+		},
+	}).Update("image_file_id", imageFile.ID).Error; err != nil {
+		iar.logger.Errorf("Failed to update image file of image annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (iar ImageAnnotateRepository) Delete(ctx context.Context, tx *gorm.DB, id string) error {
+	iar.logger.Debugf("Delete image annotate with id: %s \n", id)
+
+	db := iar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if err := db.WithContext(ctx).Model(&model.ImageAnnotate{}).Where(model.ImageAnnotate{
+		BaseModel: model.BaseModel{
+			ID: id,
+		},
+	}).Delete(&model.ImageAnnotate{}).Error; err != nil {
+		iar.logger.Errorf("Failed to delete image annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/SeakMengs/AutoCert/internal/auth"
 	constant "github.com/SeakMengs/AutoCert/internal/constant"
 	"github.com/SeakMengs/AutoCert/internal/model"
+	"github.com/SeakMengs/AutoCert/pkg/autocert"
 	"gorm.io/gorm"
 )
 
@@ -28,6 +30,26 @@ func (pr ProjectRepository) Create(ctx context.Context, tx *gorm.DB, project *mo
 	return project, nil
 }
 
+// Return the project without its files and annotates, Eg: to read the settings saved earlier in the transaction
+func (pr ProjectRepository) GetById(ctx context.Context, tx *gorm.DB, projectId string) (*model.Project, error) {
+	pr.logger.Debugf("Get project by id: %s \n", projectId)
+
+	db := pr.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	var project model.Project
+	if err := db.WithContext(ctx).Model(&model.Project{}).Where(&model.Project{
+		BaseModel: model.BaseModel{
+			ID: projectId,
+		},
+	}).First(&project).Error; err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
 func (pr ProjectRepository) GetRoleOfProject(ctx context.Context, tx *gorm.DB, projectID string, authUser *auth.JWTPayload) ([]constant.ProjectRole, *model.Project, error) {
 	pr.logger.Debugf("Get role of project with projectID: %s and userID: %s \n", projectID, authUser.ID)
 
@@ -49,6 +71,19 @@ func (pr ProjectRepository) GetRoleOfProject(ctx context.Context, tx *gorm.DB, p
 		Preload("ColumnAnnotates", func(db *gorm.DB) *gorm.DB {
 			return db.Order("column_annotates.created_at ASC")
 		}).
+		Preload("ImageBundleFile").
+		Preload("HTMLTemplateFile").
+		Preload("ImageAnnotates.ImageFile").
+		Preload("ImageAnnotates", func(db *gorm.DB) *gorm.DB {
+			return db.Order("image_annotates.created_at ASC")
+		}).
+		Preload("BarcodeAnnotates", func(db *gorm.DB) *gorm.DB {
+			return db.Order("barcode_annotates.created_at ASC")
+		}).
+		Preload("QRCodeAnnotates.LogoFile").
+		Preload("QRCodeAnnotates", func(db *gorm.DB) *gorm.DB {
+			return db.Order("qr_code_annotates.created_at ASC")
+		}).
 		First(&project).Error; err != nil {
 		if err != gorm.ErrRecordNotFound {
 			return nil, nil, err
@@ -251,20 +286,69 @@ func (pr ProjectRepository) GetProjectsForSignatory(ctx context.Context, tx *gor
 	return projectRes, totalProjects, nil
 }
 
-func (pr ProjectRepository) UpdateSetting(ctx context.Context, tx *gorm.DB, projectId string, embedQr bool) error {
-	pr.logger.Debugf("Update project setting with projectId: %s and embedQr: %v \n", projectId, embedQr)
+func (pr ProjectRepository) UpdateSetting(ctx context.Context, tx *gorm.DB, projectId string, embedQr bool, rasterFormats []autocert.RasterFormat, rasterDPI float64, pdfa bool, signatureLevel autocert.SignatureLevel, metadataTitle, metadataSubject string, encryption *autocert.PdfEncryption, fileNameTemplate, zipGroupByColumn, outlineTitle string, mergedIndex bool, imposition *autocert.Imposition) error {
+	pr.logger.Debugf("Update project setting with projectId: %s, embedQr: %v, rasterFormats: %v, rasterDPI: %v, pdfa: %v, signatureLevel: %v, metadataTitle: %s, metadataSubject: %s, encryption: %v, fileNameTemplate: %s, zipGroupByColumn: %s, outlineTitle: %s, mergedIndex: %v and imposition: %+v \n", projectId, embedQr, rasterFormats, rasterDPI, pdfa, signatureLevel, metadataTitle, metadataSubject, encryption != nil, fileNameTemplate, zipGroupByColumn, outlineTitle, mergedIndex, imposition)
+
+	formats := make(model.StringArray, 0, len(rasterFormats))
+	for _, format := range rasterFormats {
+		if !slices.Contains(formats, string(format)) {
+			formats = append(formats, string(format))
+		}
+	}
+
+	// Disabling the encryption clears its settings
+	var ownerPassword, userPasswordColumn string
+	permissions := model.StringArray{}
+	if encryption != nil {
+		ownerPassword = encryption.OwnerPassword
+		userPasswordColumn = encryption.UserPasswordColumn
+		for _, permission := range encryption.Permissions {
+			if !slices.Contains(permissions, string(permission)) {
+				permissions = append(permissions, string(permission))
+			}
+		}
+	}
+
+	// Disabling the imposition clears its settings
+	var impose autocert.Imposition
+	if imposition != nil {
+		impose = *imposition
+	}
 
 	db := pr.getDB(tx)
 	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
 	defer cancel()
 
 	// Need to select because gorm does not allow none-zero value to be updated unless selected
-	if err := db.WithContext(ctx).Model(&model.Project{}).Select("embed_qr").Where(&model.Project{
+	if err := db.WithContext(ctx).Model(&model.Project{}).Select("embed_qr", "raster_formats", "raster_dpi", "pdfa", "signature_level", "metadata_title", "metadata_subject", "encrypt_pdf", "pdf_owner_password", "pdf_permissions", "pdf_user_password_column", "file_name_template", "zip_group_by_column", "outline_title", "merged_index", "impose_pdf", "impose_sheet", "impose_sheet_width", "impose_sheet_height", "impose_columns", "impose_rows", "impose_gutter", "impose_bleed", "impose_marks").Where(&model.Project{
 		BaseModel: model.BaseModel{
 			ID: projectId,
 		},
 	}).Updates(&model.Project{
-		EmbedQr: embedQr,
+		EmbedQr:               embedQr,
+		RasterFormats:         formats,
+		RasterDPI:             rasterDPI,
+		PDFA:                  pdfa,
+		SignatureLevel:        string(signatureLevel),
+		MetadataTitle:         metadataTitle,
+		MetadataSubject:       metadataSubject,
+		EncryptPdf:            encryption != nil,
+		PdfOwnerPassword:      ownerPassword,
+		PdfPermissions:        permissions,
+		PdfUserPasswordColumn: userPasswordColumn,
+		FileNameTemplate:      fileNameTemplate,
+		ZipGroupByColumn:      zipGroupByColumn,
+		OutlineTitle:          outlineTitle,
+		MergedIndex:           mergedIndex,
+		ImposePdf:             imposition != nil,
+		ImposeSheet:           string(impose.Sheet),
+		ImposeSheetWidth:      impose.Width,
+		ImposeSheetHeight:     impose.Height,
+		ImposeColumns:         impose.Columns,
+		ImposeRows:            impose.Rows,
+		ImposeGutter:          impose.Gutter,
+		ImposeBleed:           impose.Bleed,
+		ImposeMarks:           string(impose.Marks),
 	}).Error; err != nil {
 		return err
 	}
@@ -327,6 +411,40 @@ func (pr ProjectRepository) UpdateCSVFile(ctx context.Context, tx *gorm.DB, proj
 	return nil
 }
 
+func (pr ProjectRepository) UpdateImageBundleFile(ctx context.Context, tx *gorm.DB, project model.Project, imageBundleFile *model.File) error {
+	pr.logger.Debugf("Update project image bundle file with data: %v \n", project)
+
+	db := pr.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if project.ImageBundleFileID == "" {
+		if err := db.WithContext(ctx).Model(&model.File{}).Create(imageBundleFile).Error; err != nil {
+			return err
+		}
+	} else {
+		if err := db.WithContext(ctx).Model(&model.File{}).Where(&model.File{
+			BaseModel: model.BaseModel{
+				ID: project.ImageBundleFileID,
+			},
+		}).Updates(imageBundleFile).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := db.WithContext(ctx).Model(&model.Project{}).Where(&model.Project{
+		BaseModel: model.BaseModel{
+			ID: project.ID,
+		},
+	}).Updates(&model.Project{
+		ImageBundleFileID: imageBundleFile.ID,
+	}).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (pr ProjectRepository) UpdateProjectVisibility(ctx context.Context, tx *gorm.DB, projectId string, isPublic bool) error {
 	pr.logger.Debugf("Toggle project visibility with projectId: %s and isPublic: %v \n", projectId, isPublic)
 
@@ -428,6 +546,33 @@ func (pr ProjectRepository) Delete(ctx context.Context, tx *gorm.DB, projectId s
 		return err
 	}
 
+	// Delete associated image annotations
+	if err := tx.Model(&model.ImageAnnotate{}).Where(&model.ImageAnnotate{
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			ProjectID: p.ID,
+		},
+	}).Delete(&model.ImageAnnotate{}).Error; err != nil {
+		return err
+	}
+
+	// Delete associated barcode annotations
+	if err := tx.Model(&model.BarcodeAnnotate{}).Where(&model.BarcodeAnnotate{
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			ProjectID: p.ID,
+		},
+	}).Delete(&model.BarcodeAnnotate{}).Error; err != nil {
+		return err
+	}
+
+	// Delete associated QR code annotations
+	if err := tx.Model(&model.QRCodeAnnotate{}).Where(&model.QRCodeAnnotate{
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			ProjectID: p.ID,
+		},
+	}).Delete(&model.QRCodeAnnotate{}).Error; err != nil {
+		return err
+	}
+
 	// Delete certificates associated with the project
 	if err := tx.Model(&model.Certificate{}).Where(&model.Certificate{
 		ProjectID: p.ID,
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	constant "github.com/SeakMengs/AutoCert/internal/constant"
+	"github.com/SeakMengs/AutoCert/internal/model"
+	"gorm.io/gorm"
+)
+
+type QRCodeAnnotateRepository struct {
+	*baseRepository
+}
+
+// logoFile is the uploaded centre logo, nil if the QR code has no logo
+func (qar QRCodeAnnotateRepository) Create(ctx context.Context, tx *gorm.DB, qa *model.QRCodeAnnotate, logoFile *model.File) error {
+	qar.logger.Debugf("Create QR code annotate with data: %v \n", qa)
+
+	db := qar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if logoFile != nil {
+		if err := db.WithContext(ctx).Model(&model.File{}).Create(logoFile).Error; err != nil {
+			return err
+		}
+		qa.LogoFileID = logoFile.ID
+	}
+
+	if err := db.WithContext(ctx).Model(&model.QRCodeAnnotate{}).Create(&qa).Error; err != nil {
+		qar.logger.Errorf("Failed to create QR code annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (qar QRCodeAnnotateRepository) GetById(ctx context.Context, tx *gorm.DB, id string, projectId string) (*model.QRCodeAnnotate, error) {
+	qar.logger.Debugf("Get QR code annotate with id: %s \n", id)
+
+	db := qar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	qa := &model.QRCodeAnnotate{}
+	if err := db.WithContext(ctx).Model(&model.QRCodeAnnotate{}).Where(model.QRCodeAnnotate{
+		BaseModel: model.BaseModel{
+			ID: id,
+		},
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			ProjectID: projectId,
+		},
+	}).Preload("LogoFile").First(qa).Error; err != nil {
+		qar.logger.Errorf("Failed to get QR code annotate: %v", err)
+		return nil, err
+	}
+
+	return qa, nil
+}
+
+func (qar QRCodeAnnotateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB, projectId string) ([]model.QRCodeAnnotate, error) {
+	qar.logger.Debugf("Get QR code annotates of project id: %s \n", projectId)
+
+	db := qar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	var qas []model.QRCodeAnnotate
+	if err := db.WithContext(ctx).Model(&model.QRCodeAnnotate{}).Where(model.QRCodeAnnotate{
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			ProjectID: projectId,
+		},
+	}).Find(&qas).Error; err != nil {
+		qar.logger.Errorf("Failed to get QR code annotates: %v", err)
+		return nil, err
+	}
+
+	return qas, nil
+}
+
+func (qar QRCodeAnnotateRepository) Update(ctx context.Context, tx *gorm.DB, qa map[string]any) error {
+	qar.logger.Debugf("Update QR code annotate with data: %v \n", qa)
+
+	db := qar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if qa["id"] == "" {
+		qar.logger.Errorf("Failed to update QR code annotate: ID is empty")
+		return errors.New("ID cannot be empty for update operation")
+	}
+
+	// remove key that cannot be updated
+	var forbiddenKeys = []string{"created_at", "updated_at"}
+
+	for _, key := range forbiddenKeys {
+		delete(qa, key)
+	}
+
+	if err := db.WithContext(ctx).Model(&model.QRCodeAnnotate{}).Where(model.QRCodeAnnotate{
+		BaseModel: model.BaseModel{
+			ID: qa["id"].(string),
+		},
+	}).Updates(&qa).Error; err != nil {
+		qar.logger.Errorf("Failed to update QR code annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Replace the logo of the QR code annotate, the old file is not deleted from the storage
+func (qar QRCodeAnnotateRepository) UpdateLogoFile(ctx context.Context, tx *gorm.DB, id string, logoFile *model.File) error {
+	qar.logger.Debugf("Update logo file of QR code annotate with id: %s \n", id)
+
+	db := qar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if err := db.WithContext(ctx).Model(&model.File{}).Create(logoFile).Error; err != nil {
+		return err
+	}
+
+	if err := db.WithContext(ctx).Model(&model.QRCodeAnnotate{}).Where(model.QRCodeAnnotate{
+		BaseModel: model.BaseModel{
+			ID: id,
+		},
+	}).Update("logo_file_id", logoFile.ID).Error; err != nil {
+		qar.logger.Errorf("Failed to update logo file of QR code annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (qar QRCodeAnnotateRepository) Delete(ctx context.Context, tx *gorm.DB, id string) error {
+	qar.logger.Debugf("Delete QR code annotate with id: %s \n", id)
+
+	db := qar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if err := db.WithContext(ctx).Model(&model.QRCodeAnnotate{}).Where(model.QRCodeAnnotate{
+		BaseModel: model.BaseModel{
+			ID: id,
+		},
+	}).Delete(&model.QRCodeAnnotate{}).Error; err != nil {
+		qar.logger.Errorf("Failed to delete QR code annotate: %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -27,7 +27,11 @@ type Repository struct {
 	File              *FileRepository
 	ColumnAnnotate    *ColumnAnnotateRepository
 	SignatureAnnotate *SignatureAnnotateRepository
+	ImageAnnotate     *ImageAnnotateRepository
+	BarcodeAnnotate   *BarcodeAnnotateRepository
+	QRCodeAnnotate    *QRCodeAnnotateRepository
 	Signature         *SignatureRepository
+	SigningKey        *SigningKeyRepository
 	Certificate       *CertificateRepository
 	ProjectLog        *ProjectLogRepository
 }
@@ -49,7 +53,11 @@ func NewRepository(db *gorm.DB, logger *zap.SugaredLogger, jwtService auth.JWTIn
 		File:              &FileRepository{baseRepository: br},
 		ColumnAnnotate:    &ColumnAnnotateRepository{baseRepository: br},
 		SignatureAnnotate: &SignatureAnnotateRepository{baseRepository: br},
+		ImageAnnotate:     &ImageAnnotateRepository{baseRepository: br},
+		BarcodeAnnotate:   &BarcodeAnnotateRepository{baseRepository: br},
+		QRCodeAnnotate:    &QRCodeAnnotateRepository{baseRepository: br},
 		Signature:         &SignatureRepository{baseRepository: br},
+		SigningKey:        &SigningKeyRepository{baseRepository: br},
 		Certificate:       &CertificateRepository{baseRepository: br},
 		ProjectLog:        &ProjectLogRepository{baseRepository: br},
 	}
@@ -50,6 +50,26 @@ func (sar SignatureAnnotateRepository) GetById(ctx context.Context, tx *gorm.DB,
 	return sa, nil
 }
 
+func (sar SignatureAnnotateRepository) GetByProjectId(ctx context.Context, tx *gorm.DB, projectId string) ([]model.SignatureAnnotate, error) {
+	sar.logger.Debugf("Get signature annotates of project id: %s \n", projectId)
+
+	db := sar.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	var sas []model.SignatureAnnotate
+	if err := db.WithContext(ctx).Model(&model.SignatureAnnotate{}).Where(model.SignatureAnnotate{
+		BaseAnnotateModel: model.BaseAnnotateModel{
+			ProjectID: projectId,
+		},
+	}).Find(&sas).Error; err != nil {
+		sar.logger.Errorf("Failed to get signature annotates: %v", err)
+		return nil, err
+	}
+
+	return sas, nil
+}
+
 func (sar SignatureAnnotateRepository) Update(ctx context.Context, tx *gorm.DB, sa map[string]any) error {
 	sar.logger.Debugf("Update signature annotate with data: %v \n", sa)
 
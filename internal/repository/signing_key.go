@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	constant "github.com/SeakMengs/AutoCert/internal/constant"
+	"github.com/SeakMengs/AutoCert/internal/model"
+	"gorm.io/gorm"
+)
+
+type SigningKeyRepository struct {
+	*baseRepository
+}
+
+func (skr SigningKeyRepository) Create(ctx context.Context, tx *gorm.DB, signingKey *model.SigningKey) (*model.SigningKey, error) {
+	skr.logger.Debugf("Create signing key of user: %s \n", signingKey.UserID)
+
+	db := skr.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if err := db.WithContext(ctx).Model(&model.SigningKey{}).Create(signingKey).Error; err != nil {
+		return signingKey, err
+	}
+
+	return signingKey, nil
+}
+
+func (skr SigningKeyRepository) GetByUserId(ctx context.Context, tx *gorm.DB, userId string) (*model.SigningKey, error) {
+	skr.logger.Debugf("Get signing key of user: %s \n", userId)
+
+	db := skr.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	signingKey := model.SigningKey{}
+
+	if err := db.WithContext(ctx).Model(&model.SigningKey{}).Preload("KeyFile").Where(model.SigningKey{
+		UserID: userId,
+	}).First(&signingKey).Error; err != nil {
+		return nil, err
+	}
+
+	return &signingKey, nil
+}
+
+func (skr SigningKeyRepository) DeleteByUserId(ctx context.Context, tx *gorm.DB, userId string) error {
+	skr.logger.Debugf("Delete signing key of user: %s \n", userId)
+
+	db := skr.getDB(tx)
+	ctx, cancel := context.WithTimeout(ctx, constant.QUERY_TIMEOUT_DURATION)
+	defer cancel()
+
+	if err := db.WithContext(ctx).Model(&model.SigningKey{}).Where(model.SigningKey{
+		UserID: userId,
+	}).Delete(&model.SigningKey{}).Error; err != nil {
+		skr.logger.Errorf("Failed to delete signing key: %v", err)
+		return err
+	}
+
+	return nil
+}
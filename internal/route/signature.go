@@ -15,3 +15,13 @@ func V1_Signatures(r *gin.RouterGroup, sc *controller.SignatureController, middl
 		v1.GET("/:signatureId", sc.GetSignatureById)
 	}
 }
+
+func V1_SigningKey(r *gin.RouterGroup, sc *controller.SignatureController, middleware *middleware.Middleware) {
+	v1 := r.Group("/v1/me/signing-key")
+	v1.Use(middleware.AuthMiddleware)
+	{
+		v1.PUT("", sc.PutSigningKey)
+		v1.GET("", sc.GetSigningKey)
+		v1.DELETE("", sc.RemoveSigningKey)
+	}
+}
@@ -1,6 +1,13 @@
 package util
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
 	gonanoid "github.com/matoous/go-nanoid/v2"
 )
 
@@ -11,3 +18,52 @@ func GenerateNChar(n int) (string, error) {
 	}
 	return id, nil
 }
+
+func newSecretGCM(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, errors.New("secret is empty")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt the value with AES-256-GCM under the secret to keep it in storage, Eg: a PDF owner password, see OpenString
+func SealString(value, secret string) (string, error) {
+	gcm, err := newSecretGCM(secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(value), nil)), nil
+}
+
+// Decrypt a value sealed by SealString with the same secret
+func OpenString(sealed, secret string) (string, error) {
+	gcm, err := newSecretGCM(secret)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("sealed value is too short")
+	}
+
+	value, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("failed to open sealed value, the secret may have changed")
+	}
+	return string(value), nil
+}
@@ -29,3 +29,39 @@ func TestGenerateNChar(t *testing.T) {
 		})
 	}
 }
+
+func TestSealString(t *testing.T) {
+	sealed, err := SealString("owner-secret", "server-secret")
+	if err != nil {
+		t.Fatalf("SealString() error = %v", err)
+	}
+	if sealed == "owner-secret" {
+		t.Fatalf("SealString() returned the value in plaintext")
+	}
+
+	tests := []struct {
+		name    string
+		sealed  string
+		secret  string
+		want    string
+		wantErr bool
+	}{
+		{"Same secret", sealed, "server-secret", "owner-secret", false},
+		{"Other secret", sealed, "other-secret", "", true},
+		{"Empty secret", sealed, "", "", true},
+		{"Not sealed", "owner-secret", "server-secret", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OpenString(tt.sealed, tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("OpenString() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("OpenString() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,15 @@ var rolePermissions = map[constant.ProjectRole][]constant.ProjectPermission{
 		constant.AnnotateSignatureUpdate,
 		constant.AnnotateSignatureRemove,
 		constant.AnnotateSignatureInvite,
+		constant.AnnotateImageAdd,
+		constant.AnnotateImageUpdate,
+		constant.AnnotateImageRemove,
+		constant.AnnotateBarcodeAdd,
+		constant.AnnotateBarcodeUpdate,
+		constant.AnnotateBarcodeRemove,
+		constant.AnnotateQRCodeAdd,
+		constant.AnnotateQRCodeUpdate,
+		constant.AnnotateQRCodeRemove,
 		constant.SettingsUpdate,
 		constant.TableUpdate,
 	},
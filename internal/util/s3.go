@@ -30,6 +30,12 @@ func ToGeneratedCertificateDirectoryPath(projectId string, filename string) stri
 	return filepath.Join(GetGeneratedCertificateDirectoryPath(projectId), filepath.Base(filename))
 }
 
+// Value of the Content-Disposition header of a file, disposition is "inline" or "attachment". A name which is not
+// ASCII, Eg: a Khmer name, is encoded as filename* of RFC 2231.
+func ContentDisposition(disposition string, fileName string) string {
+	return mime.FormatMediaType(disposition, map[string]string{"filename": fileName})
+}
+
 func createBucketIfNotExists(s3 *filestorage.MinioClient, bucketName string) error {
 	exists, err := s3.BucketExists(context.Background(), bucketName)
 	if err != nil {
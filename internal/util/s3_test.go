@@ -0,0 +1,21 @@
+package util
+
+import "testing"
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		disposition string
+		fileName    string
+		want        string
+	}{
+		{disposition: "attachment", fileName: "certificates.zip", want: "attachment; filename=certificates.zip"},
+		{disposition: "inline", fileName: "1001_Jane Doe.pdf", want: `inline; filename="1001_Jane Doe.pdf"`},
+		{disposition: "inline", fileName: "សុខ.pdf", want: "inline; filename*=utf-8''%E1%9E%9F%E1%9E%BB%E1%9E%81.pdf"},
+	}
+
+	for _, tt := range tests {
+		if got := ContentDisposition(tt.disposition, tt.fileName); got != tt.want {
+			t.Errorf("ContentDisposition(%q, %q) = %q, want %q", tt.disposition, tt.fileName, got, tt.want)
+		}
+	}
+}
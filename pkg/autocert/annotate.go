@@ -1,10 +1,15 @@
 package autocert
 
+import "fmt"
+
 type AnnotateType string
 
 const (
 	AnnotateTypeColumn    AnnotateType = "column"
 	AnnotateTypeSignature AnnotateType = "signature"
+	AnnotateTypeImage     AnnotateType = "image"
+	AnnotateTypeBarcode   AnnotateType = "barcode"
+	AnnotateTypeQRCode    AnnotateType = "qrcode"
 )
 
 type Position struct {
@@ -22,26 +27,56 @@ type BaseAnnotate struct {
 	Type     AnnotateType `json:"type" form:"type" binding:"required"`
 	Position `json:"position" form:"position" binding:"required"`
 	Size     `json:"size" form:"size" binding:"required"`
+	// Optional expression evaluated per CSV row, the annotate is stamped only when true. See Condition
+	Condition string `json:"condition" form:"condition"`
 }
 
 type ColumnAnnotate struct {
 	BaseAnnotate
-	// column name in the CSV file
-	Value          string     `json:"value" form:"value" binding:"required"`
-	FontName       string     `json:"fontName" form:"fontName"`
-	FontColor      string     `json:"fontColor" form:"fontColor"`
-	FontSize       float64    `json:"fontSize" form:"fontSize"`
-	FontWeight     FontWeight `json:"fontWeight" form:"fontWeight"`
-	TextFitRectBox bool       `json:"textFitRectBox" form:"textFitRectBox"`
-	TextAlign      TextAlign  `json:"textAlign" form:"textAlign"`
+	// column name in the CSV file or a template of columns, see ValueTemplate
+	Value          string        `json:"value" form:"value" binding:"required"`
+	FontName       string        `json:"fontName" form:"fontName"`
+	FontColor      string        `json:"fontColor" form:"fontColor"`
+	FontSize       float64       `json:"fontSize" form:"fontSize"`
+	FontWeight     FontWeight    `json:"fontWeight" form:"fontWeight"`
+	FontItalic     bool          `json:"fontItalic" form:"fontItalic"`
+	FontFallbacks  []string      `json:"fontFallbacks" form:"fontFallbacks"`
+	TextFitRectBox bool          `json:"textFitRectBox" form:"textFitRectBox"`
+	TextAlign      TextAlign     `json:"textAlign" form:"textAlign"`
+	VerticalAlign  VerticalAlign `json:"verticalAlign" form:"verticalAlign"`
+	LetterSpacing  float64       `json:"letterSpacing" form:"letterSpacing"`
+	LineHeight     float64       `json:"lineHeight" form:"lineHeight"`
+	TextTransform  TextTransform `json:"textTransform" form:"textTransform"`
+	TextWrap       bool          `json:"textWrap" form:"textWrap"`
+	MaxLines       int           `json:"maxLines" form:"maxLines"`
+	TextOverflow   TextOverflow  `json:"textOverflow" form:"textOverflow"`
 }
 
 func (ca ColumnAnnotate) Font() *Font {
 	return &Font{
-		Name:   ca.FontName,
-		Color:  ca.FontColor,
-		Size:   ca.FontSize,
-		Weight: ca.FontWeight,
+		Name:      ca.FontName,
+		Color:     ca.FontColor,
+		Size:      ca.FontSize,
+		Weight:    ca.FontWeight,
+		Italic:    ca.FontItalic,
+		Fallbacks: ca.FontFallbacks,
+	}
+}
+
+func (ca ColumnAnnotate) Wrap() *Wrap {
+	return &Wrap{
+		Enabled:  ca.TextWrap,
+		MaxLines: ca.MaxLines,
+		Overflow: ca.TextOverflow,
+	}
+}
+
+func (ca ColumnAnnotate) Typography() *Typography {
+	return &Typography{
+		VerticalAlign: ca.VerticalAlign,
+		LetterSpacing: ca.LetterSpacing,
+		LineHeight:    ca.LineHeight,
+		Transform:     ca.TextTransform,
 	}
 }
 
@@ -65,10 +100,155 @@ func (sa SignatureAnnotate) Rect() *Rect {
 	}
 }
 
-// Each page has a list of annotates
+type ImageAnnotate struct {
+	BaseAnnotate
+	// column name in the CSV file holding the file name of the image in the image bundle, empty for a static image
+	Value string `json:"value" form:"value"`
+	// static image used when Value is empty
+	ImageFilePath string   `json:"imageFilePath"`
+	Fit           ImageFit `json:"fit" form:"fit"`
+}
+
+func (ia ImageAnnotate) Rect() *Rect {
+	return &Rect{
+		Width:  ia.Size.Width,
+		Height: ia.Size.Height,
+	}
+}
+
+// Whether the image is read from the image bundle for each row
+func (ia ImageAnnotate) IsPerRow() bool {
+	return ia.Value != ""
+}
+
+type BarcodeAnnotate struct {
+	BaseAnnotate
+	// column name in the CSV file holding the content of the barcode, empty to encode the certificate id
+	Value     string           `json:"value" form:"value"`
+	Symbology BarcodeSymbology `json:"symbology" form:"symbology"`
+	BarColor  string           `json:"barColor" form:"barColor"`
+	// Draw the encoded content beneath the barcode
+	ShowText bool   `json:"showText" form:"showText"`
+	FontName string `json:"fontName" form:"fontName"`
+}
+
+func (ba BarcodeAnnotate) Rect() *Rect {
+	return &Rect{
+		Width:  ba.Size.Width,
+		Height: ba.Size.Height,
+	}
+}
+
+// Font of the human readable text, nil when the text is hidden
+func (ba BarcodeAnnotate) Font() *Font {
+	if !ba.ShowText {
+		return nil
+	}
+	return &Font{
+		Name:  ba.FontName,
+		Color: ba.BarColor,
+	}
+}
+
+// Whether the barcode encodes the certificate id instead of a column
+func (ba BarcodeAnnotate) IsCertificateID() bool {
+	return ba.Value == ""
+}
+
+// QR code of the verification URL of the certificate, see Settings.QrURLPattern
+type QRCodeAnnotate struct {
+	BaseAnnotate
+	ForegroundColor string `json:"foregroundColor" form:"foregroundColor"`
+	// Empty for a transparent background
+	BackgroundColor string            `json:"backgroundColor" form:"backgroundColor"`
+	ErrorCorrection QRErrorCorrection `json:"errorCorrection" form:"errorCorrection"`
+	// Empty modules around the symbol, see DefaultQRQuietZone
+	QuietZone int `json:"quietZone" form:"quietZone"`
+	// Optional image drawn at the centre of the symbol, requires error correction Q or H
+	LogoFilePath string `json:"logoFilePath"`
+	// Draw Caption beneath the QR code, the verification URL when Caption is empty
+	ShowCaption bool   `json:"showCaption" form:"showCaption"`
+	Caption     string `json:"caption" form:"caption"`
+	FontName    string `json:"fontName" form:"fontName"`
+}
+
+func (qa QRCodeAnnotate) Rect() *Rect {
+	return &Rect{
+		Width:  qa.Size.Width,
+		Height: qa.Size.Height,
+	}
+}
+
+// Font of the caption, nil when the caption is hidden
+func (qa QRCodeAnnotate) Font() *Font {
+	if !qa.ShowCaption {
+		return nil
+	}
+	return &Font{
+		Name:  qa.FontName,
+		Color: qa.ForegroundColor,
+	}
+}
+
+// The caption drawn beneath the QR code of the url, empty when hidden
+func (qa QRCodeAnnotate) CaptionOf(url string) string {
+	if !qa.ShowCaption {
+		return ""
+	}
+	if qa.Caption == "" {
+		return url
+	}
+	return qa.Caption
+}
+
+// Each page has a list of annotates, pages start at 1
 type PageSignatureAnnotations map[uint][]SignatureAnnotate
 type PageColumnAnnotations map[uint][]ColumnAnnotate
+type PageImageAnnotations map[uint][]ImageAnnotate
+type PageBarcodeAnnotations map[uint][]BarcodeAnnotate
+type PageQRCodeAnnotations map[uint][]QRCodeAnnotate
 type PageAnnotations struct {
 	PageSignatureAnnotations PageSignatureAnnotations
 	PageColumnAnnotations    PageColumnAnnotations
+	PageImageAnnotations     PageImageAnnotations
+	PageBarcodeAnnotations   PageBarcodeAnnotations
+	PageQRCodeAnnotations    PageQRCodeAnnotations
+}
+
+// Check that every annotate is on a page of a template of pageCount pages
+func (pa PageAnnotations) ValidatePages(pageCount int) error {
+	check := func(annotType AnnotateType, page uint) error {
+		if page < 1 || int(page) > pageCount {
+			return fmt.Errorf("%s annotation is on page %d but the template has %d page(s)", annotType, page, pageCount)
+		}
+		return nil
+	}
+
+	for page := range pa.PageSignatureAnnotations {
+		if err := check(AnnotateTypeSignature, page); err != nil {
+			return err
+		}
+	}
+	for page := range pa.PageColumnAnnotations {
+		if err := check(AnnotateTypeColumn, page); err != nil {
+			return err
+		}
+	}
+	for page := range pa.PageImageAnnotations {
+		if err := check(AnnotateTypeImage, page); err != nil {
+			return err
+		}
+	}
+	for page := range pa.PageBarcodeAnnotations {
+		if err := check(AnnotateTypeBarcode, page); err != nil {
+			return err
+		}
+	}
+	for page := range pa.PageQRCodeAnnotations {
+		if err := check(AnnotateTypeQRCode, page); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
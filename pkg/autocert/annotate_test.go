@@ -0,0 +1,47 @@
+package autocert
+
+import "testing"
+
+func TestValidatePages(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations PageAnnotations
+		pageCount   int
+		wantErr     bool
+	}{
+		{name: "No annotations", annotations: PageAnnotations{}, pageCount: 1},
+		{
+			name: "Annotations on every page",
+			annotations: PageAnnotations{
+				PageColumnAnnotations:    PageColumnAnnotations{1: {{}}},
+				PageSignatureAnnotations: PageSignatureAnnotations{2: {{}}},
+				PageQRCodeAnnotations:    PageQRCodeAnnotations{2: {{}}},
+			},
+			pageCount: 2,
+		},
+		{
+			name:        "Signature beyond the last page",
+			annotations: PageAnnotations{PageSignatureAnnotations: PageSignatureAnnotations{3: {{}}}},
+			pageCount:   2,
+			wantErr:     true,
+		},
+		{
+			name:        "QR code on page 0",
+			annotations: PageAnnotations{PageQRCodeAnnotations: PageQRCodeAnnotations{0: {{}}}},
+			pageCount:   2,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.annotations.ValidatePages(tt.pageCount)
+			if tt.wantErr && err == nil {
+				t.Errorf("ValidatePages(%d) expected error, got nil", tt.pageCount)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ValidatePages(%d) unexpected error: %v", tt.pageCount, err)
+			}
+		})
+	}
+}
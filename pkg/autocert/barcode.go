@@ -0,0 +1,225 @@
+package autocert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/datamatrix"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/pdf417"
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers"
+)
+
+type BarcodeSymbology string
+
+const (
+	BarcodeSymbologyCode128    BarcodeSymbology = "code128"
+	BarcodeSymbologyPDF417     BarcodeSymbology = "pdf417"
+	BarcodeSymbologyDataMatrix BarcodeSymbology = "datamatrix"
+	// EAN-8 or EAN-13 by the number of digits, the check digit is computed when left out
+	BarcodeSymbologyEAN BarcodeSymbology = "ean"
+)
+
+// Empty is valid, it is treated as Code 128
+func (s BarcodeSymbology) IsValid() bool {
+	switch s {
+	case "", BarcodeSymbologyCode128, BarcodeSymbologyPDF417, BarcodeSymbologyDataMatrix, BarcodeSymbologyEAN:
+		return true
+	}
+	return false
+}
+
+var ErrEmptyBarcodeContent = errors.New("barcode content is empty")
+
+// Security level of the PDF417 error correction, 2 is the minimum recommended by the specification
+const pdf417SecurityLevel = 2
+
+// The part of the box height used by the text beneath a barcode or QR code
+const captionHeightRatio = 0.2
+
+func EncodeBarcode(content string, symbology BarcodeSymbology) (barcode.Barcode, error) {
+	if content == "" {
+		return nil, ErrEmptyBarcodeContent
+	}
+
+	switch symbology {
+	case "", BarcodeSymbologyCode128:
+		return code128.Encode(content)
+	case BarcodeSymbologyPDF417:
+		return pdf417.Encode(content, pdf417SecurityLevel)
+	case BarcodeSymbologyDataMatrix:
+		return datamatrix.Encode(content)
+	case BarcodeSymbologyEAN:
+		bc, err := ean.Encode(strings.TrimSpace(content))
+		if err != nil {
+			return nil, fmt.Errorf("EAN requires 7, 8, 12 or 13 digits: %w", err)
+		}
+		return bc, nil
+	default:
+		return nil, fmt.Errorf("unsupported barcode symbology: %s", symbology)
+	}
+}
+
+// Number of empty modules kept around the symbol horizontally and vertically such that scanners can find its edges
+func quietZone(bc barcode.Barcode) (int, int) {
+	switch bc.Metadata().CodeKind {
+	case barcode.TypeDataMatrix:
+		return 1, 1
+	case barcode.TypePDF:
+		return 2, 2
+	case barcode.TypeEAN8, barcode.TypeEAN13:
+		return 11, 0
+	default:
+		return 10, 0
+	}
+}
+
+func isDarkModule(c color.Color) bool {
+	r, g, b, a := c.RGBA()
+	return a > 0 && (r+g+b)/3 < 0x8000
+}
+
+// BarcodeRenderer draws a barcode and its optional human readable text beneath in a box of px
+type BarcodeRenderer struct {
+	rect      Rect
+	symbology BarcodeSymbology
+	color     string
+	// Draws the human readable text, nil when the text is hidden
+	textRenderer *TextRenderer
+}
+
+// font is the font of the human readable text, nil to hide the text
+func NewBarcodeRenderer(cfg Config, rect Rect, symbology BarcodeSymbology, barColor string, font *Font) (*BarcodeRenderer, error) {
+	if !symbology.IsValid() {
+		return nil, fmt.Errorf("unsupported barcode symbology: %s", symbology)
+	}
+	if barColor == "" {
+		barColor = "#000000"
+	}
+
+	br := &BarcodeRenderer{
+		rect:      rect,
+		symbology: symbology,
+		color:     barColor,
+	}
+
+	if font != nil {
+		textFont := *font
+		if textFont.Color == "" {
+			textFont.Color = barColor
+		}
+		// The text is fitted to its part of the box
+		textFont.Size = 0
+
+		textRect := Rect{Width: rect.Width, Height: rect.Height * captionHeightRatio}
+		textRenderer, err := NewTextRenderer(cfg, textRect, textFont, Wrap{}, Typography{VerticalAlign: VerticalAlignMiddle}, Settings{RemoveLineBreaksBool: true})
+		if err != nil {
+			return nil, err
+		}
+		br.textRenderer = textRenderer
+	}
+
+	return br, nil
+}
+
+func (br *BarcodeRenderer) renderCanvas(content string) (*canvas.Canvas, error) {
+	bc, err := EncodeBarcode(content, br.symbology)
+	if err != nil {
+		return nil, err
+	}
+
+	rectMM := br.rect.toMM()
+	c := canvas.New(rectMM.Width, rectMM.Height)
+	ctx := canvas.NewContext(c)
+
+	// Origin is bottom-left, the text takes the bottom of the box and the symbol the rest
+	barsHeight := rectMM.Height
+	if br.textRenderer != nil {
+		textHeight := rectMM.Height * captionHeightRatio
+		barsHeight -= textHeight
+
+		textCanvas, err := br.textRenderer.renderCanvas(bc.Content(), TextAlignCenter)
+		if err != nil {
+			return nil, err
+		}
+		textCanvas.RenderViewTo(c, canvas.Identity)
+	}
+
+	ctx.SetFillColor(canvas.Hex(br.color))
+	quietX, quietY := quietZone(bc)
+	// Linear barcodes and PDF417 are stretched to the box, DataMatrix keeps square modules
+	square := bc.Metadata().CodeKind == barcode.TypeDataMatrix
+	drawModules(ctx, bc, rectMM.Width, barsHeight, rectMM.Height-barsHeight, quietX, quietY, square)
+
+	return c, nil
+}
+
+// Draw the dark modules of the barcode as rectangles in a box of width x height mm whose bottom is at y,
+// leaving quietX and quietY empty modules around the symbol. A square symbol keeps square modules and is centered.
+func drawModules(ctx *canvas.Context, bc barcode.Barcode, width, height, y float64, quietX, quietY int, square bool) {
+	bounds := bc.Bounds()
+	cols, rows := bounds.Dx(), bounds.Dy()
+
+	moduleW := width / float64(cols+2*quietX)
+	moduleH := height / float64(rows+2*quietY)
+	// Linear barcodes have a single row, its bars take the full height
+	if bc.Metadata().Dimensions == 1 {
+		moduleH = height
+		quietY = 0
+	}
+
+	offsetX, offsetY := 0.0, 0.0
+	if square {
+		size := math.Min(moduleW, moduleH)
+		offsetX = (width - size*float64(cols+2*quietX)) / 2
+		offsetY = (height - size*float64(rows+2*quietY)) / 2
+		moduleW, moduleH = size, size
+	}
+
+	top := y + height - offsetY - float64(quietY)*moduleH
+	left := offsetX + float64(quietX)*moduleW
+
+	// Consecutive dark modules of a row are one rectangle to keep the PDF small, all rectangles are filled as
+	// a single path such that adjacent rows have no anti-aliasing seam between them
+	modules := &canvas.Path{}
+	for row := range rows {
+		rowY := top - float64(row+1)*moduleH
+		for col := 0; col < cols; {
+			if !isDarkModule(bc.At(bounds.Min.X+col, bounds.Min.Y+row)) {
+				col++
+				continue
+			}
+
+			start := col
+			for col < cols && isDarkModule(bc.At(bounds.Min.X+col, bounds.Min.Y+row)) {
+				col++
+			}
+
+			rect := canvas.Rectangle(float64(col-start)*moduleW, moduleH).Translate(left+float64(start)*moduleW, rowY)
+			modules = modules.Append(rect)
+		}
+	}
+	ctx.DrawPath(0, 0, modules)
+}
+
+// Render the barcode of the content as a PDF of the size of the box, used by Compositor
+func (br *BarcodeRenderer) RenderBarcodeAsPdf(content string) ([]byte, error) {
+	c, err := br.renderCanvas(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf, renderers.PDF()); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,85 @@
+package autocert
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncodeBarcode(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		symbology BarcodeSymbology
+		expected  string
+		wantErr   bool
+	}{
+		{name: "Default is Code 128", content: "STU-0001", symbology: "", expected: "STU-0001"},
+		{name: "Code 128", content: "9b2f6c1e-0d4a-4c55-9e0b-6f7f6c3a1d2e", symbology: BarcodeSymbologyCode128, expected: "9b2f6c1e-0d4a-4c55-9e0b-6f7f6c3a1d2e"},
+		{name: "PDF417", content: "Dara Sok, Computer Science", symbology: BarcodeSymbologyPDF417, expected: "Dara Sok, Computer Science"},
+		{name: "DataMatrix", content: "STU-0001", symbology: BarcodeSymbologyDataMatrix, expected: "STU-0001"},
+		{name: "EAN-13 adds check digit", content: "590123412345", symbology: BarcodeSymbologyEAN, expected: "5901234123457"},
+		{name: "EAN-8 with check digit", content: "96385074", symbology: BarcodeSymbologyEAN, expected: "96385074"},
+		{name: "EAN wrong check digit", content: "5901234123450", symbology: BarcodeSymbologyEAN, wantErr: true},
+		{name: "EAN not digits", content: "STU-0001", symbology: BarcodeSymbologyEAN, wantErr: true},
+		{name: "Unknown symbology", content: "STU-0001", symbology: "aztec", wantErr: true},
+		{name: "Empty content", content: "", symbology: BarcodeSymbologyCode128, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, err := EncodeBarcode(tt.content, tt.symbology)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("EncodeBarcode(%q, %q) expected error, got nil", tt.content, tt.symbology)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EncodeBarcode(%q, %q) unexpected error: %v", tt.content, tt.symbology, err)
+			}
+			if bc.Content() != tt.expected {
+				t.Errorf("Content() = %q, want %q", bc.Content(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderBarcodeAsPdf(t *testing.T) {
+	symbologies := []BarcodeSymbology{BarcodeSymbologyCode128, BarcodeSymbologyPDF417, BarcodeSymbologyDataMatrix}
+
+	for _, symbology := range symbologies {
+		t.Run(string(symbology), func(t *testing.T) {
+			br, err := NewBarcodeRenderer(Config{}, Rect{Width: 200, Height: 60}, symbology, "", nil)
+			if err != nil {
+				t.Fatalf("NewBarcodeRenderer failed: %v", err)
+			}
+
+			pdf, err := br.RenderBarcodeAsPdf("STU-0001")
+			if err != nil {
+				t.Fatalf("RenderBarcodeAsPdf failed: %v", err)
+			}
+
+			w, h, err := GetPdfSizeByPage(bytes.NewReader(pdf), 1)
+			if err != nil {
+				t.Fatalf("Failed to read rendered PDF: %v", err)
+			}
+			// The PDF has the size of the box, allow rounding of the mm conversion
+			if w < 199 || w > 201 || h < 59 || h > 61 {
+				t.Errorf("PDF size = %.2fx%.2f, want 200x60", w, h)
+			}
+		})
+	}
+
+	br, err := NewBarcodeRenderer(Config{}, Rect{Width: 200, Height: 60}, BarcodeSymbologyCode128, "", nil)
+	if err != nil {
+		t.Fatalf("NewBarcodeRenderer failed: %v", err)
+	}
+	if _, err := br.RenderBarcodeAsPdf(""); !errors.Is(err, ErrEmptyBarcodeContent) {
+		t.Errorf("RenderBarcodeAsPdf of empty content error = %v, want %v", err, ErrEmptyBarcodeContent)
+	}
+
+	if _, err := NewBarcodeRenderer(Config{}, Rect{Width: 200, Height: 60}, "aztec", "", nil); err == nil {
+		t.Error("NewBarcodeRenderer with unknown symbology expected error, got nil")
+	}
+}
@@ -0,0 +1,419 @@
+package autocert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+/*
+ * Minimal CMS (RFC 5652) SignedData with the signed attributes of CAdES baseline: content type, message digest and
+ * signing certificate v2, always SHA-256. The signing time is not a signed attribute, PAdES puts it in the
+ * signature dictionary instead. Used by the PAdES signatures and by the time-stamp tokens of LocalTimestampAuthority.
+ */
+
+var (
+	oidData                     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo                  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidAttrContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrMessageDigest        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttrSigningCertificateV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidAttrTimeStampToken       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	oidSHA256                   = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA256WithRSA            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidECDSAWithSHA256          = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// An attribute of a signer with a single value, value is DER encoded
+type cmsAttribute struct {
+	Type  asn1.ObjectIdentifier
+	Value []byte
+}
+
+type cmsSigner struct {
+	cert  *x509.Certificate
+	chain []*x509.Certificate
+	key   crypto.Signer
+}
+
+// Sign the digest of the content as a CMS SignedData, the content is encapsulated when not nil. unsigned returns
+// the unsigned attributes computed from the signature value, Eg: a signature time-stamp, it can be nil.
+func (s cmsSigner) sign(contentType asn1.ObjectIdentifier, content, digest []byte, unsigned func(signature []byte) ([]cmsAttribute, error)) ([]byte, error) {
+	var sigAlg asn1.ObjectIdentifier
+	switch s.key.Public().(type) {
+	case *rsa.PublicKey:
+		sigAlg = oidRSAEncryption
+	case *ecdsa.PublicKey:
+		sigAlg = oidECDSAWithSHA256
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T, only RSA and ECDSA are supported", s.key.Public())
+	}
+
+	contentTypeValue, err := asn1.Marshal(contentType)
+	if err != nil {
+		return nil, err
+	}
+	digestValue, err := asn1.Marshal(digest)
+	if err != nil {
+		return nil, err
+	}
+	signedAttrs, err := marshalCMSAttributes([]cmsAttribute{
+		{Type: oidAttrContentType, Value: contentTypeValue},
+		{Type: oidAttrMessageDigest, Value: digestValue},
+		{Type: oidAttrSigningCertificateV2, Value: signingCertificateV2(s.cert)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.Sum256(signedAttrs)
+	signature, err := s.key.Sign(rand.Reader, h[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	var unsignedAttrs []byte
+	if unsigned != nil {
+		attrs, err := unsigned(signature)
+		if err != nil {
+			return nil, err
+		}
+		if len(attrs) > 0 {
+			if unsignedAttrs, err = marshalCMSAttributes(attrs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var b cryptobyte.Builder
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1ObjectIdentifier(oidSignedData)
+		b.AddASN1(cbasn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+			b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1Int64(1)
+				b.AddASN1(cbasn1.SET, func(b *cryptobyte.Builder) {
+					addAlgorithmIdentifier(b, oidSHA256, false)
+				})
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					b.AddASN1ObjectIdentifier(contentType)
+					if content != nil {
+						b.AddASN1(cbasn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+							b.AddASN1OctetString(content)
+						})
+					}
+				})
+				b.AddASN1(cbasn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+					b.AddBytes(s.cert.Raw)
+					for _, cert := range s.chain {
+						b.AddBytes(cert.Raw)
+					}
+				})
+				b.AddASN1(cbasn1.SET, func(b *cryptobyte.Builder) {
+					b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+						b.AddASN1Int64(1)
+						addIssuerAndSerial(b, s.cert)
+						addAlgorithmIdentifier(b, oidSHA256, false)
+						// The signed attributes are signed as a SET and stored with an implicit tag
+						b.AddASN1(cbasn1.Tag(0).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+							b.AddBytes(asn1Content(signedAttrs))
+						})
+						addAlgorithmIdentifier(b, sigAlg, slices.Equal(sigAlg, oidRSAEncryption))
+						b.AddASN1OctetString(signature)
+						if unsignedAttrs != nil {
+							b.AddASN1(cbasn1.Tag(1).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+								b.AddBytes(asn1Content(unsignedAttrs))
+							})
+						}
+					})
+				})
+			})
+		})
+	})
+
+	return b.Bytes()
+}
+
+// DER encoding of a SET OF attributes, sorted as DER requires
+func marshalCMSAttributes(attrs []cmsAttribute) ([]byte, error) {
+	encoded := make([][]byte, 0, len(attrs))
+	for _, attr := range attrs {
+		var b cryptobyte.Builder
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(attr.Type)
+			b.AddASN1(cbasn1.SET, func(b *cryptobyte.Builder) {
+				b.AddBytes(attr.Value)
+			})
+		})
+		der, err := b.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, der)
+	}
+	slices.SortFunc(encoded, bytes.Compare)
+
+	var b cryptobyte.Builder
+	b.AddASN1(cbasn1.SET, func(b *cryptobyte.Builder) {
+		for _, der := range encoded {
+			b.AddBytes(der)
+		}
+	})
+	return b.Bytes()
+}
+
+// The ESS signing certificate v2 attribute binds the signer certificate to the signature, required by CAdES
+func signingCertificateV2(cert *x509.Certificate) []byte {
+	h := sha256.Sum256(cert.Raw)
+
+	var b cryptobyte.Builder
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			// ESSCertIDv2, the hash algorithm is omitted since SHA-256 is the default
+			b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1OctetString(h[:])
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+						// directoryName of GeneralName
+						b.AddASN1(cbasn1.Tag(4).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+							b.AddBytes(cert.RawIssuer)
+						})
+					})
+					b.AddASN1BigInt(cert.SerialNumber)
+				})
+			})
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+func addAlgorithmIdentifier(b *cryptobyte.Builder, oid asn1.ObjectIdentifier, nullParams bool) {
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1ObjectIdentifier(oid)
+		if nullParams {
+			b.AddASN1NULL()
+		}
+	})
+}
+
+func addIssuerAndSerial(b *cryptobyte.Builder, cert *x509.Certificate) {
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddBytes(cert.RawIssuer)
+		b.AddASN1BigInt(cert.SerialNumber)
+	})
+}
+
+// The content octets of a DER element without its tag and length
+func asn1Content(der []byte) []byte {
+	s := cryptobyte.String(der)
+	var content cryptobyte.String
+	var tag cbasn1.Tag
+	if !s.ReadAnyASN1(&content, &tag) {
+		return nil
+	}
+	return content
+}
+
+type cmsSignedData struct {
+	contentType asn1.ObjectIdentifier
+	// Encapsulated content, nil when detached
+	content []byte
+	certs   []*x509.Certificate
+
+	// The first signer
+	issuer    []byte
+	serial    *big.Int
+	sigAlg    asn1.ObjectIdentifier
+	signature []byte
+	// The signed attributes encoded as a SET, as signed
+	signedAttrs   []byte
+	attrs         map[string][]byte
+	unsignedAttrs map[string][]byte
+}
+
+var errMalformedCMS = errors.New("malformed CMS signed data")
+
+// Parse a CMS ContentInfo of SignedData, trailing bytes are ignored, Eg: the padding of a PDF signature
+func parseCMS(der []byte) (*cmsSignedData, error) {
+	input := cryptobyte.String(der)
+	var contentInfo, signedData cryptobyte.String
+	var contentInfoType asn1.ObjectIdentifier
+	if !input.ReadASN1(&contentInfo, cbasn1.SEQUENCE) ||
+		!contentInfo.ReadASN1ObjectIdentifier(&contentInfoType) || !contentInfoType.Equal(oidSignedData) ||
+		!contentInfo.ReadASN1(&signedData, cbasn1.Tag(0).Constructed().ContextSpecific()) ||
+		!signedData.ReadASN1(&signedData, cbasn1.SEQUENCE) {
+		return nil, errMalformedCMS
+	}
+
+	var version int64
+	var digestAlgs, encap, signerInfos cryptobyte.String
+	sd := &cmsSignedData{}
+	if !signedData.ReadASN1Integer(&version) ||
+		!signedData.ReadASN1(&digestAlgs, cbasn1.SET) ||
+		!signedData.ReadASN1(&encap, cbasn1.SEQUENCE) ||
+		!encap.ReadASN1ObjectIdentifier(&sd.contentType) {
+		return nil, errMalformedCMS
+	}
+
+	var eContent cryptobyte.String
+	var hasContent bool
+	if !encap.ReadOptionalASN1(&eContent, &hasContent, cbasn1.Tag(0).Constructed().ContextSpecific()) {
+		return nil, errMalformedCMS
+	}
+	if hasContent {
+		var content cryptobyte.String
+		if !eContent.ReadASN1(&content, cbasn1.OCTET_STRING) {
+			return nil, errMalformedCMS
+		}
+		sd.content = content
+	}
+
+	var certs cryptobyte.String
+	var hasCerts bool
+	if !signedData.ReadOptionalASN1(&certs, &hasCerts, cbasn1.Tag(0).Constructed().ContextSpecific()) {
+		return nil, errMalformedCMS
+	}
+	for !certs.Empty() {
+		var raw cryptobyte.String
+		if !certs.ReadASN1Element(&raw, cbasn1.SEQUENCE) {
+			return nil, errMalformedCMS
+		}
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate of CMS signed data: %w", err)
+		}
+		sd.certs = append(sd.certs, cert)
+	}
+
+	// Revocation information is not used
+	if !signedData.SkipOptionalASN1(cbasn1.Tag(1).Constructed().ContextSpecific()) ||
+		!signedData.ReadASN1(&signerInfos, cbasn1.SET) {
+		return nil, errMalformedCMS
+	}
+
+	var signerInfo, sid, issuer, digestAlg, signedAttrs, sigAlg, signature cryptobyte.String
+	if !signerInfos.ReadASN1(&signerInfo, cbasn1.SEQUENCE) ||
+		!signerInfo.ReadASN1Integer(&version) ||
+		!signerInfo.ReadASN1(&sid, cbasn1.SEQUENCE) ||
+		!sid.ReadASN1Element(&issuer, cbasn1.SEQUENCE) ||
+		!signerInfo.ReadASN1(&digestAlg, cbasn1.SEQUENCE) ||
+		!signerInfo.ReadASN1(&signedAttrs, cbasn1.Tag(0).Constructed().ContextSpecific()) ||
+		!signerInfo.ReadASN1(&sigAlg, cbasn1.SEQUENCE) ||
+		!sigAlg.ReadASN1ObjectIdentifier(&sd.sigAlg) ||
+		!signerInfo.ReadASN1(&signature, cbasn1.OCTET_STRING) {
+		return nil, errMalformedCMS
+	}
+	sd.issuer = issuer
+	sd.serial = new(big.Int)
+	if !sid.ReadASN1Integer(sd.serial) {
+		return nil, errMalformedCMS
+	}
+
+	var digestOid asn1.ObjectIdentifier
+	if !digestAlg.ReadASN1ObjectIdentifier(&digestOid) || !digestOid.Equal(oidSHA256) {
+		return nil, errors.New("unsupported digest algorithm of CMS signed data, only SHA-256 is supported")
+	}
+	sd.signature = signature
+
+	var b cryptobyte.Builder
+	b.AddASN1(cbasn1.SET, func(b *cryptobyte.Builder) {
+		b.AddBytes(signedAttrs)
+	})
+	var err error
+	if sd.signedAttrs, err = b.Bytes(); err != nil {
+		return nil, err
+	}
+	if sd.attrs, err = parseCMSAttributes(signedAttrs); err != nil {
+		return nil, err
+	}
+
+	var unsignedAttrs cryptobyte.String
+	var hasUnsigned bool
+	if !signerInfo.ReadOptionalASN1(&unsignedAttrs, &hasUnsigned, cbasn1.Tag(1).Constructed().ContextSpecific()) {
+		return nil, errMalformedCMS
+	}
+	if sd.unsignedAttrs, err = parseCMSAttributes(unsignedAttrs); err != nil {
+		return nil, err
+	}
+
+	return sd, nil
+}
+
+// The first value of each attribute, by OID
+func parseCMSAttributes(s cryptobyte.String) (map[string][]byte, error) {
+	attrs := make(map[string][]byte)
+	for !s.Empty() {
+		var attr, values, value cryptobyte.String
+		var oid asn1.ObjectIdentifier
+		if !s.ReadASN1(&attr, cbasn1.SEQUENCE) ||
+			!attr.ReadASN1ObjectIdentifier(&oid) ||
+			!attr.ReadASN1(&values, cbasn1.SET) ||
+			!values.ReadAnyASN1Element(&value, nil) {
+			return nil, errMalformedCMS
+		}
+		attrs[oid.String()] = value
+	}
+	return attrs, nil
+}
+
+// Verify the signature of the first signer over the digest of the content and return its certificate. The
+// certificate is not verified against a trust store, only that it made the signature.
+func (sd *cmsSignedData) verify(digest []byte) (*x509.Certificate, error) {
+	var signer *x509.Certificate
+	for _, cert := range sd.certs {
+		if bytes.Equal(cert.RawIssuer, sd.issuer) && cert.SerialNumber.Cmp(sd.serial) == 0 {
+			signer = cert
+			break
+		}
+	}
+	if signer == nil {
+		return nil, errors.New("signer certificate is not embedded")
+	}
+
+	var contentType asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(sd.attrs[oidAttrContentType.String()], &contentType); err != nil || !contentType.Equal(sd.contentType) {
+		return nil, errors.New("content type attribute does not match the content")
+	}
+
+	var messageDigest []byte
+	if _, err := asn1.Unmarshal(sd.attrs[oidAttrMessageDigest.String()], &messageDigest); err != nil || !bytes.Equal(messageDigest, digest) {
+		return nil, errors.New("message digest does not match the signed content")
+	}
+
+	if essCert, ok := sd.attrs[oidAttrSigningCertificateV2.String()]; ok && !bytes.Contains(essCert, signingCertificateHash(signer)) {
+		return nil, errors.New("signing certificate attribute does not match the signer certificate")
+	}
+
+	var alg x509.SignatureAlgorithm
+	switch {
+	case sd.sigAlg.Equal(oidRSAEncryption), sd.sigAlg.Equal(oidSHA256WithRSA):
+		alg = x509.SHA256WithRSA
+	case sd.sigAlg.Equal(oidECDSAWithSHA256):
+		alg = x509.ECDSAWithSHA256
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm %s", sd.sigAlg)
+	}
+	if err := signer.CheckSignature(alg, sd.signedAttrs, sd.signature); err != nil {
+		return nil, fmt.Errorf("signature does not match: %w", err)
+	}
+
+	return signer, nil
+}
+
+func signingCertificateHash(cert *x509.Certificate) []byte {
+	h := sha256.Sum256(cert.Raw)
+	return h[:]
+}
@@ -0,0 +1,122 @@
+package autocert
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+/*
+ * Compositor loads a PDF once, stamps every overlay (text, signature, qr code) as form XObjects into the same
+ * model.Context and writes the result once. Compare to ApplyWatermarkToPdf which read, validate and rewrite
+ * the whole PDF for each overlay.
+ */
+type Compositor struct {
+	ctx *model.Context
+}
+
+func NewCompositor(rs io.ReadSeeker) (*Compositor, error) {
+	ctx, err := api.ReadAndValidate(rs, model.NewDefaultConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	return &Compositor{ctx: ctx}, nil
+}
+
+func NewCompositorFromFile(inFile string) (*Compositor, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF file: %w", err)
+	}
+	defer f.Close()
+
+	return NewCompositor(f)
+}
+
+func (c *Compositor) PageCount() int {
+	return c.ctx.PageCount
+}
+
+// return as width and height in px
+func (c *Compositor) PageSize(pageNum int) (float64, float64, error) {
+	if pageNum < 1 || pageNum > c.ctx.PageCount {
+		return 0, 0, fmt.Errorf("page number %d is out of range (max page count is %d)", pageNum, c.ctx.PageCount)
+	}
+
+	dims, err := c.ctx.PageDims()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get page dimensions: %v", err)
+	}
+
+	dim := dims[pageNum-1]
+	return dim.Width, dim.Height, nil
+}
+
+func (c *Compositor) stamp(pageNum int, wm *model.Watermark) error {
+	if pageNum < 1 || pageNum > c.ctx.PageCount {
+		return fmt.Errorf("page number %d is out of range (max page count is %d)", pageNum, c.ctx.PageCount)
+	}
+
+	return pdfcpu.AddWatermarks(c.ctx, types.IntSet{pageNum: true}, wm)
+}
+
+// Stamp the first page of a PDF overlay at position x, y (px, anchored at top-left) of the selected page
+func (c *Compositor) StampPdf(pageNum int, overlay io.ReadSeeker, posX, posY float64) error {
+	wm, err := api.PDFWatermarkForReadSeeker(overlay, 1, watermarkDescription(posX, posY), true, false, types.POINTS)
+	if err != nil {
+		return err
+	}
+
+	return c.stamp(pageNum, wm)
+}
+
+// Stamp an image overlay at position x, y (px, anchored at top-left) of the selected page
+func (c *Compositor) StampImage(pageNum int, overlay io.Reader, posX, posY float64) error {
+	return c.StampScaledImage(pageNum, overlay, posX, posY, 1)
+}
+
+// Stamp an image overlay drawn at scale times its pixel size, Eg: an image rendered at 3x of the box is stamped
+// with a scale of 1/3 to keep its resolution
+func (c *Compositor) StampScaledImage(pageNum int, overlay io.Reader, posX, posY, scale float64) error {
+	wm, err := api.ImageWatermarkForReader(overlay, scaledWatermarkDescription(posX, posY, scale), true, false, types.POINTS)
+	if err != nil {
+		return err
+	}
+
+	return c.stamp(pageNum, wm)
+}
+
+// Stamp a pdf or image file, the overlay type is detected by file extension
+func (c *Compositor) StampFile(pageNum int, overlayFile string, posX, posY float64) error {
+	data, err := os.ReadFile(overlayFile)
+	if err != nil {
+		return err
+	}
+
+	switch ext := filepath.Ext(overlayFile); ext {
+	case ".pdf":
+		return c.StampPdf(pageNum, bytes.NewReader(data), posX, posY)
+	case ".png", ".jpg", ".jpeg":
+		return c.StampImage(pageNum, bytes.NewReader(data), posX, posY)
+	default:
+		return fmt.Errorf("unsupported watermark file type: %s", ext)
+	}
+}
+
+func (c *Compositor) Write(w io.Writer) error {
+	c.ctx.EnsureVersionForWriting()
+	return api.WriteContext(c.ctx, w)
+}
+
+func (c *Compositor) WriteFile(outFile string) error {
+	c.ctx.EnsureVersionForWriting()
+	return api.WriteContextFile(c.ctx, outFile)
+}
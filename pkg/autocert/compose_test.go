@@ -0,0 +1,153 @@
+package autocert
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Count the form and image XObjects drawn by a page, one for the template image and one for each stamp
+func pageXObjectCount(t *testing.T, ctx *model.Context, pageNum int) int {
+	t.Helper()
+
+	_, _, inh, err := ctx.PageDict(pageNum, true)
+	if err != nil {
+		t.Fatalf("Failed to read page %d: %v", pageNum, err)
+	}
+	if inh == nil || inh.Resources == nil {
+		return 0
+	}
+	xobjects, err := ctx.DereferenceDict(inh.Resources["XObject"])
+	if err != nil {
+		t.Fatalf("Failed to read XObjects of page %d: %v", pageNum, err)
+	}
+	return len(xobjects)
+}
+
+func TestCompositor(t *testing.T) {
+	dir := t.TempDir()
+
+	png := filepath.Join(dir, "page.png")
+	writeTestPng(t, png, 300, 200)
+	page := filepath.Join(dir, "page.pdf")
+	if err := ImageTemplateToPdfFile(png, page, TemplateOptions{PaperSize: PaperSizeCustom, Width: 300, Height: 200}); err != nil {
+		t.Fatalf("ImageTemplateToPdfFile failed: %v", err)
+	}
+	template := filepath.Join(dir, "template.pdf")
+	if err := MergePdf([]string{page, page, page}, template); err != nil {
+		t.Fatalf("MergePdf failed: %v", err)
+	}
+
+	overlayPng := filepath.Join(dir, "overlay.png")
+	writeTestPng(t, overlayPng, 60, 30)
+	overlayPdf := filepath.Join(dir, "overlay.pdf")
+	if err := ImageTemplateToPdfFile(overlayPng, overlayPdf, TemplateOptions{PaperSize: PaperSizeCustom, Width: 60, Height: 30}); err != nil {
+		t.Fatalf("ImageTemplateToPdfFile failed: %v", err)
+	}
+
+	c, err := NewCompositorFromFile(template)
+	if err != nil {
+		t.Fatalf("NewCompositorFromFile failed: %v", err)
+	}
+	if c.PageCount() != 3 {
+		t.Fatalf("PageCount = %d, want 3", c.PageCount())
+	}
+	width, height, err := c.PageSize(2)
+	if err != nil {
+		t.Fatalf("PageSize failed: %v", err)
+	}
+
+	if err := c.StampFile(2, overlayPdf, 10, 10); err != nil {
+		t.Fatalf("StampFile of a PDF failed: %v", err)
+	}
+	overlay, err := os.Open(overlayPng)
+	if err != nil {
+		t.Fatalf("Failed to open overlay: %v", err)
+	}
+	defer overlay.Close()
+	if err := c.StampScaledImage(2, overlay, 100, 50, 0.5); err != nil {
+		t.Fatalf("StampScaledImage failed: %v", err)
+	}
+
+	// Every overlay is written at once
+	out := filepath.Join(dir, "out.pdf")
+	if err := c.WriteFile(out); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	ctx, err := api.ReadContextFile(out)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if ctx.PageCount != 3 {
+		t.Fatalf("Output has %d pages, want 3", ctx.PageCount)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	defer f.Close()
+	outWidth, outHeight, err := GetPdfSizeByPage(f, 2)
+	if err != nil {
+		t.Fatalf("GetPdfSizeByPage failed: %v", err)
+	}
+	if outWidth != width || outHeight != height {
+		t.Errorf("Page 2 size = %gx%g, want %gx%g", outWidth, outHeight, width, height)
+	}
+
+	base := pageXObjectCount(t, ctx, 1)
+	if n := pageXObjectCount(t, ctx, 3); n != base {
+		t.Errorf("Page 3 draws %d XObjects, want %d like page 1", n, base)
+	}
+	if n := pageXObjectCount(t, ctx, 2); n != base+2 {
+		t.Errorf("Page 2 draws %d XObjects, want %d", n, base+2)
+	}
+}
+
+func TestCompositorStampFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	png := filepath.Join(dir, "page.png")
+	writeTestPng(t, png, 300, 200)
+	template := filepath.Join(dir, "template.pdf")
+	if err := ImageTemplateToPdfFile(png, template, TemplateOptions{PaperSize: PaperSizeCustom, Width: 300, Height: 200}); err != nil {
+		t.Fatalf("ImageTemplateToPdfFile failed: %v", err)
+	}
+	gif := filepath.Join(dir, "overlay.gif")
+	if err := os.WriteFile(gif, []byte("GIF89a"), 0644); err != nil {
+		t.Fatalf("Failed to write overlay: %v", err)
+	}
+
+	c, err := NewCompositorFromFile(template)
+	if err != nil {
+		t.Fatalf("NewCompositorFromFile failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		pageNum int
+		file    string
+		wantErr string
+	}{
+		{name: "Unsupported extension", pageNum: 1, file: gif, wantErr: "unsupported watermark file type: .gif"},
+		{name: "Page before first", pageNum: 0, file: png, wantErr: "page number 0 is out of range"},
+		{name: "Page after last", pageNum: 2, file: png, wantErr: "page number 2 is out of range"},
+		{name: "Missing file", pageNum: 1, file: filepath.Join(dir, "missing.png"), wantErr: "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.StampFile(tt.pageNum, tt.file, 0, 0)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("StampFile error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, _, err := c.PageSize(2); err == nil {
+		t.Error("PageSize of page 2 succeeded, want an error")
+	}
+}
@@ -0,0 +1,345 @@
+package autocert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+ * A condition decides per CSV row whether an annotation is stamped, Eg:
+ *
+ *	Grade == "A"
+ *	Score >= 90 && Status != "Withdrawn"
+ *	!(Honours == "") || (upper Grade) == "A"
+ *
+ * Operands are the same as the arguments of a value template: a column, a quoted string, a number
+ * or a function call wrapped in parentheses. Both sides are compared as numbers when they both
+ * parse as numbers, otherwise as strings. An operand alone is true when it is not blank.
+ * An empty condition is always true.
+ */
+
+var ErrInvalidCondition = errors.New("invalid condition")
+
+type Condition struct {
+	source string
+	root   conditionNode
+}
+
+type conditionNode interface {
+	eval(row map[string]string) (bool, error)
+}
+
+type notNode struct {
+	operand conditionNode
+}
+
+func (n notNode) eval(row map[string]string) (bool, error) {
+	ok, err := n.operand.eval(row)
+	return !ok, err
+}
+
+type logicalNode struct {
+	op          string
+	left, right conditionNode
+}
+
+func (n logicalNode) eval(row map[string]string) (bool, error) {
+	left, err := n.left.eval(row)
+	if err != nil {
+		return false, err
+	}
+
+	// Short circuit like Go so the right side is not evaluated needlessly
+	if n.op == "&&" && !left || n.op == "||" && left {
+		return left, nil
+	}
+
+	return n.right.eval(row)
+}
+
+type compareNode struct {
+	op          string
+	left, right valueNode
+}
+
+func (n compareNode) eval(row map[string]string) (bool, error) {
+	left, err := n.left.eval(row)
+	if err != nil {
+		return false, err
+	}
+	right, err := n.right.eval(row)
+	if err != nil {
+		return false, err
+	}
+
+	return compareValues(n.op, left, right), nil
+}
+
+type truthyNode struct {
+	operand valueNode
+}
+
+func (n truthyNode) eval(row map[string]string) (bool, error) {
+	value, err := n.operand.eval(row)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(value) != "", nil
+}
+
+var compareOperators = []string{"==", "!=", "<", "<=", ">", ">="}
+
+func compareValues(op, left, right string) bool {
+	left, right = strings.TrimSpace(left), strings.TrimSpace(right)
+
+	var cmp int
+	if l, r, ok := parseNumbers(left, right); ok {
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(left, right)
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func parseNumbers(left, right string) (float64, float64, bool) {
+	l, err := strconv.ParseFloat(strings.ReplaceAll(left, ",", ""), 64)
+	if err != nil || math.IsNaN(l) {
+		return 0, 0, false
+	}
+	r, err := strconv.ParseFloat(strings.ReplaceAll(right, ",", ""), 64)
+	if err != nil || math.IsNaN(r) {
+		return 0, 0, false
+	}
+	return l, r, true
+}
+
+func ParseCondition(expr string) (*Condition, error) {
+	c := &Condition{source: expr}
+	if strings.TrimSpace(expr) == "" {
+		return c, nil
+	}
+
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCondition, err)
+	}
+
+	p := &actionParser{tokens: tokens}
+	c.root, err = p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCondition, err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %s in %q", ErrInvalidCondition, p.tokens[p.pos].describe(), expr)
+	}
+
+	return c, nil
+}
+
+func (c *Condition) String() string {
+	return c.source
+}
+
+// Return the unique columns read by the condition in order of appearance
+func (c *Condition) Columns() []string {
+	var columns []string
+
+	var walkValue func(node valueNode)
+	walkValue = func(node valueNode) {
+		switch n := node.(type) {
+		case columnNode:
+			if !slices.Contains(columns, string(n)) {
+				columns = append(columns, string(n))
+			}
+		case callNode:
+			for _, arg := range n.args {
+				walkValue(arg)
+			}
+		}
+	}
+
+	var walk func(node conditionNode)
+	walk = func(node conditionNode) {
+		switch n := node.(type) {
+		case notNode:
+			walk(n.operand)
+		case logicalNode:
+			walk(n.left)
+			walk(n.right)
+		case compareNode:
+			walkValue(n.left)
+			walkValue(n.right)
+		case truthyNode:
+			walkValue(n.operand)
+		}
+	}
+
+	if c.root != nil {
+		walk(c.root)
+	}
+
+	return columns
+}
+
+// Check that every column read by the condition is one of the CSV headers
+func (c *Condition) Validate(headers []string) error {
+	for _, column := range c.Columns() {
+		if !slices.Contains(headers, column) {
+			return fmt.Errorf("%w: column %q is not in the table", ErrInvalidCondition, column)
+		}
+	}
+	return nil
+}
+
+// Evaluate the condition with a row of the CSV, a column missing in the row is empty
+func (c *Condition) Evaluate(row map[string]string) (bool, error) {
+	if c.root == nil {
+		return true, nil
+	}
+
+	ok, err := c.root.eval(row)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate %q: %w", c.source, err)
+	}
+	return ok, nil
+}
+
+func (p *actionParser) peekOperator(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return "", false
+	}
+	op := p.tokens[p.pos].value
+	return op, slices.Contains(ops, op)
+}
+
+// or = and ("||" and)*
+func (p *actionParser) parseOr() (conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peekOperator("||"); !ok {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+}
+
+// and = not ("&&" not)*
+func (p *actionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peekOperator("&&"); !ok {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+}
+
+// not = "!" not | "(" or ")" | comparison
+func (p *actionParser) parseNot() (conditionNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("unexpected end of condition")
+	}
+
+	if _, ok := p.peekOperator("!"); ok {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+
+	// A parenthesis not followed by a function groups a sub condition
+	if p.tokens[p.pos].kind == tokenLeftParen && !p.isCallAt(p.pos+1) {
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenRightParen {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+// comparison = arg (operator arg)?
+func (p *actionParser) parseComparison() (conditionNode, error) {
+	if p.tokens[p.pos].kind == tokenOperator {
+		return nil, fmt.Errorf("unexpected %s, a value is needed", p.tokens[p.pos].describe())
+	}
+
+	left, err := p.parseArg()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.peekOperator(compareOperators...)
+	if !ok {
+		return truthyNode{operand: left}, nil
+	}
+	p.pos++
+
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("missing value after %s", op)
+	}
+	if p.tokens[p.pos].kind == tokenOperator {
+		return nil, fmt.Errorf("unexpected %s after %s", p.tokens[p.pos].describe(), op)
+	}
+	right, err := p.parseArg()
+	if err != nil {
+		return nil, err
+	}
+
+	return compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *actionParser) isCallAt(pos int) bool {
+	return pos < len(p.tokens) && p.tokens[pos].kind == tokenWord && isValueFunc(p.tokens[pos].value)
+}
@@ -0,0 +1,117 @@
+package autocert
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConditionEvaluate(t *testing.T) {
+	row := map[string]string{
+		"Grade":   "A",
+		"Score":   "92.5",
+		"Credits": "1,200",
+		"Status":  "Completed",
+		"Honours": "",
+		"ថ្នាក់":  "ល្អ",
+	}
+
+	tests := []struct {
+		name      string
+		condition string
+		expected  bool
+	}{
+		{name: "Empty condition", condition: "", expected: true},
+		{name: "String equal", condition: `Grade == "A"`, expected: true},
+		{name: "String not equal", condition: `Grade != "A"`, expected: false},
+		{name: "Number greater or equal", condition: "Score >= 90", expected: true},
+		{name: "Number compared numerically", condition: "Score > 100", expected: false},
+		{name: "Number with thousands separator", condition: "Credits >= 1000", expected: true},
+		{name: "And", condition: `Score >= 90 && Status == "Completed"`, expected: true},
+		{name: "Or", condition: `Grade == "B" || Score > 90`, expected: true},
+		{name: "Not", condition: `!(Grade == "A")`, expected: false},
+		{name: "Grouping", condition: `(Grade == "B" || Grade == "A") && Score < 95`, expected: true},
+		{name: "Truthy column", condition: "Status", expected: true},
+		{name: "Blank column is false", condition: "Honours", expected: false},
+		{name: "Missing column is false", condition: "Missing", expected: false},
+		{name: "Function call", condition: `(lower Grade) == "a"`, expected: true},
+		{name: "Khmer column", condition: `ថ្នាក់ == "ល្អ"`, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCondition(tt.condition)
+			if err != nil {
+				t.Fatalf("ParseCondition(%q) failed: %v", tt.condition, err)
+			}
+
+			got, err := c.Evaluate(row)
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseConditionError(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+	}{
+		{name: "Assignment", condition: `Grade = "A"`},
+		{name: "Missing right operand", condition: "Score >="},
+		{name: "Missing left operand", condition: "== 90"},
+		{name: "Two operators", condition: "Score >= > 90"},
+		{name: "Dangling and", condition: `Grade == "A" &&`},
+		{name: "Missing closing parenthesis", condition: `(Grade == "A"`},
+		{name: "Unbalanced closing parenthesis", condition: `Grade == "A")`},
+		{name: "Two values", condition: `Grade "A"`},
+		{name: "Function without parentheses", condition: `upper Grade == "A"`},
+		{name: "Unknown function", condition: `(shout Grade) == "A"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCondition(tt.condition)
+			if !errors.Is(err, ErrInvalidCondition) {
+				t.Errorf("ParseCondition(%q) error = %v, want %v", tt.condition, err, ErrInvalidCondition)
+			}
+		})
+	}
+}
+
+func TestConditionValidate(t *testing.T) {
+	headers := []string{"Grade", "Score"}
+
+	tests := []struct {
+		name      string
+		condition string
+		columns   []string
+		wantErr   bool
+	}{
+		{name: "Known columns", condition: `Grade == "A" || (default Score 0) >= 90`, columns: []string{"Grade", "Score"}},
+		{name: "Empty condition", condition: ""},
+		{name: "Unknown column", condition: `Grade == "A" && Status == "Completed"`, columns: []string{"Grade", "Status"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCondition(tt.condition)
+			if err != nil {
+				t.Fatalf("ParseCondition(%q) failed: %v", tt.condition, err)
+			}
+
+			if columns := c.Columns(); !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("Columns() = %v, want %v", columns, tt.columns)
+			}
+
+			err = c.Validate(headers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
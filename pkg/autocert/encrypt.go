@@ -0,0 +1,158 @@
+package autocert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// PdfPermission is an operation a recipient may do on an encrypted certificate without the owner password
+type PdfPermission string
+
+const (
+	PdfPermissionPrint PdfPermission = "print"
+	// Change the content of the pages, Eg: edit the text in a PDF editor
+	PdfPermissionModify PdfPermission = "modify"
+	// Add or change comments and fill the form fields
+	PdfPermissionAnnotate PdfPermission = "annotate"
+	// Copy the text and the images
+	PdfPermissionCopy PdfPermission = "copy"
+)
+
+func (p PdfPermission) IsValid() bool {
+	switch p {
+	case PdfPermissionPrint, PdfPermissionModify, PdfPermissionAnnotate, PdfPermissionCopy:
+		return true
+	}
+	return false
+}
+
+// Permissions given when none is set: the certificate can be printed but not edited
+var DefaultPdfPermissions = []PdfPermission{PdfPermissionPrint}
+
+type PdfEncryption struct {
+	// Password which lifts the restrictions of the permissions, a random one when empty so they cannot be lifted
+	OwnerPassword string
+	Permissions   []PdfPermission
+	// Optional CSV column holding the password to open the certificate of the row, Eg: for a transcript only its
+	// recipient may read. A row with an empty value opens without password. The merged and print PDFs hold every row,
+	// so they open only with the owner password, which is then required.
+	UserPasswordColumn string
+}
+
+// Check the permissions and the user password column against the CSV headers, headers is nil to skip the column
+func (e PdfEncryption) Validate(headers []string) error {
+	for _, permission := range e.Permissions {
+		if !permission.IsValid() {
+			return fmt.Errorf("unsupported PDF permission: %s", permission)
+		}
+	}
+
+	if e.UserPasswordColumn != "" && headers != nil && !slices.Contains(headers, e.UserPasswordColumn) {
+		return fmt.Errorf("user password column %q is not in the table", e.UserPasswordColumn)
+	}
+	if e.UserPasswordColumn != "" && e.OwnerPassword == "" {
+		return errors.New("an owner password is required with a user password column, it opens the merged PDF")
+	}
+
+	return nil
+}
+
+// The access permissions of the standard security handler, see table 22 of ISO 32000
+func pdfPermissionFlags(permissions []PdfPermission) model.PermissionFlags {
+	flags := model.PermissionsNone
+	for _, permission := range permissions {
+		switch permission {
+		case PdfPermissionPrint:
+			flags |= model.PermissionPrintRev2 | model.PermissionPrintRev3
+		case PdfPermissionModify:
+			flags |= model.PermissionModify | model.PermissionAssembleRev3
+		case PdfPermissionAnnotate:
+			flags |= model.PermissionModAnnFillForm | model.PermissionFillRev3
+		case PdfPermissionCopy:
+			flags |= model.PermissionExtract | model.PermissionExtractRev3
+		}
+	}
+	return flags
+}
+
+// Encrypt the PDF of inFile with AES-256 and write it to outFile, inFile and outFile can be the same file
+func EncryptPdfFile(inFile, outFile string, encryption PdfEncryption, userPassword string) error {
+	data, err := os.ReadFile(inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := EncryptPdf(bytes.NewReader(data), &buf, encryption, userPassword); err != nil {
+		return err
+	}
+
+	return os.WriteFile(outFile, buf.Bytes(), 0644)
+}
+
+// Encrypt the PDF with AES-256. The user password is needed to open it, empty to open it without password, and the
+// recipient is restricted to the permissions of encryption.
+//
+// A signature must be made after the encryption, encrypting rewrites the PDF and breaks it.
+func EncryptPdf(rs io.ReadSeeker, w io.Writer, encryption PdfEncryption, userPassword string) error {
+	if err := encryption.Validate(nil); err != nil {
+		return err
+	}
+
+	ownerPassword := encryption.OwnerPassword
+	if ownerPassword == "" {
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			return err
+		}
+		ownerPassword = hex.EncodeToString(random)
+	}
+	if ownerPassword == userPassword {
+		return errors.New("owner password must differ from the user password, or the recipient could lift the restrictions")
+	}
+
+	return encryptPdf(rs, w, userPassword, ownerPassword, encryption.Permissions)
+}
+
+// Encrypt the PDF of inFile which holds the certificates of every row, Eg: the merged PDF, and write it to outFile.
+// With a user password column it opens only with the owner password, else anyone could read the certificate of a row
+// protected by its user password.
+func EncryptCombinedPdfFile(inFile, outFile string, encryption PdfEncryption) error {
+	if encryption.UserPasswordColumn == "" {
+		return EncryptPdfFile(inFile, outFile, encryption, "")
+	}
+	if err := encryption.Validate(nil); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := encryptPdf(bytes.NewReader(data), &buf, encryption.OwnerPassword, encryption.OwnerPassword, encryption.Permissions); err != nil {
+		return err
+	}
+
+	return os.WriteFile(outFile, buf.Bytes(), 0644)
+}
+
+func encryptPdf(rs io.ReadSeeker, w io.Writer, userPassword, ownerPassword string, permissions []PdfPermission) error {
+	conf := model.NewAESConfiguration(userPassword, ownerPassword, 256)
+	conf.Permissions = pdfPermissionFlags(permissions)
+
+	if err := api.Encrypt(rs, w, conf); err != nil {
+		return fmt.Errorf("failed to encrypt PDF: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package autocert
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+func TestEncryptPdf(t *testing.T) {
+	pdf := newTestSignablePdf(t, false)
+
+	tests := []struct {
+		name         string
+		permissions  []PdfPermission
+		userPassword string
+		allowed      model.PermissionFlags
+		denied       model.PermissionFlags
+	}{
+		{name: "Print only", permissions: DefaultPdfPermissions, allowed: model.PermissionPrintRev3, denied: model.PermissionModify | model.PermissionModAnnFillForm},
+		{name: "Annotate", permissions: []PdfPermission{PdfPermissionAnnotate}, allowed: model.PermissionModAnnFillForm, denied: model.PermissionModify | model.PermissionPrintRev3},
+		{name: "User password", permissions: DefaultPdfPermissions, userPassword: "recipient", allowed: model.PermissionPrintRev3, denied: model.PermissionExtract},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			encryption := PdfEncryption{OwnerPassword: "owner", Permissions: tt.permissions}
+			if err := EncryptPdf(bytes.NewReader(pdf), &out, encryption, tt.userPassword); err != nil {
+				t.Fatalf("EncryptPdf failed: %v", err)
+			}
+
+			if tt.userPassword != "" {
+				if _, err := api.ReadContext(bytes.NewReader(out.Bytes()), model.NewDefaultConfiguration()); err == nil {
+					t.Errorf("Reading without the user password succeeded, want error")
+				}
+			}
+
+			conf := model.NewDefaultConfiguration()
+			conf.UserPW = tt.userPassword
+			ctx, err := api.ReadContext(bytes.NewReader(out.Bytes()), conf)
+			if err != nil {
+				t.Fatalf("Failed to read encrypted PDF: %v", err)
+			}
+			if ctx.E == nil {
+				t.Fatalf("PDF is not encrypted")
+			}
+
+			p := model.PermissionFlags(ctx.E.P)
+			if p&tt.allowed != tt.allowed {
+				t.Errorf("Permissions = %b, want %b allowed", p, tt.allowed)
+			}
+			if p&tt.denied != 0 {
+				t.Errorf("Permissions = %b, want %b denied", p, tt.denied)
+			}
+		})
+	}
+}
+
+func TestEncryptCombinedPdfFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		encryption   PdfEncryption
+		wantPassword bool
+	}{
+		{name: "Without user password column", encryption: PdfEncryption{OwnerPassword: "owner", Permissions: DefaultPdfPermissions}},
+		{name: "With user password column", encryption: PdfEncryption{OwnerPassword: "owner", Permissions: DefaultPdfPermissions, UserPasswordColumn: "Password"}, wantPassword: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "merged.pdf")
+			if err := os.WriteFile(file, newTestSignablePdf(t, false), 0644); err != nil {
+				t.Fatalf("Failed to write PDF: %v", err)
+			}
+			if err := EncryptCombinedPdfFile(file, file, tt.encryption); err != nil {
+				t.Fatalf("EncryptCombinedPdfFile failed: %v", err)
+			}
+
+			_, err := api.ReadContextFile(file)
+			if (err != nil) != tt.wantPassword {
+				t.Errorf("Reading without password error = %v, want error %v", err, tt.wantPassword)
+			}
+
+			conf := model.NewDefaultConfiguration()
+			conf.OwnerPW = tt.encryption.OwnerPassword
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatalf("Failed to open PDF: %v", err)
+			}
+			defer f.Close()
+			if _, err := api.ReadContext(f, conf); err != nil {
+				t.Errorf("Reading with the owner password failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestPdfEncryptionValidate(t *testing.T) {
+	headers := []string{"Name", "Password"}
+
+	tests := []struct {
+		name       string
+		encryption PdfEncryption
+		wantErr    bool
+	}{
+		{name: "Valid", encryption: PdfEncryption{OwnerPassword: "owner", Permissions: []PdfPermission{PdfPermissionPrint, PdfPermissionCopy}, UserPasswordColumn: "Password"}},
+		{name: "User password column without owner password", encryption: PdfEncryption{UserPasswordColumn: "Password"}, wantErr: true},
+		{name: "Unknown permission", encryption: PdfEncryption{Permissions: []PdfPermission{"delete"}}, wantErr: true},
+		{name: "Unknown column", encryption: PdfEncryption{OwnerPassword: "owner", UserPasswordColumn: "Birthday"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.encryption.Validate(headers); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
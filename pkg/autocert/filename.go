@@ -0,0 +1,194 @@
+package autocert
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+/*
+ * The file name of each certificate is a template of its row, Eg: {{StudentID}}_{{upper LastName}}, see
+ * ParseTextTemplate. Besides the columns, the template can read the system fields below; a column of the same name
+ * takes precedence. The name is sanitised to be valid on every file system and in a ZIP, and rows whose names are the
+ * same get a _2, _3... suffix in row order.
+ */
+
+const (
+	// Number of the certificate, from 1 in the order of the rows
+	FileNameFieldNumber = "Number"
+	// Id of the certificate, the one of its QR code
+	FileNameFieldCertificateID = "CertificateID"
+	// Date of the generation, Eg: 2025-03-07
+	FileNameFieldDate = "Date"
+)
+
+var fileNameFields = []string{FileNameFieldNumber, FileNameFieldCertificateID, FileNameFieldDate}
+
+const (
+	// In bytes, without the extension, most file systems limit a name to 255 bytes
+	maxFileNameLength = 200
+	// The name of a certificate whose template renders to nothing
+	defaultFileName = "certificate"
+	khmerCoeng      = '\u17d2'
+)
+
+// Characters which are not allowed in a file name on Windows, or which are a path separator
+const unsafeFileNameChars = `<>:"/\|?*`
+
+// Names Windows reserves for devices, with or without an extension
+var reservedFileNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+// Check that the template reads only the CSV headers and the system fields, headers is nil to skip the check
+func ValidateFileNameTemplate(vt *ValueTemplate, headers []string) error {
+	if headers == nil {
+		return nil
+	}
+
+	for _, column := range vt.Columns() {
+		if !slices.Contains(headers, column) && !slices.Contains(fileNameFields, column) {
+			return fmt.Errorf("%w: column %q of the file name is not in the table", ErrInvalidValueTemplate, column)
+		}
+	}
+	return nil
+}
+
+// The row with the system fields of the file name, a column of the same name is kept
+func fileNameRow(row map[string]string, number int, certId string, date time.Time) map[string]string {
+	fields := make(map[string]string, len(row)+len(fileNameFields))
+	fields[FileNameFieldNumber] = fmt.Sprint(number)
+	fields[FileNameFieldCertificateID] = certId
+	fields[FileNameFieldDate] = date.Format(time.DateOnly)
+	for column, value := range row {
+		fields[column] = value
+	}
+	return fields
+}
+
+// Make a file name from any text: normalise it to NFC so that the same text typed differently, Eg: a Khmer name
+// whose marks are in another order, gives the same name, and replace what a file system or a ZIP does not allow.
+// The name has no extension and is never empty.
+func SanitizeFileName(name string) string {
+	name = norm.NFC.String(name)
+
+	var b strings.Builder
+	// Runs of separators and spaces become a single one, a separator if the run has one
+	pending := rune(0)
+	for _, r := range name {
+		switch {
+		case r == utf8.RuneError:
+			continue
+		// The format characters are invisible, except the zero width joiners
+		case unicode.Is(unicode.Cf, r) && !isZeroWidthJoiner(r):
+			continue
+		// Before the control characters, tabs and line breaks are spaces
+		case unicode.IsSpace(r):
+			if pending == 0 {
+				pending = ' '
+			}
+			continue
+		case unicode.IsControl(r) || strings.ContainsRune(unsafeFileNameChars, r):
+			pending = '_'
+			continue
+		}
+
+		if pending != 0 {
+			if b.Len() > 0 {
+				b.WriteRune(pending)
+			}
+			pending = 0
+		}
+		b.WriteRune(r)
+	}
+
+	sanitized := truncateFileName(b.String(), maxFileNameLength)
+	// Windows drops the dots and spaces at the end, a leading dot hides the file
+	sanitized = strings.Trim(sanitized, ". ")
+	if sanitized == "" {
+		return defaultFileName
+	}
+
+	base, _, _ := strings.Cut(sanitized, ".")
+	if slices.Contains(reservedFileNames, strings.ToUpper(strings.TrimSpace(base))) {
+		sanitized = "_" + sanitized
+	}
+
+	return sanitized
+}
+
+// Cut the name to at most max bytes without splitting a character or leaving a mark without its base, Eg: the vowel
+// sign of a Khmer consonant
+func truncateFileName(name string, max int) string {
+	if len(name) <= max {
+		return name
+	}
+
+	end := max
+	for end > 0 && !utf8.RuneStart(name[end]) {
+		end--
+	}
+	for end > 0 {
+		r, _ := utf8.DecodeRuneInString(name[end:])
+		if !unicode.Is(unicode.M, r) && !isZeroWidthJoiner(r) {
+			break
+		}
+		_, size := utf8.DecodeLastRuneInString(name[:end])
+		end -= size
+	}
+	// A Khmer coeng stacks the next consonant below its base, the base is dropped with it
+	for end > 0 {
+		r, size := utf8.DecodeLastRuneInString(name[:end])
+		if isZeroWidthJoiner(r) {
+			end -= size
+			continue
+		}
+		if r != khmerCoeng {
+			break
+		}
+		end -= size
+		_, size = utf8.DecodeLastRuneInString(name[:end])
+		end -= size
+	}
+	return name[:end]
+}
+
+// Zero width non-joiner and joiner, they shape Khmer and Indic scripts
+func isZeroWidthJoiner(r rune) bool {
+	return r == '\u200c' || r == '\u200d'
+}
+
+// Resolves the collisions of the file names in the order they are added. Names are compared ignoring case since
+// the file systems of Windows and macOS do.
+type fileNamer struct {
+	used map[string]bool
+}
+
+func newFileNamer(reserved ...string) *fileNamer {
+	fn := &fileNamer{used: make(map[string]bool)}
+	for _, name := range reserved {
+		fn.used[strings.ToLower(name)] = true
+	}
+	return fn
+}
+
+// Return the sanitised name, with a _2, _3... suffix if it is already used
+func (fn *fileNamer) name(name string) string {
+	name = SanitizeFileName(name)
+
+	unique := name
+	for i := 2; fn.used[strings.ToLower(unique)]; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		unique = truncateFileName(name, maxFileNameLength-len(suffix)) + suffix
+	}
+	fn.used[strings.ToLower(unique)] = true
+
+	return unique
+}
@@ -0,0 +1,131 @@
+package autocert
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "Plain", input: "1001_Doe", want: "1001_Doe"},
+		{name: "Unsafe characters", input: `a/b\c:d*e?"f<g>|h`, want: "a_b_c_d_e_f_g_h"},
+		{name: "Runs of unsafe characters", input: "Doe // Jane", want: "Doe_Jane"},
+		{name: "Spaces", input: "  Jane \t\n Doe  ", want: "Jane Doe"},
+		{name: "Control characters", input: "Jane\x00Doe", want: "Jane_Doe"},
+		{name: "Invisible format characters", input: "\uFEFFJane\u200bDoe", want: "JaneDoe"},
+		{name: "Trailing dots", input: "Jane Doe...", want: "Jane Doe"},
+		{name: "Leading dot", input: ".hidden", want: "hidden"},
+		{name: "Reserved name", input: "con", want: "_con"},
+		{name: "Reserved name with extension", input: "NUL.txt", want: "_NUL.txt"},
+		{name: "Empty", input: "", want: defaultFileName},
+		{name: "Only unsafe characters", input: "???", want: defaultFileName},
+		// Decomposed e and combining acute accent
+		{name: "NFC", input: "Jose\u0301", want: "Jos\u00e9"},
+		{name: "Khmer", input: "សុខ សុភា", want: "សុខ សុភា"},
+		{name: "Khmer zero width non-joiner", input: "\u179f\u200c\u17b6", want: "\u179f\u200c\u17b6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeFileName(tt.input); got != tt.want {
+				t.Errorf("SanitizeFileName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateFileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		max   int
+		want  string
+	}{
+		{name: "Short", input: "Jane", max: 10, want: "Jane"},
+		{name: "ASCII", input: "Jane Doe", max: 4, want: "Jane"},
+		// é is 2 bytes
+		{name: "Multibyte", input: "ééé", max: 5, want: "éé"},
+		// ស is a consonant and ុ its vowel sign, 3 bytes each
+		{name: "Khmer vowel sign", input: "សុសុ", max: 9, want: "សុ"},
+		// ្ is a coeng, ខ after it is stacked below ស
+		{name: "Khmer coeng", input: "សស្ខ", max: 9, want: "ស"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateFileName(tt.input, tt.max)
+			if got != tt.want {
+				t.Errorf("truncateFileName(%q, %d) = %q, want %q", tt.input, tt.max, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncateFileName(%q, %d) = %q, is not valid UTF-8", tt.input, tt.max, got)
+			}
+		})
+	}
+
+	if got := SanitizeFileName(strings.Repeat("សុ", 100)); len(got) > maxFileNameLength {
+		t.Errorf("SanitizeFileName length = %d, want at most %d", len(got), maxFileNameLength)
+	}
+}
+
+func TestFileNamer(t *testing.T) {
+	namer := newFileNamer("certificate_merged")
+
+	inputs := []string{"Jane", "jane", "Jane", "Jane_2", "certificate_merged", "a/b", "a:b"}
+	want := []string{"Jane", "jane_2", "Jane_3", "Jane_2_2", "certificate_merged_2", "a_b", "a_b_2"}
+
+	for i, input := range inputs {
+		if got := namer.name(input); got != want[i] {
+			t.Errorf("name(%q) = %q, want %q", input, got, want[i])
+		}
+	}
+}
+
+func TestFileNameTemplate(t *testing.T) {
+	headers := []string{"StudentID", "LastName", "Number"}
+	date := time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		template string
+		row      map[string]string
+		want     string
+		wantErr  bool
+	}{
+		{name: "Columns", template: "{{StudentID}}_{{upper LastName}}", row: map[string]string{"StudentID": "1001", "LastName": "Doe"}, want: "1001_DOE"},
+		{name: "System fields", template: "{{Date}}_{{CertificateID}}", want: "2025-03-07_id-1"},
+		{name: "Column over system field", template: "{{Number}}", row: map[string]string{"Number": "A-7"}, want: "A-7"},
+		{name: "Unknown column", template: "{{Birthday}}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vt, err := ParseTextTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("ParseTextTemplate failed: %v", err)
+			}
+
+			err = ValidateFileNameTemplate(vt, headers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateFileNameTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got, err := vt.Execute(fileNameRow(tt.row, 3, "id-1", date))
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
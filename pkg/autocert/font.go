@@ -1,47 +1,199 @@
 package autocert
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"image/color"
 	"io/fs"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
 
+	gotextfont "github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"github.com/go-text/typesetting/segmenter"
 	"github.com/tdewolff/canvas"
 	"golang.org/x/image/font/sfnt"
+	"golang.org/x/text/unicode/norm"
 )
 
 type FontWeight string
 
+// Named weights, a weight can also be given as a number from 100 to 900 (Eg: "300")
 const (
-	FontWeightRegular FontWeight = "regular"
-	FontWeightBold    FontWeight = "bold"
+	FontWeightThin       FontWeight = "thin"
+	FontWeightExtraLight FontWeight = "extralight"
+	FontWeightLight      FontWeight = "light"
+	FontWeightRegular    FontWeight = "regular"
+	FontWeightNormal     FontWeight = "normal"
+	FontWeightMedium     FontWeight = "medium"
+	FontWeightSemiBold   FontWeight = "semibold"
+	FontWeightBold       FontWeight = "bold"
+	FontWeightExtraBold  FontWeight = "extrabold"
+	FontWeightBlack      FontWeight = "black"
 )
 
+var fontWeightValues = map[FontWeight]int{
+	FontWeightThin:       100,
+	FontWeightExtraLight: 200,
+	FontWeightLight:      300,
+	FontWeightRegular:    400,
+	FontWeightNormal:     400,
+	FontWeightMedium:     500,
+	FontWeightSemiBold:   600,
+	FontWeightBold:       700,
+	FontWeightExtraBold:  800,
+	FontWeightBlack:      900,
+}
+
+// Return the numeric weight (100 - 900), empty is regular and 0 is returned for an invalid weight
+func (fw FontWeight) Value() int {
+	if fw == "" {
+		return 400
+	}
+
+	if value, ok := fontWeightValues[FontWeight(strings.ToLower(string(fw)))]; ok {
+		return value
+	}
+
+	value, err := strconv.Atoi(string(fw))
+	if err != nil || value < 100 || value > 900 {
+		return 0
+	}
+	return value
+}
+
+func (fw FontWeight) IsValid() bool {
+	return fw.Value() != 0
+}
+
 type Font struct {
 	Name   string
 	Size   float64
 	Color  string
 	Weight FontWeight
+	Italic bool
+	// Font names tried in order when a glyph is missing in the font, before the global fallbacks
+	Fallbacks []string
 }
 
-// Get font weight of canvas type
+// Get font weight and style of canvas type
 func (f *Font) GetFontStyle() canvas.FontStyle {
-	switch f.Weight {
-	case FontWeightRegular:
-		return canvas.FontRegular
-	case FontWeightBold:
-		return canvas.FontBold
+	return fontStyleOf(f.Weight.Value(), f.Italic)
+}
+
+// Map a numeric weight to the closest canvas weight, canvas only has the weights of multiple of 100
+func fontStyleOf(weight int, italic bool) canvas.FontStyle {
+	var style canvas.FontStyle
+	switch {
+	case weight <= 0:
+		style = canvas.FontRegular
+	case weight < 150:
+		style = canvas.FontThin
+	case weight < 250:
+		style = canvas.FontExtraLight
+	case weight < 350:
+		style = canvas.FontLight
+	case weight < 450:
+		style = canvas.FontRegular
+	case weight < 550:
+		style = canvas.FontMedium
+	case weight < 650:
+		style = canvas.FontSemiBold
+	case weight < 750:
+		style = canvas.FontBold
+	case weight < 850:
+		style = canvas.FontExtraBold
 	default:
-		return canvas.FontRegular
+		style = canvas.FontBlack
+	}
+
+	if italic {
+		style |= canvas.FontItalic
 	}
+	return style
+}
+
+const (
+	FontStyleNormal = "normal"
+	FontStyleItalic = "italic"
+)
+
+// FontAxis is a variation axis of a variable font, Eg: wght from 100 to 900
+type FontAxis struct {
+	Tag     string  `json:"tag"`
+	Min     float64 `json:"min"`
+	Default float64 `json:"default"`
+	Max     float64 `json:"max"`
 }
 
 type FontMetadata struct {
 	Name string `json:"name"`
 	Path string `json:"path"`
+	// Weight (100 - 900) and style (normal or italic) of the face, a variable font records its default instance.
+	// Metadata scanned before weights were recorded has neither, such a file is used for any weight and style as is.
+	Weight int    `json:"weight,omitempty"`
+	Style  string `json:"style,omitempty"`
+	// Variation axes of a variable font
+	Axes []FontAxis `json:"axes,omitempty"`
+}
+
+func (fm *FontMetadata) IsItalic() bool {
+	return fm.Style == FontStyleItalic
+}
+
+func (fm *FontMetadata) IsVariable() bool {
+	return len(fm.Axes) > 0
+}
+
+func (fm *FontMetadata) axis(tag string) (FontAxis, bool) {
+	for _, axis := range fm.Axes {
+		if axis.Tag == tag {
+			return axis, true
+		}
+	}
+	return FontAxis{}, false
+}
+
+// Return the weights the face can draw without faking the weight, a range for a variable font with a wght axis
+func (fm *FontMetadata) weightRange() (float64, float64) {
+	if axis, ok := fm.axis("wght"); ok {
+		return axis.Min, axis.Max
+	}
+	return float64(fm.Weight), float64(fm.Weight)
+}
+
+// Whether the face can draw italic without faking the slant
+func (fm *FontMetadata) hasItalic() bool {
+	if fm.IsItalic() {
+		return true
+	}
+	_, ital := fm.axis("ital")
+	_, slnt := fm.axis("slnt")
+	return ital || slnt
+}
+
+// Return the variation settings of the instance closest to the style, Eg: "wght=300,ital=1"
+func (fm *FontMetadata) variations(style canvas.FontStyle) string {
+	var settings []string
+	if axis, ok := fm.axis("wght"); ok {
+		weight := min(max(float64(style.CSS()), axis.Min), axis.Max)
+		settings = append(settings, fmt.Sprintf("wght=%g", weight))
+	}
+	if axis, ok := fm.axis("ital"); ok && style.Italic() {
+		settings = append(settings, fmt.Sprintf("ital=%g", axis.Max))
+	} else if axis, ok := fm.axis("slnt"); ok && style.Italic() {
+		// Slant is negative for a clockwise (italic) slant
+		settings = append(settings, fmt.Sprintf("slnt=%g", axis.Min))
+	}
+	return strings.Join(settings, ",")
 }
 
 func getFontMetadataByPath(fontPath string) (*FontMetadata, error) {
@@ -55,15 +207,66 @@ func getFontMetadataByPath(fontPath string) (*FontMetadata, error) {
 		return nil, err
 	}
 
-	name, err := font.Name(nil, sfnt.NameIDFamily)
+	// The typographic family groups every weight of a family (Eg: "Roboto" rather than "Roboto Light"),
+	// fonts with only the regular, italic, bold and bold italic faces do not have it
+	name, err := font.Name(nil, sfnt.NameIDTypographicFamily)
+	if err != nil {
+		name, err = font.Name(nil, sfnt.NameIDFamily)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &FontMetadata{
+	fontMetadata := &FontMetadata{
 		Name: name,
 		Path: fontPath,
-	}, nil
+	}
+	if err := describeFont(fontMetadata, fontBytes); err != nil {
+		return nil, err
+	}
+
+	return fontMetadata, nil
+}
+
+// Read the weight and style from the OS/2 table and the variation axes from the fvar table of a TrueType font
+func describeFont(fontMetadata *FontMetadata, fontBytes []byte) error {
+	loader, err := ot.NewLoader(bytes.NewReader(fontBytes))
+	if err != nil {
+		return err
+	}
+
+	desc, _ := gotextfont.Describe(loader, nil)
+	fontMetadata.Weight = int(desc.Aspect.Weight)
+	fontMetadata.Style = FontStyleNormal
+	if desc.Aspect.Style == gotextfont.StyleItalic {
+		fontMetadata.Style = FontStyleItalic
+	}
+
+	fvarTable, err := loader.RawTable(ot.MustNewTag("fvar"))
+	if err != nil {
+		// Not a variable font
+		return nil
+	}
+	if _, err := loader.RawTable(ot.MustNewTag("glyf")); err != nil {
+		// A CFF2 variable font cannot be instanced, it is used as its default instance like a static font
+		return nil
+	}
+
+	fvar, _, err := tables.ParseFvar(fvarTable)
+	if err != nil {
+		return fmt.Errorf("failed to parse fvar table: %w", err)
+	}
+
+	for _, axis := range fvar.Axis {
+		fontMetadata.Axes = append(fontMetadata.Axes, FontAxis{
+			Tag:     axis.Tag.String(),
+			Min:     float64(axis.Minimum),
+			Default: float64(axis.Default),
+			Max:     float64(axis.Maximum),
+		})
+	}
+
+	return nil
 }
 
 // Scan through the directory to process .ttf and .otf files.
@@ -100,9 +303,15 @@ func ScanFontDir(dir string) ([]FontMetadata, error) {
 	return fonts, nil
 }
 
-// List the available font family and its path
-func GetAvailableFonts(path string) ([]*FontMetadata, error) {
-	var fonts []*FontMetadata
+// font_metadata.json is either a list of fonts or an object with the fonts and the global fallback font names
+type FontConfig struct {
+	Fonts []*FontMetadata `json:"fonts"`
+	// Font names tried in order when a glyph is missing in the annotation's font and its fallbacks
+	Fallbacks []string `json:"fallbacks"`
+}
+
+func GetFontConfig(path string) (*FontConfig, error) {
+	var fontConfig FontConfig
 
 	if path == "" {
 		path = "font_metadata.json"
@@ -110,31 +319,49 @@ func GetAvailableFonts(path string) ([]*FontMetadata, error) {
 
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return fonts, fmt.Errorf("error reading %s: %v", path, err)
+		return &fontConfig, fmt.Errorf("error reading %s: %v", path, err)
 	}
 
-	if err := json.Unmarshal(data, &fonts); err != nil {
-		return fonts, fmt.Errorf("error unmarshalling %s: %v", path, err)
+	// Support the old format which is only the list of fonts
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &fontConfig.Fonts)
+	} else {
+		err = json.Unmarshal(data, &fontConfig)
+	}
+	if err != nil {
+		return &fontConfig, fmt.Errorf("error unmarshalling %s: %v", path, err)
 	}
 
-	return fonts, nil
+	return &fontConfig, nil
+}
+
+// List the available font family and its path
+func GetAvailableFonts(path string) ([]*FontMetadata, error) {
+	fontConfig, err := GetFontConfig(path)
+	if err != nil {
+		return fontConfig.Fonts, err
+	}
+
+	return fontConfig.Fonts, nil
 }
 
 type FontLoader struct {
 	Cfg            Config
 	AvailableFonts []*FontMetadata
+	Fallbacks      []string
 }
 
 func NewFontLoader(cfg Config) (*FontLoader, error) {
 	// Load the font metadata from the JSON file
-	fonts, err := GetAvailableFonts(cfg.FontMetadataPath)
+	fontConfig, err := GetFontConfig(cfg.FontMetadataPath)
 	if err != nil {
 		return nil, err
 	}
 
 	return &FontLoader{
 		Cfg:            cfg,
-		AvailableFonts: fonts,
+		AvailableFonts: fontConfig.Fonts,
+		Fallbacks:      fontConfig.Fallbacks,
 	}, nil
 }
 
@@ -148,8 +375,72 @@ func (fl *FontLoader) GetAvailableFontMetadataByName(fontName string) (*FontMeta
 	return nil, fmt.Errorf("font %s not found", fontName)
 }
 
+// Load the face of the font family which matches the style best, see SelectFontMetadata
 func (fl *FontLoader) LoadFont(fontName string, fontStyle canvas.FontStyle) (*canvas.FontFamily, error) {
-	fontMetadata, err := fl.GetAvailableFontMetadataByName(fontName)
+	fontMetadata, err := fl.getFontMetadataOrFallback(fontName, fontStyle)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadFontFamily(fontMetadata, fontStyle)
+}
+
+// Select the face of the font family closest to the style. Like CSS font matching, a face with the requested
+// slant is preferred over any weight, then the face with the nearest weight (or a variable font covering it).
+// When two faces are equally far, the heavier face is used for weights above 400 and the lighter one otherwise.
+func (fl *FontLoader) SelectFontMetadata(fontName string, fontStyle canvas.FontStyle) (*FontMetadata, error) {
+	var selected *FontMetadata
+	minDistance := math.Inf(1)
+
+	for _, font := range fl.AvailableFonts {
+		if font.Name != fontName {
+			continue
+		}
+
+		if distance := font.distance(fontStyle); distance < minDistance {
+			selected = font
+			minDistance = distance
+		}
+	}
+
+	if selected == nil {
+		return nil, fmt.Errorf("font %s not found", fontName)
+	}
+
+	return selected, nil
+}
+
+// Lower is closer to the style, 0 is an exact match
+func (fm *FontMetadata) distance(style canvas.FontStyle) float64 {
+	lo, hi := fm.weightRange()
+	if !fm.IsVariable() && fm.Weight == 0 {
+		lo, hi = 400, 400
+	}
+
+	weight := float64(style.CSS())
+	var distance float64
+	switch {
+	case weight < lo:
+		distance = lo - weight
+		if weight <= 400 {
+			distance += 0.5
+		}
+	case weight > hi:
+		distance = weight - hi
+		if weight > 400 {
+			distance += 0.5
+		}
+	}
+
+	if style.Italic() && !fm.hasItalic() || !style.Italic() && fm.IsItalic() {
+		distance += 1000
+	}
+
+	return distance
+}
+
+func (fl *FontLoader) getFontMetadataOrFallback(fontName string, fontStyle canvas.FontStyle) (*FontMetadata, error) {
+	fontMetadata, err := fl.SelectFontMetadata(fontName, fontStyle)
 	if err != nil {
 		// Fallback to the first available font
 		if len(fl.AvailableFonts) > 0 {
@@ -164,17 +455,231 @@ func (fl *FontLoader) LoadFont(fontName string, fontStyle canvas.FontStyle) (*ca
 		return nil, fmt.Errorf("font metadata is nil")
 	}
 
+	return fontMetadata, nil
+}
+
+// The file is registered under its own weight and slant when it is lighter than requested or upright, such that
+// canvas fakes the difference, Eg: bold is drawn with a thicker outline when the family has no bold face.
+// Like browsers, a face is never made lighter or upright.
+// A variable font is instanced at the weight and slant of the style within its axes, see instanceVariableFont, and
+// only the difference beyond its axes is faked.
+func loadFontFamily(fontMetadata *FontMetadata, fontStyle canvas.FontStyle) (*canvas.FontFamily, error) {
+	// Metadata without weight is loaded as the requested style, like before weights were recorded
+	fileStyle := fontStyle
+	if fontMetadata.Weight != 0 {
+		faceWeight, faceItalic := fontMetadata.Weight, fontMetadata.IsItalic()
+		if fontMetadata.IsVariable() {
+			lo, hi := fontMetadata.weightRange()
+			faceWeight = int(min(max(float64(fontStyle.CSS()), lo), hi))
+			faceItalic = fontMetadata.hasItalic()
+		}
+
+		fileStyle = fontStyle.Weight()
+		if fileWeight := fontStyleOf(faceWeight, false); fileWeight.CSS() < fontStyle.CSS() {
+			fileStyle = fileWeight
+		}
+		if fontStyle.Italic() && faceItalic {
+			fileStyle |= canvas.FontItalic
+		}
+	}
+
 	fontFamily := canvas.NewFontFamily(fontMetadata.Name)
-	err = fontFamily.LoadFontFile(fontMetadata.Path, fontStyle)
+	if !fontMetadata.IsVariable() {
+		if err := fontFamily.LoadFontFile(fontMetadata.Path, fileStyle); err != nil {
+			return nil, err
+		}
+		return fontFamily, nil
+	}
+
+	instance, err := loadFontInstance(fontMetadata.Path, fontMetadata.variations(fontStyle))
 	if err != nil {
 		return nil, err
 	}
+	if err := fontFamily.LoadFont(instance, 0, fileStyle); err != nil {
+		return nil, err
+	}
+	return fontFamily, nil
+}
 
-	// TODO: load fallback font
-	// err := fontFamily.LoadFontFile(FallBackFont, canvas.FontRegular)
-	// if err != nil {
-	// 	log.Fatalf("failed to load fallback font: %v", err)
-	// }
+// Load the font followed by its fallback fonts, the given fallbacks take precedence over the global fallbacks.
+// A fallback font that cannot be loaded is skipped, only the main font is required.
+func (fl *FontLoader) LoadFontChain(fontName string, fallbacks []string, fontStyle canvas.FontStyle) (*FontChain, error) {
+	fontMetadata, err := fl.getFontMetadataOrFallback(fontName, fontStyle)
+	if err != nil {
+		return nil, err
+	}
 
-	return fontFamily, nil
+	fontFamily, err := loadFontFamily(fontMetadata, fontStyle)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := newFontChain(fontStyle)
+	chain.add(fontMetadata.Name, fontFamily)
+
+	for _, name := range slices.Concat(fallbacks, fl.Fallbacks) {
+		if chain.has(name) {
+			continue
+		}
+
+		fallbackMetadata, err := fl.SelectFontMetadata(name, fontStyle)
+		if err != nil {
+			log.Printf("Skipping fallback font %s: %v", name, err)
+			continue
+		}
+
+		fallbackFamily, err := loadFontFamily(fallbackMetadata, fontStyle)
+		if err != nil {
+			log.Printf("Skipping fallback font %s: %v", name, err)
+			continue
+		}
+
+		chain.add(name, fallbackFamily)
+	}
+
+	return chain, nil
+}
+
+// FontChain is a list of font families where each rune is drawn by the first family which has its glyph
+type FontChain struct {
+	style    canvas.FontStyle
+	names    []string
+	families []*canvas.FontFamily
+	// Used to look up glyphs, one per family
+	fonts []*canvas.Font
+}
+
+func newFontChain(style canvas.FontStyle) *FontChain {
+	return &FontChain{style: style}
+}
+
+func (fc *FontChain) add(name string, family *canvas.FontFamily) {
+	fc.names = append(fc.names, name)
+	fc.families = append(fc.families, family)
+	fc.fonts = append(fc.fonts, family.Face(12, fc.style, canvas.FontNormal).Font)
+}
+
+func (fc *FontChain) has(name string) bool {
+	return slices.Contains(fc.names, name)
+}
+
+// The main font family of the chain
+func (fc *FontChain) Primary() *canvas.FontFamily {
+	return fc.families[0]
+}
+
+// Return the index of the first family which has the glyph, -1 if none of them has it
+func (fc *FontChain) resolve(r rune) int {
+	for i, font := range fc.fonts {
+		if font.SFNT.GlyphIndex(r) != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Whitespace, control characters and marks do not pick a font by themselves, they stay with the previous rune
+// so that the shaper sees a whole cluster (Eg: Khmer coeng and vowel signs, Thai tone marks) in one font
+func keepsPreviousFont(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsControl(r) || unicode.Is(unicode.M, r) || r == '\u200b' || r == '\u200c' || r == '\u200d'
+}
+
+// Build a rich text where each run of runes uses the first font of the chain which has their glyphs.
+// Letter spacing (in em) is inserted between grapheme clusters as an empty inline object.
+func (fc *FontChain) RichText(text string, size float64, col color.Color, letterSpacing float64) *canvas.RichText {
+	faces := make([]*canvas.FontFace, len(fc.families))
+	face := func(i int) *canvas.FontFace {
+		if faces[i] == nil {
+			faces[i] = fc.families[i].Face(size, col, fc.style, canvas.FontNormal)
+		}
+		return faces[i]
+	}
+
+	rt := canvas.NewRichText(face(0))
+
+	var spacer *canvas.Canvas
+	var spacings map[int]bool
+	if letterSpacing != 0 {
+		spacer = canvas.New(pxToMM(size*letterSpacing), 0)
+		spacings = letterSpacingPositions([]rune(text))
+	}
+
+	current := 0
+	var run strings.Builder
+	runeIndex := 0
+	for _, r := range text {
+		next := current
+		if !keepsPreviousFont(r) {
+			// Missing glyphs are drawn by the main font
+			next = max(fc.resolve(r), 0)
+		}
+
+		if (next != current || spacings[runeIndex]) && run.Len() > 0 {
+			rt.WriteFace(face(current), run.String())
+			run.Reset()
+		}
+		if spacings[runeIndex] {
+			rt.WriteCanvas(spacer, canvas.Baseline)
+		}
+
+		current = next
+		run.WriteRune(r)
+		runeIndex++
+	}
+
+	if run.Len() > 0 {
+		rt.WriteFace(face(current), run.String())
+	}
+
+	return rt
+}
+
+// Return the rune indexes where letter spacing is inserted, which are the grapheme cluster boundaries except:
+// after a virama (Eg: Khmer coeng) as the next consonant is drawn below it, and around letters of a cursive script
+// (Eg: Arabic) as spacing would break the joining of the letters.
+func letterSpacingPositions(runes []rune) map[int]bool {
+	positions := make(map[int]bool)
+
+	var seg segmenter.Segmenter
+	seg.Init(runes)
+	iter := seg.GraphemeIterator()
+	for iter.Next() {
+		i := iter.Grapheme().Offset
+		if i == 0 {
+			continue
+		}
+
+		prev := runes[i-1]
+		if norm.NFC.PropertiesString(string(prev)).CCC() == viramaCombiningClass {
+			continue
+		}
+		if isCursive(prev) || isCursive(runes[i]) {
+			continue
+		}
+
+		positions[i] = true
+	}
+
+	return positions
+}
+
+const viramaCombiningClass = 9
+
+func isCursive(r rune) bool {
+	return unicode.In(r, unicode.Arabic, unicode.Syriac, unicode.Nko, unicode.Mongolian, unicode.Mandaic, unicode.Adlam, unicode.Hanifi_Rohingya)
+}
+
+// Return the unique runes in the text which no font of the chain has a glyph for
+func (fc *FontChain) MissingGlyphs(text string) []rune {
+	var missing []rune
+	for _, r := range text {
+		if keepsPreviousFont(r) || slices.Contains(missing, r) {
+			continue
+		}
+
+		if fc.resolve(r) < 0 {
+			missing = append(missing, r)
+		}
+	}
+	return missing
 }
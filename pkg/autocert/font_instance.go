@@ -0,0 +1,350 @@
+package autocert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	gotextfont "github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+)
+
+// Tables of a variable font which no longer apply to a static instance, or describe the hinted glyphs which an
+// instance replaces
+var droppedInstanceTables = []string{"fvar", "gvar", "avar", "cvar", "HVAR", "VVAR", "MVAR", "STAT", "hdmx", "LTSH", "VDMX"}
+
+// Instances by font path and variations, a font is instanced once per process
+var instancedFonts sync.Map
+
+// Return the instance of the variable font of path at the variations, made once per process
+func loadFontInstance(path, variations string) ([]byte, error) {
+	key := path + "\x00" + variations
+	if instance, ok := instancedFonts.Load(key); ok {
+		return instance.([]byte), nil
+	}
+
+	fontBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := instanceVariableFont(fontBytes, variations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instance variable font %s at %s: %w", path, variations, err)
+	}
+
+	stored, _ := instancedFonts.LoadOrStore(key, instance)
+	return stored.([]byte), nil
+}
+
+// Make a static TrueType font of the instance of a variable font at the variations, Eg: "wght=700,ital=1". Canvas
+// only draws the outlines and advances of the default instance, so the instance is computed beforehand.
+// The glyphs lose their hinting instructions and composite glyphs are flattened, neither is used by a PDF.
+// Only TrueType outlines are supported, a CFF2 variable font is used as its default instance, see describeFont.
+func instanceVariableFont(fontBytes []byte, variations string) ([]byte, error) {
+	ld, err := ot.NewLoader(bytes.NewReader(fontBytes))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ld.RawTable(ot.MustNewTag("glyf")); err != nil {
+		return nil, errors.New("only variable fonts with TrueType outlines can be instanced")
+	}
+
+	vars, err := parseFontVariations(variations)
+	if err != nil {
+		return nil, err
+	}
+
+	ft, err := gotextfont.NewFont(ld)
+	if err != nil {
+		return nil, err
+	}
+	face := gotextfont.NewFace(ft)
+	face.SetVariations(vars)
+
+	tables := make(map[string][]byte)
+	for _, tag := range ld.Tables() {
+		name := tag.String()
+		if slices.Contains(droppedInstanceTables, name) {
+			continue
+		}
+		table, err := ld.RawTable(tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s table: %w", name, err)
+		}
+		// Copied since the tables below are modified in place
+		tables[name] = slices.Clone(table)
+	}
+
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errors.New("invalid head, hhea or maxp table")
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+
+	var glyf, loca, hmtx []byte
+	bbox := [4]int16{math.MaxInt16, math.MaxInt16, math.MinInt16, math.MinInt16}
+	var maxPoints, maxContours, advanceWidthMax uint16
+	minLsb, minRsb, maxExtent := int16(math.MaxInt16), int16(math.MaxInt16), int16(math.MinInt16)
+	for gid := range numGlyphs {
+		loca = binary.BigEndian.AppendUint32(loca, uint32(len(glyf)))
+
+		var segments []gotextfont.Segment
+		if outline, ok := face.GlyphData(gotextfont.GID(gid)).(gotextfont.GlyphOutline); ok {
+			segments = outline.Segments
+		}
+		glyph, err := encodeSimpleGlyph(segments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to instance glyph %d: %w", gid, err)
+		}
+		glyf = append(glyf, glyph.data...)
+
+		advance := uint16(max(math.Round(float64(face.HorizontalAdvance(gotextfont.GID(gid)))), 0))
+		advanceWidthMax = max(advanceWidthMax, advance)
+		hmtx = binary.BigEndian.AppendUint16(hmtx, advance)
+		hmtx = binary.BigEndian.AppendUint16(hmtx, uint16(glyph.bbox[0]))
+
+		if glyph.points == 0 {
+			continue
+		}
+		maxPoints = max(maxPoints, glyph.points)
+		maxContours = max(maxContours, glyph.contours)
+		bbox = [4]int16{min(bbox[0], glyph.bbox[0]), min(bbox[1], glyph.bbox[1]), max(bbox[2], glyph.bbox[2]), max(bbox[3], glyph.bbox[3])}
+		minLsb = min(minLsb, glyph.bbox[0])
+		minRsb = min(minRsb, int16(int(advance)-int(glyph.bbox[2])))
+		maxExtent = max(maxExtent, glyph.bbox[2])
+	}
+	loca = binary.BigEndian.AppendUint32(loca, uint32(len(glyf)))
+	if maxPoints == 0 {
+		bbox = [4]int16{}
+		minLsb, minRsb, maxExtent = 0, 0, 0
+	}
+	tables["glyf"], tables["loca"], tables["hmtx"] = glyf, loca, hmtx
+
+	// Long offsets of loca and the bounding box of every glyph
+	binary.BigEndian.PutUint16(head[50:], 1)
+	for i, v := range bbox {
+		binary.BigEndian.PutUint16(head[36+2*i:], uint16(v))
+	}
+
+	// Every glyph has its own advance
+	binary.BigEndian.PutUint16(hhea[10:], advanceWidthMax)
+	binary.BigEndian.PutUint16(hhea[12:], uint16(minLsb))
+	binary.BigEndian.PutUint16(hhea[14:], uint16(minRsb))
+	binary.BigEndian.PutUint16(hhea[16:], uint16(maxExtent))
+	binary.BigEndian.PutUint16(hhea[34:], uint16(numGlyphs))
+
+	// The composite glyphs are flattened
+	if len(maxp) >= 32 {
+		binary.BigEndian.PutUint16(maxp[6:], maxPoints)
+		binary.BigEndian.PutUint16(maxp[8:], maxContours)
+		binary.BigEndian.PutUint16(maxp[10:], 0)
+		binary.BigEndian.PutUint16(maxp[12:], 0)
+		binary.BigEndian.PutUint16(maxp[28:], 0)
+		binary.BigEndian.PutUint16(maxp[30:], 0)
+	}
+
+	if os2 := tables["OS/2"]; len(os2) >= 6 {
+		for _, v := range vars {
+			if v.Tag == ot.MustNewTag("wght") {
+				binary.BigEndian.PutUint16(os2[4:], uint16(math.Round(float64(v.Value))))
+			}
+		}
+	}
+
+	return writeSfnt(tables), nil
+}
+
+// Parse variation settings such as "wght=700,ital=1"
+func parseFontVariations(variations string) ([]gotextfont.Variation, error) {
+	var vars []gotextfont.Variation
+	for setting := range strings.SplitSeq(variations, ",") {
+		if setting == "" {
+			continue
+		}
+		tag, value, ok := strings.Cut(setting, "=")
+		if !ok || len(tag) != 4 {
+			return nil, fmt.Errorf("invalid font variation %q", setting)
+		}
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid font variation %q: %w", setting, err)
+		}
+		vars = append(vars, gotextfont.Variation{Tag: ot.MustNewTag(tag), Value: float32(v)})
+	}
+	return vars, nil
+}
+
+type instancedGlyph struct {
+	data     []byte
+	bbox     [4]int16
+	points   uint16
+	contours uint16
+}
+
+// Encode the quadratic outline as a simple glyph of the glyf table, an empty outline is an empty glyph
+func encodeSimpleGlyph(segments []gotextfont.Segment) (instancedGlyph, error) {
+	type point struct {
+		x, y    float32
+		onCurve bool
+	}
+
+	var contours [][]point
+	for _, segment := range segments {
+		switch segment.Op {
+		case ot.SegmentOpMoveTo:
+			contours = append(contours, []point{{segment.Args[0].X, segment.Args[0].Y, true}})
+		case ot.SegmentOpLineTo:
+			contours[len(contours)-1] = append(contours[len(contours)-1], point{segment.Args[0].X, segment.Args[0].Y, true})
+		case ot.SegmentOpQuadTo:
+			contours[len(contours)-1] = append(contours[len(contours)-1],
+				point{segment.Args[0].X, segment.Args[0].Y, false},
+				point{segment.Args[1].X, segment.Args[1].Y, true})
+		default:
+			return instancedGlyph{}, errors.New("cubic outlines cannot be stored in a TrueType glyph")
+		}
+	}
+
+	type glyphPoint struct {
+		x, y    int16
+		onCurve bool
+	}
+	var points []glyphPoint
+	var endPoints []uint16
+	for _, contour := range contours {
+		// The outline returns to the start of the contour, which a glyph closes implicitly
+		if n := len(contour); n > 1 && contour[n-1] == contour[0] {
+			contour = contour[:n-1]
+		}
+
+		n := len(contour)
+		for i, p := range contour {
+			// An on-curve point halfway between two off-curve points is implied by them and is not stored, rounding
+			// it would bend the curves, the start of the contour is kept so that it starts at the same point
+			prev, next := contour[(i+n-1)%n], contour[(i+1)%n]
+			if i > 0 && n > 2 && p.onCurve && !prev.onCurve && !next.onCurve && p.x == (prev.x+next.x)/2 && p.y == (prev.y+next.y)/2 {
+				continue
+			}
+			points = append(points, glyphPoint{int16(math.Round(float64(p.x))), int16(math.Round(float64(p.y))), p.onCurve})
+		}
+		if len(points) > 0 && (len(endPoints) == 0 || int(endPoints[len(endPoints)-1]) != len(points)-1) {
+			endPoints = append(endPoints, uint16(len(points)-1))
+		}
+	}
+
+	if len(points) == 0 {
+		return instancedGlyph{}, nil
+	}
+
+	g := instancedGlyph{
+		bbox:     [4]int16{points[0].x, points[0].y, points[0].x, points[0].y},
+		points:   uint16(len(points)),
+		contours: uint16(len(endPoints)),
+	}
+	for _, p := range points {
+		g.bbox = [4]int16{min(g.bbox[0], p.x), min(g.bbox[1], p.y), max(g.bbox[2], p.x), max(g.bbox[3], p.y)}
+	}
+
+	b := binary.BigEndian.AppendUint16(nil, g.contours)
+	for _, v := range g.bbox {
+		b = binary.BigEndian.AppendUint16(b, uint16(v))
+	}
+	for _, end := range endPoints {
+		b = binary.BigEndian.AppendUint16(b, end)
+	}
+	// No instructions
+	b = binary.BigEndian.AppendUint16(b, 0)
+	// The coordinates are 16-bit deltas from the previous point, the flag only tells whether a point is on the curve
+	for _, p := range points {
+		var flag byte
+		if p.onCurve {
+			flag = 0x01
+		}
+		b = append(b, flag)
+	}
+	var prev int16
+	for _, p := range points {
+		b = binary.BigEndian.AppendUint16(b, uint16(p.x-prev))
+		prev = p.x
+	}
+	prev = 0
+	for _, p := range points {
+		b = binary.BigEndian.AppendUint16(b, uint16(p.y-prev))
+		prev = p.y
+	}
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+
+	g.data = b
+	return g, nil
+}
+
+// Write the tables to a TrueType font with their checksums
+func writeSfnt(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	numTables := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= numTables {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+
+	b := binary.BigEndian.AppendUint32(nil, 0x00010000)
+	b = binary.BigEndian.AppendUint16(b, uint16(numTables))
+	b = binary.BigEndian.AppendUint16(b, uint16(searchRange))
+	b = binary.BigEndian.AppendUint16(b, uint16(entrySelector))
+	b = binary.BigEndian.AppendUint16(b, uint16(numTables*16-searchRange))
+
+	if head, ok := tables["head"]; ok {
+		binary.BigEndian.PutUint32(head[8:], 0)
+	}
+
+	offset := len(b) + 16*numTables
+	var headOffset int
+	var data []byte
+	for _, tag := range tags {
+		table := tables[tag]
+		if tag == "head" {
+			headOffset = offset
+		}
+
+		b = append(b, tag...)
+		b = binary.BigEndian.AppendUint32(b, sfntChecksum(table))
+		b = binary.BigEndian.AppendUint32(b, uint32(offset))
+		b = binary.BigEndian.AppendUint32(b, uint32(len(table)))
+
+		data = append(data, table...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+		offset = 12 + 16*numTables + len(data)
+	}
+	b = append(b, data...)
+
+	if headOffset != 0 {
+		binary.BigEndian.PutUint32(b[headOffset+8:], 0xB1B0AFBA-sfntChecksum(b))
+	}
+	return b
+}
+
+func sfntChecksum(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(b); i += 4 {
+		var word [4]byte
+		copy(word[:], b[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
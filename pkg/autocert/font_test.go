@@ -2,6 +2,9 @@ package autocert
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/tdewolff/canvas"
@@ -36,3 +39,269 @@ func TestFontLoader(t *testing.T) {
 		})
 	}
 }
+
+func TestGetFontConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		wantFonts     int
+		wantFallbacks []string
+	}{
+		{
+			name:      "List of fonts",
+			content:   `[{"name": "Calibri", "path": "fonts/calibri-regular.ttf"}]`,
+			wantFonts: 1,
+		},
+		{
+			name:          "Fonts with fallbacks",
+			content:       `{"fonts": [{"name": "Calibri", "path": "fonts/calibri-regular.ttf"}], "fallbacks": ["Khmer OS Siemreap"]}`,
+			wantFonts:     1,
+			wantFallbacks: []string{"Khmer OS Siemreap"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "font_metadata.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write font metadata: %v", err)
+			}
+
+			fontConfig, err := GetFontConfig(path)
+			if err != nil {
+				t.Fatalf("GetFontConfig failed: %v", err)
+			}
+			if len(fontConfig.Fonts) != tt.wantFonts {
+				t.Errorf("Expected %d fonts, got %d", tt.wantFonts, len(fontConfig.Fonts))
+			}
+			if !reflect.DeepEqual(fontConfig.Fallbacks, tt.wantFallbacks) {
+				t.Errorf("Expected fallbacks %v, got %v", tt.wantFallbacks, fontConfig.Fallbacks)
+			}
+		})
+	}
+}
+
+func TestFontChainFallback(t *testing.T) {
+	fontLoader, err := NewFontLoader(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("Failed to create FontLoader: %v", err)
+	}
+
+	text := "Sok សុខ"
+
+	withoutFallback, err := fontLoader.LoadFontChain("Calibri", nil, canvas.FontRegular)
+	if err != nil {
+		t.Fatalf("LoadFontChain failed: %v", err)
+	}
+	if missing := withoutFallback.MissingGlyphs(text); len(missing) == 0 {
+		t.Errorf("Expected Khmer glyphs to be missing without fallback")
+	}
+
+	withFallback, err := fontLoader.LoadFontChain("Calibri", []string{"Khmer OS Siemreap", "Unknown Font"}, canvas.FontRegular)
+	if err != nil {
+		t.Fatalf("LoadFontChain failed: %v", err)
+	}
+	if missing := withFallback.MissingGlyphs(text); len(missing) != 0 {
+		t.Errorf("Expected no missing glyphs with fallback, got %q", string(missing))
+	}
+
+	fonts := withFallback.RichText(text, 12, canvas.Black, 0).ToText(0, 0, canvas.Left, canvas.Top, 0, 0).Fonts()
+	if len(fonts) != 2 {
+		t.Errorf("Expected text to be drawn with 2 fonts, got %d", len(fonts))
+	}
+}
+
+func TestFontWeightValue(t *testing.T) {
+	tests := []struct {
+		weight FontWeight
+		want   int
+	}{
+		{weight: "", want: 400},
+		{weight: FontWeightRegular, want: 400},
+		{weight: FontWeightNormal, want: 400},
+		{weight: FontWeightLight, want: 300},
+		{weight: "SemiBold", want: 600},
+		{weight: "100", want: 100},
+		{weight: "550", want: 550},
+		{weight: "950", want: 0},
+		{weight: "heavy", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.weight), func(t *testing.T) {
+			if got := tt.weight.Value(); got != tt.want {
+				t.Errorf("Value() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFontMetadata(t *testing.T) {
+	fontLoader := &FontLoader{
+		AvailableFonts: []*FontMetadata{
+			{Name: "Roboto", Path: "Roboto-Light.ttf", Weight: 300, Style: FontStyleNormal},
+			{Name: "Roboto", Path: "Roboto-Regular.ttf", Weight: 400, Style: FontStyleNormal},
+			{Name: "Roboto", Path: "Roboto-Italic.ttf", Weight: 400, Style: FontStyleItalic},
+			{Name: "Roboto", Path: "Roboto-Bold.ttf", Weight: 700, Style: FontStyleNormal},
+			{Name: "Mada", Path: "Mada-VF.ttf", Weight: 500, Style: FontStyleNormal, Axes: []FontAxis{{Tag: "wght", Min: 200, Default: 500, Max: 900}}},
+			{Name: "Calibri", Path: "calibri-regular.ttf"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		fontName string
+		style    canvas.FontStyle
+		want     string
+	}{
+		{name: "Exact weight", fontName: "Roboto", style: canvas.FontLight, want: "Roboto-Light.ttf"},
+		{name: "Italic over weight", fontName: "Roboto", style: canvas.FontBold | canvas.FontItalic, want: "Roboto-Italic.ttf"},
+		{name: "Heavier face for semibold", fontName: "Roboto", style: canvas.FontSemiBold, want: "Roboto-Bold.ttf"},
+		{name: "Lighter face for extra light", fontName: "Roboto", style: canvas.FontExtraLight, want: "Roboto-Light.ttf"},
+		{name: "Nearest face for medium", fontName: "Roboto", style: canvas.FontMedium, want: "Roboto-Regular.ttf"},
+		{name: "Variable font covers the weight", fontName: "Mada", style: canvas.FontBlack, want: "Mada-VF.ttf"},
+		{name: "Without recorded weight", fontName: "Calibri", style: canvas.FontBold, want: "calibri-regular.ttf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fontMetadata, err := fontLoader.SelectFontMetadata(tt.fontName, tt.style)
+			if err != nil {
+				t.Fatalf("SelectFontMetadata failed: %v", err)
+			}
+			if fontMetadata.Path != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, fontMetadata.Path)
+			}
+		})
+	}
+}
+
+func TestScanFontDir(t *testing.T) {
+	fonts, err := ScanFontDir("testdata/fonts")
+	if err != nil {
+		t.Fatalf("ScanFontDir failed: %v", err)
+	}
+
+	var mada *FontMetadata
+	for i := range fonts {
+		if fonts[i].Name == "Mada" {
+			mada = &fonts[i]
+		}
+	}
+	if mada == nil {
+		t.Fatalf("Expected the typographic family name Mada, got %+v", fonts)
+	}
+
+	if !mada.IsVariable() {
+		t.Fatalf("Expected Mada to be a variable font")
+	}
+
+	want := FontAxis{Tag: "wght", Min: 100, Default: 520, Max: 1000}
+	if !reflect.DeepEqual(mada.Axes, []FontAxis{want}) {
+		t.Errorf("Expected axes %+v, got %+v", []FontAxis{want}, mada.Axes)
+	}
+	if mada.Weight != 500 || mada.Style != FontStyleNormal {
+		t.Errorf("Expected weight 500 and normal style, got %d and %s", mada.Weight, mada.Style)
+	}
+
+	if got := mada.variations(canvas.FontBold); got != "wght=700" {
+		t.Errorf("Expected variations wght=700, got %s", got)
+	}
+}
+
+func TestInstanceVariableFont(t *testing.T) {
+	fontBytes, err := os.ReadFile("testdata/fonts/Mada-VF.ttf")
+	if err != nil {
+		t.Fatalf("Failed to read font: %v", err)
+	}
+
+	const text = "Certificate of Completion"
+	render := func(t *testing.T, b []byte) (string, float64) {
+		t.Helper()
+		family := canvas.NewFontFamily("Mada")
+		if err := family.LoadFont(b, 0, canvas.FontRegular); err != nil {
+			t.Fatalf("Failed to load font: %v", err)
+		}
+		face := family.Face(24, canvas.Black)
+		path, _, err := face.ToPath(text)
+		if err != nil {
+			t.Fatalf("Failed to draw text: %v", err)
+		}
+		return path.String(), face.TextWidth(text)
+	}
+	instance := func(t *testing.T, variations string) []byte {
+		t.Helper()
+		b, err := instanceVariableFont(fontBytes, variations)
+		if err != nil {
+			t.Fatalf("instanceVariableFont(%s) failed: %v", variations, err)
+		}
+		return b
+	}
+
+	// The default instance has the outlines and advances of the font itself
+	originalPath, originalWidth := render(t, fontBytes)
+	defaultPath, defaultWidth := render(t, instance(t, "wght=520"))
+	if defaultPath != originalPath || defaultWidth != originalWidth {
+		t.Errorf("Default instance differs from the font: width %g, want %g", defaultWidth, originalWidth)
+	}
+
+	lightPath, lightWidth := render(t, instance(t, "wght=300"))
+	blackPath, blackWidth := render(t, instance(t, "wght=900"))
+	if lightPath == blackPath {
+		t.Errorf("Light and black instances have the same outlines")
+	}
+	if !(lightWidth < originalWidth && originalWidth < blackWidth) {
+		t.Errorf("Expected widths light < default < black, got %g, %g and %g", lightWidth, originalWidth, blackWidth)
+	}
+
+	if _, err := instanceVariableFont(fontBytes, "wght"); err == nil {
+		t.Errorf("Expected an error for an invalid variation")
+	}
+}
+
+func TestLoadVariableFontFamily(t *testing.T) {
+	mada := &FontMetadata{Name: "Mada", Path: "testdata/fonts/Mada-VF.ttf", Weight: 500, Style: FontStyleNormal, Axes: []FontAxis{{Tag: "wght", Min: 100, Default: 520, Max: 1000}}}
+
+	const text = "Certificate"
+	widths := make(map[canvas.FontStyle]float64)
+	for _, style := range []canvas.FontStyle{canvas.FontLight, canvas.FontRegular, canvas.FontBold} {
+		family, err := loadFontFamily(mada, style)
+		if err != nil {
+			t.Fatalf("loadFontFamily(%v) failed: %v", style, err)
+		}
+		widths[style] = family.Face(24, canvas.Black, style).TextWidth(text)
+	}
+
+	if !(widths[canvas.FontLight] < widths[canvas.FontRegular] && widths[canvas.FontRegular] < widths[canvas.FontBold]) {
+		t.Errorf("Expected widths light < regular < bold, got %v", widths)
+	}
+}
+
+func TestLetterSpacingPositions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{name: "Latin", text: "Sok", want: []int{1, 2}},
+		{name: "Combining mark", text: "éa", want: []int{2}},
+		{name: "Khmer subscript consonant", text: "ស្រី", want: nil},
+		{name: "Arabic", text: "شهادة", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions := letterSpacingPositions([]rune(tt.text))
+
+			var got []int
+			for i := range len([]rune(tt.text)) {
+				if positions[i] {
+					got = append(got, i)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected letter spacing at %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
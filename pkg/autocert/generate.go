@@ -1,17 +1,25 @@
 package autocert
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
 )
 
 type CertificateType int
@@ -20,6 +28,12 @@ const (
 	CertificateTypeNormal CertificateType = iota
 	CertificateTypeMerged
 	CertificateTypeZip
+	// Images of a normal certificate, they share its Number, see Settings.RasterFormats
+	CertificateTypePNG
+	CertificateTypeJPEG
+	CertificateTypeWebP
+	// Sheets of the certificates for a print shop, see Settings.Imposition
+	CertificateTypeImposed
 )
 
 type GeneratedResult struct {
@@ -38,17 +52,64 @@ type ProgressInfo struct {
 	TimeLeft     time.Duration `json:"time_left"`
 	CurrentPhase string        `json:"current_phase"`
 	EstimatedETA time.Time     `json:"estimated_eta"`
+	// Non fatal issue found during generation, Eg: a glyph that no font can draw
+	Warning string `json:"warning,omitempty"`
 }
 
 type ProgressCallback func(progress ProgressInfo)
 
+// Receive a result as soon as it is generated, an error stops the generation
+type ResultCallback func(result GeneratedResult) error
+
 type Settings struct {
 	RemoveLineBreaksBool bool
-	EmbedQRCode          bool
-	QrURLPattern         string
-	MergeAfterGenerate   bool
-	ZipAfterGenerate     bool
-	ProgressCallback     ProgressCallback
+	// Stamp a QR code at the bottom right of the first page when there is no QR code annotate
+	EmbedQRCode bool
+	// Verification URL encoded by the QR codes, %s is the certificate id
+	QrURLPattern       string
+	MergeAfterGenerate bool
+	ZipAfterGenerate   bool
+	// Image formats each certificate is also exported to, every page is an image, see RasterizePdfFile. They are included in the ZIP.
+	RasterFormats []RasterFormat
+	// Resolution of the images, 0 is DefaultRasterDPI
+	RasterDPI float64
+	// Convert every certificate and the merged PDF to PDF/A-2b, what cannot be converted is reported as a warning
+	PDFA bool
+	// Sign every certificate after it is converted and exported, nil to not sign. The merged PDF is not signed.
+	Signer *PdfSigner
+	// Title and subject of the document information of each certificate, templates of the row, see
+	// ParseTextTemplate. Eg: "Certificate of Completion – {{FirstName}} {{LastName}}"
+	TitleTemplate   string
+	SubjectTemplate string
+	// Title of the document information when TitleTemplate is empty, used as is, Eg: the title of the project
+	Title string
+	// Author of the document information of the certificates, Eg: the name of the issuer
+	Author string
+	// Encrypt every certificate, the merged and the print PDFs to restrict what the recipients can do, nil to not encrypt.
+	// It cannot be combined with PDFA, Signer, or RasterFormats when the certificates have a user password.
+	Encryption *PdfEncryption
+	// Name of each certificate file without extension, a template of the row and of the file name fields, see
+	// SanitizeFileName. Eg: "{{StudentID}}_{{LastName}}". Empty names the files with OutFilePattern.
+	FileNameTemplate string
+	// Optional CSV column whose values are the folders of the certificates in the ZIP, Eg: one folder per
+	// "Department". The certificates of a row with an empty value are at the root.
+	ZipGroupByColumn string
+	// Title of the bookmark of each certificate in the merged PDF, a column or a template of the row like the value of
+	// a column annotation, Eg: "FullName". Empty titles the bookmarks with the file names.
+	OutlineTitle string
+	// Start the merged PDF with index pages listing the bookmarks and their page
+	MergedIndex bool
+	// Font of the index, the font of the first column annotation when its name is empty. Its size and color are unused.
+	IndexFont Font
+	// Impose the certificates on the sheets of a print shop after they are merged, nil to not impose. The sheets are
+	// neither converted to PDF/A nor encrypted.
+	Imposition       *Imposition
+	ProgressCallback ProgressCallback
+	// Called from a single goroutine with each certificate and its images once its row is generated, in the order the
+	// rows finish, then with the ZIP, the merged and the imposed PDF. The generator no longer reads the file of a
+	// result passed to it, so the callback may upload and remove it right away. A slow callback holds back the workers
+	// instead of letting the certificates pile up on disk.
+	ResultCallback ResultCallback
 }
 
 func NewDefaultSettings(qrUrlPattern string) *Settings {
@@ -73,7 +134,37 @@ type CertificateGenerator struct {
 	// Eg: "certificate_%s"
 	OutFilePattern string
 	csvData        []map[string]string
+	// Headers of the CSV in their order, the keys of the rows of csvData
+	csvHeaders     []string
 	textRenderers  map[string]*TextRenderer
+	valueTemplates map[string]*ValueTemplate
+	// Renderers of the barcode annotates, by annotate id
+	barcodeRenderers map[string]*BarcodeRenderer
+	// Renderers of the QR code annotates, by annotate id
+	qrCodeRenderers map[string]*QRCodeRenderer
+	// Conditions of the annotates which have one, by annotate id
+	conditions map[string]*Condition
+	// Converted files of the signatures stamped per row instead of onto the base file, see isStampedPerRow
+	signatureFiles map[string]string
+	// Fitted files of the static images stamped per row instead of onto the base file, see isStampedPerRow
+	imageFiles map[string]string
+	// Optional ZIP of images read by the image annotates which have a column value, see ImageBundle
+	ImageBundlePath string
+	imageBundle     *ImageBundle
+	// Optional HTML template rendered as the page of each certificate, see HTMLTemplate.
+	// TemplatePath is then its preview, whose first page gives the size of the rendered pages.
+	HTMLTemplatePath string
+	htmlTemplate     *HTMLTemplate
+	htmlRenderer     *HTMLRenderer
+	// Parsed Settings.TitleTemplate and Settings.SubjectTemplate, nil if empty
+	titleTemplate   *ValueTemplate
+	subjectTemplate *ValueTemplate
+	// Parsed Settings.FileNameTemplate, nil if empty
+	fileNameTemplate *ValueTemplate
+	// Parsed Settings.OutlineTitle, nil if empty
+	outlineTitleTemplate *ValueTemplate
+	// Warnings already reported, to avoid reporting the same warning for every row
+	warned sync.Map
 
 	// Progress tracking fields
 	startTime      time.Time
@@ -84,14 +175,20 @@ type CertificateGenerator struct {
 
 func NewCertificateGenerator(id, templatePath, csvPath string, cfg Config, annotations PageAnnotations, settings Settings, outFilePattern string) *CertificateGenerator {
 	return &CertificateGenerator{
-		ID:             id,
-		TemplatePath:   templatePath,
-		CSVPath:        csvPath,
-		Cfg:            cfg,
-		Annotations:    annotations,
-		Settings:       settings,
-		OutFilePattern: outFilePattern,
-		textRenderers:  make(map[string]*TextRenderer),
+		ID:               id,
+		TemplatePath:     templatePath,
+		CSVPath:          csvPath,
+		Cfg:              cfg,
+		Annotations:      annotations,
+		Settings:         settings,
+		OutFilePattern:   outFilePattern,
+		textRenderers:    make(map[string]*TextRenderer),
+		valueTemplates:   make(map[string]*ValueTemplate),
+		barcodeRenderers: make(map[string]*BarcodeRenderer),
+		qrCodeRenderers:  make(map[string]*QRCodeRenderer),
+		conditions:       make(map[string]*Condition),
+		signatureFiles:   make(map[string]string),
+		imageFiles:       make(map[string]string),
 	}
 }
 
@@ -103,6 +200,20 @@ func (cg *CertificateGenerator) initializeProgress() {
 }
 
 func (cg *CertificateGenerator) updateProgress(phase string) {
+	cg.reportProgress(phase, "")
+}
+
+// Report a warning through the progress callback, each warning is reported only once per generation
+func (cg *CertificateGenerator) warn(warning string) {
+	if _, loaded := cg.warned.LoadOrStore(warning, true); loaded {
+		return
+	}
+
+	log.Printf("Warning for project id %s: %s\n", cg.ID, warning)
+	cg.reportProgress("Warning", warning)
+}
+
+func (cg *CertificateGenerator) reportProgress(phase, warning string) {
 	if cg.Settings.ProgressCallback == nil {
 		return
 	}
@@ -136,6 +247,7 @@ func (cg *CertificateGenerator) updateProgress(phase string) {
 		TimeLeft:     timeLeft,
 		CurrentPhase: phase,
 		EstimatedETA: eta,
+		Warning:      warning,
 	}
 
 	// Call the callback in a separate goroutine to avoid blocking
@@ -167,17 +279,59 @@ func (cg *CertificateGenerator) TempDir() string {
 	return tmpDir
 }
 
-func (cg *CertificateGenerator) embedSignatures(inputFile string) (string, error) {
-	currentFile := inputFile
+// Stamp all static images and signatures onto the template in one pass and return the path of the resulting base file.
+// When perRow is true, the ones of isStampedPerRow are only converted and left to be stamped per row,
+// otherwise their condition is evaluated against an empty row since there is no CSV data.
+func (cg *CertificateGenerator) embedStaticAnnotations(inputFile string, perRow bool) (string, error) {
+	if len(cg.Annotations.PageSignatureAnnotations) == 0 && len(cg.Annotations.PageImageAnnotations) == 0 {
+		return inputFile, nil
+	}
 
-	for page, sigAnnots := range cg.Annotations.PageSignatureAnnotations {
-		for _, annot := range sigAnnots {
-			tmpOut, err := os.CreateTemp(cg.TempDir(), "autocert_*.pdf")
+	cmp, err := NewCompositorFromFile(inputFile)
+	if err != nil {
+		return "", err
+	}
+
+	for page, imgAnnots := range cg.Annotations.PageImageAnnotations {
+		for _, annot := range imgAnnots {
+			// Images of the bundle are stamped per row
+			if annot.IsPerRow() {
+				continue
+			}
+
+			if _, err := os.Stat(annot.ImageFilePath); err != nil {
+				log.Printf("Image file %s does not exist, skipping annotation %s\n", annot.ImageFilePath, annot.ID)
+				continue
+			}
+
+			if !perRow {
+				stamp, err := cg.shouldStamp(annot.ID, nil)
+				if err != nil {
+					return "", fmt.Errorf("failed to evaluate condition of image annotation %s: %w", annot.ID, err)
+				}
+				if !stamp {
+					continue
+				}
+			}
+
+			imageFile, err := cg.fitImageAnnotate(annot.ImageFilePath, annot, cg.TempDir())
 			if err != nil {
 				return "", err
 			}
 
-			selectedPages := []string{fmt.Sprintf("%d", page)}
+			if cg.isStampedPerRow(annot.ID, perRow) {
+				cg.imageFiles[annot.ID] = imageFile
+				continue
+			}
+
+			if err := cg.stampImageAnnotate(cmp, int(page), imageFile, annot); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	for page, sigAnnots := range cg.Annotations.PageSignatureAnnotations {
+		for _, annot := range sigAnnots {
 			signatureFile := annot.SignatureFilePath
 
 			if _, err := os.Stat(signatureFile); os.IsNotExist(err) {
@@ -185,20 +339,84 @@ func (cg *CertificateGenerator) embedSignatures(inputFile string) (string, error
 				continue
 			}
 
+			if !perRow {
+				stamp, err := cg.shouldStamp(annot.ID, nil)
+				if err != nil {
+					return "", fmt.Errorf("failed to evaluate condition of signature annotation %s: %w", annot.ID, err)
+				}
+				if !stamp {
+					continue
+				}
+			}
+
 			signatureFile, err = cg.convertSignatureFormat(signatureFile, annot)
 			if err != nil {
 				return "", err
 			}
 
-			if err := ApplyWatermarkToPdf(currentFile, tmpOut.Name(), selectedPages, signatureFile, annot.X, annot.Y); err != nil {
-				return "", fmt.Errorf("failed to apply signature watermark for annotation %s: %w", annot.ID, err)
+			if cg.isStampedPerRow(annot.ID, perRow) {
+				cg.signatureFiles[annot.ID] = signatureFile
+				continue
 			}
 
-			currentFile = tmpOut.Name()
+			if err := cmp.StampFile(int(page), signatureFile, annot.X, annot.Y); err != nil {
+				return "", fmt.Errorf("failed to apply signature watermark for annotation %s: %w", annot.ID, err)
+			}
 		}
 	}
 
-	return currentFile, nil
+	tmpOut, err := os.CreateTemp(cg.TempDir(), "autocert_*.pdf")
+	if err != nil {
+		return "", err
+	}
+	tmpOut.Close()
+
+	if err := cmp.WriteFile(tmpOut.Name()); err != nil {
+		return "", fmt.Errorf("failed to write template with static annotations: %w", err)
+	}
+
+	return tmpOut.Name(), nil
+}
+
+// Whether a static image or signature is stamped per row instead of onto the base file: when it is conditional,
+// or when the page of an HTML template is rendered for each row since there is no shared base file
+func (cg *CertificateGenerator) isStampedPerRow(id string, perRow bool) bool {
+	if !perRow {
+		return false
+	}
+	_, ok := cg.conditions[id]
+	return ok || cg.htmlTemplate != nil
+}
+
+// Image annotates are rendered at imageAnnotateScale times the size of their box such that photos stay sharp in print
+const imageAnnotateScale = 3.0
+
+// Fit the image to the box of the annotate and return the path of the fitted png in dir
+func (cg *CertificateGenerator) fitImageAnnotate(imageFile string, annot ImageAnnotate, dir string) (string, error) {
+	tmpImg, err := os.CreateTemp(dir, "autocert_img_*.png")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary image file: %w", err)
+	}
+	tmpImg.Close()
+
+	if err := FitImage(imageFile, tmpImg.Name(), annot.Width*imageAnnotateScale, annot.Height*imageAnnotateScale, annot.Fit); err != nil {
+		return "", fmt.Errorf("failed to fit image for annotation %s: %w", annot.ID, err)
+	}
+
+	return tmpImg.Name(), nil
+}
+
+func (cg *CertificateGenerator) stampImageAnnotate(cmp *Compositor, page int, fittedFile string, annot ImageAnnotate) error {
+	f, err := os.Open(fittedFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := cmp.StampScaledImage(page, f, annot.X, annot.Y, 1/imageAnnotateScale); err != nil {
+		return fmt.Errorf("failed to apply image annotation %s: %w", annot.ID, err)
+	}
+	return nil
 }
 
 func (cg *CertificateGenerator) convertSignatureFormat(signatureFile string, annot SignatureAnnotate) (string, error) {
@@ -221,7 +439,11 @@ func (cg *CertificateGenerator) convertSignatureFormat(signatureFile string, ann
 		}
 
 		if err := SvgToPdf(signatureFile, tmpSvg.Name(), annot.Width, annot.Height); err != nil {
-			return "", fmt.Errorf("failed to convert SVG to PDF for annotation %s: %w", annot.ID, err)
+			// Some SVG features are not supported by the pure Go parser, let the browser handle it
+			cg.warn(fmt.Sprintf("signature SVG of annotation %s is rendered with Chrome, it cannot be converted without a browser: %v", annot.ID, err))
+			if chromeErr := SvgToPdfWithChrome(signatureFile, tmpSvg.Name(), annot.Width, annot.Height); chromeErr != nil {
+				return "", fmt.Errorf("failed to convert SVG to PDF for annotation %s: %w", annot.ID, errors.Join(err, chromeErr))
+			}
 		}
 
 		return tmpSvg.Name(), nil
@@ -244,45 +466,279 @@ func (cg *CertificateGenerator) initializeTextRenderers() error {
 				font.Size = 0
 			}
 
-			textRenderer, err := NewTextRenderer(cg.Cfg, *annot.Rect(), *font, cg.Settings)
+			textRenderer, err := NewTextRenderer(cg.Cfg, *annot.Rect(), *font, *annot.Wrap(), *annot.Typography(), cg.Settings)
 			if err != nil {
 				return fmt.Errorf("failed to create text renderer for annotation %s: %w", annot.ID, err)
 			}
 
 			cg.textRenderers[annot.ID] = textRenderer
+
+			valueTemplate, err := ParseValueTemplate(annot.Value)
+			if err != nil {
+				return fmt.Errorf("failed to parse value of annotation %s: %w", annot.ID, err)
+			}
+
+			cg.valueTemplates[annot.ID] = valueTemplate
+		}
+	}
+	return nil
+}
+
+func (cg *CertificateGenerator) initializeBarcodeRenderers() error {
+	for _, barcodeAnnots := range cg.Annotations.PageBarcodeAnnotations {
+		for _, annot := range barcodeAnnots {
+			if _, exists := cg.barcodeRenderers[annot.ID]; exists {
+				continue
+			}
+
+			barcodeRenderer, err := NewBarcodeRenderer(cg.Cfg, *annot.Rect(), annot.Symbology, annot.BarColor, annot.Font())
+			if err != nil {
+				return fmt.Errorf("failed to create barcode renderer for annotation %s: %w", annot.ID, err)
+			}
+
+			cg.barcodeRenderers[annot.ID] = barcodeRenderer
 		}
 	}
 	return nil
 }
 
-func (cg *CertificateGenerator) embedTextAnnotation(currentFile string, page uint, annot ColumnAnnotate, tmpDir string) (string, error) {
-	selectedPages := []string{fmt.Sprintf("%d", page)}
+func (cg *CertificateGenerator) initializeQRCodeRenderers() error {
+	for _, qrAnnots := range cg.Annotations.PageQRCodeAnnotations {
+		for _, annot := range qrAnnots {
+			if _, exists := cg.qrCodeRenderers[annot.ID]; exists {
+				continue
+			}
+
+			qrCodeRenderer, err := NewQRCodeRenderer(cg.Cfg, annot)
+			if err != nil {
+				return fmt.Errorf("failed to create QR code renderer for annotation %s: %w", annot.ID, err)
+			}
 
-	dir := tmpDir
-	if dir == "" {
-		dir = cg.TempDir()
+			cg.qrCodeRenderers[annot.ID] = qrCodeRenderer
+		}
 	}
+	return nil
+}
 
-	tmpOut, err := os.CreateTemp(dir, "autocert_temp_template_pdf_*.pdf")
+// Annotates may be on any page of the template but not beyond its last page
+func (cg *CertificateGenerator) validatePages() error {
+	f, err := os.Open(cg.TemplatePath)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to open template: %w", err)
 	}
+	defer f.Close()
 
-	txtFile, err := os.CreateTemp(dir, "autocert_svg_text_*.pdf")
+	pageCount, err := GetPageCount(f)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to get template page count: %w", err)
+	}
+
+	return cg.Annotations.ValidatePages(pageCount)
+}
+
+// Without QR code annotate, Settings.EmbedQRCode places one of 6% of the page width at the bottom right of the first page
+func (cg *CertificateGenerator) addDefaultQRCodeAnnotate() error {
+	if !cg.Settings.EmbedQRCode || len(cg.Annotations.PageQRCodeAnnotations) > 0 {
+		return nil
+	}
+
+	f, err := os.Open(cg.TemplatePath)
+	if err != nil {
+		return fmt.Errorf("failed to open template: %w", err)
+	}
+	defer f.Close()
+
+	w, h, err := GetPdfSizeByPage(f, 1)
+	if err != nil {
+		return fmt.Errorf("failed to get PDF page size: %w", err)
+	}
+	size := float64(QRCodeSizeByPageWidth(w))
+
+	cg.Annotations.PageQRCodeAnnotations = PageQRCodeAnnotations{
+		1: {
+			{
+				BaseAnnotate: BaseAnnotate{
+					ID:       "default-qrcode",
+					Type:     AnnotateTypeQRCode,
+					Position: Position{X: w - size, Y: h - size},
+					Size:     Size{Width: size, Height: size},
+				},
+				BackgroundColor: "#FFFFFF",
+				ErrorCorrection: QRErrorCorrectionMedium,
+				QuietZone:       DefaultQRQuietZone,
+			},
+		},
+	}
+	return nil
+}
+
+func (cg *CertificateGenerator) initializeConditions() error {
+	parse := func(id, expr string) error {
+		if expr == "" {
+			return nil
+		}
+
+		condition, err := ParseCondition(expr)
+		if err != nil {
+			return fmt.Errorf("failed to parse condition of annotation %s: %w", id, err)
+		}
+
+		cg.conditions[id] = condition
+		return nil
+	}
+
+	for _, colAnnots := range cg.Annotations.PageColumnAnnotations {
+		for _, annot := range colAnnots {
+			if err := parse(annot.ID, annot.Condition); err != nil {
+				return err
+			}
+		}
+	}
+	for _, sigAnnots := range cg.Annotations.PageSignatureAnnotations {
+		for _, annot := range sigAnnots {
+			if err := parse(annot.ID, annot.Condition); err != nil {
+				return err
+			}
+		}
+	}
+	for _, imgAnnots := range cg.Annotations.PageImageAnnotations {
+		for _, annot := range imgAnnots {
+			if err := parse(annot.ID, annot.Condition); err != nil {
+				return err
+			}
+		}
+	}
+	for _, barcodeAnnots := range cg.Annotations.PageBarcodeAnnotations {
+		for _, annot := range barcodeAnnots {
+			if err := parse(annot.ID, annot.Condition); err != nil {
+				return err
+			}
+		}
+	}
+	for _, qrAnnots := range cg.Annotations.PageQRCodeAnnotations {
+		for _, annot := range qrAnnots {
+			if err := parse(annot.ID, annot.Condition); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Whether the annotate is stamped for the row, an annotate without condition is always stamped
+func (cg *CertificateGenerator) shouldStamp(id string, row map[string]string) (bool, error) {
+	condition, ok := cg.conditions[id]
+	if !ok {
+		return true, nil
+	}
+	return condition.Evaluate(row)
+}
+
+// Whether the certificates differ by row: by their column annotates, barcodes, QR codes, images of the bundle,
+// conditional annotates or the columns of the HTML template
+func (cg *CertificateGenerator) variesPerRow() bool {
+	if len(cg.Annotations.PageColumnAnnotations) > 0 || cg.hasCertificateCodes() || cg.hasPerRowImages() {
+		return true
+	}
+	if cg.htmlTemplate != nil && len(cg.htmlTemplate.Columns()) > 0 {
+		return true
+	}
+
+	for _, sigAnnots := range cg.Annotations.PageSignatureAnnotations {
+		for _, annot := range sigAnnots {
+			if _, ok := cg.conditions[annot.ID]; ok {
+				return true
+			}
+		}
+	}
+	for _, imgAnnots := range cg.Annotations.PageImageAnnotations {
+		for _, annot := range imgAnnots {
+			if _, ok := cg.conditions[annot.ID]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Whether there are barcodes or QR codes, which differ by certificate even without CSV data
+func (cg *CertificateGenerator) hasCertificateCodes() bool {
+	return len(cg.Annotations.PageBarcodeAnnotations) > 0 || len(cg.Annotations.PageQRCodeAnnotations) > 0
+}
+
+func (cg *CertificateGenerator) hasPerRowImages() bool {
+	for _, imgAnnots := range cg.Annotations.PageImageAnnotations {
+		for _, annot := range imgAnnots {
+			if annot.IsPerRow() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Parse the HTML template, check its columns against the CSV headers and start the browser, the pages have the size
+// of the first page of the preview
+func (cg *CertificateGenerator) initializeHTMLTemplate() error {
+	htmlTemplate, err := ParseHTMLTemplateFile(cg.HTMLTemplatePath)
+	if err != nil {
+		return err
+	}
+	if headers := cg.headers(); headers != nil {
+		if err := htmlTemplate.Validate(headers); err != nil {
+			return fmt.Errorf("invalid HTML template: %w", err)
+		}
+	}
+
+	f, err := os.Open(cg.TemplatePath)
+	if err != nil {
+		return fmt.Errorf("failed to open template: %w", err)
+	}
+	defer f.Close()
+
+	w, h, err := GetPdfSizeByPage(f, 1)
+	if err != nil {
+		return fmt.Errorf("failed to get PDF page size: %w", err)
+	}
+
+	// Printing is CPU bound in the browser, more tabs than CPUs only queue up
+	poolSize := min(DeterminWorkers(max(len(cg.csvData), 1)), runtime.GOMAXPROCS(0))
+	htmlRenderer, err := NewHTMLRenderer(poolSize, w, h)
+	if err != nil {
+		return err
 	}
 
-	textRenderer := cg.textRenderers[annot.ID]
+	cg.htmlTemplate = htmlTemplate
+	cg.htmlRenderer = htmlRenderer
+	return nil
+}
+
+// Render the page of the HTML template for the row
+func (cg *CertificateGenerator) renderHTMLPage(row map[string]string) ([]byte, error) {
+	html, err := cg.htmlTemplate.Execute(row)
+	if err != nil {
+		return nil, err
+	}
+	return cg.htmlRenderer.RenderPdf(html)
+}
 
-	if err := textRenderer.RenderSvgTextAsPdf(annot.Value, annot.TextAlign, txtFile.Name()); err != nil {
+// Render the page of the HTML template shared by every certificate to a file in the temp dir
+func (cg *CertificateGenerator) renderHTMLPageFile(row map[string]string) (string, error) {
+	pdfBuf, err := cg.renderHTMLPage(row)
+	if err != nil {
 		return "", err
 	}
 
-	if err := ApplyWatermarkToPdf(currentFile, tmpOut.Name(), selectedPages, txtFile.Name(), annot.X, annot.Y); err != nil {
+	tmpOut, err := os.CreateTemp(cg.TempDir(), "autocert_html_*.pdf")
+	if err != nil {
 		return "", err
 	}
+	defer tmpOut.Close()
 
+	if _, err := tmpOut.Write(pdfBuf); err != nil {
+		return "", fmt.Errorf("failed to write HTML page: %w", err)
+	}
 	return tmpOut.Name(), nil
 }
 
@@ -307,13 +763,20 @@ type generationJob struct {
 	index  int
 	data   map[string]string
 	tmpDir string
+	certId string
+	// Name of the certificate file without extension, see fileNames
+	fileName string
 }
 
 type generationResult struct {
-	id         string
-	index      int
-	outputFile string
-	err        error
+	id          string
+	index       int
+	outputFile  string
+	rasterFiles []RasterFile
+	// The certificate before it is signed or encrypted, merged instead of outputFile since a merged signature would
+	// be broken and an encrypted PDF cannot be merged
+	plainFile string
+	err       error
 }
 
 func (cg *CertificateGenerator) Generate() ([]GeneratedResult, error) {
@@ -321,13 +784,6 @@ func (cg *CertificateGenerator) Generate() ([]GeneratedResult, error) {
 
 	defer os.RemoveAll(cg.TempDir())
 
-	cg.updateProgress("Preparing template")
-
-	baseFile, err := cg.embedSignatures(cg.TemplatePath)
-	if err != nil {
-		return nil, err
-	}
-
 	cg.updateProgress("Loading CSV data")
 
 	csvDataMaps, err := cg.loadCSVData()
@@ -342,106 +798,296 @@ func (cg *CertificateGenerator) Generate() ([]GeneratedResult, error) {
 		cg.totalCount = 1
 	}
 
-	if len(cg.csvData) == 0 || len(cg.Annotations.PageColumnAnnotations) == 0 {
-		return cg.generateSingleCertificate(baseFile)
+	if err := ValidateRasterSettings(cg.Settings.RasterFormats, cg.Settings.RasterDPI); err != nil {
+		return nil, err
 	}
 
-	cg.updateProgress("Initializing text renderers")
+	if err := cg.validateEncryption(); err != nil {
+		return nil, err
+	}
 
-	if err := cg.initializeTextRenderers(); err != nil {
+	if err := cg.validateZipGroupByColumn(); err != nil {
 		return nil, err
 	}
 
-	return cg.generateBatchCertificates(baseFile)
-}
+	if imp := cg.Settings.Imposition; imp != nil {
+		if err := imp.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid imposition: %w", err)
+		}
+	}
 
-func (cg *CertificateGenerator) generateSingleCertificate(baseFile string) ([]GeneratedResult, error) {
-	cg.updateProgress("Generating single certificate")
+	cg.updateProgress("Preparing template")
 
-	outputFile := filepath.Join(cg.OutputDir(), fmt.Sprintf(cg.OutFilePattern, "1")+".pdf")
+	if err := cg.validatePages(); err != nil {
+		return nil, err
+	}
 
-	// Use copy instead of os.Rename to avoid invalid cross-device link
-	if err := copyFile(baseFile, outputFile); err != nil {
+	if err := cg.addDefaultQRCodeAnnotate(); err != nil {
 		return nil, err
 	}
-	os.Remove(baseFile)
 
-	cg.incrementProgress()
+	if err := cg.validateQRCodeURL(); err != nil {
+		return nil, err
+	}
 
-	results := make(chan generationResult, 1)
-	results <- generationResult{
-		id:         uuid.NewString(),
-		index:      0,
-		outputFile: outputFile,
-		err:        nil,
+	if err := cg.initializeConditions(); err != nil {
+		return nil, err
 	}
-	close(results)
 
-	return cg.aggregateResults(results, 1)
-}
+	if err := cg.initializeMetadataTemplates(); err != nil {
+		return nil, err
+	}
 
-func (cg *CertificateGenerator) generateBatchCertificates(baseFile string) ([]GeneratedResult, error) {
-	maxWorkers := DeterminWorkers(len(cg.csvData))
-	fmt.Printf("Using %d workers for generating certificate for project id: %s\n", maxWorkers, cg.ID)
+	if err := cg.initializeFileNameTemplate(); err != nil {
+		return nil, err
+	}
 
-	cg.updateProgress("Starting batch generation")
+	if err := cg.initializeOutlineTitleTemplate(); err != nil {
+		return nil, err
+	}
 
-	jobs := make(chan generationJob, len(cg.csvData))
-	results := make(chan generationResult, len(cg.csvData))
+	if cg.HTMLTemplatePath != "" {
+		cg.updateProgress("Starting browser")
 
-	var wg sync.WaitGroup
-	for range maxWorkers {
-		wg.Add(1)
-		go cg.processWorkerJobs(jobs, results, baseFile, &wg)
+		if err := cg.initializeHTMLTemplate(); err != nil {
+			return nil, err
+		}
+		defer cg.htmlRenderer.Close()
 	}
 
-	for i, row := range cg.csvData {
-		workerID := fmt.Sprintf("worker-%d", i)
-		workerTmpDir := filepath.Join(cg.TempDir(), workerID)
-		if err := os.MkdirAll(workerTmpDir, 0755); err != nil {
-			results <- generationResult{index: i, outputFile: "", err: fmt.Errorf("failed to create worker tmp dir: %w", err)}
-			continue
+	perRow := len(cg.csvData) > 0 && cg.variesPerRow()
+
+	if perRow && cg.hasPerRowImages() {
+		if cg.ImageBundlePath == "" {
+			return nil, errors.New("image annotations read their image from a column but no image bundle is given")
 		}
 
-		jobs <- generationJob{index: i, data: row, tmpDir: workerTmpDir}
+		imageBundle, err := OpenImageBundle(cg.ImageBundlePath)
+		if err != nil {
+			return nil, err
+		}
+		defer imageBundle.Close()
+		cg.imageBundle = imageBundle
 	}
-	close(jobs)
-
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
 
-	return cg.aggregateResults(results, len(cg.csvData))
-}
-
-func (cg *CertificateGenerator) loadCSVData() ([]map[string]string, error) {
-	if cg.CSVPath == "" {
-		return []map[string]string{}, nil
+	templateFile := cg.TemplatePath
+	if cg.htmlTemplate != nil && !perRow {
+		// The page is the same for every row, render it once without CSV data
+		templateFile, err = cg.renderHTMLPageFile(nil)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	records, err := ReadCSVFromFile(cg.CSVPath)
+	baseFile, err := cg.embedStaticAnnotations(templateFile, perRow)
 	if err != nil {
 		return nil, err
 	}
 
-	return ParseCSVToMap(records)
-}
-
-func DeterminWorkers(jobCount int) int {
-	return min(max(runtime.GOMAXPROCS(0)*2, 1), jobCount)
-}
+	if !perRow {
+		return cg.generateSingleCertificate(baseFile)
+	}
 
-func (cg *CertificateGenerator) processWorkerJobs(jobs <-chan generationJob, results chan<- generationResult, baseFile string, wg *sync.WaitGroup) {
-	defer wg.Done()
+	cg.updateProgress("Initializing text renderers")
 
-	for job := range jobs {
-		outputFile, certId, err := cg.generateSingleCertificateFromJob(job, baseFile)
-		results <- generationResult{
-			id:         certId,
-			index:      job.index,
-			outputFile: outputFile,
-			err:        err,
+	if err := cg.initializeTextRenderers(); err != nil {
+		return nil, err
+	}
+	if err := cg.initializeBarcodeRenderers(); err != nil {
+		return nil, err
+	}
+	if err := cg.initializeQRCodeRenderers(); err != nil {
+		return nil, err
+	}
+
+	return cg.generateBatchCertificates(baseFile)
+}
+
+func (cg *CertificateGenerator) generateSingleCertificate(baseFile string) ([]GeneratedResult, error) {
+	cg.updateProgress("Generating single certificate")
+
+	certId := uuid.NewString()
+	fileNames, err := cg.fileNames([]map[string]string{nil}, []string{certId})
+	if err != nil {
+		return nil, err
+	}
+	outputFile := filepath.Join(cg.OutputDir(), fileNames[0]+".pdf")
+
+	if cg.hasCertificateCodes() {
+		// QR codes and barcodes of the certificate id are still stamped without CSV data, the barcodes of a column are left blank
+		if err := cg.initializeBarcodeRenderers(); err != nil {
+			return nil, err
+		}
+		if err := cg.initializeQRCodeRenderers(); err != nil {
+			return nil, err
+		}
+
+		cmp, err := NewCompositorFromFile(baseFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := cg.stampBarcodes(cmp, nil, certId, 0); err != nil {
+			return nil, err
+		}
+		if err := cg.stampQRCodes(cmp, nil, certId, 0); err != nil {
+			return nil, err
+		}
+		if err := cmp.WriteFile(outputFile); err != nil {
+			return nil, fmt.Errorf("failed to finalize certificate: %w", err)
+		}
+	} else if err := copyFile(baseFile, outputFile); err != nil {
+		// Use copy instead of os.Rename to avoid invalid cross-device link
+		return nil, err
+	}
+	os.Remove(baseFile)
+
+	if err := cg.convertToPdfA(outputFile); err != nil {
+		return nil, err
+	}
+
+	if err := cg.writeMetadata(outputFile, nil, certId, 0); err != nil {
+		return nil, err
+	}
+
+	rasterFiles, err := cg.rasterize(outputFile, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	plainFile, err := cg.protect(outputFile, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cg.incrementProgress()
+
+	results := make(chan generationResult, 1)
+	results <- generationResult{
+		id:          certId,
+		index:       0,
+		outputFile:  outputFile,
+		rasterFiles: rasterFiles,
+		plainFile:   plainFile,
+		err:         nil,
+	}
+	close(results)
+
+	return cg.aggregateResults(results, 1)
+}
+
+func (cg *CertificateGenerator) generateBatchCertificates(baseFile string) ([]GeneratedResult, error) {
+	// Read the base file once, each worker parse it from memory instead of copying it to disk
+	base, err := os.ReadFile(baseFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base file: %w", err)
+	}
+
+	maxWorkers := DeterminWorkers(len(cg.csvData))
+	fmt.Printf("Using %d workers for generating certificate for project id: %s\n", maxWorkers, cg.ID)
+
+	// Name the files before the workers start, the names of the rows depend on each other
+	certIds := make([]string, len(cg.csvData))
+	for i := range certIds {
+		certIds[i] = uuid.NewString()
+	}
+	fileNames, err := cg.fileNames(cg.csvData, certIds)
+	if err != nil {
+		return nil, err
+	}
+
+	cg.updateProgress("Starting batch generation")
+
+	jobs := make(chan generationJob, len(cg.csvData))
+	// The workers wait for the results to be aggregated, see Settings.ResultCallback
+	results := make(chan generationResult, maxWorkers)
+
+	var wg sync.WaitGroup
+	for range maxWorkers {
+		wg.Add(1)
+		go cg.processWorkerJobs(jobs, results, base, &wg)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, row := range cg.csvData {
+			workerID := fmt.Sprintf("worker-%d", i)
+			workerTmpDir := filepath.Join(cg.TempDir(), workerID)
+			if err := os.MkdirAll(workerTmpDir, 0755); err != nil {
+				results <- generationResult{index: i, outputFile: "", err: fmt.Errorf("failed to create worker tmp dir: %w", err)}
+				continue
+			}
+
+			jobs <- generationJob{index: i, data: row, tmpDir: workerTmpDir, certId: certIds[i], fileName: fileNames[i]}
+		}
+		close(jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return cg.aggregateResults(results, len(cg.csvData))
+}
+
+func (cg *CertificateGenerator) loadCSVData() ([]map[string]string, error) {
+	if cg.CSVPath == "" {
+		return []map[string]string{}, nil
+	}
+
+	records, err := ReadCSVFromFile(cg.CSVPath)
+	if err != nil {
+		return nil, err
+	}
+
+	csvData, err := ParseCSVToMap(records)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		// Renamed by ParseCSVToMap when duplicated
+		cg.csvHeaders = records[0]
+	}
+
+	return csvData, nil
+}
+
+func DeterminWorkers(jobCount int) int {
+	return min(max(runtime.GOMAXPROCS(0)*2, 1), jobCount)
+}
+
+func (cg *CertificateGenerator) processWorkerJobs(jobs <-chan generationJob, results chan<- generationResult, base []byte, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for job := range jobs {
+		outputFile, certId, err := cg.generateSingleCertificateFromJob(job, base)
+
+		if err == nil {
+			err = cg.convertToPdfA(outputFile)
+		}
+
+		if err == nil {
+			err = cg.writeMetadata(outputFile, job.data, certId, job.index)
+		}
+
+		var rasterFiles []RasterFile
+		if err == nil {
+			rasterFiles, err = cg.rasterize(outputFile, job.index)
+		}
+
+		var plainFile string
+		if err == nil {
+			plainFile, err = cg.protect(outputFile, job.data, job.index)
+		}
+
+		results <- generationResult{
+			id:          certId,
+			index:       job.index,
+			outputFile:  outputFile,
+			rasterFiles: rasterFiles,
+			plainFile:   plainFile,
+			err:         err,
 		}
 
 		// Update progress after each certificate is generated
@@ -453,115 +1099,894 @@ func (cg *CertificateGenerator) processWorkerJobs(jobs <-chan generationJob, res
 	}
 }
 
-func (cg *CertificateGenerator) generateSingleCertificateFromJob(job generationJob, baseFile string) (string, string, error) {
-	certId := uuid.NewString()
+func (cg *CertificateGenerator) generateSingleCertificateFromJob(job generationJob, base []byte) (string, string, error) {
+	certId := job.certId
+
+	if cg.htmlTemplate != nil {
+		page, err := cg.renderHTMLPage(job.data)
+		if err != nil {
+			return "", certId, fmt.Errorf("failed to render HTML template for row %d: %w", job.index, err)
+		}
+		base = page
+	}
 
-	workerBaseFile := filepath.Join(job.tmpDir, "base.pdf")
-	if err := copyFile(baseFile, workerBaseFile); err != nil {
+	cmp, err := NewCompositor(bytes.NewReader(base))
+	if err != nil {
 		return "", certId, err
 	}
 
-	currentFile := workerBaseFile
+	if err := cg.stampRowImages(cmp, job); err != nil {
+		return "", certId, err
+	}
+
+	for page, sigAnnots := range cg.Annotations.PageSignatureAnnotations {
+		for _, annot := range sigAnnots {
+			signatureFile, ok := cg.signatureFiles[annot.ID]
+			if !ok {
+				continue
+			}
+
+			stamp, err := cg.shouldStamp(annot.ID, job.data)
+			if err != nil {
+				return "", certId, fmt.Errorf("failed to evaluate condition of signature annotation on page %d for row %d: %w", page, job.index, err)
+			}
+			if !stamp {
+				continue
+			}
+
+			if err := cmp.StampFile(int(page), signatureFile, annot.X, annot.Y); err != nil {
+				return "", certId, fmt.Errorf("failed to apply signature annotation on page %d for row %d: %w", page, job.index, err)
+			}
+		}
+	}
 
 	for page, colAnnots := range cg.Annotations.PageColumnAnnotations {
 		for _, annot := range colAnnots {
-			modifiedAnnot := annot
-			modifiedAnnot.Value = job.data[annot.Value]
+			stamp, err := cg.shouldStamp(annot.ID, job.data)
+			if err != nil {
+				return "", certId, fmt.Errorf("failed to evaluate condition of text annotation on page %d for row %d: %w", page, job.index, err)
+			}
+			if !stamp {
+				continue
+			}
+
+			textRenderer := cg.textRenderers[annot.ID]
+			value, err := cg.valueTemplates[annot.ID].Execute(job.data)
+			if err != nil {
+				return "", certId, fmt.Errorf("failed to render value of text annotation on page %d for row %d: %w", page, job.index, err)
+			}
+
+			if missing := textRenderer.MissingGlyphs(value); len(missing) > 0 {
+				cg.warn(fmt.Sprintf("no font can render %q in value %q, font %q and its fallbacks are missing these glyphs", string(missing), annot.Value, annot.FontName))
+			}
 
-			var err error
-			currentFile, err = cg.embedTextAnnotation(currentFile, page, modifiedAnnot, job.tmpDir)
+			txtPdf, err := textRenderer.RenderTextAsPdf(value, annot.TextAlign)
 			if err != nil {
+				return "", certId, fmt.Errorf("failed to render text annotation on page %d for row %d: %w", page, job.index, err)
+			}
+
+			if err := cmp.StampPdf(int(page), bytes.NewReader(txtPdf), annot.X, annot.Y); err != nil {
 				return "", certId, fmt.Errorf("failed to apply text annotation on page %d for row %d: %w", page, job.index, err)
 			}
 		}
 	}
 
-	if cg.Settings.EmbedQRCode {
-		_, err := cg.embedQRCode(currentFile, certId, job.tmpDir, job.index)
-		if err != nil {
-			return "", certId, err
-		}
+	if err := cg.stampBarcodes(cmp, job.data, certId, job.index); err != nil {
+		return "", certId, err
+	}
+
+	if err := cg.stampQRCodes(cmp, job.data, certId, job.index); err != nil {
+		return "", certId, err
 	}
 
-	outputFile := filepath.Join(cg.OutputDir(), fmt.Sprintf(cg.OutFilePattern, fmt.Sprint(job.index+1))+".pdf")
-	if err := os.Rename(currentFile, outputFile); err != nil {
+	outputFile := filepath.Join(cg.OutputDir(), job.fileName+".pdf")
+	if err := cmp.WriteFile(outputFile); err != nil {
 		return "", certId, fmt.Errorf("failed to finalize certificate for row %d: %w", job.index, err)
 	}
 
 	return outputFile, certId, nil
 }
 
-func (cg *CertificateGenerator) embedQRCode(currentFile, certId, tmpDir string, index int) (string, error) {
-	tmpQrCodeFile, err := os.CreateTemp(tmpDir, "autocert_qr_*.pdf")
+func (cg *CertificateGenerator) initializeFileNameTemplate() error {
+	if cg.Settings.FileNameTemplate == "" {
+		return nil
+	}
+
+	fileNameTemplate, err := ParseTextTemplate(cg.Settings.FileNameTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse file name template: %w", err)
+	}
+	if err := ValidateFileNameTemplate(fileNameTemplate, cg.headers()); err != nil {
+		return err
+	}
+	cg.fileNameTemplate = fileNameTemplate
+
+	return nil
+}
+
+// Name the certificates of the rows without extension. The names are resolved in the order of the rows so that the
+// same table always gives the same names, and none is the name of the merged or the imposed PDF.
+func (cg *CertificateGenerator) fileNames(rows []map[string]string, certIds []string) ([]string, error) {
+	namer := newFileNamer(cg.mergedFileName(), cg.imposedFileName())
+
+	names := make([]string, len(rows))
+	for i, row := range rows {
+		if cg.fileNameTemplate == nil {
+			names[i] = namer.name(fmt.Sprintf(cg.OutFilePattern, fmt.Sprint(i+1)))
+			continue
+		}
+
+		name, err := cg.fileNameTemplate.Execute(fileNameRow(row, i+1, certIds[i], cg.startTime))
+		if err != nil {
+			return nil, fmt.Errorf("failed to render file name for row %d: %w", i, err)
+		}
+		names[i] = namer.name(name)
+	}
+
+	return names, nil
+}
+
+func (cg *CertificateGenerator) initializeOutlineTitleTemplate() error {
+	if cg.Settings.OutlineTitle == "" {
+		return nil
+	}
+
+	outlineTitleTemplate, err := ParseValueTemplate(cg.Settings.OutlineTitle)
+	if err != nil {
+		return fmt.Errorf("failed to parse outline title: %w", err)
+	}
+	if headers := cg.headers(); headers != nil {
+		if err := outlineTitleTemplate.Validate(headers); err != nil {
+			return fmt.Errorf("invalid outline title: %w", err)
+		}
+	}
+	cg.outlineTitleTemplate = outlineTitleTemplate
+
+	return nil
+}
+
+func (cg *CertificateGenerator) mergedFileName() string {
+	return fmt.Sprintf(cg.OutFilePattern, "merged")
+}
+
+func (cg *CertificateGenerator) imposedFileName() string {
+	return fmt.Sprintf(cg.OutFilePattern, "print")
+}
+
+// The headers of the CSV in their order, nil without CSV data
+func (cg *CertificateGenerator) headers() []string {
+	if len(cg.csvData) == 0 {
+		return nil
+	}
+	if cg.csvHeaders != nil {
+		return cg.csvHeaders
+	}
+
+	headers := make([]string, 0, len(cg.csvData[0]))
+	for header := range cg.csvData[0] {
+		headers = append(headers, header)
+	}
+	slices.Sort(headers)
+	return headers
+}
+
+func (cg *CertificateGenerator) validateZipGroupByColumn() error {
+	column := cg.Settings.ZipGroupByColumn
+	if column == "" {
+		return nil
+	}
+
+	headers := cg.headers()
+	if headers != nil && !slices.Contains(headers, column) {
+		return fmt.Errorf("ZIP group by column %q is not in the table", column)
+	}
+	return nil
+}
+
+// The QR codes encode the verification URL, which needs Settings.QrURLPattern
+func (cg *CertificateGenerator) validateQRCodeURL() error {
+	if cg.Settings.QrURLPattern != "" {
+		return nil
+	}
+	for _, qrAnnots := range cg.Annotations.PageQRCodeAnnotations {
+		if len(qrAnnots) > 0 {
+			return errors.New("QR code annotations require a QR URL pattern")
+		}
+	}
+	return nil
+}
+
+// The verification URL of the certificate, empty without Settings.QrURLPattern
+func (cg *CertificateGenerator) verifyURL(certId string) string {
+	if cg.Settings.QrURLPattern == "" {
+		return ""
+	}
+	return fmt.Sprintf(cg.Settings.QrURLPattern, certId)
+}
+
+// Convert the certificate to PDF/A-2b when Settings.PDFA is set and warn about the violations left, Eg: a font of
+// the template which is not embedded
+func (cg *CertificateGenerator) convertToPdfA(outputFile string) error {
+	if !cg.Settings.PDFA {
+		return nil
+	}
+
+	if err := ConvertToPdfAFile(outputFile, outputFile); err != nil {
+		return fmt.Errorf("failed to convert %s to PDF/A: %w", filepath.Base(outputFile), err)
+	}
+
+	violations, err := ValidatePdfAFile(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to validate PDF/A of %s: %w", filepath.Base(outputFile), err)
+	}
+	for _, violation := range violations {
+		cg.warn("certificate is not PDF/A-2b conformant: " + violation)
+	}
+
+	return nil
+}
+
+func (cg *CertificateGenerator) initializeMetadataTemplates() error {
+	var err error
+	if cg.Settings.TitleTemplate != "" {
+		if cg.titleTemplate, err = ParseTextTemplate(cg.Settings.TitleTemplate); err != nil {
+			return fmt.Errorf("failed to parse title template: %w", err)
+		}
+	}
+	if cg.Settings.SubjectTemplate != "" {
+		if cg.subjectTemplate, err = ParseTextTemplate(cg.Settings.SubjectTemplate); err != nil {
+			return fmt.Errorf("failed to parse subject template: %w", err)
+		}
+	}
+	return nil
+}
+
+// The document information and XMP metadata of the certificate of the row. The row is nil for the merged PDF, which
+// has no certificate id.
+func (cg *CertificateGenerator) metadata(row map[string]string, certId string) (PdfMetadata, error) {
+	meta := PdfMetadata{
+		Author:    cg.Settings.Author,
+		Creator:   "AutoCert",
+		ProjectID: cg.ID,
+	}
+
+	var err error
+	if cg.titleTemplate != nil {
+		if meta.Title, err = cg.titleTemplate.Execute(row); err != nil {
+			return meta, err
+		}
+	} else {
+		meta.Title = cg.Settings.Title
+	}
+	if cg.subjectTemplate != nil {
+		if meta.Subject, err = cg.subjectTemplate.Execute(row); err != nil {
+			return meta, err
+		}
+	}
+
+	if certId != "" {
+		meta.CertificateID = certId
+		meta.VerifyURL = cg.verifyURL(certId)
+	}
+
+	return meta, nil
+}
+
+// Write the document information and the XMP metadata of the certificate of the row, before it is exported and signed
+func (cg *CertificateGenerator) writeMetadata(outputFile string, row map[string]string, certId string, index int) error {
+	meta, err := cg.metadata(row, certId)
+	if err != nil {
+		return fmt.Errorf("failed to render metadata for row %d: %w", index, err)
+	}
+
+	if err := SetPdfMetadataFile(outputFile, outputFile, meta); err != nil {
+		return fmt.Errorf("failed to write metadata for row %d: %w", index, err)
+	}
+	return nil
+}
+
+// Export the certificate of the row to the images of Settings.RasterFormats
+func (cg *CertificateGenerator) rasterize(outputFile string, index int) ([]RasterFile, error) {
+	rasterFiles, err := RasterizePdfFile(outputFile, cg.Settings.RasterFormats, cg.Settings.RasterDPI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export certificate image for row %d: %w", index, err)
+	}
+	return rasterFiles, nil
+}
+
+// Encryption rewrites the PDF, it would break a signature or the PDF/A conformance, and the images of a certificate
+// cannot be protected by its password
+func (cg *CertificateGenerator) validateEncryption() error {
+	encryption := cg.Settings.Encryption
+	if encryption == nil {
+		return nil
+	}
+
+	if cg.Settings.PDFA {
+		return errors.New("PDF/A does not allow encryption, disable one of them")
+	}
+	if cg.Settings.Signer != nil {
+		return errors.New("encrypted certificates cannot be signed, disable one of them")
+	}
+	if encryption.UserPasswordColumn != "" && len(cg.Settings.RasterFormats) > 0 {
+		return errors.New("images cannot be protected by the user password, disable the image export")
+	}
+
+	return encryption.Validate(cg.headers())
+}
+
+// Sign or encrypt the certificate of the row and return the path of the certificate before, see
+// generationResult.plainFile
+func (cg *CertificateGenerator) protect(outputFile string, row map[string]string, index int) (string, error) {
+	if cg.Settings.Encryption != nil {
+		return cg.encrypt(outputFile, row, index)
+	}
+	return cg.sign(outputFile, index)
+}
+
+// Copy the certificate to the dir of the temporary directory before it is rewritten
+func (cg *CertificateGenerator) plainCopy(outputFile, dir string) (string, error) {
+	plainDir := filepath.Join(cg.TempDir(), dir)
+	if err := os.MkdirAll(plainDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s dir: %w", dir, err)
+	}
+	plainFile := filepath.Join(plainDir, filepath.Base(outputFile))
+	if err := copyFile(outputFile, plainFile); err != nil {
+		return "", err
+	}
+	return plainFile, nil
+}
+
+// Sign the certificate of the row with Settings.Signer and return the path of the certificate before it was signed
+func (cg *CertificateGenerator) sign(outputFile string, index int) (string, error) {
+	if cg.Settings.Signer == nil {
+		return outputFile, nil
+	}
+
+	unsignedFile, err := cg.plainCopy(outputFile, "unsigned")
+	if err != nil {
+		return "", err
+	}
+
+	if err := cg.Settings.Signer.SignFile(unsignedFile, outputFile); err != nil {
+		return "", fmt.Errorf("failed to sign certificate for row %d: %w", index, err)
+	}
+	return unsignedFile, nil
+}
+
+// Encrypt the certificate of the row with Settings.Encryption and the user password of the row, return the path of
+// the certificate before it was encrypted
+func (cg *CertificateGenerator) encrypt(outputFile string, row map[string]string, index int) (string, error) {
+	encryption := cg.Settings.Encryption
+
+	var userPassword string
+	if column := encryption.UserPasswordColumn; column != "" && row != nil {
+		userPassword = row[column]
+		if userPassword == "" {
+			cg.warn(fmt.Sprintf("row %d has no password in column %q, its certificate opens without a password", index, column))
+		}
+	}
+
+	unencryptedFile, err := cg.plainCopy(outputFile, "unencrypted")
 	if err != nil {
 		return "", err
 	}
-	defer os.Remove(tmpQrCodeFile.Name())
 
-	err = GenerateQRCodeAsPdfByPdfPage(fmt.Sprintf(cg.Settings.QrURLPattern, certId), currentFile, 1, tmpQrCodeFile.Name())
+	if err := EncryptPdfFile(unencryptedFile, outputFile, *encryption, userPassword); err != nil {
+		return "", fmt.Errorf("failed to encrypt certificate for row %d: %w", index, err)
+	}
+	return unencryptedFile, nil
+}
+
+// Stamp the images of the bundle named by the row and the static images of isStampedPerRow
+func (cg *CertificateGenerator) stampRowImages(cmp *Compositor, job generationJob) error {
+	for page, imgAnnots := range cg.Annotations.PageImageAnnotations {
+		for _, annot := range imgAnnots {
+			imageFile, deferred := cg.imageFiles[annot.ID]
+			if !annot.IsPerRow() && !deferred {
+				continue
+			}
+
+			stamp, err := cg.shouldStamp(annot.ID, job.data)
+			if err != nil {
+				return fmt.Errorf("failed to evaluate condition of image annotation on page %d for row %d: %w", page, job.index, err)
+			}
+			if !stamp {
+				continue
+			}
+
+			if annot.IsPerRow() {
+				// A row without image, Eg: a recipient without photo, is left blank
+				name := strings.TrimSpace(job.data[annot.Value])
+				if name == "" {
+					continue
+				}
+
+				extracted, err := cg.imageBundle.Extract(name, job.tmpDir)
+				if err != nil {
+					return fmt.Errorf("failed to read image of column %q on page %d for row %d: %w", annot.Value, page, job.index, err)
+				}
+
+				imageFile, err = cg.fitImageAnnotate(extracted, annot, job.tmpDir)
+				if err != nil {
+					return fmt.Errorf("failed to render image annotation on page %d for row %d: %w", page, job.index, err)
+				}
+			}
+
+			if err := cg.stampImageAnnotate(cmp, int(page), imageFile, annot); err != nil {
+				return fmt.Errorf("failed to apply image annotation on page %d for row %d: %w", page, job.index, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Stamp the barcodes of the row, a barcode of a blank cell is left out
+func (cg *CertificateGenerator) stampBarcodes(cmp *Compositor, row map[string]string, certId string, index int) error {
+	for page, barcodeAnnots := range cg.Annotations.PageBarcodeAnnotations {
+		for _, annot := range barcodeAnnots {
+			stamp, err := cg.shouldStamp(annot.ID, row)
+			if err != nil {
+				return fmt.Errorf("failed to evaluate condition of barcode annotation on page %d for row %d: %w", page, index, err)
+			}
+			if !stamp {
+				continue
+			}
+
+			content := certId
+			if !annot.IsCertificateID() {
+				content = strings.TrimSpace(row[annot.Value])
+			}
+			if content == "" {
+				continue
+			}
+
+			barcodePdf, err := cg.barcodeRenderers[annot.ID].RenderBarcodeAsPdf(content)
+			if err != nil {
+				return fmt.Errorf("failed to render barcode annotation on page %d for row %d: %w", page, index, err)
+			}
+
+			if err := cmp.StampPdf(int(page), bytes.NewReader(barcodePdf), annot.X, annot.Y); err != nil {
+				return fmt.Errorf("failed to apply barcode annotation on page %d for row %d: %w", page, index, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Stamp the QR codes of the verification URL of the certificate
+func (cg *CertificateGenerator) stampQRCodes(cmp *Compositor, row map[string]string, certId string, index int) error {
+	url := cg.verifyURL(certId)
+
+	for page, qrAnnots := range cg.Annotations.PageQRCodeAnnotations {
+		for _, annot := range qrAnnots {
+			stamp, err := cg.shouldStamp(annot.ID, row)
+			if err != nil {
+				return fmt.Errorf("failed to evaluate condition of QR code annotation on page %d for row %d: %w", page, index, err)
+			}
+			if !stamp {
+				continue
+			}
+
+			qrPdf, err := cg.qrCodeRenderers[annot.ID].RenderQRCodeAsPdf(url, annot.CaptionOf(url))
+			if err != nil {
+				return fmt.Errorf("failed to generate QR code on page %d for row %d: %w", page, index, err)
+			}
+
+			if err := cmp.StampPdf(int(page), bytes.NewReader(qrPdf), annot.X, annot.Y); err != nil {
+				return fmt.Errorf("failed to embed QR code on page %d for row %d: %w", page, index, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// The merged PDF has the author and the project of the certificates, and their title and subject when they do not
+// read a column
+func (cg *CertificateGenerator) writeMergedMetadata(mergeOut string) error {
+	meta := PdfMetadata{
+		Author:    cg.Settings.Author,
+		Creator:   "AutoCert",
+		ProjectID: cg.ID,
+	}
+	if cg.titleTemplate == nil {
+		meta.Title = cg.Settings.Title
+	} else if len(cg.titleTemplate.Columns()) == 0 {
+		meta.Title, _ = cg.titleTemplate.Execute(nil)
+	}
+	if cg.subjectTemplate != nil && len(cg.subjectTemplate.Columns()) == 0 {
+		meta.Subject, _ = cg.subjectTemplate.Execute(nil)
+	}
+
+	if err := SetPdfMetadataFile(mergeOut, mergeOut, meta); err != nil {
+		return fmt.Errorf("failed to write metadata of the merged PDF: %w", err)
+	}
+	return nil
+}
+
+// The ZIP of the certificates, written as they are generated
+type certificateZip struct {
+	writer   *ZipWriter
+	manifest Manifest
+}
+
+// Create the ZIP to which addToZip adds each certificate, in the folder of Settings.ZipGroupByColumn, and closeZip
+// the manifests of the certificates at the root
+func (cg *CertificateGenerator) createZip(zipOut string) (*certificateZip, error) {
+	writer, err := NewZipWriter(zipOut)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ZIP file: %w", err)
+	}
+
+	z := &certificateZip{
+		writer: writer,
+		manifest: Manifest{
+			ProjectID:    cg.ID,
+			Columns:      []string{},
+			Certificates: make([]ManifestEntry, 0, cg.totalCount),
+		},
+	}
+	// The passwords of the certificates are not given away with them
+	for _, column := range cg.headers() {
+		if cg.Settings.Encryption == nil || column != cg.Settings.Encryption.UserPasswordColumn {
+			z.manifest.Columns = append(z.manifest.Columns, column)
+		}
+	}
+
+	return z, nil
+}
+
+// Zip the PDF and the images of the certificate
+func (cg *CertificateGenerator) addToZip(z *certificateZip, r generationResult) error {
+	var row map[string]string
+	if r.index < len(cg.csvData) {
+		row = cg.csvData[r.index]
+	}
+
+	folder := ""
+	if column := cg.Settings.ZipGroupByColumn; column != "" && strings.TrimSpace(row[column]) != "" {
+		folder = SanitizeFileName(row[column])
+	}
+
+	filePath := path.Join(folder, filepath.Base(r.outputFile))
+	if err := z.writer.Add(ZipEntry{FilePath: r.outputFile, ArchivePath: filePath}); err != nil {
+		return err
+	}
+	for _, rf := range r.rasterFiles {
+		if err := z.writer.Add(ZipEntry{FilePath: rf.Path, ArchivePath: path.Join(folder, filepath.Base(rf.Path))}); err != nil {
+			return err
+		}
+	}
+
+	manifestRow := make(map[string]string, len(z.manifest.Columns))
+	for _, column := range z.manifest.Columns {
+		manifestRow[column] = row[column]
+	}
+	z.manifest.Certificates = append(z.manifest.Certificates, ManifestEntry{
+		Number:        r.index + 1,
+		CertificateID: r.id,
+		FilePath:      filePath,
+		VerifyURL:     cg.verifyURL(r.id),
+		Row:           manifestRow,
+	})
+
+	return nil
+}
+
+// Add the manifests, in the order of the rows, and close the ZIP
+func (cg *CertificateGenerator) closeZip(z *certificateZip) error {
+	slices.SortFunc(z.manifest.Certificates, func(a, b ManifestEntry) int {
+		return a.Number - b.Number
+	})
+
+	manifestDir := filepath.Join(cg.TempDir(), "manifest")
+	if err := os.MkdirAll(manifestDir, 0755); err != nil {
+		z.writer.Close()
+		return fmt.Errorf("failed to create manifest dir: %w", err)
+	}
+	manifestEntries, err := z.manifest.WriteFiles(manifestDir)
+	if err != nil {
+		z.writer.Close()
+		return err
+	}
+	for _, entry := range manifestEntries {
+		if err := z.writer.Add(entry); err != nil {
+			z.writer.Close()
+			return err
+		}
+	}
+
+	return z.writer.Close()
+}
+
+// The certificate which the merged and the imposed PDF read. The callback may remove the certificate once it has it,
+// so they read a link to it when it is not signed nor encrypted.
+func (cg *CertificateGenerator) retainPlainFile(r generationResult) (string, error) {
+	if r.plainFile != r.outputFile || cg.Settings.ResultCallback == nil || (!cg.Settings.MergeAfterGenerate && cg.Settings.Imposition == nil) {
+		return r.plainFile, nil
+	}
+
+	retainedDir := filepath.Join(cg.TempDir(), "retained")
+	if err := os.MkdirAll(retainedDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create retained dir: %w", err)
+	}
+	retained := filepath.Join(retainedDir, filepath.Base(r.outputFile))
+	if err := os.Link(r.outputFile, retained); err != nil {
+		// Eg: the temporary directory is on another file system
+		if err := copyFile(r.outputFile, retained); err != nil {
+			return "", fmt.Errorf("failed to retain certificate for row %d: %w", r.index, err)
+		}
+	}
+	return retained, nil
+}
+
+// Pass the result to Settings.ResultCallback
+func (cg *CertificateGenerator) emit(result GeneratedResult) error {
+	if cg.Settings.ResultCallback == nil {
+		return nil
+	}
+	if err := cg.Settings.ResultCallback(result); err != nil {
+		return fmt.Errorf("failed to handle %s: %w", result.FileName, err)
+	}
+	return nil
+}
+
+// Merge the certificates, after the index pages of Settings.MergedIndex, with a bookmark for each of them and page
+// labels which number the certificates from 1
+func (cg *CertificateGenerator) merge(results []generationResult, inFiles []string, mergeOut string) error {
+	if len(inFiles) == 0 {
+		return MergePdf(inFiles, mergeOut)
+	}
+
+	// Every certificate has the pages of the template, or the single page of the HTML template
+	pageCount, err := api.PageCountFile(inFiles[0])
+	if err != nil {
+		return fmt.Errorf("failed to count pages of certificate: %w", err)
+	}
+
+	items := make([]OutlineItem, len(results))
+	entries := make([]IndexEntry, len(results))
+	page := 1
+	for i, r := range results {
+		title, err := cg.outlineTitle(r)
+		if err != nil {
+			return err
+		}
+
+		items[i] = OutlineItem{Title: title, Page: page}
+		entries[i] = IndexEntry{Title: title, Page: strconv.Itoa(page)}
+		page += pageCount
+	}
+
+	indexPages := 0
+	if cg.Settings.MergedIndex {
+		indexFile := filepath.Join(cg.TempDir(), "index.pdf")
+		if indexPages, err = cg.renderIndex(entries, inFiles[0], indexFile); err != nil {
+			return err
+		}
+
+		for i := range items {
+			items[i].Page += indexPages
+		}
+		items = append([]OutlineItem{{Title: "Index", Page: 1}}, items...)
+		inFiles = append([]string{indexFile}, inFiles...)
+	}
+
+	if err := MergePdf(inFiles, mergeOut); err != nil {
+		return fmt.Errorf("failed to merge PDF files: %w", err)
+	}
+	if err := SetPdfOutlineFile(mergeOut, mergeOut, items, mergedPageLabels(indexPages)); err != nil {
+		return fmt.Errorf("failed to bookmark the merged PDF: %w", err)
+	}
+
+	return nil
+}
+
+// Impose the plain certificates, without the index of the merged PDF, on the sheets of Settings.Imposition
+func (cg *CertificateGenerator) impose(inFiles []string, imposeOut string) error {
+	mergeOut := filepath.Join(cg.TempDir(), "print.pdf")
+	if err := MergePdf(inFiles, mergeOut); err != nil {
+		return fmt.Errorf("failed to merge PDF files for print: %w", err)
+	}
+	if err := ImposePdfFile(mergeOut, imposeOut, *cg.Settings.Imposition); err != nil {
+		return fmt.Errorf("failed to impose certificates: %w", err)
+	}
+	if cg.Settings.Encryption != nil {
+		if err := EncryptCombinedPdfFile(imposeOut, imposeOut, *cg.Settings.Encryption); err != nil {
+			return fmt.Errorf("failed to encrypt the print PDF: %w", err)
+		}
+	}
+	return nil
+}
+
+// The title of the bookmark of the certificate, its file name when Settings.OutlineTitle is empty or renders to nothing
+func (cg *CertificateGenerator) outlineTitle(r generationResult) (string, error) {
+	fileName := strings.TrimSuffix(filepath.Base(r.outputFile), filepath.Ext(r.outputFile))
+	if cg.outlineTitleTemplate == nil {
+		return fileName, nil
+	}
+
+	var row map[string]string
+	if r.index < len(cg.csvData) {
+		row = cg.csvData[r.index]
+	}
+	title, err := cg.outlineTitleTemplate.Execute(row)
+	if err != nil {
+		return "", fmt.Errorf("failed to render outline title for row %d: %w", r.index, err)
+	}
+
+	title = strings.Join(strings.Fields(title), " ")
+	if title == "" {
+		return fileName, nil
+	}
+	return title, nil
+}
+
+// Render the index pages to indexFile in the size of the first page of the first certificate, return their count
+func (cg *CertificateGenerator) renderIndex(entries []IndexEntry, firstFile, indexFile string) (int, error) {
+	dims, err := api.PageDimsFile(firstFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read page size of the certificates: %w", err)
+	}
+
+	font := cg.indexFont()
+	fontLoader, err := NewFontLoader(cg.Cfg)
+	if err != nil {
+		return 0, err
+	}
+	fontChain, err := fontLoader.LoadFontChain(font.Name, font.Fallbacks, font.GetFontStyle())
 	if err != nil {
-		return "", fmt.Errorf("failed to generate QR code for row %d: %w", index, err)
+		return 0, fmt.Errorf("failed to load font of the index: %w", err)
+	}
+	var missing []rune
+	for _, entry := range entries {
+		for _, r := range fontChain.MissingGlyphs(entry.Title) {
+			if !slices.Contains(missing, r) {
+				missing = append(missing, r)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		cg.warn(fmt.Sprintf("no font can render %q in the index, font %q and its fallbacks are missing these glyphs", string(missing), font.Name))
 	}
 
-	err = EmbedQRCodeToPdf(currentFile, currentFile, tmpQrCodeFile.Name(), []string{})
+	f, err := os.Create(indexFile)
 	if err != nil {
-		return "", fmt.Errorf("failed to embed QR code for row %d: %w", index, err)
+		return 0, fmt.Errorf("failed to create index file: %w", err)
 	}
+	defer f.Close()
 
-	return currentFile, nil
+	// Page dimensions are in pt
+	return RenderIndexPdf(f, entries, fontChain, pxToMM(dims[0].Width), pxToMM(dims[0].Height))
+}
+
+// Settings.IndexFont, or the font of the first column annotation, which can draw the names of the recipients
+func (cg *CertificateGenerator) indexFont() Font {
+	if cg.Settings.IndexFont.Name != "" {
+		return cg.Settings.IndexFont
+	}
+
+	for _, page := range slices.Sorted(maps.Keys(cg.Annotations.PageColumnAnnotations)) {
+		if annots := cg.Annotations.PageColumnAnnotations[page]; len(annots) > 0 {
+			return Font{Name: annots[0].FontName, Fallbacks: annots[0].FontFallbacks}
+		}
+	}
+	// The font loader falls back to the first available font
+	return Font{}
 }
 
 func (cg *CertificateGenerator) aggregateResults(results <-chan generationResult, totalCount int) ([]GeneratedResult, error) {
 	resultMap := make(map[int]generationResult)
-	inFile := make([]string, totalCount)
+	// The results of each row, the certificate then its images
+	rowFiles := make(map[int][]GeneratedResult)
 	var firstErr error
 
+	var z *certificateZip
+	zipOut := filepath.Join(cg.OutputDir(), "certificates.zip")
+	if cg.Settings.ZipAfterGenerate {
+		var err error
+		if z, err = cg.createZip(zipOut); err != nil {
+			firstErr = err
+		}
+	}
+
+	// The certificates are zipped and passed to the callback as they finish, the results are drained on error so
+	// that the workers do not block
 	for r := range results {
+		if firstErr != nil {
+			continue
+		}
 		if r.err != nil {
-			if firstErr == nil {
-				firstErr = r.err
+			firstErr = r.err
+			continue
+		}
+
+		if z != nil {
+			if err := cg.addToZip(z, r); err != nil {
+				firstErr = fmt.Errorf("failed to zip certificate for row %d: %w", r.index, err)
+				continue
+			}
+		}
+
+		plainFile, err := cg.retainPlainFile(r)
+		if err != nil {
+			firstErr = err
+			continue
+		}
+		r.plainFile = plainFile
+		resultMap[r.index] = r
+
+		files := []GeneratedResult{{
+			Number:   r.index + 1,
+			FilePath: r.outputFile,
+			FileName: filepath.Base(r.outputFile),
+			Type:     CertificateTypeNormal,
+			ID:       r.id,
+		}}
+		for _, rf := range r.rasterFiles {
+			files = append(files, GeneratedResult{
+				Number:   r.index + 1,
+				FilePath: rf.Path,
+				FileName: filepath.Base(rf.Path),
+				Type:     rf.Format.CertificateType(),
+				ID:       uuid.NewString(),
+			})
+		}
+		rowFiles[r.index] = files
+
+		for _, file := range files {
+			if err := cg.emit(file); err != nil {
+				firstErr = err
+				break
 			}
-		} else {
-			resultMap[r.index] = r
 		}
 	}
 
 	if firstErr != nil {
+		if z != nil {
+			z.writer.Close()
+		}
 		return nil, firstErr
 	}
 
+	inFile := make([]string, totalCount)
+	// The results in the order of the rows
+	ordered := make([]generationResult, 0, totalCount)
 	generatedFiles := make([]GeneratedResult, 0, totalCount)
 	for i := range totalCount {
-		if r, ok := resultMap[i]; ok {
-			generatedFiles = append(generatedFiles, GeneratedResult{
-				Number:   i + 1,
-				FilePath: r.outputFile,
-				FileName: filepath.Base(r.outputFile),
-				Type:     CertificateTypeNormal,
-				ID:       r.id,
-			})
-			inFile[i] = r.outputFile
-		} else {
+		r, ok := resultMap[i]
+		if !ok {
+			if z != nil {
+				z.writer.Close()
+			}
 			return nil, fmt.Errorf("missing result for row %d", i)
 		}
+		inFile[i] = r.plainFile
+		ordered = append(ordered, r)
+		generatedFiles = append(generatedFiles, rowFiles[i]...)
 	}
 
-	if cg.Settings.ZipAfterGenerate {
+	if z != nil {
 		zipNow := time.Now()
 
 		cg.updateProgress("Creating ZIP archive")
-		zipOut := filepath.Join(cg.OutputDir(), "certificates.zip")
-		err := ZipFiles(inFile, zipOut)
-		if err != nil {
+		if err := cg.closeZip(z); err != nil {
 			return nil, fmt.Errorf("failed to zip generated files: %w", err)
 		}
-		generatedFiles = append(generatedFiles, GeneratedResult{
+		zipResult := GeneratedResult{
 			Number:   -2,
 			FilePath: zipOut,
 			FileName: filepath.Base(zipOut),
 			Type:     CertificateTypeZip,
 			ID:       uuid.NewString(),
-		})
+		}
+		generatedFiles = append(generatedFiles, zipResult)
+		if err := cg.emit(zipResult); err != nil {
+			return nil, err
+		}
 
 		cg.updateProgress(fmt.Sprintf("ZIP archive created in %s", time.Since(zipNow).Truncate(time.Second)))
 	}
@@ -570,22 +1995,59 @@ func (cg *CertificateGenerator) aggregateResults(results <-chan generationResult
 		mergeNow := time.Now()
 
 		cg.updateProgress("Merging PDF files")
-		mergeOut := filepath.Join(cg.OutputDir(), fmt.Sprintf(cg.OutFilePattern, "merged")+".pdf")
-		err := MergePdf(inFile, mergeOut)
-		if err != nil {
-			return nil, fmt.Errorf("failed to merge PDF files: %w", err)
+		mergeOut := filepath.Join(cg.OutputDir(), cg.mergedFileName()+".pdf")
+		if err := cg.merge(ordered, inFile, mergeOut); err != nil {
+			return nil, err
+		}
+		if err := cg.convertToPdfA(mergeOut); err != nil {
+			return nil, err
+		}
+		if err := cg.writeMergedMetadata(mergeOut); err != nil {
+			return nil, err
+		}
+		if cg.Settings.Encryption != nil {
+			if err := EncryptCombinedPdfFile(mergeOut, mergeOut, *cg.Settings.Encryption); err != nil {
+				return nil, fmt.Errorf("failed to encrypt the merged PDF: %w", err)
+			}
 		}
-		generatedFiles = append(generatedFiles, GeneratedResult{
+		mergeResult := GeneratedResult{
 			Number:   -1,
 			FilePath: mergeOut,
 			FileName: filepath.Base(mergeOut),
 			Type:     CertificateTypeMerged,
 			ID:       uuid.NewString(),
-		})
+		}
+		generatedFiles = append(generatedFiles, mergeResult)
+		if err := cg.emit(mergeResult); err != nil {
+			return nil, err
+		}
 
 		cg.updateProgress(fmt.Sprintf("PDF files merged in %s", time.Since(mergeNow).Truncate(time.Second)))
 	}
 
+	if cg.Settings.Imposition != nil {
+		imposeNow := time.Now()
+
+		cg.updateProgress("Imposing certificates for print")
+		imposeOut := filepath.Join(cg.OutputDir(), cg.imposedFileName()+".pdf")
+		if err := cg.impose(inFile, imposeOut); err != nil {
+			return nil, err
+		}
+		imposeResult := GeneratedResult{
+			Number:   -3,
+			FilePath: imposeOut,
+			FileName: filepath.Base(imposeOut),
+			Type:     CertificateTypeImposed,
+			ID:       uuid.NewString(),
+		}
+		generatedFiles = append(generatedFiles, imposeResult)
+		if err := cg.emit(imposeResult); err != nil {
+			return nil, err
+		}
+
+		cg.updateProgress(fmt.Sprintf("Certificates imposed in %s", time.Since(imposeNow).Truncate(time.Second)))
+	}
+
 	cg.updateProgress("Generation complete")
 
 	return generatedFiles, nil
@@ -0,0 +1,168 @@
+package autocert
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// Generator of a two page certificate for each of rows rows, stamped per row with a barcode of its id
+func newStreamingTestGenerator(t *testing.T, rows int, settings Settings) *CertificateGenerator {
+	t.Helper()
+	dir := t.TempDir()
+
+	png := filepath.Join(dir, "page.png")
+	writeTestPng(t, png, 200, 100)
+	page := filepath.Join(dir, "page.pdf")
+	if err := ImageTemplateToPdfFile(png, page, TemplateOptions{PaperSize: PaperSizeCustom, Width: 200, Height: 100}); err != nil {
+		t.Fatalf("ImageTemplateToPdfFile failed: %v", err)
+	}
+	template := filepath.Join(dir, "template.pdf")
+	if err := MergePdf([]string{page, page}, template); err != nil {
+		t.Fatalf("MergePdf failed: %v", err)
+	}
+
+	csv := "Name\n"
+	for i := range rows {
+		csv += "Student " + string(rune('A'+i)) + "\n"
+	}
+	csvPath := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(csvPath, []byte(csv), 0644); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	annotations := PageAnnotations{
+		PageBarcodeAnnotations: PageBarcodeAnnotations{
+			1: {{
+				BaseAnnotate: BaseAnnotate{
+					ID:       "barcode",
+					Type:     AnnotateTypeBarcode,
+					Position: Position{X: 10, Y: 10},
+					Size:     Size{Width: 200, Height: 60},
+				},
+				Symbology: BarcodeSymbologyCode128,
+			}},
+		},
+	}
+	cfg := newTestConfig(t)
+	cfg.OutputDir = filepath.Join(dir, "output")
+	cfg.TmpDir = filepath.Join(dir, "tmp")
+
+	return NewCertificateGenerator("streaming", template, csvPath, cfg, annotations, settings, "certificate_%s")
+}
+
+// Run Generate, failing the test instead of hanging when the workers are not drained
+func generateWithTimeout(t *testing.T, cg *CertificateGenerator) ([]GeneratedResult, error) {
+	t.Helper()
+
+	type generated struct {
+		results []GeneratedResult
+		err     error
+	}
+	done := make(chan generated, 1)
+	go func() {
+		results, err := cg.Generate()
+		done <- generated{results, err}
+	}()
+
+	select {
+	case g := <-done:
+		return g.results, g.err
+	case <-time.After(time.Minute):
+		t.Fatal("Generate did not return")
+		return nil, nil
+	}
+}
+
+func TestGenerateResultCallback(t *testing.T) {
+	const rows = 3
+
+	emitted := map[CertificateType]int{}
+	var mergedPages, imposedPages int
+	settings := Settings{
+		MergeAfterGenerate: true,
+		ZipAfterGenerate:   true,
+		Imposition:         &Imposition{Sheet: PaperSizeA4, Marks: MarkStyleNone},
+		// Remove each file once it is handled, like an upload does
+		ResultCallback: func(result GeneratedResult) error {
+			emitted[result.Type]++
+			var err error
+			switch result.Type {
+			case CertificateTypeMerged:
+				mergedPages, err = api.PageCountFile(result.FilePath)
+			case CertificateTypeImposed:
+				imposedPages, err = api.PageCountFile(result.FilePath)
+			}
+			if err != nil {
+				t.Errorf("Failed to count pages of %s: %v", result.FileName, err)
+			}
+			return os.Remove(result.FilePath)
+		},
+	}
+	cg := newStreamingTestGenerator(t, rows, settings)
+
+	if _, err := generateWithTimeout(t, cg); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	want := map[CertificateType]int{
+		CertificateTypeNormal:  rows,
+		CertificateTypeZip:     1,
+		CertificateTypeMerged:  1,
+		CertificateTypeImposed: 1,
+	}
+	for typ, count := range want {
+		if emitted[typ] != count {
+			t.Errorf("Emitted %d result(s) of type %d, want %d", emitted[typ], typ, count)
+		}
+	}
+	if mergedPages != rows*2 {
+		t.Errorf("Merged PDF has %d pages, want %d", mergedPages, rows*2)
+	}
+	if imposedPages == 0 {
+		t.Error("Imposed PDF has no pages")
+	}
+}
+
+func TestGenerateResultCallbackError(t *testing.T) {
+	errUpload := errors.New("upload failed")
+
+	calls := 0
+	settings := Settings{
+		MergeAfterGenerate: true,
+		ZipAfterGenerate:   true,
+		ResultCallback: func(result GeneratedResult) error {
+			calls++
+			if calls == 2 {
+				return errUpload
+			}
+			return nil
+		},
+	}
+	// More rows than workers, so that some of them wait for the aggregation when the callback fails
+	cg := newStreamingTestGenerator(t, 12, settings)
+
+	_, err := generateWithTimeout(t, cg)
+	if !errors.Is(err, errUpload) {
+		t.Fatalf("Generate error = %v, want %v", err, errUpload)
+	}
+	if calls != 2 {
+		t.Errorf("Callback called %d times, want 2", calls)
+	}
+}
+
+func TestGenerateQRCodeWithoutURLPattern(t *testing.T) {
+	cg := newStreamingTestGenerator(t, 1, Settings{EmbedQRCode: true})
+
+	if _, err := cg.Generate(); err == nil || !strings.Contains(err.Error(), "QR URL pattern") {
+		t.Fatalf("Generate error = %v, want a missing QR URL pattern error", err)
+	}
+	if entries, _ := os.ReadDir(cg.OutputDir()); len(entries) > 0 {
+		t.Errorf("Generate wrote %d file(s), want none", len(entries))
+	}
+}
@@ -0,0 +1,315 @@
+package autocert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+/*
+ * An HTML template is a certificate designed in HTML and CSS whose text interpolates the columns of a row
+ * with the same {{ }} actions as ValueTemplate, Eg:
+ *
+ *	<h1 class="name">{{upper FirstName}} {{LastName}}</h1>
+ *	<p>Completed on {{date CompletedAt "2 January 2006"}}</p>
+ *
+ * The values are HTML escaped, the markup of the template is kept as is. The page is loaded from about:blank
+ * without scripts nor network access, so images, fonts and stylesheets must be inlined, Eg: data URLs.
+ * Each row is printed by a headless Chrome to a single page PDF of a fixed size, the content overflowing the page is cut.
+ */
+
+var HTMLTemplateExtensions = []string{".html", ".htm"}
+
+// Time given to Chrome to load and print a page
+const htmlRenderTimeout = 30 * time.Second
+
+type HTMLTemplate struct {
+	source string
+	parts  []valueNode
+}
+
+func IsHTMLTemplate(fileName string) bool {
+	return slices.Contains(HTMLTemplateExtensions, strings.ToLower(filepath.Ext(fileName)))
+}
+
+func ParseHTMLTemplate(source string) (*HTMLTemplate, error) {
+	parts, err := parseValueParts(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTMLTemplate{source: source, parts: parts}, nil
+}
+
+func ParseHTMLTemplateFile(path string) (*HTMLTemplate, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HTML template: %w", err)
+	}
+
+	return ParseHTMLTemplate(string(source))
+}
+
+func (ht *HTMLTemplate) String() string {
+	return ht.source
+}
+
+// Return the unique columns read by the template in order of appearance
+func (ht *HTMLTemplate) Columns() []string {
+	return partColumns(ht.parts)
+}
+
+// Check that every column read by the template is one of the CSV headers
+func (ht *HTMLTemplate) Validate(headers []string) error {
+	for _, column := range ht.Columns() {
+		if !slices.Contains(headers, column) {
+			return fmt.Errorf("%w: column %q is not in the table", ErrInvalidValueTemplate, column)
+		}
+	}
+	return nil
+}
+
+// Render the HTML of a row of the CSV, a column missing in the row is empty
+func (ht *HTMLTemplate) Execute(row map[string]string) (string, error) {
+	var b strings.Builder
+	for _, part := range ht.parts {
+		value, err := part.eval(row)
+		if err != nil {
+			return "", fmt.Errorf("failed to render HTML template: %w", err)
+		}
+
+		if _, ok := part.(textNode); ok {
+			b.WriteString(value)
+		} else {
+			b.WriteString(html.EscapeString(value))
+		}
+	}
+	return b.String(), nil
+}
+
+// HTMLRenderer prints HTML pages to PDF of width x height px through a pool of tabs of a single headless Chrome,
+// it is safe for concurrent use and renders up to poolSize pages at a time
+type HTMLRenderer struct {
+	widthInch  float64
+	heightInch float64
+	tabs       chan context.Context
+	cancels    []context.CancelFunc
+}
+
+func NewHTMLRenderer(poolSize int, width, height float64) (*HTMLRenderer, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid HTML page size %gx%g px", width, height)
+	}
+	poolSize = max(poolSize, 1)
+
+	// chrome print page use inch
+	hr := &HTMLRenderer{
+		widthInch:  width / DPI,
+		heightInch: height / DPI,
+		tabs:       make(chan context.Context, poolSize),
+	}
+
+	browserCtx, cancel := chromedp.NewContext(context.Background())
+	hr.cancels = append(hr.cancels, cancel)
+
+	for range poolSize {
+		tabCtx, cancel := chromedp.NewContext(browserCtx)
+		hr.cancels = append(hr.cancels, cancel)
+
+		// Open the tab now, the first run of a context must not have a timeout as its cancellation closes the tab
+		if err := chromedp.Run(tabCtx); err != nil {
+			hr.Close()
+			return nil, fmt.Errorf("failed to start chrome: %w", err)
+		}
+
+		// The templates are uploaded by users, they must not reach the network of the server, Eg: the storage or the
+		// metadata service of the cloud provider
+		chromedp.ListenTarget(tabCtx, blockRequests(tabCtx))
+		if err := chromedp.Run(tabCtx, emulation.SetScriptExecutionDisabled(true), fetch.Enable()); err != nil {
+			hr.Close()
+			return nil, fmt.Errorf("failed to isolate chrome tab: %w", err)
+		}
+		hr.tabs <- tabCtx
+	}
+
+	return hr, nil
+}
+
+// Print the HTML to a single page PDF, waits for a free tab when all are busy
+func (hr *HTMLRenderer) RenderPdf(html string) ([]byte, error) {
+	tab := <-hr.tabs
+	defer func() { hr.tabs <- tab }()
+
+	ctx, cancel := context.WithTimeout(tab, htmlRenderTimeout)
+	defer cancel()
+
+	pdfBuf, err := printHTMLToPdf(ctx, html, hr.widthInch, hr.heightInch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render HTML to PDF: %w", err)
+	}
+	return pdfBuf, nil
+}
+
+// Close the tabs and the browser
+func (hr *HTMLRenderer) Close() {
+	// The tabs are closed before the browser
+	for i := len(hr.cancels) - 1; i >= 0; i-- {
+		hr.cancels[i]()
+	}
+}
+
+// Return a listener failing every request of the pages of the tab but inlined data
+func blockRequests(tabCtx context.Context) func(ev interface{}) {
+	return func(ev interface{}) {
+		paused, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+
+		// A listener must not block, the request is answered from another goroutine
+		go func() {
+			ctx := cdp.WithExecutor(tabCtx, chromedp.FromContext(tabCtx).Target)
+			if isInlineURL(paused.Request.URL) {
+				fetch.ContinueRequest(paused.RequestID).Do(ctx)
+				return
+			}
+			fetch.FailRequest(paused.RequestID, network.ErrorReasonBlockedByClient).Do(ctx)
+		}()
+	}
+}
+
+func isInlineURL(url string) bool {
+	scheme, _, _ := strings.Cut(strings.ToLower(url), ":")
+	return scheme == "data" || scheme == "about"
+}
+
+// Load the HTML in the tab of ctx and print its first page to a PDF of widthInch x heightInch
+func printHTMLToPdf(ctx context.Context, html string, widthInch, heightInch float64) ([]byte, error) {
+	var pdfBuf []byte
+	err := chromedp.Run(ctx,
+		// Credit: https://stackoverflow.com/questions/75339208/golang-chromedp-pdf-file-download-without-saving-in-server
+		chromedp.Navigate("about:blank"),
+		// set the page content and wait until the page is loaded (including its resources).
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			lctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			loaded := make(chan struct{})
+			var once sync.Once
+			chromedp.ListenTarget(lctx, func(ev interface{}) {
+				if _, ok := ev.(*page.EventLoadEventFired); ok {
+					// It's a good habit to remove the event listener if we don't need it anymore.
+					cancel()
+					once.Do(func() { close(loaded) })
+				}
+			})
+
+			frameTree, err := page.GetFrameTree().Do(ctx)
+			if err != nil {
+				return err
+			}
+
+			if err := page.SetDocumentContent(frameTree.Frame.ID, html).Do(ctx); err != nil {
+				return err
+			}
+
+			// The load event never fires when a resource hangs, the timeout of ctx must still free the tab
+			select {
+			case <-loaded:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			// The paper size wins over a css @page size such that every page has the size of the template
+			pdfBuf, _, err = page.PrintToPDF().
+				WithPreferCSSPageSize(false).
+				WithPaperWidth(widthInch).
+				WithPaperHeight(heightInch).
+				WithPageRanges("1").
+				WithMarginTop(0).
+				WithMarginBottom(0).
+				WithMarginLeft(0).
+				WithMarginRight(0).
+				WithPrintBackground(true).Do(ctx)
+			if err != nil {
+				return err
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return pdfBuf, nil
+}
+
+// Render the preview of an HTML template to a single page PDF of the paper size, the placeholders are kept as is
+// such that the builder shows where they are. The orientation is landscape unless given since the content has no size.
+func HTMLTemplateToPdfFile(source, outFile string, opts TemplateOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if _, err := ParseHTMLTemplate(source); err != nil {
+		return err
+	}
+
+	if opts.Orientation == "" {
+		opts.Orientation = OrientationLandscape
+	}
+	widthMM, heightMM := opts.pageSize(0, 0)
+
+	hr, err := NewHTMLRenderer(1, mmToPx(widthMM), mmToPx(heightMM))
+	if err != nil {
+		return err
+	}
+	defer hr.Close()
+
+	pdfBuf, err := hr.RenderPdf(source)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outFile, pdfBuf, 0644)
+}
+
+// Same as HTMLTemplateToPdfFile but read the template from the multipart header, the PDF is also optimized
+func HTMLTemplateToPdf(srcFile multipart.FileHeader, outFile string, opts TemplateOptions) error {
+	src, err := srcFile.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	source, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(source))) == 0 {
+		return errors.New("HTML template is empty")
+	}
+
+	if err := HTMLTemplateToPdfFile(string(source), outFile, opts); err != nil {
+		return err
+	}
+
+	return OptimizePdfFile(outFile, outFile)
+}
@@ -0,0 +1,107 @@
+package autocert
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTMLTemplateExecute(t *testing.T) {
+	row := map[string]string{
+		"FirstName": "dara",
+		"LastName":  "Sok & <Sons>",
+		"Note":      `"quoted"`,
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		columns  []string
+	}{
+		{name: "Markup without placeholder", source: `<h1 class="name">Certificate</h1>`, expected: `<h1 class="name">Certificate</h1>`},
+		{name: "CSS braces are kept", source: `<style>h1 { color: red; }</style>`, expected: `<style>h1 { color: red; }</style>`},
+		{name: "Interpolation", source: "<h1>{{upper FirstName}}</h1>", expected: "<h1>DARA</h1>", columns: []string{"FirstName"}},
+		{name: "Values are escaped", source: "<p>{{LastName}}</p>", expected: "<p>Sok &amp; &lt;Sons&gt;</p>", columns: []string{"LastName"}},
+		{name: "Attribute value is escaped", source: `<img alt="{{Note}}">`, expected: `<img alt="&#34;quoted&#34;">`, columns: []string{"Note"}},
+		{name: "String literal is escaped", source: `{{"<b>"}}`, expected: "&lt;b&gt;"},
+		{name: "Missing column is empty", source: "<p>{{Missing}}</p>", expected: "<p></p>", columns: []string{"Missing"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ht, err := ParseHTMLTemplate(tt.source)
+			if err != nil {
+				t.Fatalf("ParseHTMLTemplate(%q) failed: %v", tt.source, err)
+			}
+
+			got, err := ht.Execute(row)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Execute() = %q, want %q", got, tt.expected)
+			}
+			if columns := ht.Columns(); !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("Columns() = %v, want %v", columns, tt.columns)
+			}
+		})
+	}
+}
+
+func TestParseHTMLTemplateError(t *testing.T) {
+	for _, source := range []string{"<p>{{FirstName</p>", "<p>{{unknown(FirstName)}}</p>", "<p>{{}}</p>"} {
+		t.Run(source, func(t *testing.T) {
+			_, err := ParseHTMLTemplate(source)
+			if !errors.Is(err, ErrInvalidValueTemplate) {
+				t.Errorf("ParseHTMLTemplate(%q) error = %v, want ErrInvalidValueTemplate", source, err)
+			}
+		})
+	}
+}
+
+func TestIsHTMLTemplate(t *testing.T) {
+	tests := map[string]bool{
+		"certificate.html": true,
+		"certificate.HTM":  true,
+		"certificate.pdf":  false,
+		"certificate.svg":  false,
+	}
+
+	for fileName, expected := range tests {
+		if got := IsHTMLTemplate(fileName); got != expected {
+			t.Errorf("IsHTMLTemplate(%q) = %v, want %v", fileName, got, expected)
+		}
+	}
+}
+
+func TestHTMLRendererBlocksNetwork(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	hr, err := NewHTMLRenderer(1, 300, 200)
+	if err != nil {
+		t.Skipf("Chrome is not available: %v", err)
+	}
+	defer hr.Close()
+
+	source := fmt.Sprintf(`<link rel="stylesheet" href="%[1]s/style.css">
+<img src="%[1]s/logo.png">
+<img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=">
+<script>fetch("%[1]s/script")</script>
+<p>Certificate</p>`, server.URL)
+	if _, err := hr.RenderPdf(source); err != nil {
+		t.Fatalf("RenderPdf failed: %v", err)
+	}
+
+	if got := requests.Load(); got != 0 {
+		t.Errorf("Server received %d requests, want 0", got)
+	}
+}
@@ -1,20 +1,23 @@
 package autocert
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"fmt"
 	"image"
 	"image/draw"
+	"io"
 	"math"
 	"os"
-	"sync"
+	"path/filepath"
 
-	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
 	"github.com/nfnt/resize"
 	"github.com/noelyahan/impexp"
 	"github.com/noelyahan/mergi"
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers"
 )
 
 /*
@@ -29,6 +32,37 @@ import (
  */
 // can remove mergi dependency by writing our own import/export functions
 func ResizeImage(inFile, outFile string, width, height float64, objectContain bool) error {
+	fit := ImageFitStretch
+	if objectContain {
+		fit = ImageFitContain
+	}
+
+	return FitImage(inFile, outFile, width, height, fit)
+}
+
+type ImageFit string
+
+const (
+	// Mimics object-contain, the whole image is visible and centered, the rest of the box is transparent
+	ImageFitContain ImageFit = "contain"
+	// Mimics object-cover, the image fills the box and the overflow is cropped from the center
+	ImageFitCover ImageFit = "cover"
+	// The image is resized to the box ignoring its aspect ratio
+	ImageFitStretch ImageFit = "stretch"
+)
+
+// Empty fit is valid, it means contain
+func (f ImageFit) IsValid() bool {
+	switch f {
+	case "", ImageFitContain, ImageFitCover, ImageFitStretch:
+		return true
+	default:
+		return false
+	}
+}
+
+// Resize the image to an output image of exactly width x height px according to the fit mode
+func FitImage(inFile, outFile string, width, height float64, fit ImageFit) error {
 	img, err := mergi.Import(impexp.NewFileImporter(inFile))
 	if err != nil {
 		return err
@@ -39,7 +73,29 @@ func ResizeImage(inFile, outFile string, width, height float64, objectContain bo
 	}
 
 	var resized image.Image
-	if objectContain {
+	switch fit {
+	case ImageFitStretch:
+		resized = resize.Resize(uint(width), uint(height), img, resize.Lanczos3)
+	case ImageFitCover:
+		origBounds := img.Bounds()
+		origWidth := float64(origBounds.Dx())
+		origHeight := float64(origBounds.Dy())
+
+		// Scale such that the image covers the box, then crop the overflow evenly on both sides
+		ratio := math.Max(width/origWidth, height/origHeight)
+		newWidth := uint(math.Ceil(origWidth * ratio))
+		newHeight := uint(math.Ceil(origHeight * ratio))
+
+		resizedImg := resize.Resize(newWidth, newHeight, img, resize.Lanczos3)
+
+		offsetX := (int(newWidth) - int(width)) / 2
+		offsetY := (int(newHeight) - int(height)) / 2
+
+		canvas := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+		draw.Draw(canvas, canvas.Bounds(), resizedImg, resizedImg.Bounds().Min.Add(image.Pt(offsetX, offsetY)), draw.Src)
+
+		resized = canvas
+	default:
 		// Mimics object-contain w-full h-full where w and h are the specified width and height
 		origBounds := img.Bounds()
 		origWidth := float64(origBounds.Dx())
@@ -65,8 +121,6 @@ func ResizeImage(inFile, outFile string, width, height float64, objectContain bo
 		draw.Draw(canvas, image.Rect(offsetX, offsetY, offsetX+int(newWidth), offsetY+int(newHeight)), resizedImg, image.Point{}, draw.Src)
 
 		resized = canvas
-	} else {
-		resized = resize.Resize(uint(width), uint(height), img, resize.Lanczos3)
 	}
 
 	err = mergi.Export(impexp.NewFileExporter(resized, outFile))
@@ -107,7 +161,86 @@ func svgHtml(width, height float64, base64Svg string) string {
 </html>`, width, height, base64Svg)
 }
 
+// SvgToPdf converts an SVG file to a single page PDF of width x height px without a browser.
+// The SVG is drawn as vector paths with tdewolff/canvas and mimics the object-fit: contain of svgHtml,
+// it is scaled to fit the page while keeping its aspect ratio and centered.
 func SvgToPdf(inFile, outFile string, width, height float64) error {
+	svgData, err := os.Open(inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read SVG: %w", err)
+	}
+	defer svgData.Close()
+
+	return SvgReaderToPdf(svgData, outFile, width, height)
+}
+
+// Same as SvgToPdf but read the SVG from r
+func SvgReaderToPdf(r io.Reader, outFile string, width, height float64) error {
+	if filepath.Ext(outFile) != ".pdf" {
+		return fmt.Errorf("output file is not a PDF: %s", outFile)
+	}
+
+	c, err := svgContainCanvas(r, width, height)
+	if err != nil {
+		return err
+	}
+
+	return renderers.Write(outFile, c)
+}
+
+// Same as SvgReaderToPdf but keep the PDF in memory
+func SvgReaderToPdfBytes(r io.Reader, width, height float64) ([]byte, error) {
+	c, err := svgContainCanvas(r, width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf, renderers.PDF()); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func svgContainCanvas(r io.Reader, width, height float64) (*canvas.Canvas, error) {
+	svg, err := canvas.ParseSVG(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SVG: %w", err)
+	}
+
+	return containCanvas(svg, width, height)
+}
+
+// containCanvas draws src on a new canvas of width x height px, scaled to fit while preserving
+// aspect ratio and centered, the same as css object-fit: contain.
+func containCanvas(src *canvas.Canvas, width, height float64) (*canvas.Canvas, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid size: width=%.2f, height=%.2f", width, height)
+	}
+
+	srcW, srcH := src.Size()
+	if srcW <= 0 || srcH <= 0 {
+		return nil, fmt.Errorf("source has no size: width=%.2f, height=%.2f", srcW, srcH)
+	}
+
+	widthMM, heightMM := pxToMM(width), pxToMM(height)
+	scale := math.Min(widthMM/srcW, heightMM/srcH)
+
+	// Center the content
+	dx := (widthMM - srcW*scale) / 2
+	dy := (heightMM - srcH*scale) / 2
+
+	c := canvas.New(widthMM, heightMM)
+	src.RenderViewTo(c, canvas.Identity.Translate(dx, dy).Scale(scale, scale))
+
+	return c, nil
+}
+
+// SvgToPdfWithChrome renders the SVG through a headless Chrome print, it is slower than SvgToPdf
+// and requires a Chrome binary, but supports every SVG feature the browser does.
+// Use it as a fallback when the SVG cannot be parsed by tdewolff/canvas.
+func SvgToPdfWithChrome(inFile, outFile string, width, height float64) error {
 	ctx, cancel := chromedp.NewContext(context.Background())
 	defer cancel()
 
@@ -130,52 +263,7 @@ func SvgToPdf(inFile, outFile string, width, height float64) error {
 
 	fmt.Printf("Converting SVG to PDF: %s -> %s (%.2f x %.2f px)\n", inFile, outFile, width, height)
 
-	var pdfBuf []byte
-	err = chromedp.Run(ctx,
-		// Credit: https://stackoverflow.com/questions/75339208/golang-chromedp-pdf-file-download-without-saving-in-server
-		chromedp.Navigate("about:blank"),
-		// set the page content and wait until the page is loaded (including its resources).
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			lctx, cancel := context.WithCancel(ctx)
-			defer cancel()
-			var wg sync.WaitGroup
-			wg.Add(1)
-			chromedp.ListenTarget(lctx, func(ev interface{}) {
-				if _, ok := ev.(*page.EventLoadEventFired); ok {
-					// It's a good habit to remove the event listener if we don't need it anymore.
-					cancel()
-					wg.Done()
-				}
-			})
-
-			frameTree, err := page.GetFrameTree().Do(ctx)
-			if err != nil {
-				return err
-			}
-
-			if err := page.SetDocumentContent(frameTree.Frame.ID, html).Do(ctx); err != nil {
-				return err
-			}
-			wg.Wait()
-			return nil
-		}),
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			var err error
-			pdfBuf, _, err = page.PrintToPDF().
-				WithPreferCSSPageSize(true).
-				WithPaperWidth(widthInch).
-				WithPaperHeight(heightInch).
-				WithMarginTop(0).
-				WithMarginBottom(0).
-				WithMarginLeft(0).
-				WithMarginRight(0).
-				WithPrintBackground(true).Do(ctx)
-			if err != nil {
-				return err
-			}
-			return nil
-		}),
-	)
+	pdfBuf, err := printHTMLToPdf(ctx, html, widthInch, heightInch)
 	if err != nil {
 		return fmt.Errorf("failed to render PDF: %w", err)
 	}
@@ -186,34 +274,3 @@ func SvgToPdf(inFile, outFile string, width, height float64) error {
 
 	return ResizePDFKeepOrientation(outFile, outFile, []string{"1"}, width, height)
 }
-
-// Another way to convert SVG to PDF using tdewolff/canvas but has error when resizing
-// func SvgToPdf(inFile, outFile string, width, height float64) error {
-// 	if filepath.Ext(inFile) != ".svg" {
-// 		return fmt.Errorf("input file is not an SVG: %s", inFile)
-// 	}
-
-// 	if filepath.Ext(outFile) != ".pdf" {
-// 		return fmt.Errorf("output file is not a PDF: %s", outFile)
-// 	}
-
-// 	svgData, err := os.Open(inFile)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	defer svgData.Close()
-
-// 	svg, err := canvas.ParseSVG(svgData)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	// Don't use fit, in order to keep svg's original size including margins, whitespace
-// 	// svg.Fit(pxToMM(0))
-
-// 	if err := renderers.Write(outFile, svg); err != nil {
-// 		return err
-// 	}
-
-// 	return ResizePDFKeepOrientation(outFile, outFile, []string{"1"}, width, height)
-// }
@@ -0,0 +1,133 @@
+package autocert
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Extensions of the images read from a bundle, the same as the ones supported by FitImage
+var ImageBundleExtensions = []string{".png", ".jpg", ".jpeg"}
+
+// Largest uncompressed size of an image in a bundle, protects against zip bombs
+const maxBundleImageSize = 20 << 20
+
+var ErrImageNotInBundle = errors.New("image is not in the bundle")
+
+/*
+ * ImageBundle is a ZIP of images uploaded alongside the CSV, a column of the CSV holds the file name of the
+ * image of each row. An image is found by its path in the ZIP or by its base name when it is unique, the
+ * lookup is case-insensitive since file names are typed by hand in the CSV.
+ * The names in the ZIP are never used as paths on disk, an extracted image gets a temporary name.
+ */
+type ImageBundle struct {
+	reader *zip.ReadCloser
+	files  map[string]*zip.File
+	count  int
+}
+
+func OpenImageBundle(zipPath string) (*ImageBundle, error) {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image bundle: %w", err)
+	}
+
+	b := &ImageBundle{reader: reader, files: make(map[string]*zip.File)}
+
+	byBase := make(map[string][]*zip.File)
+	for _, f := range reader.File {
+		name := path.Clean(strings.ReplaceAll(f.Name, "\\", "/"))
+		if f.FileInfo().IsDir() || isBundleMetadata(name) || !isBundleImage(name) {
+			continue
+		}
+
+		b.files[strings.ToLower(name)] = f
+		b.count++
+		base := strings.ToLower(path.Base(name))
+		byBase[base] = append(byBase[base], f)
+	}
+
+	// A base name shared by several images is ambiguous, it must be referenced by its path
+	for base, files := range byBase {
+		if _, exists := b.files[base]; !exists && len(files) == 1 {
+			b.files[base] = files[0]
+		}
+	}
+
+	return b, nil
+}
+
+func (b *ImageBundle) Close() error {
+	return b.reader.Close()
+}
+
+// Number of images in the bundle
+func (b *ImageBundle) Len() int {
+	return b.count
+}
+
+func (b *ImageBundle) lookup(name string) (*zip.File, bool) {
+	name = strings.TrimSpace(strings.ReplaceAll(name, "\\", "/"))
+	if name == "" {
+		return nil, false
+	}
+
+	f, ok := b.files[strings.ToLower(path.Clean(name))]
+	return f, ok
+}
+
+func (b *ImageBundle) Has(name string) bool {
+	_, ok := b.lookup(name)
+	return ok
+}
+
+// Extract an image of the bundle to a temporary file in dir and return its path. Safe for concurrent use.
+func (b *ImageBundle) Extract(name, dir string) (string, error) {
+	f, ok := b.lookup(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrImageNotInBundle, name)
+	}
+	if f.UncompressedSize64 > maxBundleImageSize {
+		return "", fmt.Errorf("image %q of the bundle exceeds %d MB", name, maxBundleImageSize>>20)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open image %q of the bundle: %w", name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.CreateTemp(dir, "autocert_bundle_*"+strings.ToLower(filepath.Ext(f.Name)))
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	// The size in the header can lie, read one byte more than the limit to detect it
+	n, err := io.Copy(out, io.LimitReader(rc, maxBundleImageSize+1))
+	if err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to extract image %q of the bundle: %w", name, err)
+	}
+	if n > maxBundleImageSize {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("image %q of the bundle exceeds %d MB", name, maxBundleImageSize>>20)
+	}
+
+	return out.Name(), nil
+}
+
+func isBundleImage(name string) bool {
+	return slices.Contains(ImageBundleExtensions, strings.ToLower(path.Ext(name)))
+}
+
+// Files added by archivers, Eg: __MACOSX/._photo.jpg when zipped on macOS
+func isBundleMetadata(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), "._")
+}
@@ -0,0 +1,175 @@
+package autocert
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestBundle(t *testing.T, names []string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode png: %v", err)
+	}
+
+	zipPath := filepath.Join(t.TempDir(), "bundle.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("Failed to create zip: %v", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s to zip: %v", name, err)
+		}
+		w.Write(buf.Bytes())
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+
+	return zipPath
+}
+
+func TestImageBundleLookup(t *testing.T) {
+	bundle, err := OpenImageBundle(writeTestBundle(t, []string{
+		"photos/Dara.png",
+		"photos/sok.JPG",
+		"2024/logo.png",
+		"2025/logo.png",
+		"notes.txt",
+		"__MACOSX/photos/._Dara.png",
+	}))
+	if err != nil {
+		t.Fatalf("OpenImageBundle failed: %v", err)
+	}
+	defer bundle.Close()
+
+	if bundle.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", bundle.Len())
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected bool
+	}{
+		{name: "Full path", value: "photos/Dara.png", expected: true},
+		{name: "Base name", value: "Dara.png", expected: true},
+		{name: "Case insensitive", value: "SOK.jpg", expected: true},
+		{name: "Surrounding spaces", value: " dara.png ", expected: true},
+		{name: "Windows separator", value: `photos\dara.png`, expected: true},
+		{name: "Ambiguous base name", value: "logo.png", expected: false},
+		{name: "Ambiguous base name by path", value: "2025/logo.png", expected: true},
+		{name: "Not an image", value: "notes.txt", expected: false},
+		{name: "Archiver metadata", value: "__MACOSX/photos/._Dara.png", expected: false},
+		{name: "Empty", value: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bundle.Has(tt.value); got != tt.expected {
+				t.Errorf("Has(%q) = %v, want %v", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestImageBundleExtract(t *testing.T) {
+	bundle, err := OpenImageBundle(writeTestBundle(t, []string{"../../escape/photo.png"}))
+	if err != nil {
+		t.Fatalf("OpenImageBundle failed: %v", err)
+	}
+	defer bundle.Close()
+
+	dir := t.TempDir()
+	extracted, err := bundle.Extract("photo.png", dir)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if filepath.Dir(extracted) != dir {
+		t.Errorf("Extracted to %s, want a file in %s", extracted, dir)
+	}
+
+	if _, err := bundle.Extract("missing.png", dir); !errors.Is(err, ErrImageNotInBundle) {
+		t.Errorf("Extract of missing image error = %v, want %v", err, ErrImageNotInBundle)
+	}
+}
+
+func TestFitImage(t *testing.T) {
+	// 40x20 image, left half red and right half blue
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := range 40 {
+		for y := range 20 {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	inFile := filepath.Join(t.TempDir(), "in.png")
+	f, err := os.Create(inFile)
+	if err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+	png.Encode(f, src)
+	f.Close()
+
+	tests := []struct {
+		name string
+		fit  ImageFit
+		// pixel sampled in the 20x20 output and whether it is transparent
+		at          image.Point
+		transparent bool
+	}{
+		// Contain scales to 20x10, centered vertically
+		{name: "Contain leaves padding", fit: ImageFitContain, at: image.Pt(10, 1), transparent: true},
+		{name: "Contain keeps image", fit: ImageFitContain, at: image.Pt(10, 10), transparent: false},
+		// Cover scales to 40x20 and crops the sides, the box is filled
+		{name: "Cover fills the box", fit: ImageFitCover, at: image.Pt(10, 1), transparent: false},
+		{name: "Stretch fills the box", fit: ImageFitStretch, at: image.Pt(10, 1), transparent: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outFile := filepath.Join(t.TempDir(), "out.png")
+			if err := FitImage(inFile, outFile, 20, 20, tt.fit); err != nil {
+				t.Fatalf("FitImage failed: %v", err)
+			}
+
+			out, err := os.Open(outFile)
+			if err != nil {
+				t.Fatalf("Failed to open output: %v", err)
+			}
+			defer out.Close()
+
+			img, err := png.Decode(out)
+			if err != nil {
+				t.Fatalf("Failed to decode output: %v", err)
+			}
+
+			if size := img.Bounds().Size(); size != image.Pt(20, 20) {
+				t.Fatalf("Output size = %v, want 20x20", size)
+			}
+
+			_, _, _, a := img.At(tt.at.X, tt.at.Y).RGBA()
+			if (a == 0) != tt.transparent {
+				t.Errorf("Pixel %v alpha = %d, want transparent %v", tt.at, a, tt.transparent)
+			}
+		})
+	}
+}
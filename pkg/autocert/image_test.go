@@ -0,0 +1,93 @@
+package autocert
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers/rasterizer"
+)
+
+func TestSvgToPdf(t *testing.T) {
+	dir := t.TempDir()
+
+	svg := filepath.Join(dir, "signature.svg")
+	if err := os.WriteFile(svg, []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="200" height="100"><rect width="200" height="100"/></svg>`), 0644); err != nil {
+		t.Fatalf("Failed to write SVG: %v", err)
+	}
+	pdf := filepath.Join(dir, "signature.pdf")
+	if err := SvgToPdf(svg, pdf, 300, 120); err != nil {
+		t.Fatalf("SvgToPdf failed: %v", err)
+	}
+
+	f, err := os.Open(pdf)
+	if err != nil {
+		t.Fatalf("Failed to open PDF: %v", err)
+	}
+	defer f.Close()
+
+	width, height, err := GetPdfSizeByPage(f, 1)
+	if err != nil {
+		t.Fatalf("GetPdfSizeByPage failed: %v", err)
+	}
+	if width < 299 || width > 301 || height < 119 || height > 121 {
+		t.Errorf("Page size = %gx%g, want 300x120", width, height)
+	}
+
+	malformed := filepath.Join(dir, "malformed.svg")
+	if err := os.WriteFile(malformed, []byte(`<?xml version="1.0"?><signature width="200" height="100"/>`), 0644); err != nil {
+		t.Fatalf("Failed to write SVG: %v", err)
+	}
+	if err := SvgToPdf(malformed, filepath.Join(dir, "malformed.pdf"), 300, 120); err == nil || !strings.Contains(err.Error(), "failed to parse SVG") {
+		t.Errorf("SvgToPdf of a malformed SVG error = %v, want a parse error", err)
+	}
+
+	if err := SvgToPdf(svg, filepath.Join(dir, "signature.png"), 300, 120); err == nil {
+		t.Error("SvgToPdf to a PNG file succeeded, want an error")
+	}
+}
+
+func TestContainCanvas(t *testing.T) {
+	// A 2:1 black box is fit to the width of a square and centered vertically
+	src := canvas.New(200, 100)
+	ctx := canvas.NewContext(src)
+	ctx.SetFillColor(canvas.Black)
+	ctx.DrawPath(0, 0, canvas.Rectangle(200, 100))
+
+	c, err := containCanvas(src, 100, 100)
+	if err != nil {
+		t.Fatalf("containCanvas failed: %v", err)
+	}
+	if w, h := c.Size(); w != pxToMM(100) || h != pxToMM(100) {
+		t.Fatalf("Size = %gx%g mm, want %gx%g mm", w, h, pxToMM(100), pxToMM(100))
+	}
+
+	img := rasterizer.Draw(c, canvas.DPI(DPI), canvas.DefaultColorSpace)
+	tests := []struct {
+		x, y   int
+		filled bool
+	}{
+		{x: 50, y: 10, filled: false},
+		{x: 50, y: 90, filled: false},
+		{x: 50, y: 30, filled: true},
+		{x: 50, y: 70, filled: true},
+		{x: 2, y: 50, filled: true},
+		{x: 97, y: 50, filled: true},
+	}
+	for _, tt := range tests {
+		if filled := img.RGBAAt(tt.x, tt.y).A > 128; filled != tt.filled {
+			t.Errorf("Pixel (%d, %d) filled = %v, want %v", tt.x, tt.y, filled, tt.filled)
+		}
+	}
+
+	for _, size := range [][2]float64{{0, 100}, {100, -1}} {
+		if _, err := containCanvas(src, size[0], size[1]); err == nil {
+			t.Errorf("containCanvas(%g, %g) succeeded, want an error", size[0], size[1])
+		}
+	}
+	if _, err := containCanvas(canvas.New(0, 0), 100, 100); err == nil {
+		t.Error("containCanvas of an empty source succeeded, want an error")
+	}
+}
@@ -0,0 +1,478 @@
+package autocert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/filter"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Sheet sizes of the print shops, they are not template sizes
+const (
+	PaperSizeA3 PaperSize = "A3"
+	// The A3 sheet with room for the bleed and the marks, 2-up A4
+	PaperSizeSRA3 PaperSize = "SRA3"
+)
+
+// Width and height in mm of the portrait sheet sizes
+var sheetSizes = map[PaperSize][2]float64{
+	PaperSizeA4:     paperSizes[PaperSizeA4],
+	PaperSizeLetter: paperSizes[PaperSizeLetter],
+	PaperSizeA3:     {297, 420},
+	PaperSizeSRA3:   {320, 450},
+}
+
+// Printer's marks drawn around the pages of a sheet
+type MarkStyle string
+
+const (
+	MarkStyleNone MarkStyle = ""
+	// Marks at the trim lines of the pages, outside of the grid
+	MarkStyleCrop MarkStyle = "crop"
+	// Crop marks and a registration target at the middle of each side of the grid, to check the alignment of the plates
+	MarkStyleRegistration MarkStyle = "registration"
+)
+
+const (
+	// In mm, from the bleed to the start of the marks
+	markGap = 2.0
+	// In mm
+	markLength = 5.0
+	// In pt, the usual hairline of the print shops
+	markLineWidth = 0.25
+	// Radius in mm of the circle of a registration target
+	registrationRadius = 2.0
+	// Largest side of a custom sheet in mm, B0
+	maxSheetSideMM = 1414
+)
+
+// 2-up A4 on SRA3 with 3 mm bleed and crop marks, the usual request of the print shops
+var DefaultImposition = Imposition{Sheet: PaperSizeSRA3, Bleed: 3, Marks: MarkStyleCrop}
+
+// Imposition lays the certificates out on the sheets of a print shop, Eg: 2-up A4 on SRA3 with 3 mm bleed and crop
+// marks. The pages fill the grid of each sheet row by row from the top left, every page must have the size of the
+// first one.
+type Imposition struct {
+	// Empty is SRA3
+	Sheet PaperSize
+	// Orientation of the sheet, auto picks the one which holds the most pages
+	Orientation Orientation
+	// Width and height in mm of the sheet, only used by PaperSizeCustom
+	Width  float64
+	Height float64
+	// Columns and rows of pages on each sheet, 0 fits as many as the sheet holds
+	Columns int
+	Rows    int
+	// Space in mm between the bleeds of two pages
+	Gutter float64
+	// Width in mm of the page content extended past the trim of each page, Eg: 3. The certificates have no content past
+	// their trim so the bleed mirrors the edge of the page, it is cut off.
+	Bleed float64
+	Marks MarkStyle
+}
+
+func (imp Imposition) Validate() error {
+	switch imp.Sheet {
+	case "", PaperSizeA4, PaperSizeLetter, PaperSizeA3, PaperSizeSRA3:
+	case PaperSizeCustom:
+		if imp.Width <= 0 || imp.Height <= 0 || imp.Width > maxSheetSideMM || imp.Height > maxSheetSideMM {
+			return fmt.Errorf("custom sheet size must be between 0 and %d mm, got %gx%g mm", maxSheetSideMM, imp.Width, imp.Height)
+		}
+	default:
+		return fmt.Errorf("unsupported sheet size: %s", imp.Sheet)
+	}
+
+	switch imp.Orientation {
+	case OrientationAuto, OrientationPortrait, OrientationLandscape:
+	default:
+		return fmt.Errorf("unsupported orientation: %s", imp.Orientation)
+	}
+
+	switch imp.Marks {
+	case MarkStyleNone, MarkStyleCrop, MarkStyleRegistration:
+	default:
+		return fmt.Errorf("unsupported mark style: %s", imp.Marks)
+	}
+
+	if imp.Columns < 0 || imp.Rows < 0 {
+		return fmt.Errorf("grid must not be negative, got %dx%d", imp.Columns, imp.Rows)
+	}
+	if imp.Gutter < 0 || imp.Gutter > 50 {
+		return fmt.Errorf("gutter must be between 0 and 50 mm, got %g mm", imp.Gutter)
+	}
+	if imp.Bleed < 0 || imp.Bleed > 10 {
+		return fmt.Errorf("bleed must be between 0 and 10 mm, got %g mm", imp.Bleed)
+	}
+
+	return nil
+}
+
+// Width and height in mm of the portrait sheet
+func (imp Imposition) sheetSize() (float64, float64) {
+	if imp.Sheet == PaperSizeCustom {
+		return min(imp.Width, imp.Height), max(imp.Width, imp.Height)
+	}
+
+	size, ok := sheetSizes[imp.Sheet]
+	if !ok {
+		size = sheetSizes[PaperSizeSRA3]
+	}
+	return size[0], size[1]
+}
+
+// Width in mm around the bleed of the grid taken by the marks
+func (imp Imposition) markSpace() float64 {
+	if imp.Marks == MarkStyleNone {
+		return 0
+	}
+	return markGap + markLength
+}
+
+// The number of pages of pageSize mm which fit along sheetSize mm, or count of them when it is not 0
+func (imp Imposition) fit(pageSize, sheetSize float64, count int) int {
+	cell := pageSize + 2*imp.Bleed
+	if count == 0 {
+		count = int((sheetSize - 2*imp.markSpace() + imp.Gutter) / (cell + imp.Gutter))
+	}
+
+	used := float64(count)*cell + float64(count-1)*imp.Gutter + 2*imp.markSpace()
+	// Rounding of the sizes in mm
+	if count < 1 || used > sheetSize+1e-6 {
+		return 0
+	}
+	return count
+}
+
+// An imposition of pages on a sheet, the sizes are in mm
+type impositionLayout struct {
+	sheetWidth, sheetHeight float64
+	columns, rows           int
+	// Top left corner of the trim of the first page, from the bottom left of the sheet
+	left, top float64
+}
+
+func (l impositionLayout) perSheet() int {
+	return l.columns * l.rows
+}
+
+// The layout of pages of pageWidth x pageHeight mm on the sheets of the imposition
+func (imp Imposition) layout(pageWidth, pageHeight float64) (impositionLayout, error) {
+	w, h := imp.sheetSize()
+	orientations := []Orientation{OrientationPortrait, OrientationLandscape}
+	if imp.Orientation != OrientationAuto {
+		orientations = []Orientation{imp.Orientation}
+	}
+
+	var best impositionLayout
+	for _, orientation := range orientations {
+		l := impositionLayout{sheetWidth: w, sheetHeight: h}
+		if orientation == OrientationLandscape {
+			l.sheetWidth, l.sheetHeight = h, w
+		}
+		l.columns = imp.fit(pageWidth, l.sheetWidth, imp.Columns)
+		l.rows = imp.fit(pageHeight, l.sheetHeight, imp.Rows)
+		if l.perSheet() > best.perSheet() {
+			best = l
+		}
+	}
+
+	if best.perSheet() == 0 {
+		return best, fmt.Errorf("a %dx%d grid of pages of %.0fx%.0f mm does not fit on a %.0fx%.0f mm sheet", imp.Columns, imp.Rows, pageWidth, pageHeight, w, h)
+	}
+
+	// The grid is centered on the sheet
+	gridWidth := float64(best.columns)*(pageWidth+2*imp.Bleed) + float64(best.columns-1)*imp.Gutter
+	gridHeight := float64(best.rows)*(pageHeight+2*imp.Bleed) + float64(best.rows-1)*imp.Gutter
+	best.left = (best.sheetWidth-gridWidth)/2 + imp.Bleed
+	best.top = (best.sheetHeight+gridHeight)/2 - imp.Bleed
+
+	return best, nil
+}
+
+// Impose the pages of the PDF of inFile and write the sheets to outFile, inFile and outFile can be the same file
+func ImposePdfFile(inFile, outFile string, imp Imposition) error {
+	data, err := os.ReadFile(inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ImposePdf(bytes.NewReader(data), &buf, imp); err != nil {
+		return err
+	}
+
+	return os.WriteFile(outFile, buf.Bytes(), 0644)
+}
+
+// Replace the pages of the PDF with the sheets of the imposition. The outline, page labels and XMP metadata of the
+// pages are dropped since they do not describe the sheets.
+func ImposePdf(rs io.ReadSeeker, w io.Writer, imp Imposition) error {
+	if err := imp.Validate(); err != nil {
+		return err
+	}
+
+	return rewritePdf(rs, w, func(ctx *model.Context, _ time.Time) error {
+		if ctx.Encrypt != nil {
+			return errors.New("cannot impose an encrypted PDF")
+		}
+		if ctx.PageCount == 0 {
+			return errors.New("cannot impose a PDF without pages")
+		}
+
+		forms := make([]*types.IndirectRef, ctx.PageCount)
+		var trim *types.Rectangle
+		for i := range forms {
+			form, box, err := pageForm(ctx, i+1)
+			if err != nil {
+				return fmt.Errorf("failed to read page %d: %w", i+1, err)
+			}
+			if trim == nil {
+				trim = box
+			} else if math.Abs(box.Width()-trim.Width()) > 1 || math.Abs(box.Height()-trim.Height()) > 1 {
+				return fmt.Errorf("page %d is %.0fx%.0f pt but the first page is %.0fx%.0f pt, the pages of an imposition must have the same size", i+1, box.Width(), box.Height(), trim.Width(), trim.Height())
+			}
+			forms[i] = form
+		}
+
+		layout, err := imp.layout(pxToMM(trim.Width()), pxToMM(trim.Height()))
+		if err != nil {
+			return err
+		}
+
+		pages := types.Dict{
+			"Type":     types.Name("Pages"),
+			"Count":    types.Integer(0),
+			"MediaBox": types.RectForDim(mmToPx(layout.sheetWidth), mmToPx(layout.sheetHeight)).Array(),
+		}
+		pagesRef, err := ctx.IndRefForNewObject(pages)
+		if err != nil {
+			return err
+		}
+
+		sheetCount := 0
+		for first := 0; first < len(forms); first += layout.perSheet() {
+			sheetForms := forms[first:min(first+layout.perSheet(), len(forms))]
+			if err := addSheet(ctx, pages, pagesRef, sheetForms, imp.sheetContent(layout, trim, len(sheetForms))); err != nil {
+				return fmt.Errorf("failed to create sheet %d: %w", sheetCount+1, err)
+			}
+			sheetCount++
+		}
+
+		root, err := ctx.Catalog()
+		if err != nil {
+			return err
+		}
+		root["Pages"] = *pagesRef
+		for _, key := range []string{"Outlines", "PageLabels", "PageMode", "Metadata", "StructTreeRoot", "MarkInfo", "AcroForm", "Names", "Dests", "OpenAction"} {
+			delete(root, key)
+		}
+		ctx.PageCount = sheetCount
+
+		return nil
+	})
+}
+
+// A form XObject of the page and its crop box, the trim of the page
+func pageForm(ctx *model.Context, pageNr int) (*types.IndirectRef, *types.Rectangle, error) {
+	d, _, inherited, err := ctx.PageDict(pageNr, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d == nil {
+		return nil, nil, fmt.Errorf("unknown page number: %d", pageNr)
+	}
+
+	box := inherited.MediaBox
+	if inherited.CropBox != nil {
+		box = inherited.CropBox
+	}
+	box = types.NewRectangle(box.LL.X, box.LL.Y, box.UR.X, box.UR.Y)
+
+	content, err := ctx.PageContent(d, pageNr)
+	if err != nil && !errors.Is(err, model.ErrNoContent) {
+		return nil, nil, err
+	}
+
+	// The form is upright with the page as it is displayed
+	if inherited.Rotate != 0 {
+		if inherited.Rotate%180 != 0 {
+			box.UR.X, box.UR.Y = box.LL.X+box.Height(), box.LL.Y+box.Width()
+		}
+		content = append(model.ContentBytesForPageRotation(inherited.Rotate, box.Width(), box.Height()), content...)
+	}
+
+	resources, err := ctx.IndRefForNewObject(inherited.Resources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sd := types.StreamDict{
+		Dict: types.Dict{
+			"Type":      types.Name("XObject"),
+			"Subtype":   types.Name("Form"),
+			"BBox":      box.Array(),
+			"Matrix":    types.NewNumberArray(1, 0, 0, 1, -box.LL.X, -box.LL.Y),
+			"Resources": *resources,
+		},
+		Content:        content,
+		FilterPipeline: []types.PDFFilter{{Name: filter.Flate}},
+	}
+	sd.InsertName("Filter", filter.Flate)
+	if err := sd.Encode(); err != nil {
+		return nil, nil, err
+	}
+
+	form, err := ctx.IndRefForNewObject(sd)
+	if err != nil {
+		return nil, nil, err
+	}
+	return form, box, nil
+}
+
+// The content of a sheet of the layout with count pages of the size of trim, the form of the i-th page is /Pi
+func (imp Imposition) sheetContent(l impositionLayout, trim *types.Rectangle, count int) []byte {
+	var buf bytes.Buffer
+	w, h := trim.Width(), trim.Height()
+	bleed := mmToPx(imp.Bleed)
+	step := func(size float64) float64 { return size + mmToPx(2*imp.Bleed+imp.Gutter) }
+
+	for i := range count {
+		x := mmToPx(l.left) + float64(i%l.columns)*step(w)
+		y := mmToPx(l.top) - h - float64(i/l.columns)*step(h)
+
+		// The page at the center, and its edges mirrored into the bleed on the sides and the corners
+		for _, dy := range []int{0, -1, 1} {
+			for _, dx := range []int{0, -1, 1} {
+				if bleed == 0 && (dx != 0 || dy != 0) {
+					continue
+				}
+				clipX, clipW := mirrorClip(x, w, bleed, dx)
+				clipY, clipH := mirrorClip(y, h, bleed, dy)
+				scaleX, translateX := mirrorTransform(x, w, dx)
+				scaleY, translateY := mirrorTransform(y, h, dy)
+				fmt.Fprintf(&buf, "q %.4f %.4f %.4f %.4f re W n %g 0 0 %g %.4f %.4f cm /P%d Do Q\n",
+					clipX, clipY, clipW, clipH, scaleX, scaleY, translateX, translateY, i)
+			}
+		}
+	}
+
+	if imp.Marks != MarkStyleNone {
+		imp.writeMarks(&buf, l, w, h)
+	}
+
+	return buf.Bytes()
+}
+
+// The strip of the cell at from, of size and bleed, on the side d: -1 before the page, 0 the page, 1 after it
+func mirrorClip(from, size, bleed float64, d int) (float64, float64) {
+	switch d {
+	case -1:
+		return from - bleed, bleed
+	case 1:
+		return from + size, bleed
+	}
+	return from, size
+}
+
+// The scale and translation which draw the page at from, or mirror it about its edge on the side d
+func mirrorTransform(from, size float64, d int) (float64, float64) {
+	switch d {
+	case -1:
+		return -1, from
+	case 1:
+		return -1, from + 2*size
+	}
+	return 1, from
+}
+
+// Draw the crop marks, and the registration targets, around the grid of the layout of pages of w x h pt
+func (imp Imposition) writeMarks(buf *bytes.Buffer, l impositionLayout, w, h float64) {
+	bleed, gutter := mmToPx(imp.Bleed), mmToPx(imp.Gutter)
+	gap, length := bleed+mmToPx(markGap), mmToPx(markLength)
+
+	left, top := mmToPx(l.left), mmToPx(l.top)
+	right := left + float64(l.columns)*w + float64(l.columns-1)*(2*bleed+gutter)
+	bottom := top - float64(l.rows)*h - float64(l.rows-1)*(2*bleed+gutter)
+
+	// Registration color, printed on every plate
+	fmt.Fprintf(buf, "q %g w 1 1 1 1 K 0 J\n", markLineWidth)
+	for col := range l.columns {
+		for _, x := range []float64{left + float64(col)*(w+2*bleed+gutter), left + float64(col)*(w+2*bleed+gutter) + w} {
+			fmt.Fprintf(buf, "%.4f %.4f m %.4f %.4f l S\n", x, top+gap, x, top+gap+length)
+			fmt.Fprintf(buf, "%.4f %.4f m %.4f %.4f l S\n", x, bottom-gap, x, bottom-gap-length)
+		}
+	}
+	for row := range l.rows {
+		for _, y := range []float64{top - float64(row)*(h+2*bleed+gutter), top - float64(row)*(h+2*bleed+gutter) - h} {
+			fmt.Fprintf(buf, "%.4f %.4f m %.4f %.4f l S\n", left-gap, y, left-gap-length, y)
+			fmt.Fprintf(buf, "%.4f %.4f m %.4f %.4f l S\n", right+gap, y, right+gap+length, y)
+		}
+	}
+
+	if imp.Marks == MarkStyleRegistration {
+		centerX, centerY := (left+right)/2, (top+bottom)/2
+		offset := gap + length/2
+		for _, c := range [][2]float64{{centerX, top + offset}, {centerX, bottom - offset}, {left - offset, centerY}, {right + offset, centerY}} {
+			writeRegistrationTarget(buf, c[0], c[1], mmToPx(registrationRadius))
+		}
+	}
+	buf.WriteString("Q\n")
+}
+
+// A circle of radius r with a cross through it, centered at x, y
+func writeRegistrationTarget(buf *bytes.Buffer, x, y, r float64) {
+	// Distance of the control points of a quarter circle drawn with a cubic Bézier curve
+	k := r * 0.5523
+	fmt.Fprintf(buf, "%.4f %.4f m\n", x+r, y)
+	fmt.Fprintf(buf, "%.4f %.4f %.4f %.4f %.4f %.4f c\n", x+r, y+k, x+k, y+r, x, y+r)
+	fmt.Fprintf(buf, "%.4f %.4f %.4f %.4f %.4f %.4f c\n", x-k, y+r, x-r, y+k, x-r, y)
+	fmt.Fprintf(buf, "%.4f %.4f %.4f %.4f %.4f %.4f c\n", x-r, y-k, x-k, y-r, x, y-r)
+	fmt.Fprintf(buf, "%.4f %.4f %.4f %.4f %.4f %.4f c S\n", x+k, y-r, x+r, y-k, x+r, y)
+
+	cross := r * 1.5
+	fmt.Fprintf(buf, "%.4f %.4f m %.4f %.4f l S\n", x-cross, y, x+cross, y)
+	fmt.Fprintf(buf, "%.4f %.4f m %.4f %.4f l S\n", x, y-cross, x, y+cross)
+}
+
+// Append a sheet drawing the forms with content to the page tree
+func addSheet(ctx *model.Context, pages types.Dict, pagesRef *types.IndirectRef, forms []*types.IndirectRef, content []byte) error {
+	xObjects := types.Dict{}
+	for i, form := range forms {
+		xObjects[fmt.Sprintf("P%d", i)] = *form
+	}
+	resources, err := ctx.IndRefForNewObject(types.Dict{"XObject": xObjects})
+	if err != nil {
+		return err
+	}
+
+	sd, err := ctx.NewStreamDictForBuf(content)
+	if err != nil {
+		return err
+	}
+	if err := sd.Encode(); err != nil {
+		return err
+	}
+	contents, err := ctx.IndRefForNewObject(*sd)
+	if err != nil {
+		return err
+	}
+
+	page, err := ctx.IndRefForNewObject(types.Dict{
+		"Type":      types.Name("Page"),
+		"Parent":    *pagesRef,
+		"Resources": *resources,
+		"Contents":  *contents,
+	})
+	if err != nil {
+		return err
+	}
+
+	return model.AppendPageTree(page, 1, pages)
+}
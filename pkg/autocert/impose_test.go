@@ -0,0 +1,105 @@
+package autocert
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+func TestImpositionLayout(t *testing.T) {
+	tests := []struct {
+		name                  string
+		imp                   Imposition
+		pageWidth, pageHeight float64
+		wantColumns, wantRows int
+		wantLandscape         bool
+		wantErr               bool
+	}{
+		{name: "2-up A4 on SRA3", imp: Imposition{Bleed: 3, Marks: MarkStyleCrop}, pageWidth: 210, pageHeight: 297, wantColumns: 2, wantRows: 1, wantLandscape: true},
+		{name: "2-up landscape A4 on SRA3", imp: Imposition{Bleed: 3, Marks: MarkStyleRegistration}, pageWidth: 297, pageHeight: 210, wantColumns: 1, wantRows: 2},
+		{name: "A4 on A3 without marks", imp: Imposition{Sheet: PaperSizeA3}, pageWidth: 210, pageHeight: 297, wantColumns: 2, wantRows: 1, wantLandscape: true},
+		{name: "Gutter", imp: Imposition{Bleed: 3, Gutter: 5, Marks: MarkStyleCrop}, pageWidth: 85, pageHeight: 55, wantColumns: 3, wantRows: 6},
+		{name: "Fixed orientation", imp: Imposition{Orientation: OrientationPortrait, Bleed: 3, Marks: MarkStyleCrop}, pageWidth: 210, pageHeight: 297, wantColumns: 1, wantRows: 1},
+		{name: "Fixed grid", imp: Imposition{Columns: 1, Rows: 1, Bleed: 3}, pageWidth: 210, pageHeight: 297, wantColumns: 1, wantRows: 1},
+		{name: "Grid too large", imp: Imposition{Columns: 3, Rows: 3, Bleed: 3}, pageWidth: 210, pageHeight: 297, wantErr: true},
+		{name: "Page too large", imp: Imposition{Sheet: PaperSizeA4, Bleed: 3, Marks: MarkStyleCrop}, pageWidth: 210, pageHeight: 297, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := tt.imp.layout(tt.pageWidth, tt.pageHeight)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("layout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if l.columns != tt.wantColumns || l.rows != tt.wantRows {
+				t.Errorf("layout() grid = %dx%d, want %dx%d", l.columns, l.rows, tt.wantColumns, tt.wantRows)
+			}
+			if landscape := l.sheetWidth > l.sheetHeight; landscape != tt.wantLandscape {
+				t.Errorf("layout() sheet = %gx%g mm, want landscape %v", l.sheetWidth, l.sheetHeight, tt.wantLandscape)
+			}
+
+			// The grid is centered on the sheet
+			gridWidth := float64(l.columns)*(tt.pageWidth+2*tt.imp.Bleed) + float64(l.columns-1)*tt.imp.Gutter
+			if right := l.sheetWidth - (l.left - tt.imp.Bleed + gridWidth); math.Abs(right-(l.left-tt.imp.Bleed)) > 1e-6 {
+				t.Errorf("layout() left margin = %g mm, right margin = %g mm", l.left-tt.imp.Bleed, right)
+			}
+		})
+	}
+}
+
+func TestImpositionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		imp     Imposition
+		wantErr bool
+	}{
+		{name: "Default", imp: Imposition{}},
+		{name: "Custom sheet", imp: Imposition{Sheet: PaperSizeCustom, Width: 330, Height: 488}},
+		{name: "Custom sheet without size", imp: Imposition{Sheet: PaperSizeCustom}, wantErr: true},
+		{name: "Unknown sheet", imp: Imposition{Sheet: "B2"}, wantErr: true},
+		{name: "Unknown marks", imp: Imposition{Marks: "bleed"}, wantErr: true},
+		{name: "Negative grid", imp: Imposition{Columns: -1}, wantErr: true},
+		{name: "Negative bleed", imp: Imposition{Bleed: -3}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.imp.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImposePdf(t *testing.T) {
+	// 297x210 mm pages
+	pdf, pages := newTestIndexPdf(t, 100)
+
+	var out bytes.Buffer
+	if err := ImposePdf(bytes.NewReader(pdf), &out, Imposition{Bleed: 3, Marks: MarkStyleRegistration}); err != nil {
+		t.Fatalf("ImposePdf failed: %v", err)
+	}
+
+	sheets, err := api.PageCount(bytes.NewReader(out.Bytes()), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatalf("Failed to count sheets: %v", err)
+	}
+	if want := (pages + 1) / 2; sheets != want {
+		t.Errorf("Sheets = %d, want %d for %d pages 2-up", sheets, want, pages)
+	}
+
+	dims, err := api.PageDims(bytes.NewReader(out.Bytes()), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatalf("Failed to read sheet size: %v", err)
+	}
+	if w, h := pxToMM(dims[0].Width), pxToMM(dims[0].Height); math.Abs(w-320) > 0.1 || math.Abs(h-450) > 0.1 {
+		t.Errorf("Sheet = %.1fx%.1f mm, want SRA3 320x450 mm", w, h)
+	}
+}
@@ -0,0 +1,99 @@
+package autocert
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Names of the manifests at the root of the ZIP
+const (
+	ManifestCSVName  = "manifest.csv"
+	ManifestJSONName = "manifest.json"
+)
+
+// Columns of manifest.csv before the ones of the row
+var manifestCSVHeaders = []string{"number", "certificate_id", "file_path", "verify_url"}
+
+// A certificate of the ZIP and the row of the CSV it is generated from
+type ManifestEntry struct {
+	Number        int    `json:"number"`
+	CertificateID string `json:"certificateId"`
+	// Path of the PDF in the ZIP, its images are next to it with the same name
+	FilePath string `json:"filePath"`
+	// Empty without Settings.QrURLPattern
+	VerifyURL string `json:"verifyUrl"`
+	// Empty without CSV data
+	Row map[string]string `json:"row"`
+}
+
+// Manifest maps each certificate of a ZIP to its row, Eg: to find the certificate of a student in a folder of
+// thousands, or to import the certificate ids into another system
+type Manifest struct {
+	ProjectID string `json:"projectId"`
+	// Headers of the CSV in their order, the columns of the row in manifest.csv
+	Columns      []string        `json:"columns"`
+	Certificates []ManifestEntry `json:"certificates"`
+}
+
+func (m Manifest) WriteCSV(w io.Writer) error {
+	csvWriter := csv.NewWriter(w)
+
+	if err := csvWriter.Write(append(append([]string{}, manifestCSVHeaders...), m.Columns...)); err != nil {
+		return err
+	}
+	for _, entry := range m.Certificates {
+		record := make([]string, 0, len(manifestCSVHeaders)+len(m.Columns))
+		record = append(record, fmt.Sprint(entry.Number), entry.CertificateID, entry.FilePath, entry.VerifyURL)
+		for _, column := range m.Columns {
+			record = append(record, entry.Row[column])
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func (m Manifest) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+// Write manifest.csv and manifest.json to dir and return their entries at the root of the ZIP
+func (m Manifest) WriteFiles(dir string) ([]ZipEntry, error) {
+	writers := []struct {
+		name  string
+		write func(w io.Writer) error
+	}{
+		{name: ManifestCSVName, write: m.WriteCSV},
+		{name: ManifestJSONName, write: m.WriteJSON},
+	}
+
+	entries := make([]ZipEntry, 0, len(writers))
+	for _, writer := range writers {
+		filePath := filepath.Join(dir, writer.name)
+		f, err := os.Create(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", writer.name, err)
+		}
+
+		err = writer.write(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", writer.name, err)
+		}
+
+		entries = append(entries, ZipEntry{FilePath: filePath, ArchivePath: writer.name})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,41 @@
+package autocert
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	manifest := Manifest{
+		ProjectID: "project-1",
+		Columns:   []string{"Name", "Department"},
+		Certificates: []ManifestEntry{
+			{Number: 1, CertificateID: "id-1", FilePath: "Physics/Doe.pdf", VerifyURL: "https://example.com/id-1", Row: map[string]string{"Name": "Jane Doe", "Department": "Physics"}},
+			{Number: 2, CertificateID: "id-2", FilePath: "Roe, Jo.pdf", Row: map[string]string{"Name": "Roe, Jo"}},
+		},
+	}
+
+	var csv bytes.Buffer
+	if err := manifest.WriteCSV(&csv); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	want := "number,certificate_id,file_path,verify_url,Name,Department\n" +
+		"1,id-1,Physics/Doe.pdf,https://example.com/id-1,Jane Doe,Physics\n" +
+		"2,id-2,\"Roe, Jo.pdf\",,\"Roe, Jo\",\n"
+	if csv.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", csv.String(), want)
+	}
+
+	var buf bytes.Buffer
+	if err := manifest.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var got Manifest
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to read JSON: %v", err)
+	}
+	if got.Certificates[0].Row["Department"] != "Physics" || got.Certificates[1].CertificateID != "id-2" {
+		t.Errorf("WriteJSON() = %s, want the certificates and their rows", buf.String())
+	}
+}
@@ -0,0 +1,356 @@
+package autocert
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+const (
+	// Namespace of the custom XMP properties of a certificate, declared by a PDF/A extension schema
+	xmpAutoCertNamespace = "http://ns.autocert.app/certificate/1.0/"
+	xmpAutoCertPrefix    = "autocert"
+	// Attempts to write the PDF with the same second as its XMP dates, see rewritePdf
+	metadataWriteAttempts = 3
+)
+
+// pdfcpu sets the Producer of the Info dictionary on write, the XMP must hold the same value
+var pdfaProducer = "pdfcpu " + model.VersionStr
+
+// Custom XMP properties of a certificate: property name, value type and description of the extension schema
+var xmpAutoCertProperties = []struct {
+	name, valueType, description string
+}{
+	{"CertificateID", "Text", "Identifier of the certificate"},
+	{"ProjectID", "Text", "Identifier of the project that generated the certificate"},
+	{"VerifyURL", "URI", "URL to verify the certificate"},
+}
+
+// Document metadata written to the Info dictionary and the XMP of a PDF, both must hold the same values
+type PdfMetadata struct {
+	Title    string
+	Author   string
+	Subject  string
+	Keywords string
+	Creator  string
+
+	// Custom XMP properties of a certificate
+	CertificateID string
+	ProjectID     string
+	VerifyURL     string
+
+	// Creation and modification date
+	Date time.Time
+	// Identify the document as PDF/A-2b
+	PdfA bool
+}
+
+func (m PdfMetadata) custom() map[string]string {
+	return map[string]string{
+		"CertificateID": m.CertificateID,
+		"ProjectID":     m.ProjectID,
+		"VerifyURL":     m.VerifyURL,
+	}
+}
+
+func (m PdfMetadata) hasCustom() bool {
+	return m.CertificateID != "" || m.ProjectID != "" || m.VerifyURL != ""
+}
+
+// The metadata of the Info dictionary and the custom properties of the XMP, the dates and the producer are set by
+// pdfcpu on write
+func pdfMetadataOf(ctx *model.Context) (PdfMetadata, error) {
+	var meta PdfMetadata
+
+	if root, err := ctx.Catalog(); err == nil {
+		if xmp := xmpMetadataOf(ctx, root); xmp != "" {
+			meta.CertificateID = xmpValue(xmp, xmpAutoCertPrefix+":CertificateID")
+			meta.ProjectID = xmpValue(xmp, xmpAutoCertPrefix+":ProjectID")
+			meta.VerifyURL = xmpValue(xmp, xmpAutoCertPrefix+":VerifyURL")
+			meta.PdfA = xmpValue(xmp, "pdfaid:part") != ""
+		}
+	}
+
+	if ctx.Info == nil {
+		return meta, nil
+	}
+
+	info, err := ctx.DereferenceDict(*ctx.Info)
+	if err != nil || info == nil {
+		return meta, err
+	}
+
+	fields := map[string]*string{
+		"Title":    &meta.Title,
+		"Author":   &meta.Author,
+		"Subject":  &meta.Subject,
+		"Keywords": &meta.Keywords,
+		"Creator":  &meta.Creator,
+	}
+	for key, field := range fields {
+		obj, ok := info.Find(key)
+		if !ok {
+			continue
+		}
+		if *field, err = ctx.DereferenceText(obj); err != nil {
+			return meta, fmt.Errorf("failed to read %s of the document information: %w", key, err)
+		}
+	}
+
+	return meta, nil
+}
+
+// The decoded XMP packet of the catalog, empty if the document has none
+func xmpMetadataOf(ctx *model.Context, root types.Dict) string {
+	sd, _, err := ctx.DereferenceStreamDict(root["Metadata"])
+	if err != nil || sd == nil {
+		return ""
+	}
+	if err := sd.Decode(); err != nil {
+		return ""
+	}
+	return string(sd.Content)
+}
+
+// Serialize the metadata to an XMP packet, identifying PDF/A-2b if PdfA is set. The custom properties are
+// declared by a PDF/A extension schema.
+func (m PdfMetadata) XMP() []byte {
+	date := m.Date.Format(time.RFC3339)
+
+	var b strings.Builder
+	b.WriteString("<?xpacket begin=\"\uFEFF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+
+	if m.PdfA {
+		b.WriteString("  <rdf:Description rdf:about=\"\" xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\">\n")
+		b.WriteString("   <pdfaid:part>2</pdfaid:part>\n")
+		b.WriteString("   <pdfaid:conformance>B</pdfaid:conformance>\n")
+		b.WriteString("  </rdf:Description>\n")
+	}
+
+	b.WriteString("  <rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	b.WriteString("   <dc:format>application/pdf</dc:format>\n")
+	if m.Title != "" {
+		fmt.Fprintf(&b, "   <dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", xmlEscape(m.Title))
+	}
+	if m.Author != "" {
+		fmt.Fprintf(&b, "   <dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", xmlEscape(m.Author))
+	}
+	if m.Subject != "" {
+		fmt.Fprintf(&b, "   <dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", xmlEscape(m.Subject))
+	}
+	b.WriteString("  </rdf:Description>\n")
+
+	b.WriteString("  <rdf:Description rdf:about=\"\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\">\n")
+	fmt.Fprintf(&b, "   <xmp:CreateDate>%s</xmp:CreateDate>\n", date)
+	fmt.Fprintf(&b, "   <xmp:ModifyDate>%s</xmp:ModifyDate>\n", date)
+	fmt.Fprintf(&b, "   <xmp:MetadataDate>%s</xmp:MetadataDate>\n", date)
+	if m.Creator != "" {
+		fmt.Fprintf(&b, "   <xmp:CreatorTool>%s</xmp:CreatorTool>\n", xmlEscape(m.Creator))
+	}
+	b.WriteString("  </rdf:Description>\n")
+
+	b.WriteString("  <rdf:Description rdf:about=\"\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n")
+	fmt.Fprintf(&b, "   <pdf:Producer>%s</pdf:Producer>\n", xmlEscape(pdfaProducer))
+	if m.Keywords != "" {
+		fmt.Fprintf(&b, "   <pdf:Keywords>%s</pdf:Keywords>\n", xmlEscape(m.Keywords))
+	}
+	b.WriteString("  </rdf:Description>\n")
+
+	if m.hasCustom() {
+		writeXMPExtensionSchema(&b)
+
+		custom := m.custom()
+		fmt.Fprintf(&b, "  <rdf:Description rdf:about=\"\" xmlns:%s=\"%s\">\n", xmpAutoCertPrefix, xmpAutoCertNamespace)
+		for _, p := range xmpAutoCertProperties {
+			if v := custom[p.name]; v != "" {
+				fmt.Fprintf(&b, "   <%s:%s>%s</%s:%s>\n", xmpAutoCertPrefix, p.name, xmlEscape(v), xmpAutoCertPrefix, p.name)
+			}
+		}
+		b.WriteString("  </rdf:Description>\n")
+	}
+
+	b.WriteString(" </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+
+	return []byte(b.String())
+}
+
+// PDF/A only allows the predefined XMP schemas unless a custom one is described by an extension schema
+func writeXMPExtensionSchema(b *strings.Builder) {
+	b.WriteString("  <rdf:Description rdf:about=\"\" xmlns:pdfaExtension=\"http://www.aiim.org/pdfa/ns/extension/\"" +
+		" xmlns:pdfaSchema=\"http://www.aiim.org/pdfa/ns/schema#\" xmlns:pdfaProperty=\"http://www.aiim.org/pdfa/ns/property#\">\n")
+	b.WriteString("   <pdfaExtension:schemas>\n")
+	b.WriteString("    <rdf:Bag>\n")
+	b.WriteString("     <rdf:li rdf:parseType=\"Resource\">\n")
+	b.WriteString("      <pdfaSchema:schema>AutoCert Certificate</pdfaSchema:schema>\n")
+	fmt.Fprintf(b, "      <pdfaSchema:namespaceURI>%s</pdfaSchema:namespaceURI>\n", xmpAutoCertNamespace)
+	fmt.Fprintf(b, "      <pdfaSchema:prefix>%s</pdfaSchema:prefix>\n", xmpAutoCertPrefix)
+	b.WriteString("      <pdfaSchema:property>\n")
+	b.WriteString("       <rdf:Seq>\n")
+	for _, p := range xmpAutoCertProperties {
+		b.WriteString("        <rdf:li rdf:parseType=\"Resource\">\n")
+		fmt.Fprintf(b, "         <pdfaProperty:name>%s</pdfaProperty:name>\n", p.name)
+		fmt.Fprintf(b, "         <pdfaProperty:valueType>%s</pdfaProperty:valueType>\n", p.valueType)
+		b.WriteString("         <pdfaProperty:category>external</pdfaProperty:category>\n")
+		fmt.Fprintf(b, "         <pdfaProperty:description>%s</pdfaProperty:description>\n", p.description)
+		b.WriteString("        </rdf:li>\n")
+	}
+	b.WriteString("       </rdf:Seq>\n")
+	b.WriteString("      </pdfaSchema:property>\n")
+	b.WriteString("     </rdf:li>\n")
+	b.WriteString("    </rdf:Bag>\n")
+	b.WriteString("   </pdfaExtension:schemas>\n")
+	b.WriteString("  </rdf:Description>\n")
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// The metadata stream must not be compressed so that it can be read without a PDF parser
+func xmpMetadataStream(ctx *model.Context, xmp []byte) (*types.IndirectRef, error) {
+	sd := types.StreamDict{Dict: types.NewDict(), Content: xmp}
+	sd.InsertName("Type", "Metadata")
+	sd.InsertName("Subtype", "XML")
+	if err := sd.Encode(); err != nil {
+		return nil, fmt.Errorf("failed to encode XMP metadata: %w", err)
+	}
+
+	return ctx.IndRefForNewObject(sd)
+}
+
+// Replace the XMP metadata of the catalog
+func writeXMPMetadata(ctx *model.Context, root types.Dict, meta PdfMetadata) error {
+	metadata, err := xmpMetadataStream(ctx, meta.XMP())
+	if err != nil {
+		return err
+	}
+	root.Update("Metadata", *metadata)
+	return nil
+}
+
+// A text string of the Info dictionary, UTF-16 for text outside of printable ASCII
+func pdfText(s string) types.Object {
+	for _, r := range s {
+		if r > 0x7E || r < 0x20 {
+			return types.NewHexLiteral([]byte(types.EncodeUTF16String(s)))
+		}
+	}
+
+	escaped, err := types.Escape(s)
+	if err != nil {
+		return types.StringLiteral("")
+	}
+	return types.StringLiteral(*escaped)
+}
+
+// Read the PDF, apply fn and write it. pdfcpu dates the Info dictionary on write, so fn gets the time to put in the
+// XMP and the write is retried if the second changed in between.
+func rewritePdf(rs io.ReadSeeker, w io.Writer, fn func(ctx *model.Context, now time.Time) error) error {
+	for range metadataWriteAttempts {
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		ctx, err := api.ReadAndValidate(rs, model.NewDefaultConfiguration())
+		if err != nil {
+			return fmt.Errorf("failed to read PDF: %w", err)
+		}
+
+		now := time.Now().Truncate(time.Second)
+		if err := fn(ctx, now); err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err := api.WriteContext(ctx, &buf); err != nil {
+			return fmt.Errorf("failed to write PDF: %w", err)
+		}
+
+		if time.Now().Truncate(time.Second).Equal(now) {
+			_, err := w.Write(buf.Bytes())
+			return err
+		}
+	}
+
+	return errors.New("failed to write PDF: the dates of the XMP metadata do not match the document information")
+}
+
+// Set the metadata of the PDF of inFile and write it to outFile, inFile and outFile can be the same file
+func SetPdfMetadataFile(inFile, outFile string, meta PdfMetadata) error {
+	data, err := os.ReadFile(inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := SetPdfMetadata(bytes.NewReader(data), &buf, meta); err != nil {
+		return err
+	}
+
+	return os.WriteFile(outFile, buf.Bytes(), 0644)
+}
+
+// Replace the Info dictionary and the XMP metadata of the PDF with meta, the dates are the time of the write. A PDF/A
+// stays identified as PDF/A.
+func SetPdfMetadata(rs io.ReadSeeker, w io.Writer, meta PdfMetadata) error {
+	return rewritePdf(rs, w, func(ctx *model.Context, now time.Time) error {
+		if ctx.Encrypt != nil {
+			return errors.New("cannot set the metadata of an encrypted PDF")
+		}
+
+		root, err := ctx.Catalog()
+		if err != nil {
+			return fmt.Errorf("failed to read PDF catalog: %w", err)
+		}
+
+		current, err := pdfMetadataOf(ctx)
+		if err != nil {
+			return err
+		}
+		meta.Date = now
+		meta.PdfA = meta.PdfA || current.PdfA
+
+		if ctx.Info == nil {
+			if ctx.Info, err = ctx.IndRefForNewObject(types.NewDict()); err != nil {
+				return err
+			}
+		}
+		info, err := ctx.DereferenceDict(*ctx.Info)
+		if err != nil {
+			return fmt.Errorf("failed to read the document information: %w", err)
+		}
+		if info == nil {
+			return errors.New("document information is not a dictionary")
+		}
+		for key, value := range map[string]string{
+			"Title":    meta.Title,
+			"Author":   meta.Author,
+			"Subject":  meta.Subject,
+			"Keywords": meta.Keywords,
+			"Creator":  meta.Creator,
+		} {
+			if value == "" {
+				info.Delete(key)
+			} else {
+				info.Update(key, pdfText(value))
+			}
+		}
+
+		return writeXMPMetadata(ctx, root, meta)
+	})
+}
@@ -0,0 +1,133 @@
+package autocert
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+func TestSetPdfMetadata(t *testing.T) {
+	meta := PdfMetadata{
+		Title:         "Certificate of Completion – Jane Doe",
+		Author:        "AutoCert University",
+		Subject:       "Go & PDF",
+		Creator:       "AutoCert",
+		CertificateID: "0b6f7a4e-4a3c-4d5e-9f1a-2b3c4d5e6f70",
+		ProjectID:     "project-1",
+		VerifyURL:     "https://example.com/verify/0b6f7a4e-4a3c-4d5e-9f1a-2b3c4d5e6f70",
+	}
+
+	tests := []struct {
+		name string
+		pdfa bool
+	}{
+		{name: "PDF"},
+		{name: "PDF/A", pdfa: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf := newTestSignablePdf(t, tt.pdfa)
+
+			var out bytes.Buffer
+			if err := SetPdfMetadata(bytes.NewReader(pdf), &out, meta); err != nil {
+				t.Fatalf("SetPdfMetadata failed: %v", err)
+			}
+
+			ctx, err := api.ReadContext(bytes.NewReader(out.Bytes()), model.NewDefaultConfiguration())
+			if err != nil {
+				t.Fatalf("Failed to read PDF: %v", err)
+			}
+			got, err := pdfMetadataOf(ctx)
+			if err != nil {
+				t.Fatalf("pdfMetadataOf failed: %v", err)
+			}
+			got.Date = meta.Date
+			want := meta
+			want.PdfA = tt.pdfa
+			if got != want {
+				t.Errorf("Metadata = %+v, want %+v", got, want)
+			}
+
+			root, err := ctx.Catalog()
+			if err != nil {
+				t.Fatalf("Failed to read catalog: %v", err)
+			}
+			xmp := xmpMetadataOf(ctx, root)
+			if !strings.Contains(xmp, ">"+meta.Title+"</rdf:li>") {
+				t.Errorf("XMP has no title %q", meta.Title)
+			}
+			if id := xmpValue(xmp, "autocert:CertificateID"); id != meta.CertificateID {
+				t.Errorf("XMP certificate id = %q, want %q", id, meta.CertificateID)
+			}
+			if !strings.Contains(xmp, "<pdfaSchema:namespaceURI>"+xmpAutoCertNamespace) {
+				t.Errorf("XMP has no extension schema for %s", xmpAutoCertNamespace)
+			}
+
+			violations, err := ValidatePdfA(bytes.NewReader(out.Bytes()))
+			if err != nil {
+				t.Fatalf("ValidatePdfA failed: %v", err)
+			}
+			if tt.pdfa && len(violations) > 0 {
+				t.Errorf("Violations of the PDF/A = %q, want none", violations)
+			}
+		})
+	}
+}
+
+func TestValidatePdfAXMPNamespaces(t *testing.T) {
+	tests := []struct {
+		name string
+		xmp  string
+		want int
+	}{
+		{name: "Predefined", xmp: string(PdfMetadata{PdfA: true}.XMP())},
+		{name: "Extension schema", xmp: string(PdfMetadata{PdfA: true, CertificateID: "1"}.XMP())},
+		{name: "Undeclared", xmp: `<rdf:Description xmlns:custom="http://example.com/ns/"><custom:Id>1</custom:Id></rdf:Description>`, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var violations []string
+			validatePdfAXMPNamespaces(tt.xmp, func(format string, args ...any) {
+				violations = append(violations, format)
+			})
+			if len(violations) != tt.want {
+				t.Errorf("Violations = %q, want %d", violations, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertificateMetadataTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		row      map[string]string
+		want     string
+	}{
+		{name: "Title with braces", settings: Settings{Title: "Award }} 2025 {{Name}}"}, want: "Award }} 2025 {{Name}}"},
+		{name: "Template over title", settings: Settings{Title: "Award", TitleTemplate: "Award – {{Name}}"}, row: map[string]string{"Name": "Jane"}, want: "Award – Jane"},
+		{name: "No title", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cg := &CertificateGenerator{Settings: tt.settings}
+			if err := cg.initializeMetadataTemplates(); err != nil {
+				t.Fatalf("initializeMetadataTemplates failed: %v", err)
+			}
+
+			meta, err := cg.metadata(tt.row, "")
+			if err != nil {
+				t.Fatalf("metadata failed: %v", err)
+			}
+			if meta.Title != tt.want {
+				t.Errorf("Title = %q, want %q", meta.Title, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package autocert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers/pdf"
+)
+
+// A bookmark of the outline of a PDF
+type OutlineItem struct {
+	Title string
+	// From 1
+	Page int
+}
+
+// Numbering style of a page label, see table 161 of ISO 32000
+type PageLabelStyle string
+
+const (
+	PageLabelDecimal    PageLabelStyle = "D"
+	PageLabelLowerRoman PageLabelStyle = "r"
+)
+
+// The pages from Page, until the page of the next range, are labelled Prefix followed by their number in Style
+// counted from Start, Eg: i, ii, iii for the index pages then 1, 2, 3 for the certificates
+type PageLabelRange struct {
+	// From 1
+	Page   int
+	Style  PageLabelStyle
+	Prefix string
+	// 0 is 1
+	Start int
+}
+
+// Set the outline and the page labels of the PDF of inFile and write it to outFile, inFile and outFile can be the
+// same file
+func SetPdfOutlineFile(inFile, outFile string, items []OutlineItem, labels []PageLabelRange) error {
+	data, err := os.ReadFile(inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := SetPdfOutline(bytes.NewReader(data), &buf, items, labels); err != nil {
+		return err
+	}
+
+	return os.WriteFile(outFile, buf.Bytes(), 0644)
+}
+
+// Replace the outline of the PDF with one flat bookmark per item, in order of their pages, and its page labels with
+// labels. The viewer opens the PDF with the outline shown.
+//
+// Unlike pdfcpu bookmarks, the items link to their page directly instead of through named destinations, so two
+// recipients with the same name each keep their bookmark.
+func SetPdfOutline(rs io.ReadSeeker, w io.Writer, items []OutlineItem, labels []PageLabelRange) error {
+	return rewritePdf(rs, w, func(ctx *model.Context, _ time.Time) error {
+		if ctx.Encrypt != nil {
+			return errors.New("cannot set the outline of an encrypted PDF")
+		}
+
+		root, err := ctx.Catalog()
+		if err != nil {
+			return err
+		}
+
+		delete(root, "Outlines")
+		if len(items) > 0 {
+			outlines, err := outlineDict(ctx, items)
+			if err != nil {
+				return err
+			}
+			root["Outlines"] = *outlines
+			root["PageMode"] = types.Name("UseOutlines")
+		}
+
+		delete(root, "PageLabels")
+		if len(labels) > 0 {
+			nums := make(types.Array, 0, 2*len(labels))
+			for i, label := range labels {
+				if label.Page < 1 || label.Page > ctx.PageCount || (i > 0 && label.Page <= labels[i-1].Page) {
+					return fmt.Errorf("page label range on page %d is out of order or range (page count is %d)", label.Page, ctx.PageCount)
+				}
+
+				d := types.Dict{"S": types.Name(label.Style)}
+				if label.Prefix != "" {
+					d["P"] = pdfText(label.Prefix)
+				}
+				if label.Start > 1 {
+					d["St"] = types.Integer(label.Start)
+				}
+				nums = append(nums, types.Integer(label.Page-1), d)
+			}
+			root["PageLabels"] = types.Dict{"Nums": nums}
+		}
+
+		return nil
+	})
+}
+
+// The outline dictionary whose items link to the page of each item, see 12.3.3 of ISO 32000
+func outlineDict(ctx *model.Context, items []OutlineItem) (*types.IndirectRef, error) {
+	outlines := types.Dict{"Type": types.Name("Outlines"), "Count": types.Integer(len(items))}
+	outlinesRef, err := ctx.IndRefForNewObject(outlines)
+	if err != nil {
+		return nil, err
+	}
+
+	var prev types.Dict
+	var prevRef *types.IndirectRef
+	for i, item := range items {
+		if i > 0 && item.Page < items[i-1].Page {
+			return nil, fmt.Errorf("bookmark %q is before the page of the previous bookmark", item.Title)
+		}
+
+		_, pageRef, _, err := ctx.PageDict(item.Page, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find page %d of bookmark %q: %w", item.Page, item.Title, err)
+		}
+
+		d := types.Dict{
+			"Title":  pdfText(item.Title),
+			"Parent": *outlinesRef,
+			"Dest":   types.Array{*pageRef, types.Name("Fit")},
+		}
+		ref, err := ctx.IndRefForNewObject(d)
+		if err != nil {
+			return nil, err
+		}
+
+		if prev == nil {
+			outlines["First"] = *ref
+		} else {
+			prev["Next"] = *ref
+			d["Prev"] = *prevRef
+		}
+		prev, prevRef = d, ref
+	}
+	if prevRef != nil {
+		outlines["Last"] = *prevRef
+	}
+
+	return outlinesRef, nil
+}
+
+// A line of the index pages
+type IndexEntry struct {
+	Title string
+	// Page label of the first page of the certificate, Eg: 12
+	Page string
+}
+
+const (
+	// In mm
+	indexMargin = 20.0
+	// In pt
+	indexTitleSize = 18.0
+	indexEntrySize = 10.0
+	// Of the entries, a multiple of their font size
+	indexLineHeight = 1.6
+	// Width of the column of the page numbers, in mm
+	indexPageWidth = 20.0
+)
+
+// Render the index pages listing the entries and their page, the pages are width x height mm. Return the number of
+// pages.
+func RenderIndexPdf(w io.Writer, entries []IndexEntry, fontChain *FontChain, width, height float64) (int, error) {
+	lineHeight := pxToMM(indexEntrySize) * indexLineHeight
+	titleHeight := pxToMM(indexTitleSize) * 2
+	perPage := int((height - 2*indexMargin - titleHeight) / lineHeight)
+	if perPage < 1 || width <= 2*indexMargin+indexPageWidth {
+		return 0, fmt.Errorf("page of %.0fx%.0f mm is too small for the index", width, height)
+	}
+
+	black := canvas.Hex("#000000")
+	titleWidth := width - 2*indexMargin - indexPageWidth
+	pageCount := max((len(entries)+perPage-1)/perPage, 1)
+
+	r := pdf.New(w, width, height, nil)
+	for page := range pageCount {
+		if page > 0 {
+			r.NewPage(width, height)
+		}
+		ctx := canvas.NewContext(r)
+		ctx.SetCoordSystem(canvas.CartesianIV)
+
+		heading := fontChain.RichText("Index", indexTitleSize, black, 0).ToText(width-2*indexMargin, 0, canvas.Left, canvas.Top, 0, 0)
+		ctx.DrawText(indexMargin, indexMargin, heading)
+
+		y := indexMargin + titleHeight
+		for _, entry := range entries[page*perPage : min((page+1)*perPage, len(entries))] {
+			// A title longer than its column is cut at the end of the first line
+			title := fontChain.RichText(entry.Title, indexEntrySize, black, 0).ToText(titleWidth, lineHeight, canvas.Left, canvas.Top, 0, 0)
+			ctx.DrawText(indexMargin, y, title)
+
+			number := fontChain.RichText(entry.Page, indexEntrySize, black, 0).ToText(indexPageWidth, lineHeight, canvas.Right, canvas.Top, 0, 0)
+			ctx.DrawText(indexMargin+titleWidth, y, number)
+
+			y += lineHeight
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write index: %w", err)
+	}
+	return pageCount, nil
+}
+
+// The page labels of a merged PDF of indexPages index pages followed by the certificates, with the page of each
+// certificate in the index
+func mergedPageLabels(indexPages int) []PageLabelRange {
+	if indexPages == 0 {
+		return []PageLabelRange{{Page: 1, Style: PageLabelDecimal}}
+	}
+	return []PageLabelRange{
+		{Page: 1, Style: PageLabelLowerRoman},
+		{Page: indexPages + 1, Style: PageLabelDecimal},
+	}
+}
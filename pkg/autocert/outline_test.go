@@ -0,0 +1,150 @@
+package autocert
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/tdewolff/canvas"
+)
+
+func newTestIndexPdf(t *testing.T, entries int) ([]byte, int) {
+	t.Helper()
+
+	fontLoader, err := NewFontLoader(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("Failed to create FontLoader: %v", err)
+	}
+	fontChain, err := fontLoader.LoadFontChain("FreeSerif", nil, canvas.FontRegular)
+	if err != nil {
+		t.Fatalf("LoadFontChain failed: %v", err)
+	}
+
+	index := make([]IndexEntry, entries)
+	for i := range index {
+		index[i] = IndexEntry{Title: fmt.Sprintf("Person %d", i+1), Page: fmt.Sprint(i + 1)}
+	}
+
+	var buf bytes.Buffer
+	pages, err := RenderIndexPdf(&buf, index, fontChain, 297, 210)
+	if err != nil {
+		t.Fatalf("RenderIndexPdf failed: %v", err)
+	}
+	return buf.Bytes(), pages
+}
+
+func TestRenderIndexPdf(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries int
+		want    int
+	}{
+		{name: "Empty", entries: 0, want: 1},
+		{name: "One page", entries: 10, want: 1},
+		{name: "Several pages", entries: 100, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf, pages := newTestIndexPdf(t, tt.entries)
+			if pages != tt.want {
+				t.Errorf("RenderIndexPdf() pages = %d, want %d", pages, tt.want)
+			}
+
+			got, err := api.PageCount(bytes.NewReader(pdf), model.NewDefaultConfiguration())
+			if err != nil {
+				t.Fatalf("Failed to count pages: %v", err)
+			}
+			if got != pages {
+				t.Errorf("Page count = %d, want %d", got, pages)
+			}
+		})
+	}
+}
+
+func TestSetPdfOutline(t *testing.T) {
+	pdf, pages := newTestIndexPdf(t, 100)
+	if pages < 3 {
+		t.Fatalf("Expected at least 3 pages, got %d", pages)
+	}
+
+	tests := []struct {
+		name    string
+		items   []OutlineItem
+		labels  []PageLabelRange
+		wantErr bool
+	}{
+		{
+			name:   "Duplicate titles",
+			items:  []OutlineItem{{Title: "Index", Page: 1}, {Title: "Jane Doe", Page: 2}, {Title: "Jane Doe", Page: 3}},
+			labels: mergedPageLabels(1),
+		},
+		{
+			name:   "Khmer title",
+			items:  []OutlineItem{{Title: "សុខ សុភា", Page: 1}},
+			labels: mergedPageLabels(0),
+		},
+		{name: "Bookmarks out of order", items: []OutlineItem{{Title: "B", Page: 2}, {Title: "A", Page: 1}}, wantErr: true},
+		{name: "Page out of range", items: []OutlineItem{{Title: "A", Page: pages + 1}}, wantErr: true},
+		{name: "Page labels out of order", labels: []PageLabelRange{{Page: 2, Style: PageLabelDecimal}, {Page: 1, Style: PageLabelLowerRoman}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := SetPdfOutline(bytes.NewReader(pdf), &out, tt.items, tt.labels)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetPdfOutline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			bookmarks, err := api.Bookmarks(bytes.NewReader(out.Bytes()), model.NewDefaultConfiguration())
+			if err != nil {
+				t.Fatalf("Failed to read bookmarks: %v", err)
+			}
+			if len(bookmarks) != len(tt.items) {
+				t.Fatalf("Bookmarks = %d, want %d", len(bookmarks), len(tt.items))
+			}
+			for i, bookmark := range bookmarks {
+				if bookmark.Title != tt.items[i].Title || bookmark.PageFrom != tt.items[i].Page {
+					t.Errorf("Bookmark %d = %q on page %d, want %q on page %d", i, bookmark.Title, bookmark.PageFrom, tt.items[i].Title, tt.items[i].Page)
+				}
+			}
+
+			ctx, err := api.ReadContext(bytes.NewReader(out.Bytes()), model.NewDefaultConfiguration())
+			if err != nil {
+				t.Fatalf("Failed to read PDF: %v", err)
+			}
+			root, err := ctx.Catalog()
+			if err != nil {
+				t.Fatalf("Failed to read catalog: %v", err)
+			}
+			if mode := root.NameEntry("PageMode"); mode == nil || *mode != "UseOutlines" {
+				t.Errorf("PageMode = %v, want UseOutlines", mode)
+			}
+
+			pageLabels, err := ctx.DereferenceDict(root["PageLabels"])
+			if err != nil || pageLabels == nil {
+				t.Fatalf("Failed to read page labels: %v", err)
+			}
+			nums := pageLabels.ArrayEntry("Nums")
+			if len(nums) != 2*len(tt.labels) {
+				t.Fatalf("Page labels = %v, want %d ranges", nums, len(tt.labels))
+			}
+			for i, label := range tt.labels {
+				if got := nums[2*i].(types.Integer).Value(); got != label.Page-1 {
+					t.Errorf("Page label %d starts at page index %d, want %d", i, got, label.Page-1)
+				}
+				d, _ := ctx.DereferenceDict(nums[2*i+1])
+				if style := d.NameEntry("S"); style == nil || *style != string(label.Style) {
+					t.Errorf("Page label %d style = %v, want %s", i, style, label.Style)
+				}
+			}
+		})
+	}
+}
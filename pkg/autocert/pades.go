@@ -0,0 +1,537 @@
+package autocert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+type SignatureLevel string
+
+const (
+	SignatureLevelNone SignatureLevel = ""
+	// PAdES baseline B-B, a CAdES signature of the document
+	SignatureLevelBB SignatureLevel = "B-B"
+	// PAdES baseline B-T, B-B with a time-stamp of the signature
+	SignatureLevelBT SignatureLevel = "B-T"
+)
+
+func (l SignatureLevel) IsValid() bool {
+	switch l {
+	case SignatureLevelNone, SignatureLevelBB, SignatureLevelBT:
+		return true
+	}
+	return false
+}
+
+const (
+	// Room for the signature value, the signed attributes and the unsigned time-stamp, in bytes, on top of the certificates
+	signatureReserve   = 8192
+	timestampReserve   = 8192
+	byteRangeKey       = "/ByteRange"
+	byteRangeWidth     = 48
+	signatureFieldName = "Signature"
+)
+
+var ErrPdfNotSigned = errors.New("PDF is not signed")
+
+// PdfSigner applies PAdES signatures to certificates, the document is signed by an incremental update so what
+// was signed stays byte for byte the same, Eg: a PDF/A stays conformant.
+type PdfSigner struct {
+	Certificate *x509.Certificate
+	// Intermediate certificates embedded with the signer certificate
+	Chain []*x509.Certificate
+	Key   crypto.Signer
+	// Time-stamps the signatures to reach SignatureLevelBT, nil for SignatureLevelBB
+	Timestamper Timestamper
+	Reason      string
+	Location    string
+}
+
+// Read the key and the certificates of a PKCS#12 file
+func NewPdfSignerFromPKCS12(pfx []byte, password string) (*PdfSigner, error) {
+	key, cert, chain, err := pkcs12.DecodeChain(pfx, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode PKCS#12: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported PKCS#12 key type %T", key)
+	}
+
+	return &PdfSigner{
+		Certificate: cert,
+		Chain:       chain,
+		Key:         signer,
+	}, nil
+}
+
+// Encode the key and the certificates of the signer as a PKCS#12 file protected by password
+func (s *PdfSigner) PKCS12(password string) ([]byte, error) {
+	return pkcs12.Modern.Encode(s.Key, s.Certificate, s.Chain, password)
+}
+
+func (s *PdfSigner) Level() SignatureLevel {
+	if s.Timestamper != nil {
+		return SignatureLevelBT
+	}
+	return SignatureLevelBB
+}
+
+// Sign inFile to outFile, they can be the same file
+func (s *PdfSigner) SignFile(inFile, outFile string) error {
+	pdf, err := os.ReadFile(inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	signed, err := s.Sign(pdf)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outFile, signed, 0644)
+}
+
+// Sign the PDF with an invisible signature field on the first page and return the signed PDF
+func (s *PdfSigner) Sign(pdf []byte) ([]byte, error) {
+	if s.Certificate == nil || s.Key == nil {
+		return nil, errors.New("signer has no certificate or key")
+	}
+
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), model.NewDefaultConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if ctx.Encrypt != nil {
+		return nil, errors.New("cannot sign an encrypted PDF")
+	}
+
+	prevXRef, err := lastXRefOffset(pdf)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := ctx.Catalog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	page, pageRef, err := firstPage(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var acroForm types.Dict
+	if d, err := ctx.DereferenceDict(root["AcroForm"]); err != nil {
+		return nil, fmt.Errorf("failed to read form: %w", err)
+	} else if d != nil {
+		acroForm = d.Clone().(types.Dict)
+	} else {
+		acroForm = types.NewDict()
+	}
+	fields, err := ctx.DereferenceArray(acroForm["Fields"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read form fields: %w", err)
+	}
+	annots, err := ctx.DereferenceArray(page["Annots"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read annotations: %w", err)
+	}
+
+	sigNr := *ctx.Size
+	widgetNr := sigNr + 1
+	size := widgetNr + 1
+
+	contentsSize := signatureReserve + len(s.Certificate.Raw)
+	for _, cert := range s.Chain {
+		contentsSize += len(cert.Raw)
+	}
+	if s.Timestamper != nil {
+		contentsSize += timestampReserve
+	}
+
+	var buf bytes.Buffer
+	buf.Write(pdf)
+	if pdf[len(pdf)-1] != '\n' && pdf[len(pdf)-1] != '\r' {
+		buf.WriteByte('\n')
+	}
+	offsets := make(map[int]int64)
+	generations := make(map[int]int)
+
+	offsets[sigNr] = int64(buf.Len())
+	fmt.Fprintf(&buf, "%d 0 obj\n<</Type/Sig/Filter/Adobe.PPKLite/SubFilter/ETSI.CAdES.detached", sigNr)
+	buf.WriteString(byteRangeKey + "[0 0 0 0]" + strings.Repeat(" ", byteRangeWidth-len("[0 0 0 0]")))
+	buf.WriteString("/Contents")
+	contentsStart := buf.Len()
+	buf.WriteString("<" + strings.Repeat("0", 2*contentsSize) + ">")
+	contentsEnd := buf.Len()
+	fmt.Fprintf(&buf, "/M%s", pdfTextString(types.DateString(time.Now())))
+	if name := s.Certificate.Subject.CommonName; name != "" {
+		fmt.Fprintf(&buf, "/Name%s", pdfTextString(name))
+	}
+	if s.Reason != "" {
+		fmt.Fprintf(&buf, "/Reason%s", pdfTextString(s.Reason))
+	}
+	if s.Location != "" {
+		fmt.Fprintf(&buf, "/Location%s", pdfTextString(s.Location))
+	}
+	buf.WriteString(">>\nendobj\n")
+
+	// An invisible, printed and locked widget, PDF/A allows no appearance for a zero size rectangle
+	widget := types.Dict{
+		"Type":    types.Name("Annot"),
+		"Subtype": types.Name("Widget"),
+		"FT":      types.Name("Sig"),
+		"T":       types.StringLiteral(fmt.Sprintf("%s%d", signatureFieldName, len(fields)+1)),
+		"V":       *types.NewIndirectRef(sigNr, 0),
+		"F":       types.Integer(annotFlagPrint | annotFlagLocked),
+		"Rect":    types.NewIntegerArray(0, 0, 0, 0),
+		"P":       *pageRef,
+	}
+	writeObject(&buf, offsets, widgetNr, 0, widget)
+
+	widgetRef := *types.NewIndirectRef(widgetNr, 0)
+
+	page = page.Clone().(types.Dict)
+	page["Annots"] = append(append(types.Array{}, annots...), widgetRef)
+	writeObject(&buf, offsets, pageRef.ObjectNumber.Value(), pageRef.GenerationNumber.Value(), page)
+	generations[pageRef.ObjectNumber.Value()] = pageRef.GenerationNumber.Value()
+
+	acroForm["Fields"] = append(append(types.Array{}, fields...), widgetRef)
+	// SignaturesExist and AppendOnly
+	acroForm["SigFlags"] = types.Integer(3)
+	root = root.Clone().(types.Dict)
+	root["AcroForm"] = acroForm
+	writeObject(&buf, offsets, ctx.Root.ObjectNumber.Value(), ctx.Root.GenerationNumber.Value(), root)
+	generations[ctx.Root.ObjectNumber.Value()] = ctx.Root.GenerationNumber.Value()
+
+	trailer := types.Dict{
+		"Root": *ctx.Root,
+		"Prev": types.Integer(prevXRef),
+	}
+	if ctx.Info != nil {
+		trailer["Info"] = *ctx.Info
+	}
+	if ctx.ID != nil {
+		trailer["ID"] = ctx.ID
+	}
+
+	xrefOffset := int64(buf.Len())
+	if bytes.HasPrefix(pdf[prevXRef:], []byte("xref")) {
+		trailer["Size"] = types.Integer(size)
+		writeXRefTable(&buf, offsets, generations, trailer)
+	} else {
+		// The previous section is an xref stream, which is the only kind a reader of a PDF 1.5+ without hybrid file may expect
+		offsets[size] = xrefOffset
+		trailer["Size"] = types.Integer(size + 1)
+		writeXRefStream(&buf, offsets, generations, trailer, size)
+	}
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", xrefOffset)
+
+	signed := buf.Bytes()
+	byteRange := fmt.Sprintf("[0 %d %d %d]", contentsStart, contentsEnd, len(signed)-contentsEnd)
+	byteRangeStart := contentsStart - len("/Contents") - byteRangeWidth
+	copy(signed[byteRangeStart:], byteRange+strings.Repeat(" ", byteRangeWidth-len(byteRange)))
+
+	h := sha256.New()
+	h.Write(signed[:contentsStart])
+	h.Write(signed[contentsEnd:])
+
+	cms, err := cmsSigner{cert: s.Certificate, chain: s.Chain, key: s.Key}.sign(oidData, nil, h.Sum(nil), s.timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if len(cms) > contentsSize {
+		return nil, fmt.Errorf("signature of %d bytes does not fit the %d bytes reserved", len(cms), contentsSize)
+	}
+	hex.Encode(signed[contentsStart+1:], cms)
+
+	return signed, nil
+}
+
+// The signature time-stamp of B-T, an unsigned attribute over the signature value
+func (s *PdfSigner) timestamp(signature []byte) ([]cmsAttribute, error) {
+	if s.Timestamper == nil {
+		return nil, nil
+	}
+
+	h := sha256.Sum256(signature)
+	token, err := s.Timestamper.Timestamp(h[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to time-stamp signature: %w", err)
+	}
+
+	return []cmsAttribute{{Type: oidAttrTimeStampToken, Value: token}}, nil
+}
+
+var startXRefRe = regexp.MustCompile(`startxref\s+(\d+)`)
+
+// Offset of the last cross-reference section, the Prev of the one appended
+func lastXRefOffset(pdf []byte) (int64, error) {
+	matches := startXRefRe.FindAllSubmatch(pdf[max(0, len(pdf)-1024):], -1)
+	if len(matches) == 0 {
+		return 0, errors.New("PDF has no startxref")
+	}
+
+	offset, err := strconv.ParseInt(string(matches[len(matches)-1][1]), 10, 64)
+	if err != nil || offset <= 0 || offset >= int64(len(pdf)) {
+		return 0, fmt.Errorf("PDF has an invalid startxref %s", matches[len(matches)-1][1])
+	}
+	return offset, nil
+}
+
+func firstPage(ctx *model.Context, root types.Dict) (types.Dict, *types.IndirectRef, error) {
+	node, ok := root["Pages"].(types.IndirectRef)
+	if !ok {
+		return nil, nil, errors.New("catalog has no page tree")
+	}
+
+	for range 64 {
+		d, err := ctx.DereferenceDict(node)
+		if err != nil || d == nil {
+			return nil, nil, fmt.Errorf("failed to read page tree: %v", err)
+		}
+		if d.Type() != nil && *d.Type() == "Page" {
+			return d, &node, nil
+		}
+
+		kids, err := ctx.DereferenceArray(d["Kids"])
+		if err != nil || len(kids) == 0 {
+			return nil, nil, errors.New("PDF has no page")
+		}
+		if node, ok = kids[0].(types.IndirectRef); !ok {
+			return nil, nil, errors.New("page tree has a direct kid")
+		}
+	}
+
+	return nil, nil, errors.New("page tree is too deep")
+}
+
+func writeObject(buf *bytes.Buffer, offsets map[int]int64, objNr, genNr int, d types.Dict) {
+	offsets[objNr] = int64(buf.Len())
+	fmt.Fprintf(buf, "%d %d obj\n%s\nendobj\n", objNr, genNr, d.PDFString())
+}
+
+// Consecutive runs of the object numbers, the subsections of a cross-reference section
+func xrefSubsections(offsets map[int]int64) [][]int {
+	objNrs := make([]int, 0, len(offsets))
+	for objNr := range offsets {
+		objNrs = append(objNrs, objNr)
+	}
+	slices.Sort(objNrs)
+
+	var subsections [][]int
+	for i, objNr := range objNrs {
+		if i > 0 && objNr == objNrs[i-1]+1 {
+			subsections[len(subsections)-1] = append(subsections[len(subsections)-1], objNr)
+		} else {
+			subsections = append(subsections, []int{objNr})
+		}
+	}
+	return subsections
+}
+
+func writeXRefTable(buf *bytes.Buffer, offsets map[int]int64, generations map[int]int, trailer types.Dict) {
+	buf.WriteString("xref\n")
+	for _, subsection := range xrefSubsections(offsets) {
+		fmt.Fprintf(buf, "%d %d\n", subsection[0], len(subsection))
+		for _, objNr := range subsection {
+			fmt.Fprintf(buf, "%010d %05d n\r\n", offsets[objNr], generations[objNr])
+		}
+	}
+	fmt.Fprintf(buf, "trailer\n%s\n", trailer.PDFString())
+}
+
+func writeXRefStream(buf *bytes.Buffer, offsets map[int]int64, generations map[int]int, trailer types.Dict, objNr int) {
+	var index types.Array
+	var data []byte
+	for _, subsection := range xrefSubsections(offsets) {
+		index = append(index, types.Integer(subsection[0]), types.Integer(len(subsection)))
+		for _, nr := range subsection {
+			offset := offsets[nr]
+			gen := generations[nr]
+			data = append(data, 1, byte(offset>>24), byte(offset>>16), byte(offset>>8), byte(offset), byte(gen>>8), byte(gen))
+		}
+	}
+
+	d := trailer.Clone().(types.Dict)
+	d["Type"] = types.Name("XRef")
+	d["W"] = types.NewIntegerArray(1, 4, 2)
+	d["Index"] = index
+	d["Length"] = types.Integer(len(data))
+
+	fmt.Fprintf(buf, "%d 0 obj\n%s\nstream\n", objNr, d.PDFString())
+	buf.Write(data)
+	buf.WriteString("\nendstream\nendobj\n")
+}
+
+// A PDF text string, UTF-16 when it is not ASCII
+func pdfTextString(s string) string {
+	return pdfText(s).PDFString()
+}
+
+type SignatureVerification struct {
+	// The signature matches the signed bytes and was made by the embedded certificate. The certificate is not
+	// checked against a trust store.
+	Valid bool `json:"valid"`
+	// Nothing was appended after the signature, the document is unmodified since it was signed
+	CoversWholeDocument bool           `json:"coversWholeDocument"`
+	Level               SignatureLevel `json:"level"`
+	SignerName          string         `json:"signerName"`
+	SignerOrganization  string         `json:"signerOrganization"`
+	SignedAt            time.Time      `json:"signedAt"`
+	// Time of the signature time-stamp, zero for SignatureLevelBB
+	TimestampedAt time.Time         `json:"timestampedAt"`
+	Certificate   *x509.Certificate `json:"-"`
+	// Why the signature is not valid
+	Problem string `json:"problem,omitempty"`
+}
+
+// Verify the last signature of the PDF, ErrPdfNotSigned when it has none
+func VerifyPdfSignature(pdf []byte) (*SignatureVerification, error) {
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), model.NewDefaultConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	root, err := ctx.Catalog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	acroForm, err := ctx.DereferenceDict(root["AcroForm"])
+	if err != nil || acroForm == nil {
+		return nil, ErrPdfNotSigned
+	}
+	fields, err := ctx.DereferenceArray(acroForm["Fields"])
+	if err != nil {
+		return nil, ErrPdfNotSigned
+	}
+
+	sig := lastSignature(ctx, fields, 0)
+	if sig == nil {
+		return nil, ErrPdfNotSigned
+	}
+
+	v := &SignatureVerification{}
+	if m, err := ctx.DereferenceStringOrHexLiteral(sig["M"], model.V10, nil); err == nil {
+		if t, ok := types.DateTime(m, true); ok {
+			v.SignedAt = t
+		}
+	}
+
+	byteRange, err := ctx.DereferenceArray(sig["ByteRange"])
+	if err != nil || len(byteRange) != 4 {
+		v.Problem = "signature has no byte range"
+		return v, nil
+	}
+	r := make([]int, 4)
+	for i, o := range byteRange {
+		n, ok := o.(types.Integer)
+		if !ok || n < 0 {
+			v.Problem = "signature has an invalid byte range"
+			return v, nil
+		}
+		r[i] = n.Value()
+	}
+	// The only bytes not signed must be the signature value itself
+	if r[0] != 0 || r[1] >= r[2] || r[2]+r[3] > len(pdf) || pdf[r[1]] != '<' || pdf[r[2]-1] != '>' {
+		v.Problem = "signature has an invalid byte range"
+		return v, nil
+	}
+	v.CoversWholeDocument = r[2]+r[3] == len(pdf)
+
+	// The zero padding after the value is ignored by parseCMS
+	cms, err := hex.DecodeString(string(pdf[r[1]+1 : r[2]-1]))
+	if err != nil {
+		v.Problem = "signature value is not hexadecimal"
+		return v, nil
+	}
+
+	sd, err := parseCMS(cms)
+	if err != nil {
+		v.Problem = err.Error()
+		return v, nil
+	}
+	if len(sd.certs) > 0 {
+		v.Certificate = sd.certs[0]
+	}
+
+	h := sha256.New()
+	h.Write(pdf[:r[1]])
+	h.Write(pdf[r[2] : r[2]+r[3]])
+	cert, err := sd.verify(h.Sum(nil))
+	if err != nil {
+		v.Problem = err.Error()
+		return v, nil
+	}
+	v.Certificate = cert
+	v.SignerName = cert.Subject.CommonName
+	if len(cert.Subject.Organization) > 0 {
+		v.SignerOrganization = cert.Subject.Organization[0]
+	}
+
+	v.Level = SignatureLevelBB
+	if token, ok := sd.unsignedAttrs[oidAttrTimeStampToken.String()]; ok {
+		h := sha256.Sum256(sd.signature)
+		genTime, _, err := verifyTimestampToken(token, h[:])
+		if err != nil {
+			v.Problem = err.Error()
+			return v, nil
+		}
+		v.Level = SignatureLevelBT
+		v.TimestampedAt = genTime
+	}
+
+	v.Valid = true
+	if !v.CoversWholeDocument {
+		v.Problem = "document was modified after it was signed"
+	}
+
+	return v, nil
+}
+
+// The value of the last signature field, depth first through the kids
+func lastSignature(ctx *model.Context, fields types.Array, depth int) types.Dict {
+	if depth > 16 {
+		return nil
+	}
+
+	var sig types.Dict
+	for _, o := range fields {
+		field, err := ctx.DereferenceDict(o)
+		if err != nil || field == nil {
+			continue
+		}
+
+		if kids, err := ctx.DereferenceArray(field["Kids"]); err == nil && len(kids) > 0 {
+			if d := lastSignature(ctx, kids, depth+1); d != nil {
+				sig = d
+			}
+			continue
+		}
+
+		if ft := field.NameEntry("FT"); ft == nil || *ft != "Sig" {
+			continue
+		}
+		if d, err := ctx.DereferenceDict(field["V"]); err == nil && d != nil {
+			sig = d
+		}
+	}
+	return sig
+}
@@ -0,0 +1,210 @@
+package autocert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestPdfSigner(t *testing.T) *PdfSigner {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Jane Doe", Organization: []string{"AutoCert University"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	// Round trip through PKCS#12 like a key uploaded by a user
+	pfx, err := (&PdfSigner{Certificate: cert, Key: key}).PKCS12("secret")
+	if err != nil {
+		t.Fatalf("Failed to encode PKCS#12: %v", err)
+	}
+	signer, err := NewPdfSignerFromPKCS12(pfx, "secret")
+	if err != nil {
+		t.Fatalf("NewPdfSignerFromPKCS12 failed: %v", err)
+	}
+	signer.Reason = "Certificate issued"
+	return signer
+}
+
+func newTestSignablePdf(t *testing.T, pdfa bool) []byte {
+	t.Helper()
+	dir := t.TempDir()
+
+	png := filepath.Join(dir, "template.png")
+	writeTestPng(t, png, 300, 200)
+	pdf := filepath.Join(dir, "template.pdf")
+	if err := ImageTemplateToPdfFile(png, pdf, TemplateOptions{PaperSize: PaperSizeA4}); err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	if pdfa {
+		if err := ConvertToPdfAFile(pdf, pdf); err != nil {
+			t.Fatalf("ConvertToPdfAFile failed: %v", err)
+		}
+	}
+
+	b, err := os.ReadFile(pdf)
+	if err != nil {
+		t.Fatalf("Failed to read PDF: %v", err)
+	}
+	return b
+}
+
+func TestSignPdf(t *testing.T) {
+	signer := newTestPdfSigner(t)
+	tsa, err := NewSelfSignedTimestampAuthority("AutoCert TSA")
+	if err != nil {
+		t.Fatalf("NewSelfSignedTimestampAuthority failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		timestamper Timestamper
+		pdfa        bool
+		want        SignatureLevel
+	}{
+		{name: "B-B", want: SignatureLevelBB},
+		{name: "B-T", timestamper: tsa, want: SignatureLevelBT},
+		{name: "PDF/A", timestamper: tsa, pdfa: true, want: SignatureLevelBT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf := newTestSignablePdf(t, tt.pdfa)
+			signer.Timestamper = tt.timestamper
+
+			signed, err := signer.Sign(pdf)
+			if err != nil {
+				t.Fatalf("Sign failed: %v", err)
+			}
+			if !bytes.HasPrefix(signed, pdf) {
+				t.Errorf("Signed PDF does not start with the original PDF")
+			}
+
+			v, err := VerifyPdfSignature(signed)
+			if err != nil {
+				t.Fatalf("VerifyPdfSignature failed: %v", err)
+			}
+			if !v.Valid || !v.CoversWholeDocument {
+				t.Errorf("Verification = valid %v, whole document %v, problem %q, want valid", v.Valid, v.CoversWholeDocument, v.Problem)
+			}
+			if v.Level != tt.want {
+				t.Errorf("Level = %q, want %q", v.Level, tt.want)
+			}
+			if v.SignerName != "Jane Doe" || v.SignerOrganization != "AutoCert University" {
+				t.Errorf("Signer = %q of %q, want \"Jane Doe\" of \"AutoCert University\"", v.SignerName, v.SignerOrganization)
+			}
+			if tt.timestamper != nil && v.TimestampedAt.IsZero() {
+				t.Errorf("TimestampedAt is zero, want the time of the time-stamp")
+			}
+
+			if _, err := GetPageCount(bytes.NewReader(signed)); err != nil {
+				t.Errorf("Signed PDF is not a valid PDF: %v", err)
+			}
+
+			if tt.pdfa {
+				violations, err := ValidatePdfA(bytes.NewReader(signed))
+				if err != nil {
+					t.Fatalf("ValidatePdfA failed: %v", err)
+				}
+				if len(violations) > 0 {
+					t.Errorf("Violations of the signed PDF/A = %q, want none", violations)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyPdfSignatureModified(t *testing.T) {
+	signer := newTestPdfSigner(t)
+	pdf := newTestSignablePdf(t, false)
+
+	signed, err := signer.Sign(pdf)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	t.Run("Appended", func(t *testing.T) {
+		v, err := VerifyPdfSignature(append(bytes.Clone(signed), "\n% appended\n"...))
+		if err != nil {
+			t.Fatalf("VerifyPdfSignature failed: %v", err)
+		}
+		if v.CoversWholeDocument {
+			t.Errorf("CoversWholeDocument = true, want false after appending")
+		}
+	})
+
+	t.Run("Modified", func(t *testing.T) {
+		modified := bytes.Clone(signed)
+		// A byte of the image stream of the template, the structure stays readable
+		i := bytes.Index(modified, []byte("stream")) + len("stream\n") + 16
+		modified[i] ^= 0xFF
+
+		v, err := VerifyPdfSignature(modified)
+		if err != nil {
+			t.Fatalf("VerifyPdfSignature failed: %v", err)
+		}
+		if v.Valid {
+			t.Errorf("Valid = true, want false after modifying a signed byte")
+		}
+	})
+
+	t.Run("NotSigned", func(t *testing.T) {
+		if _, err := VerifyPdfSignature(pdf); !errors.Is(err, ErrPdfNotSigned) {
+			t.Errorf("VerifyPdfSignature error = %v, want ErrPdfNotSigned", err)
+		}
+	})
+}
+
+func TestLocalTimestampAuthority(t *testing.T) {
+	tsa, err := NewSelfSignedTimestampAuthority("AutoCert TSA")
+	if err != nil {
+		t.Fatalf("NewSelfSignedTimestampAuthority failed: %v", err)
+	}
+
+	digest := sha256.Sum256([]byte("signature"))
+	token, err := tsa.Timestamp(digest[:])
+	if err != nil {
+		t.Fatalf("Timestamp failed: %v", err)
+	}
+
+	genTime, cert, err := verifyTimestampToken(token, digest[:])
+	if err != nil {
+		t.Fatalf("verifyTimestampToken failed: %v", err)
+	}
+	if time.Since(genTime) > time.Minute {
+		t.Errorf("Time of the token = %v, want about now", genTime)
+	}
+	if !cert.Equal(tsa.Certificate()) {
+		t.Errorf("Certificate of the token is not the one of the authority")
+	}
+
+	other := sha256.Sum256([]byte("other"))
+	if _, _, err := verifyTimestampToken(token, other[:]); err == nil {
+		t.Errorf("verifyTimestampToken of another digest succeeded, want error")
+	}
+}
@@ -0,0 +1,663 @@
+package autocert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+/*
+ * PDF/A-2b (ISO 19005-2, level B) is the archival profile of PDF 1.7: every font is embedded, colours are device
+ * independent through an sRGB output intent, and the document carries XMP metadata identifying the conformance.
+ * ConvertToPdfA adds what a generated certificate is missing, ValidatePdfA reports what it cannot fix, Eg: a font
+ * of the template that is not embedded.
+ */
+
+const (
+	srgbDescription   = "sRGB IEC61966-2.1"
+	colorRegistryName = "http://www.color.org"
+)
+
+// Actions a PDF/A-2 document must not contain
+var pdfaForbiddenActions = []string{
+	"Launch", "Sound", "Movie", "ResetForm", "ImportData", "JavaScript", "Hide", "SetOCGState", "Rendition", "Trans", "GoTo3DView",
+}
+
+// Annotations a PDF/A-2 document must not contain
+var pdfaForbiddenAnnotations = []string{"3D", "Sound", "Screen", "Movie"}
+
+const (
+	annotFlagInvisible    = 1
+	annotFlagHidden       = 2
+	annotFlagPrint        = 4
+	annotFlagNoView       = 32
+	annotFlagLocked       = 128
+	annotFlagToggleNoView = 256
+)
+
+// Convert the PDF of inFile to PDF/A-2b and write it to outFile, inFile and outFile can be the same file
+func ConvertToPdfAFile(inFile, outFile string) error {
+	data, err := os.ReadFile(inFile)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ConvertToPdfA(bytes.NewReader(data), &buf); err != nil {
+		return err
+	}
+
+	return os.WriteFile(outFile, buf.Bytes(), 0644)
+}
+
+// Convert the PDF to PDF/A-2b: embed the sRGB output intent and the XMP metadata, downgrade the version to 1.7,
+// set the print flag of the annotations and remove the additional actions and JavaScript.
+func ConvertToPdfA(rs io.ReadSeeker, w io.Writer) error {
+	return rewritePdf(rs, w, convertPdfA)
+}
+
+func convertPdfA(ctx *model.Context, now time.Time) error {
+	if ctx.Encrypt != nil {
+		return errors.New("an encrypted PDF cannot be converted to PDF/A")
+	}
+
+	if ctx.HeaderVersion != nil && *ctx.HeaderVersion > model.V17 {
+		v := model.V17
+		ctx.HeaderVersion = &v
+	}
+	ctx.EnsureVersionForWriting()
+
+	root, err := ctx.Catalog()
+	if err != nil {
+		return fmt.Errorf("failed to read PDF catalog: %w", err)
+	}
+
+	root.Delete("AA")
+	if names, err := ctx.DereferenceDict(root["Names"]); err == nil && names != nil {
+		names.Delete("JavaScript")
+	}
+	for i, config := range pdfaOptionalContentConfigs(ctx, root) {
+		config.Delete("AS")
+		if config["Name"] == nil {
+			name := "Default"
+			if i > 0 {
+				name = fmt.Sprintf("Configuration %d", i)
+			}
+			config.InsertString("Name", name)
+		}
+	}
+
+	for _, entry := range ctx.Table {
+		if entry == nil || entry.Free {
+			continue
+		}
+		if d, ok := pdfaDict(entry.Object); ok {
+			fixPdfADict(d)
+		}
+	}
+
+	outputIntent, err := srgbOutputIntent(ctx)
+	if err != nil {
+		return err
+	}
+	root.Update("OutputIntents", types.Array{outputIntent})
+
+	meta, err := pdfMetadataOf(ctx)
+	if err != nil {
+		return err
+	}
+	meta.Date = now
+	meta.PdfA = true
+
+	return writeXMPMetadata(ctx, root, meta)
+}
+
+// The default optional content configuration followed by the alternate ones. pdfcpu adds one for the watermarks.
+func pdfaOptionalContentConfigs(ctx *model.Context, root types.Dict) []types.Dict {
+	properties, err := ctx.DereferenceDict(root["OCProperties"])
+	if err != nil || properties == nil {
+		return nil
+	}
+
+	var configs []types.Dict
+	if d, err := ctx.DereferenceDict(properties["D"]); err == nil && d != nil {
+		configs = append(configs, d)
+	}
+	if alternates, err := ctx.DereferenceArray(properties["Configs"]); err == nil {
+		for _, obj := range alternates {
+			if d, err := ctx.DereferenceDict(obj); err == nil && d != nil {
+				configs = append(configs, d)
+			}
+		}
+	}
+	return configs
+}
+
+func pdfaDict(obj types.Object) (types.Dict, bool) {
+	switch o := obj.(type) {
+	case types.Dict:
+		return o, true
+	case types.StreamDict:
+		return o.Dict, true
+	}
+	return nil, false
+}
+
+// Fix what is safe to fix in place, the rest is left to ValidatePdfA
+func fixPdfADict(d types.Dict) {
+	d.Delete("AA")
+
+	if d.Type() != nil && *d.Type() == "Annot" || d.Subtype() != nil && d["Rect"] != nil {
+		if d.Subtype() == nil || *d.Subtype() != "Popup" {
+			flags := 0
+			if f := d.IntEntry("F"); f != nil {
+				flags = *f
+			}
+			flags &^= annotFlagInvisible | annotFlagHidden | annotFlagNoView | annotFlagToggleNoView
+			d.Update("F", types.Integer(flags|annotFlagPrint))
+		}
+	}
+
+	if d.Subtype() != nil && *d.Subtype() == "Image" {
+		if interpolate := d.BooleanEntry("Interpolate"); interpolate != nil && *interpolate {
+			d.Update("Interpolate", types.Boolean(false))
+		}
+	}
+}
+
+func srgbOutputIntent(ctx *model.Context) (types.Dict, error) {
+	sd, err := ctx.NewStreamDictForBuf(srgbICCProfile())
+	if err != nil {
+		return nil, err
+	}
+	sd.InsertInt("N", 3)
+	if err := sd.Encode(); err != nil {
+		return nil, fmt.Errorf("failed to encode ICC profile: %w", err)
+	}
+
+	profile, err := ctx.IndRefForNewObject(*sd)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.Dict(map[string]types.Object{
+		"Type":                      types.Name("OutputIntent"),
+		"S":                         types.Name("GTS_PDFA1"),
+		"OutputConditionIdentifier": types.StringLiteral(srgbDescription),
+		"Info":                      types.StringLiteral(srgbDescription),
+		"RegistryName":              types.StringLiteral(colorRegistryName),
+		"DestOutputProfile":         *profile,
+	}), nil
+}
+
+// Build an ICC v2 display profile of sRGB: the D50 adapted primaries and a tone curve of the sRGB transfer function
+func srgbICCProfile() []byte {
+	s15Fixed16 := func(values ...float64) []byte {
+		b := make([]byte, 0, 4*len(values))
+		for _, v := range values {
+			b = binary.BigEndian.AppendUint32(b, uint32(int32(math.Round(v*65536))))
+		}
+		return b
+	}
+	xyz := func(x, y, z float64) []byte {
+		return append([]byte("XYZ \x00\x00\x00\x00"), s15Fixed16(x, y, z)...)
+	}
+
+	desc := []byte("desc\x00\x00\x00\x00")
+	desc = binary.BigEndian.AppendUint32(desc, uint32(len(srgbDescription)+1))
+	desc = append(desc, srgbDescription+"\x00"...)
+	// Empty Unicode and ScriptCode descriptions
+	desc = append(desc, make([]byte, 4+4+2+1+67)...)
+
+	curve := []byte("curv\x00\x00\x00\x00")
+	const curvePoints = 1024
+	curve = binary.BigEndian.AppendUint32(curve, curvePoints)
+	for i := range curvePoints {
+		v := float64(i) / (curvePoints - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		curve = binary.BigEndian.AppendUint16(curve, uint16(math.Round(v*65535)))
+	}
+
+	tags := []struct {
+		signature string
+		data      []byte
+	}{
+		{"desc", desc},
+		{"cprt", []byte("text\x00\x00\x00\x00No copyright, use freely\x00")},
+		{"wtpt", xyz(0.9642, 1, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", curve},
+		// The green and blue curves share the data of the red curve
+		{"gTRC", nil},
+		{"bTRC", nil},
+	}
+
+	const headerSize = 128
+	tagTable := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	var data []byte
+	offset := headerSize + 4 + 12*len(tags)
+	var curveOffset, curveSize int
+	for _, tag := range tags {
+		tagOffset, tagSize := offset+len(data), len(tag.data)
+		if tag.data == nil {
+			tagOffset, tagSize = curveOffset, curveSize
+		} else {
+			data = append(data, tag.data...)
+			// Tag data is 4 bytes aligned
+			for len(data)%4 != 0 {
+				data = append(data, 0)
+			}
+		}
+		if tag.signature == "rTRC" {
+			curveOffset, curveSize = tagOffset, tagSize
+		}
+
+		tagTable = append(tagTable, tag.signature...)
+		tagTable = binary.BigEndian.AppendUint32(tagTable, uint32(tagOffset))
+		tagTable = binary.BigEndian.AppendUint32(tagTable, uint32(tagSize))
+	}
+
+	size := offset + len(data)
+	header := make([]byte, 0, headerSize)
+	header = binary.BigEndian.AppendUint32(header, uint32(size))
+	header = append(header, 0, 0, 0, 0)
+	// Version 2.1
+	header = binary.BigEndian.AppendUint32(header, 0x02100000)
+	header = append(header, "mntrRGB XYZ "...)
+	// Creation date: 2024-01-01 00:00:00
+	for _, v := range []uint16{2024, 1, 1, 0, 0, 0} {
+		header = binary.BigEndian.AppendUint16(header, v)
+	}
+	header = append(header, "acsp"...)
+	// Platform, flags, manufacturer, model, attributes and rendering intent
+	header = append(header, make([]byte, 4+4+4+4+8+4)...)
+	// Illuminant of the profile connection space, D50
+	header = append(header, s15Fixed16(0.9642, 1, 0.8249)...)
+	header = append(header, make([]byte, headerSize-len(header))...)
+
+	profile := make([]byte, 0, size)
+	profile = append(profile, header...)
+	profile = append(profile, tagTable...)
+	profile = append(profile, data...)
+	return profile
+}
+
+func ValidatePdfAFile(inFile string) ([]string, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF file: %w", err)
+	}
+	defer f.Close()
+
+	return ValidatePdfA(f)
+}
+
+// Check the PDF against the requirements of PDF/A-2b and return the violations found, sorted. A PDF without
+// violation is not proof of conformance: the content streams are not parsed, Eg: the colour spaces used by
+// the operators are not checked.
+func ValidatePdfA(rs io.ReadSeeker) ([]string, error) {
+	ctx, err := api.ReadContext(rs, model.NewDefaultConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	violations := make(map[string]bool)
+	report := func(format string, a ...any) {
+		violations[fmt.Sprintf(format, a...)] = true
+	}
+
+	if ctx.XRefTable.Version() > model.V17 || ctx.HeaderVersion != nil && *ctx.HeaderVersion > model.V17 {
+		report("PDF version is newer than 1.7")
+	}
+	if ctx.Encrypt != nil {
+		report("document is encrypted")
+	}
+	if len(ctx.ID) == 0 {
+		report("trailer has no file identifier")
+	}
+
+	root, err := ctx.Catalog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF catalog: %w", err)
+	}
+	validatePdfACatalog(ctx, root, report)
+
+	for _, entry := range ctx.Table {
+		if entry == nil || entry.Free {
+			continue
+		}
+		validatePdfAObject(ctx, entry.Object, report)
+	}
+
+	return slices.Sorted(func(yield func(string) bool) {
+		for v := range violations {
+			if !yield(v) {
+				return
+			}
+		}
+	}), nil
+}
+
+func validatePdfACatalog(ctx *model.Context, root types.Dict, report func(string, ...any)) {
+	if root["AA"] != nil {
+		report("catalog has additional actions")
+	}
+	if names, err := ctx.DereferenceDict(root["Names"]); err == nil && names != nil {
+		if names["JavaScript"] != nil {
+			report("document has JavaScript")
+		}
+		if names["EmbeddedFiles"] != nil {
+			report("document has embedded files")
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, config := range pdfaOptionalContentConfigs(ctx, root) {
+		if config["AS"] != nil {
+			report("optional content configuration has an AS entry")
+		}
+		name, _ := ctx.DereferenceText(config["Name"])
+		if name == "" {
+			report("optional content configuration has no name")
+		} else if names[name] {
+			report("optional content configuration name %q is not unique", name)
+		}
+		names[name] = true
+	}
+
+	hasOutputIntent := false
+	if intents, err := ctx.DereferenceArray(root["OutputIntents"]); err == nil {
+		for _, obj := range intents {
+			intent, err := ctx.DereferenceDict(obj)
+			if err != nil || intent == nil {
+				continue
+			}
+			if s := intent.NameEntry("S"); s != nil && *s == "GTS_PDFA1" && intent["DestOutputProfile"] != nil {
+				hasOutputIntent = true
+			}
+		}
+	}
+	if !hasOutputIntent {
+		report("catalog has no GTS_PDFA1 output intent with a destination profile")
+	}
+
+	sd, _, err := ctx.DereferenceStreamDict(root["Metadata"])
+	if err != nil || sd == nil {
+		report("catalog has no XMP metadata")
+		return
+	}
+	if sd.Dict["Filter"] != nil {
+		report("XMP metadata stream is compressed")
+	}
+	if err := sd.Decode(); err != nil {
+		report("XMP metadata stream cannot be decoded")
+		return
+	}
+
+	xmp := string(sd.Content)
+	if xmpValue(xmp, "pdfaid:part") != "2" {
+		report("XMP metadata does not identify PDF/A part 2")
+	}
+	if conformance := xmpValue(xmp, "pdfaid:conformance"); conformance != "B" && conformance != "U" && conformance != "A" {
+		report("XMP metadata does not identify a PDF/A conformance level")
+	}
+	validatePdfAXMPNamespaces(xmp, report)
+	validatePdfAInfo(ctx, xmp, report)
+}
+
+// Namespaces of the XMP schemas predefined by PDF/A, any other must be described by an extension schema
+var pdfaPredefinedXMPNamespaces = []string{
+	"adobe:ns:meta/",
+	"http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+	"http://www.aiim.org/pdfa/ns/id/",
+	"http://www.aiim.org/pdfa/ns/extension/",
+	"http://www.aiim.org/pdfa/ns/schema#",
+	"http://www.aiim.org/pdfa/ns/property#",
+	"http://www.aiim.org/pdfa/ns/type#",
+	"http://www.aiim.org/pdfa/ns/field#",
+	"http://purl.org/dc/elements/1.1/",
+	"http://ns.adobe.com/xap/1.0/",
+	"http://ns.adobe.com/xap/1.0/mm/",
+	"http://ns.adobe.com/xap/1.0/rights/",
+	"http://ns.adobe.com/xap/1.0/bj/",
+	"http://ns.adobe.com/xap/1.0/t/pg/",
+	"http://ns.adobe.com/xap/1.0/g/img/",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceEvent#",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceRef#",
+	"http://ns.adobe.com/pdf/1.3/",
+	"http://ns.adobe.com/photoshop/1.0/",
+	"http://ns.adobe.com/tiff/1.0/",
+	"http://ns.adobe.com/exif/1.0/",
+	"http://ns.adobe.com/exif/1.0/aux/",
+}
+
+var (
+	xmpNamespaceRegexp          = regexp.MustCompile(`xmlns:[A-Za-z0-9_.-]+="([^"]*)"`)
+	xmpExtensionNamespaceRegexp = regexp.MustCompile(`<pdfaSchema:namespaceURI>([^<]*)</pdfaSchema:namespaceURI>`)
+)
+
+func validatePdfAXMPNamespaces(xmp string, report func(string, ...any)) {
+	declared := map[string]bool{}
+	for _, m := range xmpExtensionNamespaceRegexp.FindAllStringSubmatch(xmp, -1) {
+		declared[strings.TrimSpace(m[1])] = true
+	}
+
+	reported := map[string]bool{}
+	for _, m := range xmpNamespaceRegexp.FindAllStringSubmatch(xmp, -1) {
+		ns := m[1]
+		if slices.Contains(pdfaPredefinedXMPNamespaces, ns) || declared[ns] || reported[ns] {
+			continue
+		}
+		reported[ns] = true
+		report("XMP namespace %s has no PDF/A extension schema", ns)
+	}
+}
+
+// The entries of the Info dictionary must be equivalent to the XMP properties
+func validatePdfAInfo(ctx *model.Context, xmp string, report func(string, ...any)) {
+	if ctx.Info == nil {
+		return
+	}
+	info, err := ctx.DereferenceDict(*ctx.Info)
+	if err != nil || info == nil {
+		return
+	}
+
+	text := func(key string) string {
+		obj, ok := info.Find(key)
+		if !ok {
+			return ""
+		}
+		s, _ := ctx.DereferenceText(obj)
+		return s
+	}
+
+	if producer := text("Producer"); producer != "" && producer != xmpValue(xmp, "pdf:Producer") {
+		report("Producer of the document information does not match the XMP metadata")
+	}
+
+	for key, property := range map[string]string{"CreationDate": "xmp:CreateDate", "ModDate": "xmp:ModifyDate"} {
+		value := text(key)
+		if value == "" {
+			continue
+		}
+		infoDate, ok := types.DateTime(value, true)
+		xmpDate, err := time.Parse(time.RFC3339, xmpValue(xmp, property))
+		if !ok || err != nil || !infoDate.Equal(xmpDate) {
+			report("%s of the document information does not match the XMP metadata", key)
+		}
+	}
+}
+
+// The value of a simple XMP property, written as an element or as an attribute
+func xmpValue(xmp, property string) string {
+	quoted := regexp.QuoteMeta(property)
+	re := regexp.MustCompile(`<` + quoted + `>([^<]*)</` + quoted + `>|` + quoted + `="([^"]*)"`)
+	m := re.FindStringSubmatch(xmp)
+	if m == nil {
+		return ""
+	}
+
+	value := m[1] + m[2]
+	var v struct {
+		Value string `xml:",chardata"`
+	}
+	if err := xml.Unmarshal([]byte("<v>"+value+"</v>"), &v); err == nil {
+		value = v.Value
+	}
+	return strings.TrimSpace(value)
+}
+
+func validatePdfAObject(ctx *model.Context, obj types.Object, report func(string, ...any)) {
+	switch o := obj.(type) {
+	case types.StreamDict:
+		for _, f := range o.FilterPipeline {
+			if f.Name == "LZWDecode" {
+				report("stream uses the LZWDecode filter")
+			}
+		}
+		validatePdfADict(ctx, o.Dict, report)
+	case types.Dict:
+		validatePdfADict(ctx, o, report)
+	case types.Array:
+		for _, v := range o {
+			validatePdfAObject(ctx, v, report)
+		}
+	}
+}
+
+func validatePdfADict(ctx *model.Context, d types.Dict, report func(string, ...any)) {
+	typ, subtype := "", ""
+	if t := d.Type(); t != nil {
+		typ = *t
+	}
+	if s := d.Subtype(); s != nil {
+		subtype = *s
+	}
+
+	if d["AA"] != nil {
+		report("%s has additional actions", pdfaDictName(typ, subtype))
+	}
+
+	if s := d.NameEntry("S"); s != nil && (typ == "" || typ == "Action") && slices.Contains(pdfaForbiddenActions, *s) {
+		report("document has a %s action", *s)
+	}
+
+	switch {
+	case typ == "Font":
+		validatePdfAFont(ctx, d, subtype, report)
+	case typ == "Annot" || subtype != "" && d["Rect"] != nil:
+		validatePdfAAnnotation(d, subtype, report)
+	case subtype == "Image":
+		if interpolate := d.BooleanEntry("Interpolate"); interpolate != nil && *interpolate {
+			report("image is interpolated")
+		}
+		if d["Alternates"] != nil {
+			report("image has alternates")
+		}
+		if d["OPI"] != nil {
+			report("image has OPI")
+		}
+		if cs := d.NameEntry("ColorSpace"); cs != nil && *cs == "DeviceCMYK" {
+			report("image uses DeviceCMYK without a CMYK output intent")
+		}
+	case subtype == "Form":
+		if d["OPI"] != nil {
+			report("form XObject has OPI")
+		}
+		if d["PS"] != nil || d.NameEntry("Subtype2") != nil && *d.NameEntry("Subtype2") == "PS" {
+			report("form XObject has PostScript")
+		}
+	case subtype == "PS":
+		report("document has a PostScript XObject")
+	}
+
+	if d["TR"] != nil {
+		report("graphics state has a transfer function")
+	}
+	if tr2 := d["TR2"]; tr2 != nil {
+		if name, ok := tr2.(types.Name); !ok || name != "Default" {
+			report("graphics state has a transfer function")
+		}
+	}
+
+	for _, v := range d {
+		switch v.(type) {
+		case types.Dict, types.Array:
+			validatePdfAObject(ctx, v, report)
+		}
+	}
+}
+
+func pdfaDictName(typ, subtype string) string {
+	switch {
+	case typ != "" && subtype != "":
+		return typ + " " + subtype
+	case typ != "":
+		return typ
+	case subtype != "":
+		return subtype
+	}
+	return "dictionary"
+}
+
+// Every font but Type 3 must embed its font program, the font of a Type 0 font is its descendant font
+func validatePdfAFont(ctx *model.Context, d types.Dict, subtype string, report func(string, ...any)) {
+	if subtype == "Type0" || subtype == "Type3" {
+		return
+	}
+
+	name := "unknown"
+	if baseFont := d.NameEntry("BaseFont"); baseFont != nil {
+		name = *baseFont
+	}
+
+	fd, err := ctx.DereferenceDict(d["FontDescriptor"])
+	if err != nil || fd == nil {
+		report("font %s is not embedded", name)
+		return
+	}
+	if fd["FontFile"] == nil && fd["FontFile2"] == nil && fd["FontFile3"] == nil {
+		report("font %s is not embedded", name)
+	}
+}
+
+func validatePdfAAnnotation(d types.Dict, subtype string, report func(string, ...any)) {
+	if slices.Contains(pdfaForbiddenAnnotations, subtype) {
+		report("document has a %s annotation", subtype)
+		return
+	}
+	if subtype == "Popup" {
+		return
+	}
+
+	flags := 0
+	if f := d.IntEntry("F"); f != nil {
+		flags = *f
+	}
+	if flags&annotFlagPrint == 0 {
+		report("%s annotation is not printed", subtype)
+	}
+	if flags&(annotFlagInvisible|annotFlagHidden|annotFlagNoView|annotFlagToggleNoView) != 0 {
+		report("%s annotation is hidden", subtype)
+	}
+}
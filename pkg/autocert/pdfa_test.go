@@ -0,0 +1,119 @@
+package autocert
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSrgbICCProfile(t *testing.T) {
+	profile := srgbICCProfile()
+
+	if size := binary.BigEndian.Uint32(profile[0:4]); int(size) != len(profile) {
+		t.Errorf("Profile size = %d, want %d", size, len(profile))
+	}
+	if sig := string(profile[36:40]); sig != "acsp" {
+		t.Errorf("Profile signature = %q, want \"acsp\"", sig)
+	}
+	if class := string(profile[12:24]); class != "mntrRGB XYZ " {
+		t.Errorf("Profile class and colour spaces = %q, want \"mntrRGB XYZ \"", class)
+	}
+
+	count := int(binary.BigEndian.Uint32(profile[128:132]))
+	for i := range count {
+		entry := profile[132+12*i : 132+12*(i+1)]
+		offset, size := binary.BigEndian.Uint32(entry[4:8]), binary.BigEndian.Uint32(entry[8:12])
+		if offset%4 != 0 || int(offset+size) > len(profile) {
+			t.Errorf("Tag %s at %d of size %d is misaligned or out of the profile", entry[0:4], offset, size)
+		}
+	}
+}
+
+func TestConvertToPdfA(t *testing.T) {
+	dir := t.TempDir()
+
+	png := filepath.Join(dir, "template.png")
+	writeTestPng(t, png, 300, 200)
+	template := filepath.Join(dir, "template.pdf")
+	if err := ImageTemplateToPdfFile(png, template, TemplateOptions{PaperSize: PaperSizeA4}); err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	// The text is drawn with an embedded font
+	tr, err := NewTextRenderer(newTestConfig(t), Rect{Width: 300, Height: 50}, Font{Name: "FreeSerif", Size: 20, Color: "#000000"}, Wrap{}, Typography{}, Settings{})
+	if err != nil {
+		t.Fatalf("Failed to create text renderer: %v", err)
+	}
+	txtPdf, err := tr.RenderTextAsPdf("Jane Doe", TextAlignCenter)
+	if err != nil {
+		t.Fatalf("Failed to render text: %v", err)
+	}
+	cmp, err := NewCompositorFromFile(template)
+	if err != nil {
+		t.Fatalf("Failed to read PDF: %v", err)
+	}
+	if err := cmp.StampPdf(1, bytes.NewReader(txtPdf), 100, 100); err != nil {
+		t.Fatalf("Failed to stamp text: %v", err)
+	}
+	certificate := filepath.Join(dir, "certificate.pdf")
+	if err := cmp.WriteFile(certificate); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+
+	violations, err := ValidatePdfAFile(certificate)
+	if err != nil {
+		t.Fatalf("ValidatePdfAFile failed: %v", err)
+	}
+	for _, want := range []string{"catalog has no XMP metadata", "catalog has no GTS_PDFA1 output intent with a destination profile"} {
+		if !slices.Contains(violations, want) {
+			t.Errorf("Violations of the plain PDF = %q, want %q", violations, want)
+		}
+	}
+
+	if err := ConvertToPdfAFile(certificate, certificate); err != nil {
+		t.Fatalf("ConvertToPdfAFile failed: %v", err)
+	}
+
+	violations, err = ValidatePdfAFile(certificate)
+	if err != nil {
+		t.Fatalf("ValidatePdfAFile failed: %v", err)
+	}
+	if len(violations) > 0 {
+		t.Errorf("Violations of the PDF/A = %q, want none", violations)
+	}
+
+	f, err := os.Open(certificate)
+	if err != nil {
+		t.Fatalf("Failed to open PDF: %v", err)
+	}
+	defer f.Close()
+	if _, err := GetPageCount(f); err != nil {
+		t.Errorf("PDF/A is not a valid PDF: %v", err)
+	}
+}
+
+func TestXmpValue(t *testing.T) {
+	xmp := string(PdfMetadata{Title: "A & B", PdfA: true}.XMP())
+
+	tests := []struct {
+		name     string
+		property string
+		want     string
+	}{
+		{name: "Part", property: "pdfaid:part", want: "2"},
+		{name: "Conformance", property: "pdfaid:conformance", want: "B"},
+		{name: "Producer", property: "pdf:Producer", want: pdfaProducer},
+		{name: "Missing", property: "pdf:Keywords", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := xmpValue(xmp, tt.property); got != tt.want {
+				t.Errorf("xmpValue(%s) = %q, want %q", tt.property, got, tt.want)
+			}
+		})
+	}
+}
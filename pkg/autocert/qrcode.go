@@ -1,12 +1,21 @@
 package autocert
 
 import (
+	"bytes"
 	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 
-	"github.com/SeakMengs/AutoCert/internal/util"
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
 	"github.com/skip2/go-qrcode"
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers"
 	qrsvg "github.com/wamuir/svg-qr-code"
 )
 
@@ -29,49 +38,224 @@ func GenerateQRCodeAsPdf(link, outFile string, size int) error {
 		return err
 	}
 
-	tmpQrSvg, err := util.CreateTemp("autocert_qr_*.svg")
-	if err != nil {
-		return err
+	return SvgReaderToPdf(strings.NewReader(qr.String()), outFile, float64(size), float64(size))
+}
+
+// The qr code size will be 6% of the page width, clamped between 50 and 200
+func QRCodeSizeByPageWidth(pageWidth float64) int {
+	size := int(pageWidth * 0.06)
+	maxSize := 200
+	minSize := 50
+
+	if size > maxSize {
+		size = maxSize
 	}
-	defer os.Remove(tmpQrSvg.Name())
 
-	if err := os.WriteFile(tmpQrSvg.Name(), []byte(qr.String()), 0644); err != nil {
-		return err
+	if size < minSize {
+		size = minSize
 	}
 
-	return SvgToPdf(tmpQrSvg.Name(), outFile, float64(size), float64(size))
+	return size
 }
 
-// Generate qr based on pdf page's dimension, the qr code size will 6% of the page width
-func GenerateQRCodeAsPdfByPdfPage(link, pdfFile string, pageNum int, outFile string) error {
-	if filepath.Ext(outFile) != ".pdf" {
-		return fmt.Errorf("output file is not a PDF: %s", outFile)
+type QRErrorCorrection string
+
+const (
+	// Recovers 7% of the symbol
+	QRErrorCorrectionLow QRErrorCorrection = "L"
+	// Recovers 15% of the symbol
+	QRErrorCorrectionMedium QRErrorCorrection = "M"
+	// Recovers 25% of the symbol
+	QRErrorCorrectionQuartile QRErrorCorrection = "Q"
+	// Recovers 30% of the symbol
+	QRErrorCorrectionHigh QRErrorCorrection = "H"
+)
+
+var qrErrorCorrectionLevels = map[QRErrorCorrection]qr.ErrorCorrectionLevel{
+	"":                        qr.M,
+	QRErrorCorrectionLow:      qr.L,
+	QRErrorCorrectionMedium:   qr.M,
+	QRErrorCorrectionQuartile: qr.Q,
+	QRErrorCorrectionHigh:     qr.H,
+}
+
+// Empty is valid, it is treated as medium
+func (e QRErrorCorrection) IsValid() bool {
+	_, ok := qrErrorCorrectionLevels[e]
+	return ok
+}
+
+// Whether enough of the symbol can be recovered to cover a centre logo
+func (e QRErrorCorrection) AllowsLogo() bool {
+	return e == QRErrorCorrectionQuartile || e == QRErrorCorrectionHigh
+}
+
+// The quiet zone recommended by the QR code specification, in modules
+const DefaultQRQuietZone = 4
+
+// The part of the symbol width covered by the centre logo, small enough to be recovered by the Q and H levels
+const qrLogoRatio = 0.22
+
+func EncodeQRCode(content string, errorCorrection QRErrorCorrection) (barcode.Barcode, error) {
+	if content == "" {
+		return nil, ErrEmptyBarcodeContent
 	}
 
-	pdfSrc, err := os.Open(pdfFile)
-	if err != nil {
-		return fmt.Errorf("failed to open PDF file: %w", err)
+	level, ok := qrErrorCorrectionLevels[errorCorrection]
+	if !ok {
+		return nil, fmt.Errorf("invalid QR code error correction level: %s", errorCorrection)
+	}
+
+	return qr.Encode(content, level, qr.Auto)
+}
+
+// QRCodeRenderer draws a QR code and its optional caption beneath in a box of px
+type QRCodeRenderer struct {
+	rect            Rect
+	foreground      string
+	background      string
+	errorCorrection QRErrorCorrection
+	quietZone       int
+	logo            image.Image
+	// Draws the caption, nil when the caption is hidden
+	textRenderer *TextRenderer
+}
+
+func NewQRCodeRenderer(cfg Config, annot QRCodeAnnotate) (*QRCodeRenderer, error) {
+	if !annot.ErrorCorrection.IsValid() {
+		return nil, fmt.Errorf("invalid QR code error correction level: %s", annot.ErrorCorrection)
+	}
+	if annot.QuietZone < 0 {
+		return nil, fmt.Errorf("QR code quiet zone must not be negative")
+	}
+
+	qrr := &QRCodeRenderer{
+		rect:            *annot.Rect(),
+		foreground:      annot.ForegroundColor,
+		background:      annot.BackgroundColor,
+		errorCorrection: annot.ErrorCorrection,
+		quietZone:       annot.QuietZone,
 	}
-	defer pdfSrc.Close()
+	if qrr.foreground == "" {
+		qrr.foreground = "#000000"
+	}
+
+	if annot.LogoFilePath != "" {
+		if !annot.ErrorCorrection.AllowsLogo() {
+			return nil, fmt.Errorf("a centre logo requires error correction level %s or %s", QRErrorCorrectionQuartile, QRErrorCorrectionHigh)
+		}
+
+		f, err := os.Open(annot.LogoFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open QR code logo: %w", err)
+		}
+		defer f.Close()
+
+		logo, _, err := image.Decode(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode QR code logo: %w", err)
+		}
+		qrr.logo = logo
+	}
+
+	if font := annot.Font(); font != nil {
+		captionFont := *font
+		if captionFont.Color == "" {
+			captionFont.Color = qrr.foreground
+		}
+		// The caption is fitted to its part of the box
+		captionFont.Size = 0
+
+		textRect := Rect{Width: qrr.rect.Width, Height: qrr.rect.Height * captionHeightRatio}
+		textRenderer, err := NewTextRenderer(cfg, textRect, captionFont, Wrap{}, Typography{VerticalAlign: VerticalAlignMiddle}, Settings{RemoveLineBreaksBool: true})
+		if err != nil {
+			return nil, err
+		}
+		qrr.textRenderer = textRenderer
+	}
+
+	return qrr, nil
+}
 
-	w, h, err := GetPdfSizeByPage(pdfSrc, pageNum)
+func (qrr *QRCodeRenderer) renderCanvas(content, caption string) (*canvas.Canvas, error) {
+	bc, err := EncodeQRCode(content, qrr.errorCorrection)
 	if err != nil {
-		return fmt.Errorf("failed to get PDF page size: %w", err)
+		return nil, err
 	}
-	if w <= 0 || h <= 0 {
-		return fmt.Errorf("invalid PDF page size: width=%.2f, height=%.2f", w, h)
+
+	rectMM := qrr.rect.toMM()
+	c := canvas.New(rectMM.Width, rectMM.Height)
+	ctx := canvas.NewContext(c)
+
+	// Origin is bottom-left, the caption takes the bottom of the box and the symbol is centered in the rest
+	symbolArea := rectMM.Height
+	if qrr.textRenderer != nil && caption != "" {
+		symbolArea -= rectMM.Height * captionHeightRatio
+
+		textCanvas, err := qrr.textRenderer.renderCanvas(caption, TextAlignCenter)
+		if err != nil {
+			return nil, err
+		}
+		textCanvas.RenderViewTo(c, canvas.Identity)
 	}
-	size := int(w * 0.06)
-	maxSize := 200
-	minSize := 50
 
-	if size > maxSize {
-		size = maxSize
+	size := math.Min(rectMM.Width, symbolArea)
+	x := (rectMM.Width - size) / 2
+	y := rectMM.Height - size
+
+	ctx.Push()
+	ctx.ComposeView(canvas.Identity.Translate(x, y))
+
+	// An empty background leaves the template visible between the modules
+	if qrr.background != "" {
+		ctx.SetFillColor(canvas.Hex(qrr.background))
+		ctx.DrawPath(0, 0, canvas.Rectangle(size, size))
 	}
 
-	if size < minSize {
-		size = minSize
+	ctx.SetFillColor(canvas.Hex(qrr.foreground))
+	drawModules(ctx, bc, size, size, 0, qrr.quietZone, qrr.quietZone, true)
+
+	if qrr.logo != nil {
+		qrr.drawLogo(ctx, bc, size)
+	}
+	ctx.Pop()
+
+	return c, nil
+}
+
+// Draw the logo at the centre of the symbol of size x size mm, on a pad of the background colour
+func (qrr *QRCodeRenderer) drawLogo(ctx *canvas.Context, bc barcode.Barcode, size float64) {
+	modules := float64(bc.Bounds().Dx() + 2*qrr.quietZone)
+	logoBox := size * float64(bc.Bounds().Dx()) / modules * qrLogoRatio
+
+	bounds := qrr.logo.Bounds()
+	scale := math.Min(logoBox/float64(bounds.Dx()), logoBox/float64(bounds.Dy()))
+	logoW, logoH := float64(bounds.Dx())*scale, float64(bounds.Dy())*scale
+
+	pad := qrr.background
+	if pad == "" {
+		pad = "#FFFFFF"
+	}
+	padding := size / modules
+	ctx.SetFillColor(canvas.Hex(pad))
+	ctx.DrawPath((size-logoW)/2-padding, (size-logoH)/2-padding, canvas.Rectangle(logoW+2*padding, logoH+2*padding))
+
+	// Resolution is the number of pixels of the logo per mm
+	ctx.DrawImage((size-logoW)/2, (size-logoH)/2, qrr.logo, canvas.DPMM(1/scale))
+}
+
+// Render the QR code of the content as a PDF of the size of the box, used by Compositor.
+// The caption is drawn only when the annotate shows it.
+func (qrr *QRCodeRenderer) RenderQRCodeAsPdf(content, caption string) ([]byte, error) {
+	c, err := qrr.renderCanvas(content, caption)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf, renderers.PDF()); err != nil {
+		return nil, err
 	}
 
-	return GenerateQRCodeAsPdf(link, outFile, size)
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,87 @@
+package autocert
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncodeQRCode(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		errorCorrection QRErrorCorrection
+		wantErr         bool
+	}{
+		{name: "Default is medium", content: "https://autocert.example/c/STU-0001", errorCorrection: ""},
+		{name: "Low", content: "https://autocert.example/c/STU-0001", errorCorrection: QRErrorCorrectionLow},
+		{name: "High", content: "https://autocert.example/c/STU-0001", errorCorrection: QRErrorCorrectionHigh},
+		{name: "Unknown level", content: "https://autocert.example/c/STU-0001", errorCorrection: "X", wantErr: true},
+		{name: "Empty content", content: "", errorCorrection: QRErrorCorrectionMedium, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc, err := EncodeQRCode(tt.content, tt.errorCorrection)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("EncodeQRCode(%q, %q) expected error, got nil", tt.content, tt.errorCorrection)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EncodeQRCode(%q, %q) unexpected error: %v", tt.content, tt.errorCorrection, err)
+			}
+			if bc.Content() != tt.content {
+				t.Errorf("Content() = %q, want %q", bc.Content(), tt.content)
+			}
+		})
+	}
+}
+
+func TestRenderQRCodeAsPdf(t *testing.T) {
+	annot := QRCodeAnnotate{
+		BaseAnnotate:    BaseAnnotate{Size: Size{Width: 80, Height: 80}},
+		BackgroundColor: "#FFFFFF",
+		QuietZone:       DefaultQRQuietZone,
+	}
+
+	qrr, err := NewQRCodeRenderer(Config{}, annot)
+	if err != nil {
+		t.Fatalf("NewQRCodeRenderer failed: %v", err)
+	}
+
+	pdf, err := qrr.RenderQRCodeAsPdf("https://autocert.example/c/STU-0001", "")
+	if err != nil {
+		t.Fatalf("RenderQRCodeAsPdf failed: %v", err)
+	}
+
+	w, h, err := GetPdfSizeByPage(bytes.NewReader(pdf), 1)
+	if err != nil {
+		t.Fatalf("Failed to read rendered PDF: %v", err)
+	}
+	// The PDF has the size of the box, allow rounding of the mm conversion
+	if w < 79 || w > 81 || h < 79 || h > 81 {
+		t.Errorf("PDF size = %.2fx%.2f, want 80x80", w, h)
+	}
+
+	if _, err := qrr.RenderQRCodeAsPdf("", ""); !errors.Is(err, ErrEmptyBarcodeContent) {
+		t.Errorf("RenderQRCodeAsPdf of empty content error = %v, want %v", err, ErrEmptyBarcodeContent)
+	}
+
+	invalid := []struct {
+		name  string
+		annot QRCodeAnnotate
+	}{
+		{name: "Unknown error correction", annot: QRCodeAnnotate{ErrorCorrection: "X"}},
+		{name: "Negative quiet zone", annot: QRCodeAnnotate{QuietZone: -1}},
+		{name: "Logo with low error correction", annot: QRCodeAnnotate{ErrorCorrection: QRErrorCorrectionLow, LogoFilePath: "logo.png"}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewQRCodeRenderer(Config{}, tt.annot); err == nil {
+				t.Error("NewQRCodeRenderer expected error, got nil")
+			}
+		})
+	}
+}
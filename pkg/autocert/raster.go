@@ -0,0 +1,150 @@
+package autocert
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+
+	"github.com/chai2010/webp"
+	"github.com/gen2brain/go-fitz"
+)
+
+// RasterFormat is an image format a certificate is exported to next to its PDF, Eg: to share it on social media
+type RasterFormat string
+
+const (
+	RasterFormatPNG  RasterFormat = "png"
+	RasterFormatJPEG RasterFormat = "jpeg"
+	RasterFormatWebP RasterFormat = "webp"
+)
+
+const (
+	DefaultRasterDPI = 150
+	MinRasterDPI     = 72
+	MaxRasterDPI     = 600
+	// Quality of the lossy formats, from 1 to 100
+	rasterQuality = 90
+)
+
+func (f RasterFormat) IsValid() bool {
+	switch f {
+	case RasterFormatPNG, RasterFormatJPEG, RasterFormatWebP:
+		return true
+	}
+	return false
+}
+
+func (f RasterFormat) Ext() string {
+	if f == RasterFormatJPEG {
+		return ".jpg"
+	}
+	return "." + string(f)
+}
+
+// The type of the generated result holding an image of the format
+func (f RasterFormat) CertificateType() CertificateType {
+	switch f {
+	case RasterFormatJPEG:
+		return CertificateTypeJPEG
+	case RasterFormatWebP:
+		return CertificateTypeWebP
+	default:
+		return CertificateTypePNG
+	}
+}
+
+// The format of an image result, false when the type is not an image
+func RasterFormatOf(certificateType CertificateType) (RasterFormat, bool) {
+	switch certificateType {
+	case CertificateTypePNG:
+		return RasterFormatPNG, true
+	case CertificateTypeJPEG:
+		return RasterFormatJPEG, true
+	case CertificateTypeWebP:
+		return RasterFormatWebP, true
+	}
+	return "", false
+}
+
+// Check the formats and the DPI of Settings, a DPI of 0 is DefaultRasterDPI
+func ValidateRasterSettings(formats []RasterFormat, dpi float64) error {
+	for _, format := range formats {
+		if !format.IsValid() {
+			return fmt.Errorf("unsupported raster format: %s", format)
+		}
+	}
+
+	if dpi != 0 && (dpi < MinRasterDPI || dpi > MaxRasterDPI) {
+		return fmt.Errorf("raster DPI must be between %d and %d, got %g", MinRasterDPI, MaxRasterDPI, dpi)
+	}
+
+	return nil
+}
+
+type RasterFile struct {
+	Format RasterFormat
+	Path   string
+}
+
+// Render every page of the PDF at dpi once and encode it to every format, the images are written next to the PDF
+// with the extension of their format and the number of the page after the first. Eg: certificate_1.pdf ->
+// certificate_1.png, certificate_1_p2.png
+func RasterizePdfFile(inFile string, formats []RasterFormat, dpi float64) ([]RasterFile, error) {
+	if len(formats) == 0 {
+		return nil, nil
+	}
+	if dpi == 0 {
+		dpi = DefaultRasterDPI
+	}
+
+	doc, err := fitz.New(inFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	defer doc.Close()
+
+	base := strings.TrimSuffix(inFile, ".pdf")
+	files := make([]RasterFile, 0, doc.NumPage()*len(formats))
+	for page := 0; page < doc.NumPage(); page++ {
+		img, err := doc.ImageDPI(page, dpi)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render page %d of PDF: %w", page+1, err)
+		}
+
+		pageBase := base
+		if page > 0 {
+			pageBase = fmt.Sprintf("%s_p%d", base, page+1)
+		}
+		for _, format := range formats {
+			outFile := pageBase + format.Ext()
+			if err := encodeRasterFile(img, outFile, format); err != nil {
+				return nil, fmt.Errorf("failed to encode page %d to %s: %w", page+1, format, err)
+			}
+			files = append(files, RasterFile{Format: format, Path: outFile})
+		}
+	}
+
+	return files, nil
+}
+
+func encodeRasterFile(img image.Image, outFile string, format RasterFormat) error {
+	file, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch format {
+	case RasterFormatPNG:
+		return png.Encode(file, img)
+	case RasterFormatJPEG:
+		return jpeg.Encode(file, img, &jpeg.Options{Quality: rasterQuality})
+	case RasterFormatWebP:
+		return webp.Encode(file, img, &webp.Options{Quality: rasterQuality})
+	default:
+		return fmt.Errorf("unsupported raster format: %s", format)
+	}
+}
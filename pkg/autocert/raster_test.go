@@ -0,0 +1,115 @@
+package autocert
+
+import (
+	"image"
+	_ "image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/chai2010/webp"
+)
+
+func TestRasterizePdfFile(t *testing.T) {
+	dir := t.TempDir()
+
+	png := filepath.Join(dir, "template.png")
+	writeTestPng(t, png, 300, 200)
+	pdf := filepath.Join(dir, "certificate_1.pdf")
+	// 100x50 mm is 283x142 pt
+	if err := ImageTemplateToPdfFile(png, pdf, TemplateOptions{PaperSize: PaperSizeCustom, Width: 100, Height: 50}); err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+
+	formats := []RasterFormat{RasterFormatPNG, RasterFormatJPEG, RasterFormatWebP}
+	files, err := RasterizePdfFile(pdf, formats, 144)
+	if err != nil {
+		t.Fatalf("RasterizePdfFile failed: %v", err)
+	}
+	if len(files) != len(formats) {
+		t.Fatalf("RasterizePdfFile returned %d files, want %d", len(files), len(formats))
+	}
+
+	for i, file := range files {
+		t.Run(string(file.Format), func(t *testing.T) {
+			if file.Format != formats[i] {
+				t.Errorf("Format = %s, want %s", file.Format, formats[i])
+			}
+			if want := filepath.Join(dir, "certificate_1"+formats[i].Ext()); file.Path != want {
+				t.Errorf("Path = %s, want %s", file.Path, want)
+			}
+
+			f, err := os.Open(file.Path)
+			if err != nil {
+				t.Fatalf("Failed to open image: %v", err)
+			}
+			defer f.Close()
+
+			cfg, _, err := image.DecodeConfig(f)
+			if err != nil {
+				t.Fatalf("Failed to decode image: %v", err)
+			}
+			// Twice the size in pt at 144 DPI, allow rounding
+			if cfg.Width < 565 || cfg.Width > 568 || cfg.Height < 282 || cfg.Height > 285 {
+				t.Errorf("Image size = %dx%d, want about 567x283", cfg.Width, cfg.Height)
+			}
+		})
+	}
+}
+
+func TestRasterizePdfFilePages(t *testing.T) {
+	dir := t.TempDir()
+
+	png := filepath.Join(dir, "template.png")
+	writeTestPng(t, png, 300, 200)
+	page := filepath.Join(dir, "page.pdf")
+	if err := ImageTemplateToPdfFile(png, page, TemplateOptions{PaperSize: PaperSizeCustom, Width: 100, Height: 50}); err != nil {
+		t.Fatalf("Failed to create PDF: %v", err)
+	}
+	pdf := filepath.Join(dir, "certificate_1.pdf")
+	if err := MergePdf([]string{page, page, page}, pdf); err != nil {
+		t.Fatalf("Failed to merge PDF: %v", err)
+	}
+
+	files, err := RasterizePdfFile(pdf, []RasterFormat{RasterFormatPNG, RasterFormatJPEG}, 0)
+	if err != nil {
+		t.Fatalf("RasterizePdfFile failed: %v", err)
+	}
+
+	want := []string{"certificate_1.png", "certificate_1.jpg", "certificate_1_p2.png", "certificate_1_p2.jpg", "certificate_1_p3.png", "certificate_1_p3.jpg"}
+	if len(files) != len(want) {
+		t.Fatalf("RasterizePdfFile returned %d files, want %d", len(files), len(want))
+	}
+	for i, file := range files {
+		if file.Path != filepath.Join(dir, want[i]) {
+			t.Errorf("Path = %s, want %s", file.Path, want[i])
+		}
+		if _, err := os.Stat(file.Path); err != nil {
+			t.Errorf("Image %s was not written: %v", want[i], err)
+		}
+	}
+}
+
+func TestValidateRasterSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		formats []RasterFormat
+		dpi     float64
+		wantErr bool
+	}{
+		{name: "No format", formats: nil, dpi: 0},
+		{name: "Every format", formats: []RasterFormat{RasterFormatPNG, RasterFormatJPEG, RasterFormatWebP}, dpi: 300},
+		{name: "Unknown format", formats: []RasterFormat{"gif"}, wantErr: true},
+		{name: "DPI too low", formats: []RasterFormat{RasterFormatPNG}, dpi: 10, wantErr: true},
+		{name: "DPI too high", formats: []RasterFormat{RasterFormatPNG}, dpi: 1200, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRasterSettings(tt.formats, tt.dpi)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRasterSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
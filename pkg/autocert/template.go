@@ -0,0 +1,225 @@
+package autocert
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/SeakMengs/AutoCert/internal/util"
+	"github.com/nfnt/resize"
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers"
+)
+
+// Image and SVG templates are converted to a single page PDF such that the rest of the pipeline stays PDF based
+var ImageTemplateExtensions = []string{".png", ".jpg", ".jpeg", ".svg"}
+
+type PaperSize string
+
+const (
+	PaperSizeA4     PaperSize = "A4"
+	PaperSizeLetter PaperSize = "letter"
+	// Width and height are given in mm
+	PaperSizeCustom PaperSize = "custom"
+)
+
+type Orientation string
+
+const (
+	// Empty follows the content, landscape for content wider than tall
+	OrientationAuto      Orientation = ""
+	OrientationPortrait  Orientation = "portrait"
+	OrientationLandscape Orientation = "landscape"
+)
+
+// Width and height in mm of the portrait paper sizes
+var paperSizes = map[PaperSize][2]float64{
+	PaperSizeA4:     {210, 297},
+	PaperSizeLetter: {215.9, 279.4},
+}
+
+const (
+	DefaultTemplateDPI = 300
+	MinTemplateDPI     = 72
+	MaxTemplateDPI     = 600
+	// A page larger than A0 is most likely a mistake in mm
+	maxTemplateSideMM = 1189
+)
+
+// TemplateOptions is the physical page of an image, SVG or HTML template
+type TemplateOptions struct {
+	// Empty is A4
+	PaperSize PaperSize
+	// Orientation of A4 and Letter, ignored by PaperSizeCustom
+	Orientation Orientation
+	// Width and height in mm of the page, only used by PaperSizeCustom
+	Width  float64
+	Height float64
+	// Resolution of the raster images embedded in the page, 0 is DefaultTemplateDPI.
+	// Larger images are downsampled to it, smaller images are never upsampled.
+	DPI float64
+}
+
+func (o TemplateOptions) Validate() error {
+	switch o.PaperSize {
+	case "", PaperSizeA4, PaperSizeLetter:
+	case PaperSizeCustom:
+		if o.Width <= 0 || o.Height <= 0 || o.Width > maxTemplateSideMM || o.Height > maxTemplateSideMM {
+			return fmt.Errorf("custom page size must be between 0 and %d mm, got %gx%g mm", maxTemplateSideMM, o.Width, o.Height)
+		}
+	default:
+		return fmt.Errorf("unsupported paper size: %s", o.PaperSize)
+	}
+
+	switch o.Orientation {
+	case OrientationAuto, OrientationPortrait, OrientationLandscape:
+	default:
+		return fmt.Errorf("unsupported orientation: %s", o.Orientation)
+	}
+
+	if o.DPI != 0 && (o.DPI < MinTemplateDPI || o.DPI > MaxTemplateDPI) {
+		return fmt.Errorf("DPI must be between %d and %d, got %g", MinTemplateDPI, MaxTemplateDPI, o.DPI)
+	}
+
+	return nil
+}
+
+// Page size in mm for content of srcW x srcH, the orientation of A4 and Letter follows the content unless given
+func (o TemplateOptions) pageSize(srcW, srcH float64) (float64, float64) {
+	if o.PaperSize == PaperSizeCustom {
+		return o.Width, o.Height
+	}
+
+	size, ok := paperSizes[o.PaperSize]
+	if !ok {
+		size = paperSizes[PaperSizeA4]
+	}
+	landscape := srcW > srcH
+	if o.Orientation != OrientationAuto {
+		landscape = o.Orientation == OrientationLandscape
+	}
+	if landscape {
+		return size[1], size[0]
+	}
+	return size[0], size[1]
+}
+
+func (o TemplateOptions) dpi() float64 {
+	if o.DPI == 0 {
+		return DefaultTemplateDPI
+	}
+	return o.DPI
+}
+
+func IsImageTemplate(fileName string) bool {
+	return slices.Contains(ImageTemplateExtensions, strings.ToLower(filepath.Ext(fileName)))
+}
+
+// Convert a PNG, JPG or SVG template to a single page PDF of the paper size. The content is scaled to fit
+// the page while keeping its aspect ratio and centered, the same as css object-fit: contain.
+func ImageTemplateToPdfFile(inFile, outFile string, opts TemplateOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	f, err := os.Open(inFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var c *canvas.Canvas
+	switch strings.ToLower(filepath.Ext(inFile)) {
+	case ".svg":
+		c, err = svgTemplateCanvas(f, opts)
+	case ".png", ".jpg", ".jpeg":
+		c, err = rasterTemplateCanvas(f, opts)
+	default:
+		return fmt.Errorf("unsupported template file type: %s", filepath.Ext(inFile))
+	}
+	if err != nil {
+		return err
+	}
+
+	return renderers.Write(outFile, c)
+}
+
+// Same as ImageTemplateToPdfFile but read the template from the multipart header, the PDF is also optimized
+func ImageTemplateToPdf(srcFile multipart.FileHeader, outFile string, opts TemplateOptions) error {
+	src, err := srcFile.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// The extension tells the converter the type of the template
+	tmpFile, err := util.CreateTemp("autocert_template_image_*" + strings.ToLower(filepath.Ext(srcFile.Filename)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := io.Copy(tmpFile, src); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	tmpFile.Close()
+
+	if err := ImageTemplateToPdfFile(tmpFile.Name(), outFile, opts); err != nil {
+		return err
+	}
+
+	return OptimizePdfFile(outFile, outFile)
+}
+
+func svgTemplateCanvas(r io.Reader, opts TemplateOptions) (*canvas.Canvas, error) {
+	svg, err := canvas.ParseSVG(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SVG: %w", err)
+	}
+
+	srcW, srcH := svg.Size()
+	widthMM, heightMM := opts.pageSize(srcW, srcH)
+
+	return containCanvas(svg, mmToPx(widthMM), mmToPx(heightMM))
+}
+
+func rasterTemplateCanvas(r io.Reader, opts TemplateOptions) (*canvas.Canvas, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	srcW, srcH := float64(bounds.Dx()), float64(bounds.Dy())
+	if srcW == 0 || srcH == 0 {
+		return nil, errors.New("image has no size")
+	}
+
+	widthMM, heightMM := opts.pageSize(srcW, srcH)
+	scale := math.Min(widthMM/srcW, heightMM/srcH)
+	drawW, drawH := srcW*scale, srcH*scale
+
+	// Downsample to the DPI at the drawn size, never upsample
+	maxW := drawW / 25.4 * opts.dpi()
+	if srcW > maxW {
+		img = resize.Resize(uint(math.Round(maxW)), 0, img, resize.Lanczos3)
+		bounds = img.Bounds()
+	}
+
+	c := canvas.New(widthMM, heightMM)
+	ctx := canvas.NewContext(c)
+	// Resolution is the number of pixels of the image per mm
+	ctx.DrawImage((widthMM-drawW)/2, (heightMM-drawH)/2, img, canvas.DPMM(float64(bounds.Dx())/drawW))
+
+	return c, nil
+}
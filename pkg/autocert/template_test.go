@@ -0,0 +1,98 @@
+package autocert
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestPng(t *testing.T, path string, width, height int) {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		for y := range height {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+}
+
+func TestImageTemplateToPdfFile(t *testing.T) {
+	dir := t.TempDir()
+
+	landscape := filepath.Join(dir, "landscape.png")
+	writeTestPng(t, landscape, 300, 200)
+	portrait := filepath.Join(dir, "portrait.png")
+	writeTestPng(t, portrait, 200, 300)
+
+	svg := filepath.Join(dir, "template.svg")
+	svgContent := `<svg xmlns="http://www.w3.org/2000/svg" width="400" height="300"><rect width="400" height="300" fill="#123456"/></svg>`
+	if err := os.WriteFile(svg, []byte(svgContent), 0644); err != nil {
+		t.Fatalf("Failed to write SVG: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		inFile     string
+		opts       TemplateOptions
+		wantWidth  float64
+		wantHeight float64
+		wantErr    bool
+	}{
+		{name: "Default is A4 following the image orientation", inFile: landscape, opts: TemplateOptions{}, wantWidth: 842, wantHeight: 595},
+		{name: "A4 portrait", inFile: portrait, opts: TemplateOptions{PaperSize: PaperSizeA4, DPI: 150}, wantWidth: 595, wantHeight: 842},
+		{name: "Letter landscape", inFile: landscape, opts: TemplateOptions{PaperSize: PaperSizeLetter}, wantWidth: 792, wantHeight: 612},
+		{name: "Orientation overrides the image", inFile: landscape, opts: TemplateOptions{Orientation: OrientationPortrait}, wantWidth: 595, wantHeight: 842},
+		{name: "Custom size", inFile: landscape, opts: TemplateOptions{PaperSize: PaperSizeCustom, Width: 100, Height: 50}, wantWidth: 283, wantHeight: 142},
+		{name: "SVG", inFile: svg, opts: TemplateOptions{PaperSize: PaperSizeA4}, wantWidth: 842, wantHeight: 595},
+		{name: "Custom size without width", inFile: landscape, opts: TemplateOptions{PaperSize: PaperSizeCustom, Height: 50}, wantErr: true},
+		{name: "Unknown paper size", inFile: landscape, opts: TemplateOptions{PaperSize: "A3"}, wantErr: true},
+		{name: "Unknown orientation", inFile: landscape, opts: TemplateOptions{Orientation: "sideways"}, wantErr: true},
+		{name: "DPI too high", inFile: landscape, opts: TemplateOptions{DPI: 1200}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outFile := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".pdf")
+			err := ImageTemplateToPdfFile(tt.inFile, outFile, tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("ImageTemplateToPdfFile expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ImageTemplateToPdfFile failed: %v", err)
+			}
+
+			f, err := os.Open(outFile)
+			if err != nil {
+				t.Fatalf("Failed to open PDF: %v", err)
+			}
+			defer f.Close()
+
+			w, h, err := GetPdfSizeByPage(f, 1)
+			if err != nil {
+				t.Fatalf("Failed to read PDF: %v", err)
+			}
+			// Allow rounding of the mm conversion
+			if w < tt.wantWidth-1 || w > tt.wantWidth+1 || h < tt.wantHeight-1 || h > tt.wantHeight+1 {
+				t.Errorf("PDF size = %.2fx%.2f, want %.0fx%.0f", w, h, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
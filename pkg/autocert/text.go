@@ -1,12 +1,18 @@
 package autocert
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/tdewolff/canvas"
 	"github.com/tdewolff/canvas/renderers"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/bidi"
 )
 
 /*
@@ -29,8 +35,192 @@ const (
 	TextAlignCenter TextAlign = iota
 	TextAlignLeft
 	TextAlignRight
+	// Left for left-to-right text, right for right-to-left text such as Arabic
+	TextAlignStart
+	// Right for left-to-right text, left for right-to-left text such as Arabic
+	TextAlignEnd
 )
 
+var textAlignNames = map[TextAlign]string{
+	TextAlignCenter: "center",
+	TextAlignLeft:   "left",
+	TextAlignRight:  "right",
+	TextAlignStart:  "start",
+	TextAlignEnd:    "end",
+}
+
+func (ta TextAlign) String() string {
+	return textAlignNames[ta]
+}
+
+// Parse the name of an alignment, empty is center
+func ParseTextAlign(name string) (TextAlign, bool) {
+	if name == "" {
+		return TextAlignCenter, true
+	}
+
+	for ta, taName := range textAlignNames {
+		if taName == name {
+			return ta, true
+		}
+	}
+	return TextAlignCenter, false
+}
+
+// Resolve start and end to left or right by the direction of the text, other alignments are returned as is
+func (ta TextAlign) resolve(text string) TextAlign {
+	rtl := isRightToLeft(text)
+
+	switch {
+	case ta == TextAlignStart && rtl, ta == TextAlignEnd && !rtl:
+		return TextAlignRight
+	case ta == TextAlignStart, ta == TextAlignEnd:
+		return TextAlignLeft
+	default:
+		return ta
+	}
+}
+
+// The direction of a paragraph is the direction of its first strong character (rule P2 and P3 of the Unicode bidi algorithm)
+func isRightToLeft(text string) bool {
+	for _, r := range text {
+		props, _ := bidi.LookupRune(r)
+		switch props.Class() {
+		case bidi.L:
+			return false
+		case bidi.R, bidi.AL:
+			return true
+		}
+	}
+	return false
+}
+
+type TextOverflow string
+
+const (
+	// Reduce the font size until the wrapped text fits in the rect box
+	TextOverflowShrink TextOverflow = "shrink"
+	// Keep the font size and truncate the text with an ellipsis
+	TextOverflowEllipsis TextOverflow = "ellipsis"
+	// Return ErrTextOverflow which fails the row
+	TextOverflowFail TextOverflow = "fail"
+)
+
+func (to TextOverflow) IsValid() bool {
+	switch to {
+	case "", TextOverflowShrink, TextOverflowEllipsis, TextOverflowFail:
+		return true
+	default:
+		return false
+	}
+}
+
+var ErrTextOverflow = errors.New("text overflows the rect box")
+
+// Wrap controls word wrapping of text inside the rect box
+type Wrap struct {
+	Enabled bool
+	// 0 means no limit other than the rect box height
+	MaxLines int
+	// Default to shrink if empty
+	Overflow TextOverflow
+}
+
+func (w Wrap) Validate() error {
+	if !w.Overflow.IsValid() {
+		return fmt.Errorf("invalid text overflow: %s", w.Overflow)
+	}
+	if w.MaxLines < 0 {
+		return fmt.Errorf("max lines must not be negative, got %d", w.MaxLines)
+	}
+	return nil
+}
+
+type VerticalAlign string
+
+const (
+	VerticalAlignTop    VerticalAlign = "top"
+	VerticalAlignMiddle VerticalAlign = "middle"
+	VerticalAlignBottom VerticalAlign = "bottom"
+)
+
+func (va VerticalAlign) IsValid() bool {
+	switch va {
+	case "", VerticalAlignTop, VerticalAlignMiddle, VerticalAlignBottom:
+		return true
+	default:
+		return false
+	}
+}
+
+// Return the y position (from the top) of a text box of the given height in the rect box height
+func (va VerticalAlign) offset(rectHeight, textHeight float64) float64 {
+	switch va {
+	case VerticalAlignTop:
+		return 0
+	case VerticalAlignBottom:
+		return rectHeight - textHeight
+	default:
+		return (rectHeight - textHeight) / 2
+	}
+}
+
+type TextTransform string
+
+const (
+	TextTransformNone      TextTransform = "none"
+	TextTransformUppercase TextTransform = "uppercase"
+	TextTransformLowercase TextTransform = "lowercase"
+	// Uppercase the first letter of each word, the other letters are kept as is (Eg: McDonald)
+	TextTransformTitlecase TextTransform = "titlecase"
+)
+
+func (tt TextTransform) IsValid() bool {
+	switch tt {
+	case "", TextTransformNone, TextTransformUppercase, TextTransformLowercase, TextTransformTitlecase:
+		return true
+	default:
+		return false
+	}
+}
+
+func (tt TextTransform) apply(text string) string {
+	switch tt {
+	case TextTransformUppercase:
+		return cases.Upper(language.Und).String(text)
+	case TextTransformLowercase:
+		return cases.Lower(language.Und).String(text)
+	case TextTransformTitlecase:
+		return cases.Title(language.Und, cases.NoLower).String(text)
+	default:
+		return text
+	}
+}
+
+// Letter spacing is limited such that the text stays readable
+const (
+	MinLetterSpacing = -0.5
+	MaxLetterSpacing = 2.0
+)
+
+// Typography controls how the text is laid out in the rect box
+type Typography struct {
+	// Default to middle if empty
+	VerticalAlign VerticalAlign
+	// Extra space between characters in em (multiple of the font size), Eg: 0.1. Negative tightens the text.
+	LetterSpacing float64
+	// Multiplier of the font's default line height, 0 is treated as 1
+	LineHeight float64
+	Transform  TextTransform
+}
+
+func (t Typography) lineStretch() float64 {
+	if t.LineHeight <= 0 {
+		return 0
+	}
+	return t.LineHeight - 1
+}
+
 // Rect accept px, will convert to mm by the system when needed
 // It's the rectangle box of the text that will be rendered
 type Rect struct {
@@ -49,22 +239,28 @@ type TextRenderer struct {
 	cfg  Config
 	rect Rect
 	font Font
-	// FontFamily is the struct that allow us to use font face function
-	fontFamily *canvas.FontFamily
+	// FontChain holds the font family and its fallbacks, each rune is drawn by the first family that has the glyph
+	fontChain  *FontChain
+	wrap       Wrap
+	typography Typography
 	setting    Settings
 }
 
-func NewTextRenderer(cfg Config, rect Rect, font Font, setting Settings) (*TextRenderer, error) {
+func NewTextRenderer(cfg Config, rect Rect, font Font, wrap Wrap, typography Typography, setting Settings) (*TextRenderer, error) {
+	if err := wrap.Validate(); err != nil {
+		return nil, err
+	}
+
 	fontLoader, err := NewFontLoader(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	fontFamily, err := fontLoader.LoadFont(font.Name, font.GetFontStyle())
+	fontChain, err := fontLoader.LoadFontChain(font.Name, font.Fallbacks, font.GetFontStyle())
 	if err != nil {
 		return nil, err
 	}
-	if fontFamily == nil {
+	if fontChain == nil {
 		return nil, fmt.Errorf("font family not found: %s", font.Name)
 	}
 
@@ -72,29 +268,37 @@ func NewTextRenderer(cfg Config, rect Rect, font Font, setting Settings) (*TextR
 		cfg:        cfg,
 		rect:       rect,
 		font:       font,
-		fontFamily: fontFamily,
+		fontChain:  fontChain,
+		wrap:       wrap,
+		typography: typography,
 		setting:    setting,
 	}, nil
 }
 
+func (tr *TextRenderer) richText(text string, fontSize float64) *canvas.RichText {
+	return tr.fontChain.RichText(text, fontSize, canvas.Hex(tr.font.Color), tr.typography.LetterSpacing)
+}
+
+// Return the runes of the text which neither the font nor its fallbacks can draw
+func (tr *TextRenderer) MissingGlyphs(text string) []rune {
+	return tr.fontChain.MissingGlyphs(tr.typography.Transform.apply(text))
+}
+
 func (tr *TextRenderer) drawText(ctx *canvas.Context, text string, alignment TextAlign) {
 	fontSize := tr.font.Size
 	if fontSize <= 0 {
 		fontSize = tr.getFontSizeFitRectBox(text)
 	}
 
-	face := tr.fontFamily.Face(fontSize, canvas.Hex(tr.font.Color), tr.font.GetFontStyle(), canvas.FontNormal)
-
-	rt := canvas.NewRichText(face)
-	rt.WriteString(text)
+	rt := tr.richText(text, fontSize)
 
 	rectMM := tr.rect.toMM()
 
-	textBox := rt.ToText(rectMM.Width, rectMM.Height, canvas.Left, canvas.Top, 0.0, 0.0)
+	textBox := rt.ToText(rectMM.Width, rectMM.Height, canvas.Left, canvas.Top, 0.0, tr.typography.lineStretch())
 
 	textWidthMM, textHeightMM := textBox.Bounds().W(), textBox.Bounds().H()
 
-	centerYMM := (rectMM.Height - textHeightMM) / 2
+	yPosition := tr.typography.VerticalAlign.offset(rectMM.Height, textHeightMM)
 
 	var xPosition float64
 	switch alignment {
@@ -106,7 +310,7 @@ func (tr *TextRenderer) drawText(ctx *canvas.Context, text string, alignment Tex
 		xPosition = (rectMM.Width - textWidthMM) / 2
 	}
 
-	ctx.DrawText(xPosition, centerYMM, textBox)
+	ctx.DrawText(xPosition, yPosition, textBox)
 }
 
 func (tr *TextRenderer) drawCenteredText(ctx *canvas.Context, text string) {
@@ -128,8 +332,7 @@ func (tr *TextRenderer) getFontSizeFitRectBox(text string) float64 {
 	var textWidthMM, textHeightMM float64
 
 	for {
-		face := tr.fontFamily.Face(fontSize, canvas.Hex(tr.font.Color), tr.font.GetFontStyle(), canvas.FontNormal)
-		textBox := canvas.NewTextBox(face, text, 0, 0, canvas.Left, canvas.Top, 0.0, 0.0)
+		textBox := tr.richText(text, fontSize).ToText(0, 0, canvas.Left, canvas.Top, 0.0, tr.typography.lineStretch())
 
 		textWidthMM, textHeightMM = textBox.Bounds().W(), textBox.Bounds().H()
 
@@ -144,22 +347,125 @@ func (tr *TextRenderer) getFontSizeFitRectBox(text string) float64 {
 	return fontSize
 }
 
+func (tr *TextRenderer) drawWrappedText(ctx *canvas.Context, text string, alignment TextAlign) error {
+	fontSize := tr.font.Size
+	if fontSize <= 0 {
+		fontSize = tr.getFontSizeFitWrappedRectBox(text, alignment)
+	}
+
+	textBox := tr.wrapText(text, fontSize, alignment)
+	if !tr.fitsRectBox(textBox) {
+		switch tr.wrap.Overflow {
+		case TextOverflowFail:
+			return fmt.Errorf("%w: %q", ErrTextOverflow, text)
+		case TextOverflowEllipsis:
+			textBox = tr.truncateWithEllipsis(text, fontSize, alignment)
+		default:
+			for fontSize > 1 && !tr.fitsRectBox(textBox) {
+				fontSize--
+				textBox = tr.wrapText(text, fontSize, alignment)
+			}
+		}
+	}
+
+	rectMM := tr.rect.toMM()
+	yPosition := tr.typography.VerticalAlign.offset(rectMM.Height, textBox.Height)
+
+	ctx.DrawText(0, yPosition, textBox)
+	return nil
+}
+
+// Word wrap the text to the rect box width, the height of the returned text is the height of all lines
+func (tr *TextRenderer) wrapText(text string, fontSize float64, alignment TextAlign) *canvas.Text {
+	rt := tr.richText(text, fontSize)
+
+	halign := canvas.Center
+	switch alignment {
+	case TextAlignLeft:
+		halign = canvas.Left
+	case TextAlignRight:
+		halign = canvas.Right
+	}
+
+	return rt.ToText(tr.rect.toMM().Width, 0.0, halign, canvas.Top, 0.0, tr.typography.lineStretch())
+}
+
+func (tr *TextRenderer) fitsRectBox(textBox *canvas.Text) bool {
+	if textBox.Overflows {
+		return false
+	}
+
+	if tr.wrap.MaxLines > 0 && textBox.Lines() > tr.wrap.MaxLines {
+		return false
+	}
+
+	return textBox.Height <= tr.rect.toMM().Height
+}
+
+// Same as getFontSizeFitRectBox but allow the text to span multiple lines
+func (tr *TextRenderer) getFontSizeFitWrappedRectBox(text string, alignment TextAlign) float64 {
+	fontSize := 1.0
+
+	// Font size (pt) can never be larger than the rect box height (px)
+	for size := 2.0; size <= tr.rect.Height; size++ {
+		if !tr.fitsRectBox(tr.wrapText(text, size, alignment)) {
+			break
+		}
+		fontSize = size
+	}
+
+	return fontSize
+}
+
+// Find the longest prefix of the text that fits in the rect box with an ellipsis appended
+func (tr *TextRenderer) truncateWithEllipsis(text string, fontSize float64, alignment TextAlign) *canvas.Text {
+	runes := []rune(text)
+	truncate := func(n int) string {
+		return strings.TrimRightFunc(string(runes[:n]), unicode.IsSpace) + "…"
+	}
+
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if tr.fitsRectBox(tr.wrapText(truncate(mid), fontSize, alignment)) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return tr.wrapText(truncate(lo), fontSize, alignment)
+}
+
 func (tr *TextRenderer) removeLineBreaks(text string) string {
 	re := regexp.MustCompile(`[\r\n]+`)
 	return strings.TrimSpace(re.ReplaceAllString(text, ""))
 }
 
-func (tr *TextRenderer) RenderSvgTextAsPdf(text string, align TextAlign, outFile string) error {
+func (tr *TextRenderer) renderCanvas(text string, align TextAlign) (*canvas.Canvas, error) {
 	rectMM := tr.rect.toMM()
 	c := canvas.New(rectMM.Width, rectMM.Height)
 	canvasCtx := canvas.NewContext(c)
 	// Change coordination from bottom-left to top-left
 	canvasCtx.SetCoordSystem(canvas.CartesianIV)
 
-	if tr.setting.RemoveLineBreaksBool {
+	// Line breaks from the data are meaningful when wrapping
+	if tr.setting.RemoveLineBreaksBool && !tr.wrap.Enabled {
 		text = tr.removeLineBreaks(text)
 	}
 
+	text = tr.typography.Transform.apply(text)
+
+	// Shaping and bidi reordering are done by canvas (HarfBuzz and fribidi), only the alignment depends on the direction
+	align = align.resolve(text)
+
+	if tr.wrap.Enabled {
+		if err := tr.drawWrappedText(canvasCtx, text, align); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
 	switch align {
 	case TextAlignCenter:
 		tr.drawCenteredText(canvasCtx, text)
@@ -171,9 +477,33 @@ func (tr *TextRenderer) RenderSvgTextAsPdf(text string, align TextAlign, outFile
 		tr.drawCenteredText(canvasCtx, text)
 	}
 
+	return c, nil
+}
+
+func (tr *TextRenderer) RenderSvgTextAsPdf(text string, align TextAlign, outFile string) error {
+	c, err := tr.renderCanvas(text, align)
+	if err != nil {
+		return err
+	}
+
 	if err := renderers.Write(outFile, c); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// Same as RenderSvgTextAsPdf but keep the PDF in memory, used by Compositor to skip temp files
+func (tr *TextRenderer) RenderTextAsPdf(text string, align TextAlign) ([]byte, error) {
+	c, err := tr.renderCanvas(text, align)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf, renderers.PDF()); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,355 @@
+package autocert
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers/rasterizer"
+)
+
+// Run `go test ./pkg/autocert -run TestTextRendererGolden -update` to regenerate the golden images after checking the output
+var update = flag.Bool("update", false, "update golden images")
+
+// Font paths in font_metadata.json are relative to the repository root, write one relative to this package instead
+func newTestConfig(t *testing.T) Config {
+	t.Helper()
+
+	fontMetaPath := filepath.Join(t.TempDir(), "font_metadata.json")
+	data, err := json.Marshal([]FontMetadata{
+		{Name: "Calibri", Path: "../../fonts/calibri-regular.ttf", Weight: 400, Style: FontStyleNormal},
+		{Name: "Khmer OS Siemreap", Path: "../../fonts/KhmerOS_siemreap.ttf"},
+		// Test only fonts, see testdata/fonts/README.md
+		{Name: "FreeSerif", Path: "testdata/fonts/FreeSerif.ttf"},
+		{Name: "Amiri", Path: "testdata/fonts/Amiri-Regular.ttf"},
+		{Name: "Mada", Path: "testdata/fonts/Mada-VF.ttf", Weight: 500, Style: FontStyleNormal, Axes: []FontAxis{{Tag: "wght", Min: 100, Default: 520, Max: 1000}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal font metadata: %v", err)
+	}
+	if err := os.WriteFile(fontMetaPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write font metadata: %v", err)
+	}
+
+	return Config{FontMetadataPath: fontMetaPath}
+}
+
+func TestTextRendererWrap(t *testing.T) {
+	cfg := newTestConfig(t)
+	longText := "Certificate of Completion for Advanced Distributed Systems Engineering and Cloud Native Architecture"
+
+	tests := []struct {
+		name       string
+		wrap       Wrap
+		typography Typography
+		size       float64
+		wantErr    error
+	}{
+		{
+			name: "Shrink to fit",
+			wrap: Wrap{Enabled: true, MaxLines: 2, Overflow: TextOverflowShrink},
+			size: 24,
+		},
+		{
+			name: "Truncate with ellipsis",
+			wrap: Wrap{Enabled: true, MaxLines: 2, Overflow: TextOverflowEllipsis},
+			size: 24,
+		},
+		{
+			name:    "Fail the row",
+			wrap:    Wrap{Enabled: true, MaxLines: 2, Overflow: TextOverflowFail},
+			size:    24,
+			wantErr: ErrTextOverflow,
+		},
+		{
+			name:       "Fit rect box",
+			wrap:       Wrap{Enabled: true, MaxLines: 3, Overflow: TextOverflowFail},
+			typography: Typography{LineHeight: 1.2},
+			size:       0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			font := Font{Name: "Calibri", Size: tt.size, Color: "#000000", Weight: FontWeightRegular}
+			tr, err := NewTextRenderer(cfg, Rect{Width: 300, Height: 60}, font, tt.wrap, tt.typography, Settings{})
+			if err != nil {
+				t.Fatalf("Failed to create text renderer: %v", err)
+			}
+
+			_, err = tr.RenderTextAsPdf(longText, TextAlignCenter)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RenderTextAsPdf() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWrapValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		wrap    Wrap
+		wantErr bool
+	}{
+		{name: "Default", wrap: Wrap{}},
+		{name: "Max lines", wrap: Wrap{Enabled: true, MaxLines: 2, Overflow: TextOverflowEllipsis}},
+		{name: "Negative max lines", wrap: Wrap{Enabled: true, MaxLines: -1}, wantErr: true},
+		{name: "Unknown overflow", wrap: Wrap{Overflow: "scroll"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.wrap.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTruncateWithEllipsis(t *testing.T) {
+	cfg := newTestConfig(t)
+	wrap := Wrap{Enabled: true, MaxLines: 1, Overflow: TextOverflowEllipsis}
+
+	tr, err := NewTextRenderer(cfg, Rect{Width: 200, Height: 40}, Font{Name: "Calibri", Size: 20, Color: "#000000"}, wrap, Typography{}, Settings{})
+	if err != nil {
+		t.Fatalf("Failed to create text renderer: %v", err)
+	}
+
+	textBox := tr.truncateWithEllipsis("The quick brown fox jumps over the lazy dog again and again", 20, TextAlignLeft)
+	if !tr.fitsRectBox(textBox) {
+		t.Errorf("Truncated text does not fit the rect box: %q", textBox.Text)
+	}
+	if textBox.Lines() != 1 {
+		t.Errorf("Expected 1 line, got %d", textBox.Lines())
+	}
+	if !strings.HasSuffix(textBox.Text, "…") {
+		t.Errorf("Expected text to end with an ellipsis, got %q", textBox.Text)
+	}
+}
+
+func TestTextAlignResolve(t *testing.T) {
+	tests := []struct {
+		name  string
+		align TextAlign
+		text  string
+		want  TextAlign
+	}{
+		{name: "Start of Latin text", align: TextAlignStart, text: "Certificate", want: TextAlignLeft},
+		{name: "End of Latin text", align: TextAlignEnd, text: "Certificate", want: TextAlignRight},
+		{name: "Start of Khmer text", align: TextAlignStart, text: "វិញ្ញាបនបត្រ", want: TextAlignLeft},
+		{name: "Start of Arabic text", align: TextAlignStart, text: "شهادة تقدير", want: TextAlignRight},
+		{name: "End of Arabic text", align: TextAlignEnd, text: "شهادة تقدير", want: TextAlignLeft},
+		{name: "Direction from first strong character", align: TextAlignStart, text: "2025 شهادة AutoCert", want: TextAlignRight},
+		{name: "Physical alignment is kept", align: TextAlignLeft, text: "شهادة تقدير", want: TextAlignLeft},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.align.resolve(tt.text); got != tt.want {
+				t.Errorf("resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextTransform(t *testing.T) {
+	tests := []struct {
+		transform TextTransform
+		text      string
+		want      string
+	}{
+		{transform: "", text: "sok Dara", want: "sok Dara"},
+		{transform: TextTransformUppercase, text: "straße sok", want: "STRASSE SOK"},
+		{transform: TextTransformLowercase, text: "SOK Dara", want: "sok dara"},
+		{transform: TextTransformTitlecase, text: "ronald mcDonald", want: "Ronald McDonald"},
+		{transform: TextTransformUppercase, text: "សុខ", want: "សុខ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.transform)+" "+tt.text, func(t *testing.T) {
+			if got := tt.transform.apply(tt.text); got != tt.want {
+				t.Errorf("apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerticalAlignOffset(t *testing.T) {
+	tests := []struct {
+		align VerticalAlign
+		want  float64
+	}{
+		{align: VerticalAlignTop, want: 0},
+		{align: VerticalAlignMiddle, want: 15},
+		{align: "", want: 15},
+		{align: VerticalAlignBottom, want: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.align), func(t *testing.T) {
+			if got := tt.align.offset(50, 20); got != tt.want {
+				t.Errorf("offset() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextRendererGolden(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	tests := []struct {
+		name       string
+		font       Font
+		typography Typography
+		text       string
+		align      TextAlign
+	}{
+		{
+			// Coeng (subscript consonant) and vowel reordering
+			name:  "khmer",
+			font:  Font{Name: "Khmer OS Siemreap", Size: 20},
+			text:  "សូមអបអរសាទរ ស្រ្តី ក្សត្រ",
+			align: TextAlignLeft,
+		},
+		{
+			// Khmer clusters drawn by the fallback font of a Latin font
+			name:  "khmer_fallback",
+			font:  Font{Name: "Calibri", Size: 20, Fallbacks: []string{"Khmer OS Siemreap"}},
+			text:  "Certificate វិញ្ញាបនបត្រ",
+			align: TextAlignCenter,
+		},
+		{
+			// Stacked tone marks and sara am
+			name:  "thai",
+			font:  Font{Name: "FreeSerif", Size: 20},
+			text:  "ประกาศนียบัตร น้ำ ที่",
+			align: TextAlignCenter,
+		},
+		{
+			// Joined letters, right-to-left and aligned to the right as the start of the text
+			name:  "arabic",
+			font:  Font{Name: "Amiri", Size: 20},
+			text:  "شهادة تقدير محمد",
+			align: TextAlignStart,
+		},
+		{
+			// Latin and numbers embedded in right-to-left text
+			name:  "arabic_mixed",
+			font:  Font{Name: "Amiri", Size: 20},
+			text:  "شهادة AutoCert رقم 2025",
+			align: TextAlignEnd,
+		},
+		{
+			// Spacing between clusters, the Khmer subscript consonants stay attached
+			name:       "letter_spacing",
+			font:       Font{Name: "Calibri", Size: 16, Fallbacks: []string{"Khmer OS Siemreap"}},
+			typography: Typography{LetterSpacing: 0.2},
+			text:       "Sok ស្រ្តី",
+			align:      TextAlignLeft,
+		},
+		{
+			name:       "uppercase_bottom",
+			font:       Font{Name: "Calibri", Size: 20},
+			typography: Typography{VerticalAlign: VerticalAlignBottom, Transform: TextTransformUppercase},
+			text:       "certificate of completion",
+			align:      TextAlignRight,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.font.Color = "#000000"
+			tr, err := NewTextRenderer(cfg, Rect{Width: 300, Height: 50}, tt.font, Wrap{}, tt.typography, Settings{})
+			if err != nil {
+				t.Fatalf("Failed to create text renderer: %v", err)
+			}
+
+			c, err := tr.renderCanvas(tt.text, tt.align)
+			if err != nil {
+				t.Fatalf("Failed to render text: %v", err)
+			}
+			got := rasterizer.Draw(c, canvas.DPMM(4), canvas.DefaultColorSpace)
+
+			goldenPath := filepath.Join("testdata", "golden", tt.name+".png")
+			if *update {
+				writePng(t, goldenPath, got)
+			}
+
+			want := readPng(t, goldenPath)
+			if diff := diffImage(got, want); diff > 0.005 {
+				actualPath := filepath.Join(t.TempDir(), tt.name+".png")
+				writePng(t, actualPath, got)
+				t.Errorf("Rendered text differs from %s by %.2f%% of pixels, see %s", goldenPath, diff*100, actualPath)
+			}
+		})
+	}
+}
+
+func readPng(t *testing.T, path string) image.Image {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open golden image: %v", err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("Failed to decode golden image: %v", err)
+	}
+	return img
+}
+
+func writePng(t *testing.T, path string, img image.Image) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+}
+
+// Return the fraction of pixels which differ noticeably, anti-aliasing may differ slightly between platforms
+func diffImage(a, b image.Image) float64 {
+	if a.Bounds() != b.Bounds() {
+		return 1
+	}
+
+	bounds := a.Bounds()
+	diff := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			if absDiff(r1, r2) > 0x2000 || absDiff(g1, g2) > 0x2000 || absDiff(b1, b2) > 0x2000 || absDiff(a1, a2) > 0x2000 {
+				diff++
+			}
+		}
+	}
+
+	return float64(diff) / float64(bounds.Dx()*bounds.Dy())
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
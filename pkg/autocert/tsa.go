@@ -0,0 +1,167 @@
+package autocert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+)
+
+var (
+	oidExtKeyUsage  = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidAnyPolicy    = asn1.ObjectIdentifier{2, 5, 29, 32, 0}
+	oidTimeStamping = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}
+)
+
+// Timestamper returns an RFC 3161 time-stamp token over the SHA-256 digest of some data
+type Timestamper interface {
+	Timestamp(digest []byte) ([]byte, error)
+}
+
+// LocalTimestampAuthority issues RFC 3161 time-stamp tokens in process, a stand-in for a remote time-stamp authority
+type LocalTimestampAuthority struct {
+	signer cmsSigner
+
+	mu     sync.Mutex
+	serial int64
+}
+
+func NewLocalTimestampAuthority(cert *x509.Certificate, key crypto.Signer, chain []*x509.Certificate) (*LocalTimestampAuthority, error) {
+	if !slices.Contains(cert.ExtKeyUsage, x509.ExtKeyUsageTimeStamping) {
+		return nil, errors.New("time-stamp authority certificate must have the time stamping extended key usage")
+	}
+
+	return &LocalTimestampAuthority{
+		signer: cmsSigner{cert: cert, chain: chain, key: key},
+	}, nil
+}
+
+// Create a time-stamp authority with a new self-signed certificate, valid for ten years
+func NewSelfSignedTimestampAuthority(name string) (*LocalTimestampAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate time-stamp authority key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return nil, err
+	}
+
+	// RFC 3161 requires the extended key usage to be critical, which x509 does not do for ExtKeyUsage
+	eku, err := asn1.Marshal([]asn1.ObjectIdentifier{oidTimeStamping})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidExtKeyUsage, Critical: true, Value: eku},
+		},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create time-stamp authority certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLocalTimestampAuthority(cert, key, nil)
+}
+
+func (tsa *LocalTimestampAuthority) Certificate() *x509.Certificate {
+	return tsa.signer.cert
+}
+
+func (tsa *LocalTimestampAuthority) Timestamp(digest []byte) ([]byte, error) {
+	if len(digest) != sha256.Size {
+		return nil, fmt.Errorf("digest must be SHA-256, got %d bytes", len(digest))
+	}
+
+	tsa.mu.Lock()
+	tsa.serial++
+	serial := tsa.serial
+	tsa.mu.Unlock()
+
+	var b cryptobyte.Builder
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1Int64(1)
+		b.AddASN1ObjectIdentifier(oidAnyPolicy)
+		b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+			addAlgorithmIdentifier(b, oidSHA256, false)
+			b.AddASN1OctetString(digest)
+		})
+		b.AddASN1Int64(serial)
+		b.AddASN1GeneralizedTime(time.Now().UTC().Truncate(time.Second))
+	})
+	tstInfo, err := b.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.Sum256(tstInfo)
+	return tsa.signer.sign(oidTSTInfo, tstInfo, h[:], nil)
+}
+
+// Verify a time-stamp token was issued over the digest and return its time and the certificate of the authority
+func verifyTimestampToken(token, digest []byte) (time.Time, *x509.Certificate, error) {
+	sd, err := parseCMS(token)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	if !sd.contentType.Equal(oidTSTInfo) || sd.content == nil {
+		return time.Time{}, nil, errors.New("time-stamp token has no TSTInfo")
+	}
+
+	h := sha256.Sum256(sd.content)
+	cert, err := sd.verify(h[:])
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("invalid time-stamp token: %w", err)
+	}
+
+	tstInfo := cryptobyte.String(sd.content)
+	var info, imprint, alg cryptobyte.String
+	var version int64
+	var policy, algOid asn1.ObjectIdentifier
+	var imprintDigest []byte
+	var serial big.Int
+	var genTime time.Time
+	if !tstInfo.ReadASN1(&info, cbasn1.SEQUENCE) ||
+		!info.ReadASN1Integer(&version) ||
+		!info.ReadASN1ObjectIdentifier(&policy) ||
+		!info.ReadASN1(&imprint, cbasn1.SEQUENCE) ||
+		!imprint.ReadASN1(&alg, cbasn1.SEQUENCE) ||
+		!alg.ReadASN1ObjectIdentifier(&algOid) ||
+		!imprint.ReadASN1Bytes(&imprintDigest, cbasn1.OCTET_STRING) ||
+		!info.ReadASN1Integer(&serial) ||
+		!info.ReadASN1GeneralizedTime(&genTime) {
+		return time.Time{}, nil, errors.New("malformed TSTInfo")
+	}
+	if !algOid.Equal(oidSHA256) || !bytes.Equal(imprintDigest, digest) {
+		return time.Time{}, nil, errors.New("time-stamp token was not issued for this signature")
+	}
+
+	return genTime, cert, nil
+}
@@ -0,0 +1,522 @@
+package autocert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+/*
+ * The value of a column annotate is either the name of a CSV column or a template which interpolates columns in {{ }}
+ *
+ *	Mr. {{FirstName}} {{upper LastName}}
+ *	Completed on {{date CompletedAt "2 January 2006"}}
+ *	{{default Nickname FirstName}}
+ *
+ * An action is a column, a quoted string, a number or a function call. Arguments of a function are the same
+ * except that a nested call is wrapped in parentheses, Eg: {{upper (default Nickname FirstName)}}.
+ * A column whose name is not a single word (Eg: "First Name") is read with {{column "First Name"}}.
+ * Only the functions below can be called, there is no access to anything else than the columns of the row.
+ */
+
+var ErrInvalidValueTemplate = errors.New("invalid value template")
+
+type ValueTemplate struct {
+	source string
+	parts  []valueNode
+}
+
+type valueNode interface {
+	eval(row map[string]string) (string, error)
+}
+
+type literalNode string
+
+func (n literalNode) eval(map[string]string) (string, error) {
+	return string(n), nil
+}
+
+// Text of the template outside of the actions
+type textNode string
+
+func (n textNode) eval(map[string]string) (string, error) {
+	return string(n), nil
+}
+
+type columnNode string
+
+func (n columnNode) eval(row map[string]string) (string, error) {
+	return row[string(n)], nil
+}
+
+type callNode struct {
+	name string
+	fn   valueFunc
+	args []valueNode
+}
+
+func (n callNode) eval(row map[string]string) (string, error) {
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(row)
+		if err != nil {
+			return "", err
+		}
+		args[i] = value
+	}
+
+	value, err := n.fn.call(args)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", n.name, err)
+	}
+	return value, nil
+}
+
+type valueFunc struct {
+	minArgs int
+	maxArgs int
+	call    func(args []string) (string, error)
+}
+
+var valueFuncs = map[string]valueFunc{
+	"upper": {minArgs: 1, maxArgs: 1, call: func(args []string) (string, error) {
+		return cases.Upper(language.Und).String(args[0]), nil
+	}},
+	"lower": {minArgs: 1, maxArgs: 1, call: func(args []string) (string, error) {
+		return cases.Lower(language.Und).String(args[0]), nil
+	}},
+	"title": {minArgs: 1, maxArgs: 1, call: func(args []string) (string, error) {
+		return cases.Title(language.Und, cases.NoLower).String(args[0]), nil
+	}},
+	"trim": {minArgs: 1, maxArgs: 1, call: func(args []string) (string, error) {
+		return strings.TrimSpace(args[0]), nil
+	}},
+	// default value fallback: the value, or the fallback if the value is blank
+	"default": {minArgs: 2, maxArgs: 2, call: func(args []string) (string, error) {
+		if strings.TrimSpace(args[0]) == "" {
+			return args[1], nil
+		}
+		return args[0], nil
+	}},
+	// date value layout [inputLayout]: format a date with a Go layout, see dateInputLayouts for the accepted input
+	"date": {minArgs: 2, maxArgs: 3, call: formatDate},
+	// number value [decimals]: format a number with thousands separators
+	"number": {minArgs: 1, maxArgs: 2, call: formatNumber},
+}
+
+// Layouts tried in order to parse a date when the input layout is not given
+var dateInputLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"02-Jan-2006",
+	"2 January 2006",
+	"January 2, 2006",
+	"Jan 2, 2006",
+}
+
+func formatDate(args []string) (string, error) {
+	value := strings.TrimSpace(args[0])
+	if value == "" {
+		return "", nil
+	}
+
+	layouts := dateInputLayouts
+	if len(args) == 3 {
+		layouts = []string{args[2]}
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format(args[1]), nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot parse %q as a date", value)
+}
+
+func formatNumber(args []string) (string, error) {
+	value := strings.TrimSpace(args[0])
+	if value == "" {
+		return "", nil
+	}
+
+	number, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+	if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+		return "", fmt.Errorf("cannot parse %q as a number", value)
+	}
+
+	// Keep the decimals of the value if not given
+	decimals := -1
+	if len(args) == 2 {
+		decimals, err = strconv.Atoi(args[1])
+		if err != nil || decimals < 0 || decimals > 10 {
+			return "", fmt.Errorf("decimals must be a number from 0 to 10, got %q", args[1])
+		}
+	}
+
+	formatted := strconv.FormatFloat(math.Abs(number), 'f', decimals, 64)
+	integer, fraction, hasFraction := strings.Cut(formatted, ".")
+
+	var b strings.Builder
+	if number < 0 {
+		b.WriteByte('-')
+	}
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if hasFraction {
+		b.WriteByte('.')
+		b.WriteString(fraction)
+	}
+
+	return b.String(), nil
+}
+
+// Whether the value interpolates columns, otherwise it is the name of a column
+func IsValueTemplate(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+func ParseValueTemplate(value string) (*ValueTemplate, error) {
+	vt := &ValueTemplate{source: value}
+
+	// The name of a column, as before templates were supported
+	if !IsValueTemplate(value) {
+		vt.parts = []valueNode{columnNode(value)}
+		return vt, nil
+	}
+
+	parts, err := parseValueParts(value)
+	if err != nil {
+		return nil, err
+	}
+	vt.parts = parts
+
+	return vt, nil
+}
+
+// Parse a template whose text outside of {{ }} is literal, Eg: the title of a document. Unlike ParseValueTemplate,
+// a text without actions is not the name of a column.
+func ParseTextTemplate(text string) (*ValueTemplate, error) {
+	parts, err := parseValueParts(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ValueTemplate{source: text, parts: parts}, nil
+}
+
+// Split the text into literal parts and the {{ }} actions between them
+func parseValueParts(value string) ([]valueNode, error) {
+	var parts []valueNode
+
+	rest := value
+	for rest != "" {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			parts = append(parts, textNode(rest))
+			break
+		}
+		if start > 0 {
+			parts = append(parts, textNode(rest[:start]))
+		}
+
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unclosed action at %q", ErrInvalidValueTemplate, rest[start:])
+		}
+
+		node, err := parseAction(rest[start+2 : start+end])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidValueTemplate, err)
+		}
+		parts = append(parts, node)
+
+		rest = rest[start+end+2:]
+	}
+
+	return parts, nil
+}
+
+func (vt *ValueTemplate) String() string {
+	return vt.source
+}
+
+// Return the unique columns read by the template in order of appearance
+func (vt *ValueTemplate) Columns() []string {
+	return partColumns(vt.parts)
+}
+
+func partColumns(parts []valueNode) []string {
+	var columns []string
+
+	var walk func(node valueNode)
+	walk = func(node valueNode) {
+		switch n := node.(type) {
+		case columnNode:
+			if !slices.Contains(columns, string(n)) {
+				columns = append(columns, string(n))
+			}
+		case callNode:
+			for _, arg := range n.args {
+				walk(arg)
+			}
+		}
+	}
+
+	for _, part := range parts {
+		walk(part)
+	}
+
+	return columns
+}
+
+// Check that every column read by the template is one of the CSV headers
+func (vt *ValueTemplate) Validate(headers []string) error {
+	for _, column := range vt.Columns() {
+		if !slices.Contains(headers, column) {
+			return fmt.Errorf("%w: column %q is not in the table", ErrInvalidValueTemplate, column)
+		}
+	}
+	return nil
+}
+
+// Render the template with a row of the CSV, a column missing in the row is empty
+func (vt *ValueTemplate) Execute(row map[string]string) (string, error) {
+	var b strings.Builder
+	for _, part := range vt.parts {
+		value, err := part.eval(row)
+		if err != nil {
+			return "", fmt.Errorf("failed to render %q: %w", vt.source, err)
+		}
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenLeftParen
+	tokenRightParen
+	// Comparison and boolean operators of a condition, see Condition
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func tokenize(action string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(action); {
+		r, size := utf8.DecodeRuneInString(action[i:])
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen})
+			i += size
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRightParen})
+			i += size
+		case strings.ContainsRune("=!<>&|", r):
+			op := action[i : i+1]
+			if i+1 < len(action) && slices.Contains(operators, action[i:i+2]) {
+				op = action[i : i+2]
+			}
+			if !slices.Contains(operators, op) {
+				return nil, fmt.Errorf("unknown operator %q", op)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: op})
+			i += len(op)
+		case r == '"' || r == '`':
+			quoted, err := strconv.QuotedPrefix(action[i:])
+			if err != nil {
+				return nil, fmt.Errorf("unterminated string at %q", action[i:])
+			}
+			value, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", quoted)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value})
+			i += len(quoted)
+		case r == '-' || unicode.IsDigit(r):
+			j := i + size
+			for j < len(action) && (isDigit(action[j]) || action[j] == '.') {
+				j++
+			}
+			if _, err := strconv.ParseFloat(action[i:j], 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q", action[i:j])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: action[i:j]})
+			i = j
+		case isWordRune(r):
+			j := i
+			for j < len(action) {
+				r, size := utf8.DecodeRuneInString(action[j:])
+				if !isWordRune(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += size
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: action[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+
+	return tokens, nil
+}
+
+var operators = []string{"==", "!=", "<", "<=", ">", ">=", "&&", "||", "!"}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+// Column names may be in any script (Eg: Khmer), marks are part of the word
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.Is(unicode.M, r)
+}
+
+type actionParser struct {
+	tokens []token
+	pos    int
+}
+
+func parseAction(action string) (valueNode, error) {
+	tokens, err := tokenize(action)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty action {{}}")
+	}
+
+	p := &actionParser{tokens: tokens}
+
+	var node valueNode
+	if first := tokens[0]; first.kind == tokenWord && isValueFunc(first.value) {
+		node, err = p.parseCall()
+	} else {
+		node, err = p.parseArg()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s in {{%s}}, a function is needed to combine values", p.tokens[p.pos].describe(), strings.TrimSpace(action))
+	}
+
+	return node, nil
+}
+
+func isValueFunc(name string) bool {
+	_, ok := valueFuncs[name]
+	return ok || name == "column"
+}
+
+// call = function arg*
+func (p *actionParser) parseCall() (valueNode, error) {
+	name := p.tokens[p.pos].value
+	p.pos++
+
+	var args []valueNode
+	for p.pos < len(p.tokens) && p.tokens[p.pos].kind != tokenRightParen {
+		arg, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+
+	if name == "column" {
+		if len(args) != 1 {
+			return nil, errors.New("column takes the column name as a string")
+		}
+		column, ok := args[0].(literalNode)
+		if !ok {
+			return nil, errors.New("column takes the column name as a string")
+		}
+		return columnNode(column), nil
+	}
+
+	fn := valueFuncs[name]
+	if len(args) < fn.minArgs || len(args) > fn.maxArgs {
+		if fn.minArgs == fn.maxArgs {
+			return nil, fmt.Errorf("%s takes %d arguments, got %d", name, fn.minArgs, len(args))
+		}
+		return nil, fmt.Errorf("%s takes %d to %d arguments, got %d", name, fn.minArgs, fn.maxArgs, len(args))
+	}
+
+	return callNode{name: name, fn: fn, args: args}, nil
+}
+
+// arg = column | string | number | "(" call ")"
+func (p *actionParser) parseArg() (valueNode, error) {
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case tokenString, tokenNumber:
+		return literalNode(tok.value), nil
+	case tokenWord:
+		if isValueFunc(tok.value) {
+			return nil, fmt.Errorf("function %s must be wrapped in parentheses when used as an argument", tok.value)
+		}
+		return columnNode(tok.value), nil
+	case tokenLeftParen:
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenWord || !isValueFunc(p.tokens[p.pos].value) {
+			return nil, errors.New("parentheses must contain a function call")
+		}
+		node, err := p.parseCall()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenRightParen {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	default:
+		return nil, fmt.Errorf("unexpected %s", tok.describe())
+	}
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokenLeftParen:
+		return "("
+	case tokenRightParen:
+		return ")"
+	case tokenString:
+		return strconv.Quote(t.value)
+	default:
+		return t.value
+	}
+}
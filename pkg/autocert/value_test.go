@@ -0,0 +1,172 @@
+package autocert
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValueTemplateExecute(t *testing.T) {
+	row := map[string]string{
+		"FirstName":   "dara",
+		"LastName":    "Sok",
+		"Nickname":    "",
+		"CompletedAt": "2025-03-07",
+		"Score":       "1234567.891",
+		"First Name":  "Dara",
+		"ឈ្មោះ":       "សុខ",
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "Column name", value: "LastName", expected: "Sok"},
+		{name: "Missing column is empty", value: "Missing", expected: ""},
+		{name: "Interpolation", value: "Mr. {{FirstName}} {{LastName}}", expected: "Mr. dara Sok"},
+		{name: "Function", value: "{{upper LastName}}, {{title FirstName}}", expected: "SOK, Dara"},
+		{name: "Date", value: `Completed on {{date CompletedAt "2 January 2006"}}`, expected: "Completed on 7 March 2025"},
+		{name: "Date with input layout", value: `{{date "07/03/2025" "Jan 2, 2006" "02/01/2006"}}`, expected: "Mar 7, 2025"},
+		{name: "Number", value: "{{number Score}}", expected: "1,234,567.891"},
+		{name: "Number with decimals", value: "{{number Score 1}}", expected: "1,234,567.9"},
+		{name: "Negative number", value: `{{number "-1234" 2}}`, expected: "-1,234.00"},
+		{name: "Default", value: "{{default Nickname FirstName}}", expected: "dara"},
+		{name: "Nested call", value: "{{upper (default Nickname FirstName)}}", expected: "DARA"},
+		{name: "Column with space", value: `{{column "First Name"}}`, expected: "Dara"},
+		{name: "Khmer column", value: "{{ឈ្មោះ}}", expected: "សុខ"},
+		{name: "Spaces in action", value: "{{ upper  LastName }}", expected: "SOK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vt, err := ParseValueTemplate(tt.value)
+			if err != nil {
+				t.Fatalf("ParseValueTemplate(%q) failed: %v", tt.value, err)
+			}
+
+			got, err := vt.Execute(row)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Execute() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValueTemplateExecuteError(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		row   map[string]string
+	}{
+		{name: "Invalid date", value: `{{date CompletedAt "2006"}}`, row: map[string]string{"CompletedAt": "yesterday"}},
+		{name: "Invalid number", value: "{{number Score}}", row: map[string]string{"Score": "A+"}},
+		{name: "Invalid decimals", value: `{{number Score "two"}}`, row: map[string]string{"Score": "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vt, err := ParseValueTemplate(tt.value)
+			if err != nil {
+				t.Fatalf("ParseValueTemplate(%q) failed: %v", tt.value, err)
+			}
+
+			if _, err := vt.Execute(tt.row); err == nil {
+				t.Errorf("Expected Execute to fail for %q", tt.value)
+			}
+		})
+	}
+}
+
+func TestParseValueTemplateError(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "Unclosed action", value: "Mr. {{FirstName"},
+		{name: "Empty action", value: "{{ }}"},
+		{name: "Unknown function", value: "{{shout FirstName}}"},
+		{name: "Two columns without function", value: "{{FirstName LastName}}"},
+		{name: "Wrong argument count", value: "{{upper FirstName LastName}}"},
+		{name: "Function as argument", value: "{{upper default Nickname FirstName}}"},
+		{name: "Missing closing parenthesis", value: "{{upper (default Nickname FirstName}}"},
+		{name: "Unterminated string", value: `{{date CompletedAt "2 January}}`},
+		{name: "Column without name", value: "{{column FirstName}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseValueTemplate(tt.value)
+			if !errors.Is(err, ErrInvalidValueTemplate) {
+				t.Errorf("ParseValueTemplate(%q) error = %v, want %v", tt.value, err, ErrInvalidValueTemplate)
+			}
+		})
+	}
+}
+
+func TestValueTemplateValidate(t *testing.T) {
+	headers := []string{"FirstName", "LastName", "CompletedAt"}
+
+	tests := []struct {
+		name    string
+		value   string
+		columns []string
+		wantErr bool
+	}{
+		{name: "Known columns", value: `{{FirstName}} {{upper LastName}} {{date CompletedAt "2006"}}`, columns: []string{"FirstName", "LastName", "CompletedAt"}},
+		{name: "Repeated column", value: "{{FirstName}} {{lower FirstName}}", columns: []string{"FirstName"}},
+		{name: "Unknown column", value: "{{default Nickname FirstName}}", columns: []string{"Nickname", "FirstName"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vt, err := ParseValueTemplate(tt.value)
+			if err != nil {
+				t.Fatalf("ParseValueTemplate(%q) failed: %v", tt.value, err)
+			}
+
+			if columns := vt.Columns(); !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("Columns() = %v, want %v", columns, tt.columns)
+			}
+
+			err = vt.Validate(headers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTextTemplateExecute(t *testing.T) {
+	row := map[string]string{"FirstName": "Jane", "LastName": "Doe"}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "Literal text", value: "LastName", expected: "LastName"},
+		{name: "Empty", value: "", expected: ""},
+		{name: "Interpolation", value: "Certificate of Completion – {{FirstName}} {{upper LastName}}", expected: "Certificate of Completion – Jane DOE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vt, err := ParseTextTemplate(tt.value)
+			if err != nil {
+				t.Fatalf("ParseTextTemplate(%q) failed: %v", tt.value, err)
+			}
+
+			got, err := vt.Execute(row)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Execute() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
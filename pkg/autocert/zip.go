@@ -2,8 +2,10 @@ package autocert
 
 import (
 	"archive/zip"
+	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 )
 
@@ -77,18 +79,62 @@ func ZipDir(dir string, zipFile string) error {
 }
 
 func ZipFiles(inFiles []string, zipFile string) error {
-	zipWriter, archive, err := createZipArchive(zipFile)
+	entries := make([]ZipEntry, len(inFiles))
+	for i, filePath := range inFiles {
+		entries[i] = ZipEntry{FilePath: filePath, ArchivePath: filepath.Base(filePath)}
+	}
+	return ZipEntries(entries, zipFile)
+}
+
+// A file to zip and its path in the archive, Eg: "Physics/1001_Doe.pdf"
+type ZipEntry struct {
+	FilePath    string
+	ArchivePath string
+}
+
+// Zip the files at their path in the archive
+func ZipEntries(entries []ZipEntry, zipFile string) error {
+	zw, err := NewZipWriter(zipFile)
 	if err != nil {
 		return err
 	}
-	defer zipWriter.Close()
-	defer archive.Close()
 
-	for _, filePath := range inFiles {
-		if err := addFileToZip(archive, filePath, filepath.Base(filePath)); err != nil {
+	for _, entry := range entries {
+		if err := zw.Add(entry); err != nil {
+			zw.Close()
 			return err
 		}
 	}
 
-	return nil
+	return zw.Close()
+}
+
+// ZipWriter zips the files one by one, Eg: as they are generated, so that each file can be removed once it is added.
+// The archive/zip writer switches to ZIP64 when there are more than 65535 entries or a file or the archive is over
+// 4 GB.
+type ZipWriter struct {
+	file    *os.File
+	archive *zip.Writer
+}
+
+func NewZipWriter(zipFile string) (*ZipWriter, error) {
+	file, archive, err := createZipArchive(zipFile)
+	if err != nil {
+		return nil, err
+	}
+	return &ZipWriter{file: file, archive: archive}, nil
+}
+
+// Add the file at its path in the archive
+func (zw *ZipWriter) Add(entry ZipEntry) error {
+	return addFileToZip(zw.archive, entry.FilePath, path.Clean(filepath.ToSlash(entry.ArchivePath)))
+}
+
+func (zw *ZipWriter) Close() error {
+	// The central directory, and the ZIP64 end of it, is written on close
+	if err := zw.archive.Close(); err != nil {
+		zw.file.Close()
+		return fmt.Errorf("failed to write ZIP central directory: %w", err)
+	}
+	return zw.file.Close()
 }
@@ -0,0 +1,60 @@
+package autocert
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestZipEntries(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "certificate.pdf")
+	if err := os.WriteFile(file, []byte("%PDF-1.7"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		entries []ZipEntry
+	}{
+		{name: "Folders", entries: []ZipEntry{
+			{FilePath: file, ArchivePath: "Physics/1001_Doe.pdf"},
+			{FilePath: file, ArchivePath: "Chemistry/1002_Roe.pdf"},
+			{FilePath: file, ArchivePath: "1003_Poe.pdf"},
+		}},
+		// Over the 65535 entries of a ZIP without ZIP64
+		{name: "ZIP64", entries: func() []ZipEntry {
+			entries := make([]ZipEntry, 70000)
+			for i := range entries {
+				entries[i] = ZipEntry{FilePath: file, ArchivePath: fmt.Sprintf("certificate_%d.pdf", i+1)}
+			}
+			return entries
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zipFile := filepath.Join(t.TempDir(), "certificates.zip")
+			if err := ZipEntries(tt.entries, zipFile); err != nil {
+				t.Fatalf("ZipEntries failed: %v", err)
+			}
+
+			r, err := zip.OpenReader(zipFile)
+			if err != nil {
+				t.Fatalf("Failed to open ZIP: %v", err)
+			}
+			defer r.Close()
+
+			if len(r.File) != len(tt.entries) {
+				t.Fatalf("Entries = %d, want %d", len(r.File), len(tt.entries))
+			}
+			for i, f := range r.File {
+				if f.Name != tt.entries[i].ArchivePath {
+					t.Errorf("Entry %d = %q, want %q", i, f.Name, tt.entries[i].ArchivePath)
+				}
+			}
+		})
+	}
+}